@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/roster"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/pkg/database"
+
+	"github.com/joho/godotenv"
+)
+
+// Pemakaian:
+//
+//	go run ./cmd/import -file siswa.xlsx -dry-run
+//	go run ./cmd/import -file siswa.csv -out hasil.csv
+//	go run ./cmd/import -file siswa.csv -mode invite
+//
+// Dengan -out hasil per baris (termasuk password awal) ditulis ke CSV dan tidak dicetak ke layar.
+func main() {
+	file := flag.String("file", "", "roster file (.csv or .xlsx)")
+	mode := flag.String("mode", models.ImportModePassword, "password or invite")
+	dryRun := flag.Bool("dry-run", false, "validate rows without writing to the database")
+	out := flag.String("out", "", "write per-row results to this CSV file")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *mode != models.ImportModePassword && *mode != models.ImportModeInvite {
+		log.Fatalf("Invalid mode %q, use password or invite", *mode)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *file, err)
+	}
+	records, err := roster.Parse(*file, f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("Failed to parse %s: %v", *file, err)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	cfg := config.Load()
+	if err := cfg.ValidateAtlasConnection(); err != nil {
+		log.Fatal("Atlas configuration error:", err)
+	}
+
+	db, err := database.Connect(cfg.MongoURI, cfg.DBName)
+	if err != nil {
+		log.Fatal("Failed to connect to MongoDB Atlas:", err)
+	}
+
+	report, err := services.NewStudentImportService(db, cfg).Import(records, &models.StudentImportOptions{
+		DryRun: *dryRun,
+		Mode:   *mode,
+	})
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	if *out != "" {
+		if err := writeResults(*out, report.Results); err != nil {
+			log.Fatalf("Failed to write %s: %v", *out, err)
+		}
+		report.Results = nil
+	}
+
+	output, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(output))
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func writeResults(path string, results []models.StudentImportResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"line", "nis", "name", "class", "action", "initial_password", "invited", "errors"})
+	for _, result := range results {
+		w.Write([]string{
+			strconv.Itoa(result.Line),
+			result.NIS,
+			result.Name,
+			result.Class,
+			result.Action,
+			result.InitialPassword,
+			strconv.FormatBool(result.Invited),
+			strings.Join(result.Errors, "; "),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 	"strings"
 	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/middleware"
 	"ujikom-backend/internal/routes"
+	"ujikom-backend/internal/services"
 	"ujikom-backend/pkg/database"
 
 	"github.com/gofiber/fiber/v2"
@@ -38,6 +40,10 @@ func main() {
 		log.Fatal("Atlas configuration error:", err)
 	}
 
+	if err := cfg.ValidateSigningSecret(); err != nil {
+		log.Fatal("Configuration error: ", err)
+	}
+
 	atlasInfo := extractAtlasInfo(cfg.MongoURI)
 	log.Printf("Atlas Cluster: %s", atlasInfo)
 
@@ -46,9 +52,23 @@ func main() {
 		log.Fatal("Failed to connect to MongoDB Atlas:", err)
 	}
 
+	// tanpa index unik per hari, check in bersamaan bisa membuat record ganda
+	if err := database.RequireIndexes(db); err != nil {
+		log.Fatal("Database is not ready: ", err)
+	}
+
+	services.BootstrapAdmins(db, cfg)
+
+	// batas body default Fiber 4 MB, dinaikkan jika SELFIE_MAX_BYTES lebih besar
+	bodyLimit := 4 * 1024 * 1024
+	if cfg.SelfieMaxBytes+512*1024 > bodyLimit {
+		bodyLimit = cfg.SelfieMaxBytes + 512*1024
+	}
+
 	app := fiber.New(fiber.Config{
 		AppName:      "Ujikom API v" + Version + " (Atlas)",
 		ServerHeader: "Ujikom-Backend-Atlas",
+		BodyLimit:    bodyLimit,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -66,15 +86,17 @@ func main() {
 		},
 	})
 
+	app.Use(middleware.ClientIPMiddleware(cfg))
 	app.Use(logger.New(logger.Config{
-		Format: "[${time}] ${status} - ${method} ${path} - ${latency} | ${ip} | Atlas\n",
+		Format: "[${time}] ${status} - ${method} ${path} - ${latency} | ${locals:client_ip} | Atlas\n",
 	}))
 	app.Use(recover.New())
 
 	app.Use(func(c *fiber.Ctx) error {
 		c.Set("Access-Control-Allow-Origin", "*")
 		c.Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-		c.Set("Access-Control-Allow-Headers", "Origin,Content-Type,Accept,Authorization,X-Requested-With")
+		c.Set("Access-Control-Allow-Headers", "Origin,Content-Type,Accept,Authorization,X-Requested-With,"+
+			"Idempotency-Key,X-Device-ID,X-Device-Timestamp,X-Device-Nonce,X-Device-Signature,Last-Event-ID")
 		c.Set("Access-Control-Allow-Credentials", "true")
 		c.Set("X-API-Version", Version)
 		c.Set("X-Build-Time", BuildTime)
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/migrations"
+	"ujikom-backend/pkg/database"
+
+	"github.com/joho/godotenv"
+)
+
+// Pemakaian:
+//
+//	go run ./cmd/migrate -list
+//	go run ./cmd/migrate -name normalize_classes -dry-run
+//	go run ./cmd/migrate -name normalize_classes
+//	go run ./cmd/migrate -name rebucket_attendance_dates -dry-run
+func main() {
+	name := flag.String("name", "", "migration to run")
+	dryRun := flag.Bool("dry-run", false, "report changes without writing to the database")
+	list := flag.Bool("list", false, "list available migrations")
+	flag.Parse()
+
+	if *list || *name == "" {
+		fmt.Println("Available migrations:")
+		for _, m := range migrations.All() {
+			fmt.Printf("  %-28s %s\n", m.Name, m.Description)
+		}
+		if *name == "" && !*list {
+			os.Exit(2)
+		}
+		return
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	cfg := config.Load()
+	if err := cfg.ValidateAtlasConnection(); err != nil {
+		log.Fatal("Atlas configuration error:", err)
+	}
+
+	db, err := database.Connect(cfg.MongoURI, cfg.DBName)
+	if err != nil {
+		log.Fatal("Failed to connect to MongoDB Atlas:", err)
+	}
+
+	report, err := migrations.Run(context.Background(), db, cfg, *name, *dryRun)
+	if err != nil {
+		log.Fatalf("Migration %s failed: %v", *name, err)
+	}
+
+	output, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(output))
+}
@@ -18,6 +18,85 @@ type Config struct {
 	APIRateLimit int
 	APITimeout   int
 	LogLevel     string
+	AdminEmails  []string
+
+	// Secret link unduhan bertanda tangan dan ticket stream, terpisah dari JWT
+	SigningSecret string
+
+	// Rate limiting (request per menit)
+	RateLimitBackend string
+	LoginRateLimit   int // per IP + email
+	LoginIPRateLimit int // per IP, longgar karena satu sekolah bisa berbagi NAT
+	CheckInRateLimit int
+
+	// Device binding
+	MaxDevicesPerUser     int
+	DeviceBindingEnforced bool
+
+	// Check in offline: record lebih tua dari ini ditolak saat sync
+	OfflineMaxAgeHours int
+
+	// QR kiosk
+	KioskQRRotationSeconds int
+	KioskRadiusMeters      float64 // jarak maksimal HP siswa dari kiosk saat scan QR
+
+	// CIDR proxy/load balancer yang boleh mengirim header IP client, dan satu-satunya
+	// header yang di-set proxy tersebut (X-Forwarded-For, Forwarded, X-Real-IP, CF-Connecting-IP)
+	TrustedProxies     []string
+	TrustedProxyHeader string
+
+	// Export rekap absensi
+	ReportSyncMaxCells   int // hari x siswa, di atas ini laporan dibuat di background
+	ReportRetentionHours int
+
+	// Webhook keluar: batas percobaan sebelum delivery masuk dead-letter
+	WebhookMaxAttempts    int
+	WebhookTimeoutSeconds int
+
+	// hanya untuk development: izinkan webhook ke localhost/jaringan privat
+	WebhookAllowPrivateNetworks bool
+
+	// Notifikasi orang tua/siswa. Channel tanpa konfigurasi tidak dipakai.
+	NotificationLanguage    string // bahasa default template: id atau en
+	NotificationMaxAttempts int
+	SMTPHost                string
+	SMTPPort                int
+	SMTPUsername            string
+	SMTPPassword            string
+	SMTPFrom                string
+	WhatsAppGatewayURL      string
+	WhatsAppGatewayToken    string
+	SMSGatewayURL           string
+	SMSGatewayToken         string
+	PushProvider            string // log atau http
+	PushGatewayURL          string
+	PushGatewayToken        string
+
+	// Akun orang tua/wali: masa berlaku kode undangan dari sekolah
+	GuardianInviteTTLHours int
+
+	// Import siswa: link undangan membuat password mengarah ke frontend di AppURL
+	AppURL                string
+	PasswordSetupTTLHours int
+
+	// Admin login sebagai user lain untuk bantuan/debug, token berlaku singkat
+	ImpersonationTTLMinutes int
+
+	// Penyimpanan file (selfie absensi): local atau s3 (S3-compatible, mis. MinIO)
+	StorageDriver string
+	StorageDir    string
+	S3Endpoint    string
+	S3Region      string
+	S3Bucket      string
+	S3AccessKey   string
+	S3SecretKey   string
+	S3PathStyle   bool
+
+	// Selfie check in/out
+	SelfieMaxBytes      int
+	SelfieMaxDimension  int // sisi terpanjang foto yang disimpan, dalam pixel
+	SelfieThumbSize     int
+	SelfieURLTTLMinutes int
 	
 	// School location configuration
 	SchoolLatitude  float64
@@ -29,20 +108,106 @@ type Config struct {
 	SchoolStartMinute int
 	SchoolEndHour     int
 	SchoolEndMinute   int
-	LateThreshold     int // in minutes
+	LateThreshold     int // in minutes, lewat dari ini setelah jam masuk = very_late
+
+	// Aturan status (menit): toleransi jam masuk yang masih dihitung present dan
+	// toleransi pulang sebelum jam pulang yang belum dihitung early_leave
+	LateGraceMinutes       int
+	EarlyLeaveGraceMinutes int
+
+	// Jendela waktu check in/out (waktu lokal sekolah), bisa di-override per hari
+	// lewat ATTENDANCE_WINDOW_MON..ATTENDANCE_WINDOW_SUN
+	AttendanceWindow          AttendanceWindow
+	AttendanceWindowOverrides map[time.Weekday]AttendanceWindow
+
+	// Zona waktu sekolah: WIB, WITA, WIT atau nama IANA (mis. Asia/Makassar).
+	// Semua penentuan "hari sekolah" memakai zona ini.
+	SchoolTimezone string
+	schoolLocation *time.Location
+
+	// Risk scoring configuration
+	RiskFlagThreshold   float64
+	RiskRejectThreshold float64
+	RiskWeights         map[string]float64
 }
 
 func Load() *Config {
-	return &Config{
+	appEnv := getEnv("APP_ENV", "development")
+	flagThreshold, rejectThreshold := defaultRiskThresholds(appEnv)
+
+	cfg := &Config{
 		Port:         getEnv("PORT", "8080"),
 		MongoURI:     getEnv("MONGODB_URI", ""),
 		DBName:       getEnv("DB_NAME", "ujikom"),
 		JWTSecret:    getEnv("JWT_SECRET", "ujikom-secret-key"),
-		AppEnv:       getEnv("APP_ENV", "development"),
+		AppEnv:       appEnv,
 		RedisURL:     getEnv("REDIS_URL", "redis://localhost:6379"),
 		APIRateLimit: getEnvAsInt("API_RATE_LIMIT", 100),
 		APITimeout:   getEnvAsInt("API_TIMEOUT", 30),
 		LogLevel:     getEnv("LOG_LEVEL", "info"),
+		AdminEmails:  getEnvAsSlice("ADMIN_EMAILS", nil),
+
+		SigningSecret: getEnv("SIGNING_SECRET", defaultSigningSecret),
+
+		RateLimitBackend: getEnv("RATE_LIMIT_BACKEND", "memory"),
+		LoginRateLimit:   getEnvAsInt("LOGIN_RATE_LIMIT", 10),
+		LoginIPRateLimit: getEnvAsInt("LOGIN_IP_RATE_LIMIT", 300),
+		CheckInRateLimit: getEnvAsInt("CHECKIN_RATE_LIMIT", 10),
+
+		MaxDevicesPerUser:     getEnvAsInt("MAX_DEVICES_PER_USER", 2),
+		DeviceBindingEnforced: getEnvAsBool("DEVICE_BINDING_ENFORCED", true),
+
+		OfflineMaxAgeHours: getEnvAsInt("OFFLINE_MAX_AGE_HOURS", 72),
+
+		KioskQRRotationSeconds: getEnvAsInt("KIOSK_QR_ROTATION_SECONDS", 30),
+		KioskRadiusMeters:      getEnvAsFloat("KIOSK_RADIUS_METERS", 100),
+
+		TrustedProxies:     getEnvAsSlice("TRUSTED_PROXIES", []string{"127.0.0.1/32", "::1/128"}),
+		TrustedProxyHeader: getEnv("TRUSTED_PROXY_HEADER", "X-Forwarded-For"),
+
+		ReportSyncMaxCells:   getEnvAsInt("REPORT_SYNC_MAX_CELLS", 2000),
+		ReportRetentionHours: getEnvAsInt("REPORT_RETENTION_HOURS", 24),
+
+		WebhookMaxAttempts:    getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookTimeoutSeconds: getEnvAsInt("WEBHOOK_TIMEOUT_SECONDS", 10),
+
+		WebhookAllowPrivateNetworks: getEnvAsBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+
+		NotificationLanguage:    getEnv("NOTIFICATION_LANGUAGE", "id"),
+		NotificationMaxAttempts: getEnvAsInt("NOTIFICATION_MAX_ATTEMPTS", 3),
+		SMTPHost:                getEnv("SMTP_HOST", ""),
+		SMTPPort:                getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername:            getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:            getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:                getEnv("SMTP_FROM", ""),
+		WhatsAppGatewayURL:      getEnv("WHATSAPP_GATEWAY_URL", ""),
+		WhatsAppGatewayToken:    getEnv("WHATSAPP_GATEWAY_TOKEN", ""),
+		SMSGatewayURL:           getEnv("SMS_GATEWAY_URL", ""),
+		SMSGatewayToken:         getEnv("SMS_GATEWAY_TOKEN", ""),
+		PushProvider:            getEnv("PUSH_PROVIDER", ""),
+		PushGatewayURL:          getEnv("PUSH_GATEWAY_URL", ""),
+		PushGatewayToken:        getEnv("PUSH_GATEWAY_TOKEN", ""),
+
+		GuardianInviteTTLHours: getEnvAsInt("GUARDIAN_INVITE_TTL_HOURS", 168),
+
+		AppURL:                getEnv("APP_URL", "http://localhost:3000"),
+		PasswordSetupTTLHours: getEnvAsInt("PASSWORD_SETUP_TTL_HOURS", 168),
+
+		ImpersonationTTLMinutes: getEnvAsInt("IMPERSONATION_TTL_MINUTES", 30),
+
+		StorageDriver: getEnv("STORAGE_DRIVER", "local"),
+		StorageDir:    getEnv("STORAGE_DIR", "storage/uploads"),
+		S3Endpoint:    getEnv("S3_ENDPOINT", ""),
+		S3Region:      getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:      getEnv("S3_BUCKET", ""),
+		S3AccessKey:   getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:   getEnv("S3_SECRET_KEY", ""),
+		S3PathStyle:   getEnvAsBool("S3_PATH_STYLE", true),
+
+		SelfieMaxBytes:      getEnvAsInt("SELFIE_MAX_BYTES", 3*1024*1024),
+		SelfieMaxDimension:  getEnvAsInt("SELFIE_MAX_DIMENSION", 1280),
+		SelfieThumbSize:     getEnvAsInt("SELFIE_THUMB_SIZE", 240),
+		SelfieURLTTLMinutes: getEnvAsInt("SELFIE_URL_TTL_MINUTES", 15),
 		
 		SchoolLatitude:  getEnvAsFloat("SCHOOL_LATITUDE", -8.1575),
 		SchoolLongitude: getEnvAsFloat("SCHOOL_LONGITUDE", 113.722778),
@@ -53,6 +218,115 @@ func Load() *Config {
 		SchoolEndHour:     getEnvAsInt("SCHOOL_END_HOUR", 15),
 		SchoolEndMinute:   getEnvAsInt("SCHOOL_END_MINUTE", 30),
 		LateThreshold:     getEnvAsInt("LATE_THRESHOLD", 30), // 30 minutes
+
+		LateGraceMinutes:       getEnvAsInt("LATE_GRACE_MINUTES", 0),
+		EarlyLeaveGraceMinutes: getEnvAsInt("EARLY_LEAVE_GRACE_MINUTES", 0),
+
+		AttendanceWindow: AttendanceWindow{
+			CheckInOpen:   getEnvAsClock("CHECKIN_OPEN", "05:00"),
+			CheckInClose:  getEnvAsClock("CHECKIN_CLOSE", "12:00"),
+			CheckOutOpen:  getEnvAsClock("CHECKOUT_OPEN", "10:00"),
+			CheckOutClose: getEnvAsClock("CHECKOUT_CLOSE", "22:00"),
+		},
+
+		SchoolTimezone: getEnv("SCHOOL_TIMEZONE", "WIB"),
+
+		RiskFlagThreshold:   getEnvAsFloat("RISK_FLAG_THRESHOLD", flagThreshold),
+		RiskRejectThreshold: getEnvAsFloat("RISK_REJECT_THRESHOLD", rejectThreshold),
+		RiskWeights: map[string]float64{
+			"ip_range":       getEnvAsFloat("RISK_WEIGHT_IP_RANGE", 25),
+			"wifi_ssid":      getEnvAsFloat("RISK_WEIGHT_WIFI_SSID", 15),
+			"carrier":        getEnvAsFloat("RISK_WEIGHT_CARRIER", 10),
+			"vpn":            getEnvAsFloat("RISK_WEIGHT_VPN", 20),
+			"gps_precision":  getEnvAsFloat("RISK_WEIGHT_GPS_PRECISION", 40),
+			"device_binding": getEnvAsFloat("RISK_WEIGHT_DEVICE_BINDING", 20),
+			"velocity":       getEnvAsFloat("RISK_WEIGHT_VELOCITY", 40),
+		},
+	}
+	cfg.schoolLocation = loadSchoolLocation(cfg.SchoolTimezone)
+	cfg.AttendanceWindowOverrides = loadWindowOverrides(cfg.AttendanceWindow)
+
+	if cfg.SigningSecret == cfg.JWTSecret {
+		fmt.Println("Warning: SIGNING_SECRET equals JWT_SECRET, use a separate secret for signed links")
+	}
+
+	return cfg
+}
+
+// AttendanceWindow jam buka/tutup check in dan check out dalam format HH:MM.
+// Setelah CheckOutClose record yang belum check out ditandai no_checkout.
+type AttendanceWindow struct {
+	CheckInOpen   string `json:"check_in_open"`
+	CheckInClose  string `json:"check_in_close"`
+	CheckOutOpen  string `json:"check_out_open"`
+	CheckOutClose string `json:"check_out_close"`
+}
+
+var weekdayNames = map[time.Weekday]string{
+	time.Monday:    "MON",
+	time.Tuesday:   "TUE",
+	time.Wednesday: "WED",
+	time.Thursday:  "THU",
+	time.Friday:    "FRI",
+	time.Saturday:  "SAT",
+	time.Sunday:    "SUN",
+}
+
+// loadWindowOverrides membaca ATTENDANCE_WINDOW_<HARI>="check_in_open,check_in_close,check_out_open,check_out_close",
+// bagian yang kosong memakai jendela default
+func loadWindowOverrides(base AttendanceWindow) map[time.Weekday]AttendanceWindow {
+	overrides := map[time.Weekday]AttendanceWindow{}
+	for day, name := range weekdayNames {
+		key := "ATTENDANCE_WINDOW_" + name
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+
+		parts := strings.Split(value, ",")
+		for len(parts) < 4 {
+			parts = append(parts, "")
+		}
+		overrides[day] = AttendanceWindow{
+			CheckInOpen:   parseClock(key, parts[0], base.CheckInOpen),
+			CheckInClose:  parseClock(key, parts[1], base.CheckInClose),
+			CheckOutOpen:  parseClock(key, parts[2], base.CheckOutOpen),
+			CheckOutClose: parseClock(key, parts[3], base.CheckOutClose),
+		}
+	}
+	return overrides
+}
+
+// Indonesia tidak memakai DST, jadi singkatan zona cukup dipetakan ke offset tetap
+var indonesianTimezones = map[string]int{
+	"WIB":  7,
+	"WITA": 8,
+	"WIT":  9,
+}
+
+func loadSchoolLocation(name string) *time.Location {
+	abbreviation := strings.ToUpper(strings.TrimSpace(name))
+	if offset, ok := indonesianTimezones[abbreviation]; ok {
+		return time.FixedZone(abbreviation, offset*60*60)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		fmt.Printf("Warning: invalid SCHOOL_TIMEZONE %q, falling back to WIB: %v\n", name, err)
+		return time.FixedZone("WIB", 7*60*60)
+	}
+	return loc
+}
+
+// threshold default per environment, production lebih ketat dari development
+func defaultRiskThresholds(appEnv string) (float64, float64) {
+	switch appEnv {
+	case "production":
+		return 25, 50
+	case "staging":
+		return 35, 70
+	default:
+		return 50, 100
 	}
 }
 
@@ -68,6 +342,24 @@ func (c *Config) IsDevelopment() bool {
 	return c.AppEnv == "development"
 }
 
+// defaultSigningSecret hanya untuk development, nilainya publik di repo ini
+const defaultSigningSecret = "ujikom-signing-key"
+
+// ValidateSigningSecret menolak start di production jika SIGNING_SECRET tidak diisi, karena
+// kunci bawaan yang publik bisa dipakai memalsukan link unduhan dan tiket stream
+func (c *Config) ValidateSigningSecret() error {
+	if !c.IsProduction() {
+		return nil
+	}
+	if c.SigningSecret == "" || c.SigningSecret == defaultSigningSecret {
+		return fmt.Errorf("SIGNING_SECRET must be set to a private value in production")
+	}
+	if c.SigningSecret == c.JWTSecret {
+		return fmt.Errorf("SIGNING_SECRET must differ from JWT_SECRET in production")
+	}
+	return nil
+}
+
 func (c *Config) ValidateAtlasConnection() error {
 	if c.MongoURI == "" {
 		return fmt.Errorf("MONGODB_URI is required for Atlas connection")
@@ -92,6 +384,45 @@ func (c *Config) GetLateThreshold() int {
 	return c.LateThreshold
 }
 
+// Location zona waktu sekolah, default WIB
+func (c *Config) Location() *time.Location {
+	if c.schoolLocation == nil {
+		c.schoolLocation = loadSchoolLocation(c.SchoolTimezone)
+	}
+	return c.schoolLocation
+}
+
+// SchoolDate mengembalikan tanggal sekolah (kalender lokal) dari waktu t.
+// Tanggal disimpan sebagai tengah malam UTC supaya sama dengan tanggal hasil
+// parsing "YYYY-MM-DD" dari input manual.
+func (c *Config) SchoolDate(t time.Time) time.Time {
+	local := t.In(c.Location())
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Today tanggal sekolah hari ini
+func (c *Config) Today() time.Time {
+	return c.SchoolDate(time.Now())
+}
+
+// DayStart waktu tengah malam lokal dari tanggal sekolah, untuk memfilter field timestamp
+func (c *Config) DayStart(date time.Time) time.Time {
+	date = date.UTC()
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, c.Location())
+}
+
+// WindowFor jendela absensi untuk hari tersebut
+func (c *Config) WindowFor(day time.Weekday) AttendanceWindow {
+	if window, ok := c.AttendanceWindowOverrides[day]; ok {
+		return window
+	}
+	return c.AttendanceWindow
+}
+
+func (c *Config) GetRiskThresholds() (float64, float64) {
+	return c.RiskFlagThreshold, c.RiskRejectThreshold
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -115,4 +446,46 @@ func getEnvAsFloat(key string, defaultValue float64) float64 {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvAsClock(key, defaultValue string) string {
+	return parseClock(key, os.Getenv(key), defaultValue)
+}
+
+// parseClock menormalkan jam HH:MM (mis. "5:30" jadi "05:30") supaya bisa dibandingkan sebagai string
+func parseClock(key, value, defaultValue string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		fmt.Printf("Warning: invalid %s value %q, using %s\n", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed.Format("15:04")
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
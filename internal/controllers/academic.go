@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AcademicController struct {
+	db              *mongo.Database
+	validator       *validator.Validate
+	academicService services.AcademicServiceInterface
+}
+
+func NewAcademicController(db *mongo.Database, cfg *config.Config) *AcademicController {
+	return &AcademicController{
+		db:              db,
+		validator:       validator.New(),
+		academicService: services.NewAcademicService(db, cfg),
+	}
+}
+
+func (ac *AcademicController) ListAcademicYears(c *fiber.Ctx) error {
+	years, err := ac.academicService.ListAcademicYears()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Academic years retrieved", years)
+}
+
+func (ac *AcademicController) GetCurrentSemester(c *fiber.Ctx) error {
+	semester, err := ac.academicService.CurrentSemester()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+	if semester == nil {
+		return utils.SuccessResponse(c, "No semester is currently running", nil)
+	}
+
+	return utils.SuccessResponse(c, "Current semester retrieved", semester)
+}
+
+func (ac *AcademicController) GetAcademicYear(c *fiber.Ctx) error {
+	year, err := ac.academicService.GetAcademicYear(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Academic year retrieved", year)
+}
+
+func (ac *AcademicController) CreateAcademicYear(c *fiber.Ctx) error {
+	var req models.CreateAcademicYearRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := ac.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	year, err := ac.academicService.CreateAcademicYear(&req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Academic year created successfully", year)
+}
+
+func (ac *AcademicController) UpdateAcademicYear(c *fiber.Ctx) error {
+	var req models.UpdateAcademicYearRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := ac.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	year, err := ac.academicService.UpdateAcademicYear(c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Academic year updated successfully", year)
+}
+
+func (ac *AcademicController) CreateSemester(c *fiber.Ctx) error {
+	var req models.CreateSemesterRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := ac.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	semester, err := ac.academicService.CreateSemester(c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Semester created successfully", semester)
+}
+
+func (ac *AcademicController) UpdateSemester(c *fiber.Ctx) error {
+	var req models.UpdateSemesterRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := ac.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	semester, err := ac.academicService.UpdateSemester(c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Semester updated successfully", semester)
+}
+
+func (ac *AcademicController) Rollover(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.RolloverRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := ac.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	result, err := ac.academicService.Rollover(&user, c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	message := "Class promotion completed"
+	if req.DryRun {
+		message = "Class promotion preview, no changes saved"
+	}
+	return utils.SuccessResponse(c, message, result)
+}
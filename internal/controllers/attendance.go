@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"math"
+	"time"
 	"ujikom-backend/internal/config"
 	"ujikom-backend/internal/models"
 	"ujikom-backend/internal/services"
@@ -10,6 +14,7 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -17,14 +22,20 @@ type AttendanceController struct {
 	db                *mongo.Database
 	validator         *validator.Validate
 	attendanceService services.AttendanceServiceInterface
+	kioskService      services.KioskServiceInterface
+	academicService   services.AcademicServiceInterface
+	selfieService     services.SelfieServiceInterface
 	config            *config.Config
 }
 
-func NewAttendanceController(db *mongo.Database, cfg *config.Config) *AttendanceController {
+func NewAttendanceController(db *mongo.Database, cfg *config.Config, selfieService services.SelfieServiceInterface) *AttendanceController {
 	return &AttendanceController{
 		db:                db,
 		validator:         validator.New(),
 		attendanceService: services.NewAttendanceService(db, cfg),
+		kioskService:      services.NewKioskService(db, cfg),
+		academicService:   services.NewAcademicService(db, cfg),
+		selfieService:     selfieService,
 		config:            cfg,
 	}
 }
@@ -44,25 +55,29 @@ func (ac *AttendanceController) CheckIn(c *fiber.Ctx) error {
 		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
 	}
 
-	attendance, err := ac.attendanceService.CheckIn(user.ID.Hex(), &req)
+	req.DeviceID = c.Get("X-Device-ID")
+	if device, ok := c.Locals("device").(models.Device); ok {
+		req.DeviceID = device.DeviceID
+	}
+	if assessment, ok := c.Locals("risk_assessment").(*models.RiskAssessment); ok {
+		req.Risk = assessment
+	}
+
+	selfie, err := ac.readSelfie(c, user, models.SelfieCheckIn)
 	if err != nil {
-		log.Printf("CheckIn error for user %s: %v", user.Name, err)
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
 	}
+	req.Selfie = selfie
 
-	response := models.AttendanceResponse{
-		ID:        attendance.ID,
-		UserID:    attendance.UserID,
-		Date:      attendance.Date,
-		CheckIn:   attendance.CheckIn,
-		CheckOut:  attendance.CheckOut,
-		Status:    attendance.Status,
-		Location:  attendance.Location,
-		User:      ac.createUserPublic(user),
-		CreatedAt: attendance.CreatedAt,
-		UpdatedAt: attendance.UpdatedAt,
+	attendance, err := ac.attendanceService.CheckIn(user.ID.Hex(), &req)
+	if err != nil {
+		ac.selfieService.Discard(selfie)
+		log.Printf("CheckIn error for user %s: %v", user.Name, err)
+		return attendanceErrorResponse(c, err)
 	}
 
+	response := ac.createAttendanceResponse(attendance, user)
+
 	log.Printf("User %s checked in successfully at %s", user.Name, attendance.CheckIn.Format("15:04:05"))
 	return utils.SuccessResponse(c, "Check in successful", response)
 }
@@ -82,25 +97,29 @@ func (ac *AttendanceController) CheckOut(c *fiber.Ctx) error {
 		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
 	}
 
-	attendance, err := ac.attendanceService.CheckOut(user.ID.Hex(), &req)
+	req.DeviceID = c.Get("X-Device-ID")
+	if device, ok := c.Locals("device").(models.Device); ok {
+		req.DeviceID = device.DeviceID
+	}
+	if assessment, ok := c.Locals("risk_assessment").(*models.RiskAssessment); ok {
+		req.Risk = assessment
+	}
+
+	selfie, err := ac.readSelfie(c, user, models.SelfieCheckOut)
 	if err != nil {
-		log.Printf("CheckOut error for user %s: %v", user.Name, err)
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
 	}
+	req.Selfie = selfie
 
-	response := models.AttendanceResponse{
-		ID:        attendance.ID,
-		UserID:    attendance.UserID,
-		Date:      attendance.Date,
-		CheckIn:   attendance.CheckIn,
-		CheckOut:  attendance.CheckOut,
-		Status:    attendance.Status,
-		Location:  attendance.Location,
-		User:      ac.createUserPublic(user),
-		CreatedAt: attendance.CreatedAt,
-		UpdatedAt: attendance.UpdatedAt,
+	attendance, err := ac.attendanceService.CheckOut(user.ID.Hex(), &req)
+	if err != nil {
+		ac.selfieService.Discard(selfie)
+		log.Printf("CheckOut error for user %s: %v", user.Name, err)
+		return attendanceErrorResponse(c, err)
 	}
 
+	response := ac.createAttendanceResponse(attendance, user)
+
 	log.Printf("User %s checked out successfully at %s", user.Name, attendance.CheckOut.Format("15:04:05"))
 	return utils.SuccessResponse(c, "Check out successful", response)
 }
@@ -116,19 +135,12 @@ func (ac *AttendanceController) GetTodayAttendance(c *fiber.Ctx) error {
 		log.Printf("GetTodayAttendance error for user %s: %v", user.Name, err)
 		return utils.SuccessResponse(c, "No attendance record for today", nil)
 	}
-
-	response := models.AttendanceResponse{
-		ID:        attendance.ID,
-		UserID:    attendance.UserID,
-		Date:      attendance.Date,
-		CheckIn:   attendance.CheckIn,
-		CheckOut:  attendance.CheckOut,
-		Status:    attendance.Status,
-		Location:  attendance.Location,
-		User:      ac.createUserPublic(user),
-		CreatedAt: attendance.CreatedAt,
-		UpdatedAt: attendance.UpdatedAt,
+	if attendance == nil {
+		return utils.SuccessResponse(c, "No attendance record for today", nil)
 	}
+	ac.selfieService.AttachURLs(attendance)
+
+	response := ac.createAttendanceResponse(attendance, user)
 
 	return utils.SuccessResponse(c, "Today's attendance retrieved", response)
 }
@@ -139,6 +151,11 @@ func (ac *AttendanceController) GetAttendanceHistory(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
 	}
 
+	return ac.respondHistory(c, user)
+}
+
+// respondHistory juga dipakai orang tua untuk melihat riwayat anaknya
+func (ac *AttendanceController) respondHistory(c *fiber.Ctx, user models.User) error {
 	page := c.QueryInt("page", 1)
 	limit := c.QueryInt("limit", 10)
 	if page < 1 {
@@ -150,7 +167,12 @@ func (ac *AttendanceController) GetAttendanceHistory(c *fiber.Ctx) error {
 
 	offset := (page - 1) * limit
 
-	attendances, total, err := ac.attendanceService.GetAttendanceHistory(user.ID.Hex(), limit, offset)
+	period, err := parseAttendancePeriod(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	attendances, total, err := ac.attendanceService.GetAttendanceHistory(user.ID.Hex(), period, limit, offset)
 	if err != nil {
 		log.Printf("GetAttendanceHistory error for user %s: %v", user.Name, err)
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch attendance history")
@@ -158,12 +180,21 @@ func (ac *AttendanceController) GetAttendanceHistory(c *fiber.Ctx) error {
 
 	var history []models.AttendanceHistory
 	for _, attendance := range attendances {
+		ac.selfieService.AttachURLs(&attendance)
 		history = append(history, models.AttendanceHistory{
 			Date:     attendance.Date,
+			TermID:   attendance.TermID,
 			CheckIn:  attendance.CheckIn,
 			CheckOut: attendance.CheckOut,
 			Status:   attendance.Status,
 			Location: attendance.Location,
+
+			VerificationMethod: attendance.VerificationMethod,
+			SubmittedLate:      attendance.SubmittedLate,
+			CheckInSelfie:      attendance.CheckInSelfie,
+			CheckOutSelfie:     attendance.CheckOutSelfie,
+			Voided:             attendance.Voided,
+			History:            attendance.History,
 		})
 	}
 
@@ -186,15 +217,373 @@ func (ac *AttendanceController) GetAttendanceStats(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
 	}
 
-	stats, err := ac.attendanceService.GetAttendanceStats(user.ID.Hex())
+	return ac.respondStats(c, user)
+}
+
+func (ac *AttendanceController) respondStats(c *fiber.Ctx, user models.User) error {
+	period, err := parseAttendancePeriod(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// tanpa filter, statistik dihitung untuk semester berjalan (?all=true untuk semua data)
+	var term *models.Semester
+	if period == nil && !c.QueryBool("all", false) {
+		term, err = ac.academicService.CurrentSemester()
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+		}
+		if term != nil {
+			period = &models.AttendancePeriod{TermID: &term.ID}
+		}
+	} else if period != nil && period.TermID != nil {
+		if term, err = ac.academicService.GetSemester(period.TermID.Hex()); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	stats, err := ac.attendanceService.GetAttendanceStats(user.ID.Hex(), period)
 	if err != nil {
 		log.Printf("GetAttendanceStats error for user %s: %v", user.Name, err)
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to calculate stats")
 	}
+	stats.Term = term
 
 	return utils.SuccessResponse(c, "Attendance statistics retrieved", stats)
 }
 
+// CheckInQR absen masuk dengan scan QR dari kiosk. Lokasi GPS HP harus dekat kiosk dan
+// tetap di dalam radius sekolah.
+func (ac *AttendanceController) CheckInQR(c *fiber.Ctx) error {
+	return ac.attendWithQR(c, "checkin")
+}
+
+func (ac *AttendanceController) CheckOutQR(c *fiber.Ctx) error {
+	return ac.attendWithQR(c, "checkout")
+}
+
+func (ac *AttendanceController) attendWithQR(c *fiber.Ctx, action string) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var qrReq models.QRAttendanceRequest
+	if err := c.BodyParser(&qrReq); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := ac.validator.Struct(qrReq); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	kiosk, window, err := ac.kioskService.VerifyQRToken(qrReq.Token)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := ac.kioskService.CheckProximity(kiosk, qrReq.Latitude, qrReq.Longitude); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	req := models.AttendanceRequest{
+		Latitude:  qrReq.Latitude,
+		Longitude: qrReq.Longitude,
+		Address:   kiosk.Name,
+		DeviceID:  c.Get("X-Device-ID"),
+		KioskID:   &kiosk.ID,
+	}
+	if device, ok := c.Locals("device").(models.Device); ok {
+		req.DeviceID = device.DeviceID
+	}
+	if assessment, ok := c.Locals("risk_assessment").(*models.RiskAssessment); ok {
+		req.Risk = assessment
+	}
+	if req.Selfie, err = ac.readSelfie(c, user, action); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// scan ditandai sebelum absensi supaya request bersamaan dengan token yang sama ditolak,
+	// lalu dilepas lagi jika absensinya gagal
+	if err := ac.kioskService.ConsumeScan(kiosk.ID, window, user.ID, action); err != nil {
+		ac.selfieService.Discard(req.Selfie)
+		return utils.ErrorResponse(c, fiber.StatusConflict, err.Error())
+	}
+
+	var attendance *models.Attendance
+	if action == "checkout" {
+		attendance, err = ac.attendanceService.CheckOut(user.ID.Hex(), &req)
+	} else {
+		attendance, err = ac.attendanceService.CheckIn(user.ID.Hex(), &req)
+	}
+	if err != nil {
+		ac.kioskService.ReleaseScan(kiosk.ID, window, user.ID, action)
+		ac.selfieService.Discard(req.Selfie)
+		log.Printf("QR %s error for user %s at kiosk %s: %v", action, user.Name, kiosk.Name, err)
+		return attendanceErrorResponse(c, err)
+	}
+
+	log.Printf("User %s %s via kiosk %s", user.Name, action, kiosk.Name)
+	if action == "checkout" {
+		return utils.SuccessResponse(c, "Check out successful", ac.createAttendanceResponse(attendance, user))
+	}
+	return utils.SuccessResponse(c, "Check in successful", ac.createAttendanceResponse(attendance, user))
+}
+
+// GetStudentAttendance riwayat absensi siswa untuk wali kelas/admin, termasuk ID record untuk dikoreksi
+func (ac *AttendanceController) GetStudentAttendance(c *fiber.Ctx) error {
+	actor, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	period, err := parseAttendancePeriod(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	attendances, total, err := ac.attendanceService.GetStudentAttendanceHistory(&actor, c.Params("id"), period, limit, (page-1)*limit)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	if attendances == nil {
+		attendances = []models.Attendance{}
+	}
+	for i := range attendances {
+		ac.selfieService.AttachURLs(&attendances[i])
+	}
+
+	return utils.SuccessResponse(c, "Student attendance retrieved", fiber.Map{
+		"attendances": attendances,
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": int(math.Ceil(float64(total) / float64(limit))),
+		},
+	})
+}
+
+func (ac *AttendanceController) GetAttendance(c *fiber.Ctx) error {
+	actor, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	attendance, err := ac.attendanceService.GetAttendance(&actor, c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	ac.selfieService.AttachURLs(attendance)
+
+	return utils.SuccessResponse(c, "Attendance retrieved", attendance)
+}
+
+func (ac *AttendanceController) CreateManualAttendance(c *fiber.Ctx) error {
+	actor, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.ManualAttendanceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := ac.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	attendance, err := ac.attendanceService.CreateManualAttendance(&actor, &req)
+	if err != nil {
+		if services.IsAttendanceConflict(err) {
+			return utils.ErrorResponse(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Attendance created successfully", attendance)
+}
+
+func (ac *AttendanceController) UpdateAttendance(c *fiber.Ctx) error {
+	actor, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.UpdateAttendanceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := ac.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	attendance, err := ac.attendanceService.UpdateAttendance(&actor, c.Params("id"), &req)
+	if err != nil {
+		if services.IsAttendanceConflict(err) {
+			return utils.ErrorResponse(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Attendance updated successfully", attendance)
+}
+
+func (ac *AttendanceController) VoidAttendance(c *fiber.Ctx) error {
+	actor, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.VoidAttendanceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := ac.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	attendance, err := ac.attendanceService.VoidAttendance(&actor, c.Params("id"), req.Reason)
+	if err != nil {
+		if services.IsAttendanceConflict(err) {
+			return utils.ErrorResponse(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Attendance voided successfully", attendance)
+}
+
+// DownloadSelfie mengirim foto selfie lewat link bertanda tangan (?expires=&signature=).
+// Link hanya dibuat untuk siswa pemilik record dan guru/admin yang boleh melihatnya.
+func (ac *AttendanceController) DownloadSelfie(c *fiber.Ctx) error {
+	reader, contentType, err := ac.selfieService.Open(c.Params("id"), c.Params("kind"), c.Params("variant"), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		if err.Error() == "selfie not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	return c.SendStream(reader)
+}
+
+// readSelfie membaca file selfie opsional dari field "selfie" pada multipart/form-data
+func (ac *AttendanceController) readSelfie(c *fiber.Ctx, user models.User, kind string) (*models.Selfie, error) {
+	file, err := c.FormFile("selfie")
+	if err != nil {
+		// body JSON atau form tanpa selfie
+		return nil, nil
+	}
+	if file.Size > int64(ac.config.SelfieMaxBytes) {
+		return nil, fmt.Errorf("selfie must not exceed %d KB", ac.config.SelfieMaxBytes/1024)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, errors.New("failed to read selfie")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, int64(ac.config.SelfieMaxBytes)+1))
+	if err != nil {
+		return nil, errors.New("failed to read selfie")
+	}
+
+	return ac.selfieService.Store(user.ID, kind, data)
+}
+
+// parseAttendancePeriod membaca filter ?term_id= atau ?from=&to= (YYYY-MM-DD), nil jika tidak ada
+func parseAttendancePeriod(c *fiber.Ctx) (*models.AttendancePeriod, error) {
+	period := &models.AttendancePeriod{}
+	found := false
+
+	if value := c.Query("term_id"); value != "" {
+		termID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return nil, errors.New("invalid term ID")
+		}
+		period.TermID = &termID
+		found = true
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, errors.New("invalid from date, use YYYY-MM-DD")
+		}
+		period.From = &from
+		found = true
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, errors.New("invalid to date, use YYYY-MM-DD")
+		}
+		period.To = &to
+		found = true
+	}
+
+	if !found {
+		return nil, nil
+	}
+	if period.From != nil && period.To != nil && period.To.Before(*period.From) {
+		return nil, errors.New("to date must not be before from date")
+	}
+	return period, nil
+}
+
+// attendanceErrorResponse: penolakan karena jendela waktu dikirim beserta jendela dan jam server
+func attendanceErrorResponse(c *fiber.Ctx, err error) error {
+	var windowErr *services.AttendanceWindowError
+	if errors.As(err, &windowErr) {
+		return utils.ErrorResponseWithData(c, fiber.StatusUnprocessableEntity, windowErr.Message, windowErr)
+	}
+	return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+}
+
+func (ac *AttendanceController) createAttendanceResponse(attendance *models.Attendance, user models.User) models.AttendanceResponse {
+	return models.AttendanceResponse{
+		ID:        attendance.ID,
+		UserID:    attendance.UserID,
+		Date:      attendance.Date,
+		CheckIn:   attendance.CheckIn,
+		CheckOut:  attendance.CheckOut,
+		Status:    attendance.Status,
+		Location:  attendance.Location,
+		Flagged:   attendance.Flagged,
+		User:      ac.createUserPublic(user),
+
+		MinutesLate:     attendance.MinutesLate,
+		DepartureStatus: attendance.DepartureStatus,
+		MinutesEarly:    attendance.MinutesEarly,
+		CreatedAt: attendance.CreatedAt,
+		UpdatedAt: attendance.UpdatedAt,
+
+		VerificationMethod: attendance.VerificationMethod,
+		KioskID:            attendance.KioskID,
+		SubmittedLate:      attendance.SubmittedLate,
+		SubmittedAt:        attendance.SubmittedAt,
+		CheckInSelfie:      attendance.CheckInSelfie,
+		CheckOutSelfie:     attendance.CheckOutSelfie,
+		Voided:             attendance.Voided,
+		History:            attendance.History,
+	}
+}
+
 func (ac *AttendanceController) createUserPublic(user models.User) models.UserPublic {
 	return models.UserPublic{
 		ID:      user.ID,
@@ -202,6 +591,7 @@ func (ac *AttendanceController) createUserPublic(user models.User) models.UserPu
 		Name:    user.Name,
 		Kelas:   user.Kelas,
 		Jurusan: user.Jurusan,
+		ClassID: user.ClassID,
 		Email:   user.Email,
 		Phone:   user.Phone,
 	}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"log"
 	"time"
+	"ujikom-backend/internal/config"
 	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
 	"ujikom-backend/internal/utils"
 
 	"github.com/go-playground/validator/v10"
@@ -16,14 +18,20 @@ import (
 )
 
 type AuthController struct {
-	db        *mongo.Database
-	validator *validator.Validate
+	db             *mongo.Database
+	validator      *validator.Validate
+	classService   services.ClassServiceInterface
+	webhookService services.WebhookServiceInterface
+	passwordSetup  services.PasswordSetupServiceInterface
 }
 
-func NewAuthController(db *mongo.Database) *AuthController {
+func NewAuthController(db *mongo.Database, cfg *config.Config) *AuthController {
 	return &AuthController{
-		db:        db,
-		validator: validator.New(),
+		db:             db,
+		validator:      validator.New(),
+		classService:   services.NewClassService(db),
+		webhookService: services.NewWebhookService(db, cfg),
+		passwordSetup:  services.NewPasswordSetupService(db, cfg),
 	}
 }
 
@@ -54,6 +62,11 @@ func (ac *AuthController) Register(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusConflict, "Email already registered")
 	}
 
+	class, major, err := ac.classService.ResolveEnrollment(req.ClassID, req.MajorID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Printf("Error hashing password: %v", err)
@@ -64,8 +77,10 @@ func (ac *AuthController) Register(c *fiber.Ctx) error {
 	user := models.User{
 		NIS:       req.NIS,
 		Name:      req.Name,
-		Kelas:     req.Kelas,
-		Jurusan:   req.Jurusan,
+		Kelas:     class.Name,
+		Jurusan:   major.Name,
+		ClassID:   &class.ID,
+		MajorID:   &major.ID,
 		Email:     req.Email,
 		Password:  string(hashedPassword),
 		Phone:     req.Phone,
@@ -76,6 +91,10 @@ func (ac *AuthController) Register(c *fiber.Ctx) error {
 
 	result, err := collection.InsertOne(context.Background(), user)
 	if err != nil {
+		// NIS atau email didaftarkan bersamaan setelah pengecekan di atas
+		if mongo.IsDuplicateKeyError(err) {
+			return utils.ErrorResponse(c, fiber.StatusConflict, "NIS or email already registered")
+		}
 		log.Printf("Error creating user: %v", err)
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create user")
 	}
@@ -93,6 +112,8 @@ func (ac *AuthController) Register(c *fiber.Ctx) error {
 		User:  user.UserPublic(),
 	}
 
+	ac.webhookService.Enqueue(models.WebhookUserRegistered, primitive.NewObjectID().Hex(), user.UserPublic())
+
 	log.Printf("User registered successfully: %s", user.Email)
 	return utils.SuccessResponse(c, "User registered successfully", response)
 }
@@ -154,6 +175,34 @@ func (ac *AuthController) Login(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, "Login successful", response)
 }
 
+// SetPassword dipakai siswa hasil import (mode undangan) untuk membuat password dari link email
+func (ac *AuthController) SetPassword(c *fiber.Ctx) error {
+	var req models.SetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := ac.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	user, err := ac.passwordSetup.SetPassword(&req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	token, err := utils.GenerateJWT(user.ID.Hex())
+	if err != nil {
+		log.Printf("Error generating JWT: %v", err)
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate token")
+	}
+
+	return utils.SuccessResponse(c, "Password set successfully", models.LoginResponse{
+		Token: token,
+		User:  user.UserPublic(),
+	})
+}
+
 func (ac *AuthController) Logout(c *fiber.Ctx) error {
 	user, ok := c.Locals("user").(models.User)
 	if ok {
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ClassController struct {
+	db                     *mongo.Database
+	validator              *validator.Validate
+	classService           services.ClassServiceInterface
+	classAttendanceService services.ClassAttendanceServiceInterface
+	config                 *config.Config
+}
+
+func NewClassController(db *mongo.Database, cfg *config.Config) *ClassController {
+	return &ClassController{
+		db:                     db,
+		validator:              validator.New(),
+		classService:           services.NewClassService(db),
+		classAttendanceService: services.NewClassAttendanceService(db, cfg),
+		config:                 cfg,
+	}
+}
+
+func (cc *ClassController) ListMajors(c *fiber.Ctx) error {
+	majors, err := cc.classService.ListMajors(c.QueryBool("include_inactive", false))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Majors retrieved", majors)
+}
+
+func (cc *ClassController) CreateMajor(c *fiber.Ctx) error {
+	var req models.CreateMajorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := cc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	major, err := cc.classService.CreateMajor(&req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Major created successfully", major)
+}
+
+func (cc *ClassController) UpdateMajor(c *fiber.Ctx) error {
+	var req models.UpdateMajorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := cc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	major, err := cc.classService.UpdateMajor(c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Major updated successfully", major)
+}
+
+// ListClasses daftar kelas, bisa difilter ?major_id= dan ?grade=
+func (cc *ClassController) ListClasses(c *fiber.Ctx) error {
+	classes, err := cc.classService.ListClasses(c.Query("major_id"), c.QueryInt("grade", 0), c.QueryBool("include_inactive", false))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Classes retrieved", classes)
+}
+
+func (cc *ClassController) GetClass(c *fiber.Ctx) error {
+	detail, err := cc.classService.GetClassDetail(c.Params("id"))
+	if err != nil {
+		if err.Error() == "class not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Class retrieved", detail)
+}
+
+func (cc *ClassController) CreateClass(c *fiber.Ctx) error {
+	var req models.CreateClassRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := cc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	class, err := cc.classService.CreateClass(&req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Class created successfully", class)
+}
+
+func (cc *ClassController) UpdateClass(c *fiber.Ctx) error {
+	var req models.UpdateClassRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := cc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	class, err := cc.classService.UpdateClass(c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Class updated successfully", class)
+}
+
+func (cc *ClassController) AssignHomeroom(c *fiber.Ctx) error {
+	var req models.AssignHomeroomRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := cc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	class, err := cc.classService.AssignHomeroom(c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Homeroom teacher updated successfully", class)
+}
+
+// ListTeacherClasses kelas yang dashboard-nya bisa dibuka: kelas perwalian untuk guru, semua kelas untuk admin
+func (cc *ClassController) ListTeacherClasses(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	classes, err := cc.classAttendanceService.ListViewableClasses(&user)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Classes retrieved", classes)
+}
+
+// GetDailyAttendance status absensi semua siswa kelas pada ?date= (default hari ini)
+func (cc *ClassController) GetDailyAttendance(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	date := cc.config.Today()
+	if value := c.Query("date"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date, use YYYY-MM-DD")
+		}
+		date = parsed
+	}
+
+	dashboard, err := cc.classAttendanceService.GetDailyAttendance(&user, c.Params("id"), date)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Class attendance retrieved", dashboard)
+}
+
+// GetMonthlyAttendance rekap harian dan per siswa untuk ?month= (YYYY-MM, default bulan ini)
+func (cc *ClassController) GetMonthlyAttendance(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	month := cc.config.Today()
+	if value := c.Query("month"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid month, use YYYY-MM")
+		}
+		month = parsed
+	}
+
+	summary, err := cc.classAttendanceService.GetMonthlyAttendance(&user, c.Params("id"), month)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Class monthly attendance retrieved", summary)
+}
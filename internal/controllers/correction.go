@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type CorrectionController struct {
+	db                *mongo.Database
+	validator         *validator.Validate
+	correctionService services.CorrectionServiceInterface
+	selfieService     services.SelfieServiceInterface
+	config            *config.Config
+}
+
+func NewCorrectionController(db *mongo.Database, cfg *config.Config, selfieService services.SelfieServiceInterface) *CorrectionController {
+	return &CorrectionController{
+		db:                db,
+		validator:         validator.New(),
+		correctionService: services.NewCorrectionService(db, cfg),
+		selfieService:     selfieService,
+		config:            cfg,
+	}
+}
+
+func (cc *CorrectionController) SubmitRequest(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.CorrectionRequestInput
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := cc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	request, err := cc.correctionService.SubmitRequest(user.ID, &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Correction request submitted", request)
+}
+
+func (cc *CorrectionController) ListOwnRequests(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	requests, err := cc.correctionService.ListOwnRequests(user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+	for i := range requests {
+		cc.selfieService.AttachEvidenceURLs(&requests[i])
+	}
+
+	return utils.SuccessResponse(c, "Correction requests retrieved", requests)
+}
+
+// UploadEvidence menerima foto bukti (field "evidence" pada multipart/form-data) untuk
+// pengajuan sendiri yang masih pending. Foto diproses lewat pipeline selfie.
+func (cc *CorrectionController) UploadEvidence(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	file, err := c.FormFile("evidence")
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Evidence file is required")
+	}
+	if file.Size > int64(cc.config.SelfieMaxBytes) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, fmt.Sprintf("evidence must not exceed %d KB", cc.config.SelfieMaxBytes/1024))
+	}
+
+	data, err := readFormFile(file, cc.config.SelfieMaxBytes)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	evidence, err := cc.selfieService.StoreEvidence(user.ID, data)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	request, replaced, err := cc.correctionService.AttachEvidence(user.ID, c.Params("id"), evidence)
+	if err != nil {
+		cc.selfieService.Discard(evidence)
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	if replaced != nil {
+		cc.selfieService.Discard(replaced)
+	}
+
+	cc.selfieService.AttachEvidenceURLs(request)
+	return utils.SuccessResponse(c, "Evidence uploaded", request)
+}
+
+func (cc *CorrectionController) DownloadEvidence(c *fiber.Ctx) error {
+	reader, contentType, err := cc.selfieService.OpenEvidence(c.Params("id"), c.Params("variant"), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		if err.Error() == "evidence not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	return c.SendStream(reader)
+}
+
+func (cc *CorrectionController) ListRequests(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	requests, err := cc.correctionService.ListRequests(&user, c.Query("status", models.CorrectionStatusPending))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+	for i := range requests {
+		cc.selfieService.AttachEvidenceURLs(&requests[i].CorrectionRequest)
+	}
+
+	return utils.SuccessResponse(c, "Correction requests retrieved", requests)
+}
+
+func (cc *CorrectionController) ApproveRequest(c *fiber.Ctx) error {
+	return cc.reviewRequest(c, true)
+}
+
+func (cc *CorrectionController) RejectRequest(c *fiber.Ctx) error {
+	return cc.reviewRequest(c, false)
+}
+
+func (cc *CorrectionController) reviewRequest(c *fiber.Ctx, approve bool) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.ReviewRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		if err := cc.validator.Struct(req); err != nil {
+			return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+		}
+	}
+
+	request, err := cc.correctionService.ReviewRequest(&user, c.Params("id"), approve, req.Note)
+	if err != nil {
+		if services.IsAttendanceConflict(err) {
+			return utils.ErrorResponse(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	cc.selfieService.AttachEvidenceURLs(request)
+
+	message := "Correction request rejected"
+	if approve {
+		message = "Correction request approved, attendance updated"
+	}
+	return utils.SuccessResponse(c, message, request)
+}
+
+// readFormFile membaca isi file upload dengan batas ukuran
+func readFormFile(file *multipart.FileHeader, maxBytes int) ([]byte, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, errors.New("failed to read file")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, int64(maxBytes)+1))
+	if err != nil {
+		return nil, errors.New("failed to read file")
+	}
+	return data, nil
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type DeviceController struct {
+	db            *mongo.Database
+	validator     *validator.Validate
+	deviceService services.DeviceServiceInterface
+	config        *config.Config
+}
+
+func NewDeviceController(db *mongo.Database, cfg *config.Config) *DeviceController {
+	return &DeviceController{
+		db:            db,
+		validator:     validator.New(),
+		deviceService: services.NewDeviceService(db, cfg),
+		config:        cfg,
+	}
+}
+
+func (dc *DeviceController) RegisterDevice(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.RegisterDeviceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := dc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	device, err := dc.deviceService.RegisterDevice(user.ID, &req, &services.SignedRequest{
+		DeviceID:  c.Get("X-Device-ID"),
+		Timestamp: c.Get("X-Device-Timestamp"),
+		Nonce:     c.Get("X-Device-Nonce"),
+		Signature: c.Get("X-Device-Signature"),
+		Method:    c.Method(),
+		Path:      c.Path(),
+		Body:      c.Body(),
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Device registered successfully", device)
+}
+
+func (dc *DeviceController) ListDevices(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	devices, err := dc.deviceService.ListDevices(user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Devices retrieved", devices)
+}
+
+func (dc *DeviceController) RequestReset(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.DeviceResetRequestInput
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := dc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	request, err := dc.deviceService.RequestReset(user.ID, &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Device reset request submitted", request)
+}
+
+func (dc *DeviceController) ListOwnResetRequests(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	requests, err := dc.deviceService.ListOwnResetRequests(user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Device reset requests retrieved", requests)
+}
+
+func (dc *DeviceController) ListResetRequests(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	requests, err := dc.deviceService.ListResetRequests(&user, c.Query("status", models.ResetStatusPending))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Device reset requests retrieved", requests)
+}
+
+func (dc *DeviceController) ApproveResetRequest(c *fiber.Ctx) error {
+	return dc.reviewResetRequest(c, true)
+}
+
+func (dc *DeviceController) RejectResetRequest(c *fiber.Ctx) error {
+	return dc.reviewResetRequest(c, false)
+}
+
+func (dc *DeviceController) reviewResetRequest(c *fiber.Ctx, approve bool) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.ReviewRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		if err := dc.validator.Struct(req); err != nil {
+			return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+		}
+	}
+
+	request, err := dc.deviceService.ReviewResetRequest(&user, c.Params("id"), approve, req.Note)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	message := "Device reset request rejected"
+	if approve {
+		message = "Device reset request approved, devices revoked"
+	}
+	return utils.SuccessResponse(c, message, request)
+}
+
+func (dc *DeviceController) ListAttempts(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -7)
+	if value := c.Query("from"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid from date, use YYYY-MM-DD")
+		}
+		from = dc.config.DayStart(parsed)
+	}
+	if value := c.Query("to"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid to date, use YYYY-MM-DD")
+		}
+		to = dc.config.DayStart(parsed).Add(24 * time.Hour)
+	}
+
+	limit := c.QueryInt("limit", 100)
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	attempts, err := dc.deviceService.ListAttempts(&user, from, to, limit)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Unbound device attempts retrieved", fiber.Map{
+		"attempts": attempts,
+		"count":    len(attempts),
+	})
+}
@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/realtime"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	eventTicketTTL     = time.Minute
+	eventHeartbeat     = 25 * time.Second
+	eventWriteTimeout  = 10 * time.Second
+	eventReplayLimit   = 500
+	eventRetryInterval = 3000 // milidetik, saran jeda reconnect untuk EventSource
+)
+
+type EventController struct {
+	db     *mongo.Database
+	hub    *realtime.Hub
+	config *config.Config
+}
+
+func NewEventController(db *mongo.Database, cfg *config.Config, hub *realtime.Hub) *EventController {
+	return &EventController{
+		db:     db,
+		hub:    hub,
+		config: cfg,
+	}
+}
+
+// CreateTicket membuat ticket singkat untuk membuka stream dari browser. Ticket hanya
+// bisa dipakai untuk satu koneksi, lihat StreamAuthMiddleware.
+func (ec *EventController) CreateTicket(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	ticketID, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create event ticket")
+	}
+
+	expiresAt := time.Now().Add(eventTicketTTL)
+	return utils.SuccessResponse(c, "Event ticket created", models.EventTicket{
+		Ticket:    utils.SignTicket(utils.SigningKey(ec.config, utils.SigningPurposeTicket), user.ID.Hex(), ticketID, expiresAt),
+		ExpiresAt: expiresAt.UTC(),
+	})
+}
+
+// Authorize menentukan scope stream (?class_id= atau ?scope=self|class|school) dan
+// posisi replay dari header Last-Event-ID atau ?last_event_id=
+func (ec *EventController) Authorize(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	scope, err := services.ResolveEventScope(&user, c.Query("class_id"), c.Query("scope"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error())
+	}
+
+	lastEventID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	var lastID primitive.ObjectID
+	if lastEventID != "" {
+		if lastID, err = primitive.ObjectIDFromHex(lastEventID); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid Last-Event-ID")
+		}
+	}
+
+	c.Locals("event_scope", *scope)
+	c.Locals("last_event_id", lastID)
+	return c.Next()
+}
+
+// Stream mengirim event absensi lewat Server-Sent Events
+func (ec *EventController) Stream(c *fiber.Ctx) error {
+	scope := c.Locals("event_scope").(realtime.Scope)
+	lastID := c.Locals("last_event_id").(primitive.ObjectID)
+
+	sub, backlog, err := ec.subscribe(scope, lastID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load missed events")
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer ec.hub.Unsubscribe(sub)
+
+		heartbeat := time.NewTicker(eventHeartbeat)
+		defer heartbeat.Stop()
+
+		fmt.Fprintf(w, "retry: %d\n\n", eventRetryInterval)
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		last := lastID
+		send := func(event models.AttendanceEvent) error {
+			if !realtime.After(&event, last) {
+				return nil
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID.Hex(), event.Type, data)
+			last = event.ID
+			return w.Flush()
+		}
+
+		for _, event := range backlog {
+			if err := send(event); err != nil {
+				return
+			}
+		}
+
+		for {
+			select {
+			case event, ok := <-sub.Events:
+				if !ok {
+					return
+				}
+				if err := send(event); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	})
+
+	return nil
+}
+
+// RequireWebSocket menolak request biasa ke endpoint WebSocket
+func (ec *EventController) RequireWebSocket(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return utils.ErrorResponse(c, fiber.StatusUpgradeRequired, "WebSocket upgrade required")
+	}
+	return c.Next()
+}
+
+// WebSocket mengirim event yang sama dengan Stream, satu pesan JSON per event
+func (ec *EventController) WebSocket() fiber.Handler {
+	return websocket.New(func(conn *websocket.Conn) {
+		scope := conn.Locals("event_scope").(realtime.Scope)
+		lastID := conn.Locals("last_event_id").(primitive.ObjectID)
+
+		sub, backlog, err := ec.subscribe(scope, lastID)
+		if err != nil {
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "failed to load missed events"),
+				time.Now().Add(eventWriteTimeout))
+			return
+		}
+		defer ec.hub.Unsubscribe(sub)
+
+		// pesan dari client tidak dipakai, dibaca hanya untuk mendeteksi koneksi ditutup
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		heartbeat := time.NewTicker(eventHeartbeat)
+		defer heartbeat.Stop()
+
+		last := lastID
+		send := func(event models.AttendanceEvent) error {
+			if !realtime.After(&event, last) {
+				return nil
+			}
+			conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				return err
+			}
+			last = event.ID
+			return nil
+		}
+
+		for _, event := range backlog {
+			if err := send(event); err != nil {
+				return
+			}
+		}
+
+		for {
+			select {
+			case event, ok := <-sub.Events:
+				if !ok {
+					conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client too slow, reconnect with last_event_id"),
+						time.Now().Add(eventWriteTimeout))
+					return
+				}
+				if err := send(event); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventWriteTimeout)); err != nil {
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	})
+}
+
+// subscribe mendaftar ke hub sebelum mengambil backlog supaya tidak ada event yang
+// terlewat di antara keduanya; duplikat dibuang dengan membandingkan ID
+func (ec *EventController) subscribe(scope realtime.Scope, lastID primitive.ObjectID) (*realtime.Subscription, []models.AttendanceEvent, error) {
+	sub := ec.hub.Subscribe(scope)
+	if lastID.IsZero() {
+		return sub, nil, nil
+	}
+
+	backlog, err := ec.hub.Replay(lastID, scope, eventReplayLimit)
+	if err != nil {
+		log.Printf("Error replaying attendance events: %v", err)
+		ec.hub.Unsubscribe(sub)
+		return nil, nil, err
+	}
+	return sub, backlog, nil
+}
@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GuardianController melayani akun orang tua/wali (akses baca ke data anak dan surat sakit)
+// serta penerbitan kode undangan oleh sekolah
+type GuardianController struct {
+	db              *mongo.Database
+	validator       *validator.Validate
+	guardianService services.GuardianServiceInterface
+	leaveService    services.LeaveServiceInterface
+	webhookService  services.WebhookServiceInterface
+	attendance      *AttendanceController
+}
+
+func NewGuardianController(db *mongo.Database, cfg *config.Config, selfieService services.SelfieServiceInterface) *GuardianController {
+	return &GuardianController{
+		db:              db,
+		validator:       validator.New(),
+		guardianService: services.NewGuardianService(db, cfg),
+		leaveService:    services.NewLeaveService(db, cfg),
+		webhookService:  services.NewWebhookService(db, cfg),
+		attendance:      NewAttendanceController(db, cfg, selfieService),
+	}
+}
+
+// Register membuat akun orang tua, wajib dengan kode undangan dari sekolah
+func (gc *GuardianController) Register(c *fiber.Ctx) error {
+	var req models.RegisterGuardianRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := gc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	user, err := gc.guardianService.RegisterGuardian(&req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	token, err := utils.GenerateJWT(user.ID.Hex())
+	if err != nil {
+		log.Printf("Error generating JWT: %v", err)
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate token")
+	}
+
+	gc.webhookService.Enqueue(models.WebhookUserRegistered, primitive.NewObjectID().Hex(), user.UserPublic())
+
+	return utils.SuccessResponse(c, "Guardian registered successfully", models.LoginResponse{
+		Token: token,
+		User:  user.UserPublic(),
+	})
+}
+
+func (gc *GuardianController) ListChildren(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	children, err := gc.guardianService.ListChildren(user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Children retrieved", children)
+}
+
+// LinkChild menautkan anak lain dengan kode undangan baru
+func (gc *GuardianController) LinkChild(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.LinkGuardianRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := gc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	child, err := gc.guardianService.LinkStudent(&user, req.InviteCode)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Child linked successfully", child)
+}
+
+func (gc *GuardianController) GetChildAttendance(c *fiber.Ctx) error {
+	child, err := gc.child(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error())
+	}
+	return gc.attendance.respondHistory(c, *child)
+}
+
+func (gc *GuardianController) GetChildStats(c *fiber.Ctx) error {
+	child, err := gc.child(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error())
+	}
+	return gc.attendance.respondStats(c, *child)
+}
+
+func (gc *GuardianController) ListChildLeaveRequests(c *fiber.Ctx) error {
+	child, err := gc.child(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error())
+	}
+
+	requests, err := gc.leaveService.ListStudentRequests(child.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Leave requests retrieved", requests)
+}
+
+// SubmitSickNote surat sakit dari orang tua atas nama anaknya, ditinjau wali kelas
+func (gc *GuardianController) SubmitSickNote(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	child, err := gc.child(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error())
+	}
+
+	var req models.SickNoteInput
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := gc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	request, err := gc.leaveService.SubmitRequest(&user, child.ID, &models.LeaveRequestInput{
+		Type:        models.LeaveTypeSick,
+		From:        req.From,
+		To:          req.To,
+		Reason:      req.Reason,
+		EvidenceURL: req.EvidenceURL,
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Sick note submitted", request)
+}
+
+// CreateInvite menerbitkan kode undangan orang tua, kode hanya ditampilkan sekali
+func (gc *GuardianController) CreateInvite(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.CreateGuardianInviteRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		if err := gc.validator.Struct(req); err != nil {
+			return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+		}
+	}
+
+	invite, err := gc.guardianService.CreateInvite(&user, c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Guardian invite created, share this code with the parent", invite)
+}
+
+func (gc *GuardianController) ListGuardians(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	guardians, err := gc.guardianService.ListGuardians(&user, c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Guardians retrieved", guardians)
+}
+
+func (gc *GuardianController) Unlink(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	if err := gc.guardianService.Unlink(&user, c.Params("id"), c.Params("linkId")); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Guardian unlinked", nil)
+}
+
+// child mengambil anak dari :id dan memastikan sudah ditautkan ke orang tua yang login
+func (gc *GuardianController) child(c *fiber.Ctx) (*models.User, error) {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return gc.guardianService.FindChild(user.ID, c.Params("id"))
+}
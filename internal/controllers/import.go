@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"strconv"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/roster"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ImportController struct {
+	db            *mongo.Database
+	validator     *validator.Validate
+	importService services.StudentImportServiceInterface
+}
+
+func NewImportController(db *mongo.Database, cfg *config.Config) *ImportController {
+	return &ImportController{
+		db:            db,
+		validator:     validator.New(),
+		importService: services.NewStudentImportService(db, cfg),
+	}
+}
+
+// ImportStudents menerima file roster (multipart "file", .csv/.xlsx). Jika ada baris yang
+// tidak valid tidak ada data yang disimpan dan laporan per baris dikembalikan dengan status 422.
+func (ic *ImportController) ImportStudents(c *fiber.Ctx) error {
+	opts := models.StudentImportOptions{
+		Mode: c.FormValue("mode", models.ImportModePassword),
+	}
+	if value := c.FormValue("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "dry_run must be true or false")
+		}
+		opts.DryRun = dryRun
+	}
+
+	if err := ic.validator.Struct(opts); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "file is required")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "failed to read file")
+	}
+	defer file.Close()
+
+	records, err := roster.Parse(header.Filename, file)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	report, err := ic.importService.Import(records, &opts)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if !report.Applied && report.Failed > 0 {
+		return utils.ErrorResponseWithData(c, fiber.StatusUnprocessableEntity, "Some rows are invalid, nothing was saved", report)
+	}
+	if report.DryRun {
+		return utils.SuccessResponse(c, "Dry run completed, nothing was saved", report)
+	}
+	return utils.SuccessResponse(c, "Students imported", report)
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type KioskController struct {
+	db           *mongo.Database
+	validator    *validator.Validate
+	kioskService services.KioskServiceInterface
+}
+
+func NewKioskController(db *mongo.Database, cfg *config.Config) *KioskController {
+	return &KioskController{
+		db:           db,
+		validator:    validator.New(),
+		kioskService: services.NewKioskService(db, cfg),
+	}
+}
+
+func (kc *KioskController) CreateKiosk(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.CreateKioskRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := kc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	credentials, err := kc.kioskService.CreateKiosk(user.ID, &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Kiosk registered, store the API key now as it will not be shown again", credentials)
+}
+
+func (kc *KioskController) ListKiosks(c *fiber.Ctx) error {
+	kiosks, err := kc.kioskService.ListKiosks()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Kiosks retrieved", kiosks)
+}
+
+func (kc *KioskController) GetKiosk(c *fiber.Ctx) error {
+	kiosk, err := kc.kioskService.GetKiosk(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Kiosk retrieved", kiosk)
+}
+
+func (kc *KioskController) UpdateKiosk(c *fiber.Ctx) error {
+	var req models.UpdateKioskRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := kc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	kiosk, err := kc.kioskService.UpdateKiosk(c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Kiosk updated", kiosk)
+}
+
+func (kc *KioskController) RotateKey(c *fiber.Ctx) error {
+	credentials, err := kc.kioskService.RotateKey(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Kiosk key rotated, store the API key now as it will not be shown again", credentials)
+}
+
+func (kc *KioskController) DeleteKiosk(c *fiber.Ctx) error {
+	if err := kc.kioskService.DeleteKiosk(c.Params("id")); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Kiosk deactivated", nil)
+}
+
+// GetQRCode dipanggil layar kiosk secara berkala untuk menampilkan QR terbaru
+func (kc *KioskController) GetQRCode(c *fiber.Ctx) error {
+	kiosk, ok := c.Locals("kiosk").(models.Kiosk)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Kiosk not found")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return utils.SuccessResponse(c, "QR code generated", fiber.Map{
+		"kiosk": fiber.Map{
+			"id":   kiosk.ID,
+			"name": kiosk.Name,
+			"type": kiosk.Type,
+		},
+		"qr": kc.kioskService.GenerateQRCode(&kiosk),
+	})
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type LeaveController struct {
+	db           *mongo.Database
+	validator    *validator.Validate
+	leaveService services.LeaveServiceInterface
+}
+
+func NewLeaveController(db *mongo.Database, cfg *config.Config) *LeaveController {
+	return &LeaveController{
+		db:           db,
+		validator:    validator.New(),
+		leaveService: services.NewLeaveService(db, cfg),
+	}
+}
+
+// SubmitRequest pengajuan sakit/izin oleh siswa untuk dirinya sendiri
+func (lc *LeaveController) SubmitRequest(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.LeaveRequestInput
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := lc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	request, err := lc.leaveService.SubmitRequest(&user, user.ID, &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Leave request submitted", request)
+}
+
+func (lc *LeaveController) ListOwnRequests(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	requests, err := lc.leaveService.ListStudentRequests(user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Leave requests retrieved", requests)
+}
+
+func (lc *LeaveController) ListRequests(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	requests, err := lc.leaveService.ListRequests(&user, c.Query("status", models.LeaveStatusPending))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Leave requests retrieved", requests)
+}
+
+func (lc *LeaveController) ApproveRequest(c *fiber.Ctx) error {
+	return lc.reviewRequest(c, true)
+}
+
+func (lc *LeaveController) RejectRequest(c *fiber.Ctx) error {
+	return lc.reviewRequest(c, false)
+}
+
+func (lc *LeaveController) reviewRequest(c *fiber.Ctx, approve bool) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.ReviewRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+		if err := lc.validator.Struct(req); err != nil {
+			return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+		}
+	}
+
+	request, err := lc.leaveService.ReviewRequest(&user, c.Params("id"), approve, req.Note)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	message := "Leave request rejected"
+	switch {
+	case approve && len(request.FailedDates) > 0:
+		message = "Leave request approved, some days could not be updated (see failed_dates)"
+	case approve:
+		message = "Leave request approved, attendance updated"
+	}
+	return utils.SuccessResponse(c, message, request)
+}
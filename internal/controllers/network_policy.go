@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"log"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/security"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type NetworkPolicyController struct {
+	db            *mongo.Database
+	validator     *validator.Validate
+	policyService services.NetworkPolicyServiceInterface
+	engine        *security.Engine
+}
+
+func NewNetworkPolicyController(db *mongo.Database, cfg *config.Config, policyService services.NetworkPolicyServiceInterface) *NetworkPolicyController {
+	return &NetworkPolicyController{
+		db:            db,
+		validator:     validator.New(),
+		policyService: policyService,
+		engine:        security.NewEngineFromConfig(cfg),
+	}
+}
+
+func (pc *NetworkPolicyController) ListEntries(c *fiber.Ctx) error {
+	entries, err := pc.policyService.ListEntries(c.Query("type"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Network policies retrieved", fiber.Map{
+		"entries": entries,
+		"count":   len(entries),
+	})
+}
+
+func (pc *NetworkPolicyController) GetEntry(c *fiber.Ctx) error {
+	entry, err := pc.policyService.GetEntry(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Network policy retrieved", entry)
+}
+
+func (pc *NetworkPolicyController) CreateEntry(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.CreateNetworkPolicyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := pc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	entry, err := pc.policyService.CreateEntry(user.ID, &req)
+	if err != nil {
+		if err.Error() == "policy entry already exists" {
+			return utils.ErrorResponse(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Network policy created", entry)
+}
+
+func (pc *NetworkPolicyController) UpdateEntry(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.UpdateNetworkPolicyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := pc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	entry, err := pc.policyService.UpdateEntry(user.ID, c.Params("id"), &req)
+	if err != nil {
+		if err.Error() == "policy entry already exists" {
+			return utils.ErrorResponse(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Network policy updated", entry)
+}
+
+func (pc *NetworkPolicyController) DeleteEntry(c *fiber.Ctx) error {
+	if err := pc.policyService.DeleteEntry(c.Params("id")); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Network policy deleted", nil)
+}
+
+func (pc *NetworkPolicyController) GetCurrentPolicy(c *fiber.Ctx) error {
+	policy, version, err := pc.policyService.CurrentPolicy()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, err.Error())
+	}
+	thresholds := pc.engine.Thresholds()
+
+	return utils.SuccessResponse(c, "Current network policy retrieved", fiber.Map{
+		"version":          version,
+		"policy":           policy,
+		"flag_threshold":   thresholds.Flag,
+		"reject_threshold": thresholds.Reject,
+	})
+}
+
+// DryRun mengevaluasi contoh request terhadap policy yang aktif tanpa menyimpan apa pun
+func (pc *NetworkPolicyController) DryRun(c *fiber.Ctx) error {
+	var req models.NetworkPolicyDryRunRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := pc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	riskRequest := &security.Request{
+		ClientIP:       req.ClientIP,
+		UserAgent:      req.UserAgent,
+		NetworkType:    req.NetworkType,
+		WiFiSSID:       req.WiFiSSID,
+		Carrier:        req.Carrier,
+		DeviceID:       req.DeviceID,
+		Headers:        req.Headers,
+		GPSAccuracy:    req.GPSAccuracy,
+		KnownDeviceIDs: req.KnownDeviceIDs,
+		Time:           time.Now().UTC(),
+	}
+	if req.Latitude != nil && req.Longitude != nil {
+		riskRequest.Latitude = *req.Latitude
+		riskRequest.Longitude = *req.Longitude
+		riskRequest.HasLocation = true
+	}
+
+	policy, version, err := pc.policyService.CurrentPolicy()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, err.Error())
+	}
+	assessment := pc.engine.Evaluate(riskRequest, policy)
+
+	log.Printf("Network policy dry-run for %s: score %.1f (%s)", req.ClientIP, assessment.Score, assessment.Decision)
+	return utils.SuccessResponse(c, "Dry-run evaluated", fiber.Map{
+		"policy_version": version,
+		"assessment":     assessment,
+		"reasons":        security.TopReasons(assessment, 5),
+	})
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type NotificationController struct {
+	db                  *mongo.Database
+	validator           *validator.Validate
+	notificationService services.NotificationServiceInterface
+}
+
+func NewNotificationController(db *mongo.Database, notificationService services.NotificationServiceInterface) *NotificationController {
+	return &NotificationController{
+		db:                  db,
+		validator:           validator.New(),
+		notificationService: notificationService,
+	}
+}
+
+func (nc *NotificationController) GetPreferences(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	pref, err := nc.notificationService.GetPreferences(user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Notification preferences retrieved", fiber.Map{
+		"preferences":        pref,
+		"available_channels": nc.notificationService.AvailableChannels(),
+	})
+}
+
+// UpdatePreferences mengganti seluruh preferensi notifikasi (bahasa, channel, event,
+// jam tenang, kontak orang tua, token push). Orang tua memakai endpoint yang sama di
+// grup /guardian dan menerima notifikasi di email/nomor akunnya sendiri.
+func (nc *NotificationController) UpdatePreferences(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.UpdateNotificationPreferenceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := nc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+	if user.HasRole(models.RoleGuardian) && len(req.Contacts) > 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Guardians receive notifications at their own email and phone, contacts are not used")
+	}
+
+	pref, err := nc.notificationService.UpdatePreferences(user.ID, &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Notification preferences updated", pref)
+}
+
+// ListMyNotifications menampilkan notifikasi tentang absensi user yang login
+func (nc *NotificationController) ListMyNotifications(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	notifications, err := nc.notificationService.ListNotifications(&user.ID, c.Query("status"), c.QueryInt("limit", 50))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Notifications retrieved", notifications)
+}
+
+// ListNotifications untuk admin, filter ?student_id=, ?status=, ?limit=
+func (nc *NotificationController) ListNotifications(c *fiber.Ctx) error {
+	var studentID *primitive.ObjectID
+	if value := c.Query("student_id"); value != "" {
+		objectID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid student ID")
+		}
+		studentID = &objectID
+	}
+
+	notifications, err := nc.notificationService.ListNotifications(studentID, c.Query("status"), c.QueryInt("limit", 50))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Notifications retrieved", notifications)
+}
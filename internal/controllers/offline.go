@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/security"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type OfflineController struct {
+	db             *mongo.Database
+	validator      *validator.Validate
+	offlineService services.OfflineServiceInterface
+	config         *config.Config
+}
+
+func NewOfflineController(db *mongo.Database, cfg *config.Config, policies services.NetworkPolicyServiceInterface) *OfflineController {
+	return &OfflineController{
+		db:             db,
+		validator:      validator.New(),
+		offlineService: services.NewOfflineService(db, cfg, policies),
+		config:         cfg,
+	}
+}
+
+// GetNonce mengeluarkan nonce harian (?date=YYYY-MM-DD, default besok) untuk device
+// yang menandatangani request ini
+func (oc *OfflineController) GetNonce(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	device, ok := c.Locals("device").(models.Device)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Offline check in requires a registered device")
+	}
+
+	date := oc.config.Today().AddDate(0, 0, 1)
+	if value := c.Query("date"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format, use YYYY-MM-DD")
+		}
+		date = parsed
+	}
+
+	nonce, err := oc.offlineService.IssueNonce(user.ID, device.DeviceID, date)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Offline nonce issued", nonce)
+}
+
+// Sync menerima antrean check in offline; hasil dikembalikan per record
+func (oc *OfflineController) Sync(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.OfflineSyncRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := oc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	// sinyal jaringan request sync dari NetworkSecurityMiddleware, dinilai ulang per record
+	network, ok := c.Locals("risk_request").(*security.Request)
+	if !ok {
+		network = &security.Request{ClientIP: utils.ClientIP(c), UserAgent: c.Get("User-Agent")}
+	}
+
+	result := oc.offlineService.Sync(&user, &req, network)
+	return utils.SuccessResponse(c, "Offline attendance synced", result)
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/export"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ReportController struct {
+	db            *mongo.Database
+	validator     *validator.Validate
+	reportService services.ReportServiceInterface
+}
+
+func NewReportController(db *mongo.Database, cfg *config.Config, reportService services.ReportServiceInterface) *ReportController {
+	return &ReportController{
+		db:            db,
+		validator:     validator.New(),
+		reportService: reportService,
+	}
+}
+
+// CreateReport membuat rekap absensi; 200 jika langsung jadi, 202 jika diproses di background
+func (rc *ReportController) CreateReport(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.CreateReportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := rc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	job, err := rc.reportService.CreateReport(&user, &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	switch job.Status {
+	case models.ReportStatusDone:
+		return utils.SuccessResponse(c, "Report generated", job)
+	case models.ReportStatusFailed:
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate report: "+job.Error)
+	default:
+		c.Status(fiber.StatusAccepted)
+		return utils.SuccessResponse(c, "Report is being generated, check its status for the download link", job)
+	}
+}
+
+func (rc *ReportController) ListReports(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	jobs, err := rc.reportService.ListJobs(&user, c.QueryInt("limit", 20))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Reports retrieved", jobs)
+}
+
+func (rc *ReportController) GetReport(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	job, err := rc.reportService.GetJob(&user, c.Params("id"))
+	if err != nil {
+		if err.Error() == "report not found" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Report retrieved", job)
+}
+
+// Download mengunduh file laporan lewat link bertanda tangan (?expires=&signature=)
+func (rc *ReportController) Download(c *fiber.Ctx) error {
+	job, reader, err := rc.reportService.OpenDownload(c.Params("id"), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error())
+	}
+
+	c.Attachment(job.FileName)
+	c.Set(fiber.HeaderContentType, export.ContentType(job.Format))
+	return c.SendStream(reader)
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TimetableController struct {
+	db               *mongo.Database
+	validator        *validator.Validate
+	timetableService services.TimetableServiceInterface
+}
+
+func NewTimetableController(db *mongo.Database) *TimetableController {
+	return &TimetableController{
+		db:               db,
+		validator:        validator.New(),
+		timetableService: services.NewTimetableService(db),
+	}
+}
+
+func (tc *TimetableController) ListSubjects(c *fiber.Ctx) error {
+	subjects, err := tc.timetableService.ListSubjects(c.QueryBool("include_inactive", false))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Subjects retrieved", subjects)
+}
+
+func (tc *TimetableController) CreateSubject(c *fiber.Ctx) error {
+	var req models.CreateSubjectRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := tc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	subject, err := tc.timetableService.CreateSubject(&req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Subject created successfully", subject)
+}
+
+func (tc *TimetableController) UpdateSubject(c *fiber.Ctx) error {
+	var req models.UpdateSubjectRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := tc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	subject, err := tc.timetableService.UpdateSubject(c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Subject updated successfully", subject)
+}
+
+func (tc *TimetableController) DeleteSubject(c *fiber.Ctx) error {
+	if err := tc.timetableService.DeleteSubject(c.Params("id")); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Subject deleted successfully", nil)
+}
+
+// ListPeriods jadwal pelajaran, bisa difilter ?class_id= dan ?day=
+func (tc *TimetableController) ListPeriods(c *fiber.Ctx) error {
+	periods, err := tc.timetableService.ListPeriods(c.Query("class_id"), nil, c.QueryInt("day", 0))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Timetable retrieved", periods)
+}
+
+// ListOwnPeriods jadwal mengajar guru yang sedang login
+func (tc *TimetableController) ListOwnPeriods(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	periods, err := tc.timetableService.ListPeriods(c.Query("class_id"), &user.ID, c.QueryInt("day", 0))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Timetable retrieved", periods)
+}
+
+func (tc *TimetableController) CreatePeriod(c *fiber.Ctx) error {
+	var req models.CreatePeriodRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := tc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	period, err := tc.timetableService.CreatePeriod(&req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Timetable period created successfully", period)
+}
+
+func (tc *TimetableController) UpdatePeriod(c *fiber.Ctx) error {
+	var req models.UpdatePeriodRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := tc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	period, err := tc.timetableService.UpdatePeriod(c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Timetable period updated successfully", period)
+}
+
+func (tc *TimetableController) DeletePeriod(c *fiber.Ctx) error {
+	if err := tc.timetableService.DeletePeriod(c.Params("id")); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Timetable period deleted successfully", nil)
+}
@@ -3,26 +3,42 @@ package controllers
 import (
 	"context"
 	"log"
+	"math"
 	"time"
+	"ujikom-backend/internal/config"
 	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
 	"ujikom-backend/internal/utils"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserController struct {
-	db        *mongo.Database
-	validator *validator.Validate
+	db             *mongo.Database
+	config         *config.Config
+	validator      *validator.Validate
+	classService   services.ClassServiceInterface
+	userService    services.UserServiceInterface
+	webhookService services.WebhookServiceInterface
+
+	impersonationService services.ImpersonationServiceInterface
 }
 
-func NewUserController(db *mongo.Database) *UserController {
+func NewUserController(db *mongo.Database, cfg *config.Config) *UserController {
 	return &UserController{
-		db:        db,
-		validator: validator.New(),
+		db:             db,
+		config:         cfg,
+		validator:      validator.New(),
+		classService:   services.NewClassService(db),
+		userService:    services.NewUserService(db),
+		webhookService: services.NewWebhookService(db, cfg),
+
+		impersonationService: services.NewImpersonationService(db, cfg),
 	}
 }
 
@@ -58,11 +74,28 @@ func (uc *UserController) UpdateProfile(c *fiber.Ctx) error {
 	if req.Name != "" {
 		updateDoc["name"] = req.Name
 	}
-	if req.Kelas != "" {
-		updateDoc["kelas"] = req.Kelas
-	}
-	if req.Jurusan != "" {
-		updateDoc["jurusan"] = req.Jurusan
+	if req.ClassID != "" || req.MajorID != "" {
+		// kelas perwalian guru diatur admin lewat /admin/classes/:id/homeroom
+		if user.HasRole(models.RoleTeacher) || user.HasRole(models.RoleAdmin) {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Only students can change their class")
+		}
+
+		classID := req.ClassID
+		if classID == "" {
+			if user.ClassID == nil {
+				return utils.ErrorResponse(c, fiber.StatusBadRequest, "class_id is required")
+			}
+			classID = user.ClassID.Hex()
+		}
+
+		class, major, err := uc.classService.ResolveEnrollment(classID, req.MajorID)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+		}
+		updateDoc["class_id"] = class.ID
+		updateDoc["kelas"] = class.Name
+		updateDoc["major_id"] = major.ID
+		updateDoc["jurusan"] = major.Name
 	}
 	if req.Phone != "" {
 		updateDoc["phone"] = req.Phone
@@ -168,34 +201,215 @@ func (uc *UserController) DeactivateAccount(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to deactivate account")
 	}
 
+	user.IsActive = false
+	uc.webhookService.Enqueue(models.WebhookUserDeactivated, primitive.NewObjectID().Hex(), user.UserPublic())
+
 	log.Printf("Account deactivated: %s", user.Email)
 	return utils.SuccessResponse(c, "Account deactivated successfully", nil)
 }
 
-func (uc *UserController) GetAllUsers(c *fiber.Ctx) error {
-	
-	collection := uc.db.Collection("users")
-	
-	cursor, err := collection.Find(context.Background(), bson.M{"is_active": true})
-	if err != nil {
-		log.Printf("Error finding users: %v", err)
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Database error")
+// ListUsers daftar user untuk admin. ?q= mencari nama, email, atau NIS; filter ?role=,
+// ?class_id=, ?major_id=, ?status=active|inactive|all; urutan ?sort=name atau ?sort=-created_at
+func (uc *UserController) ListUsers(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	filter := models.UserFilter{
+		Role:   c.Query("role"),
+		Status: c.Query("status"),
+		Sort:   c.Query("sort"),
+	}
+	if classID := c.Query("class_id"); classID != "" {
+		objectID, err := primitive.ObjectIDFromHex(classID)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid class ID")
+		}
+		filter.ClassID = &objectID
+	}
+	if majorID := c.Query("major_id"); majorID != "" {
+		objectID, err := primitive.ObjectIDFromHex(majorID)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid major ID")
+		}
+		filter.MajorID = &objectID
 	}
-	defer cursor.Close(context.Background())
 
 	var users []models.User
-	if err = cursor.All(context.Background(), &users); err != nil {
-		log.Printf("Error decoding users: %v", err)
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Database error")
+	var total int64
+	var err error
+	if query := c.Query("q"); query != "" {
+		users, total, err = uc.userService.SearchUsers(query, &filter, limit, offset)
+	} else {
+		users, total, err = uc.userService.GetAllUsers(&filter, limit, offset)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
 	}
 
-	var publicUsers []models.User
+	publicUsers := make([]models.User, 0, len(users))
 	for _, user := range users {
 		publicUsers = append(publicUsers, user.UserPublic())
 	}
 
 	return utils.SuccessResponse(c, "Users retrieved successfully", fiber.Map{
 		"users": publicUsers,
-		"count": len(publicUsers),
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": int(math.Ceil(float64(total) / float64(limit))),
+		},
 	})
-}
\ No newline at end of file
+}
+
+func (uc *UserController) GetUser(c *fiber.Ctx) error {
+	user, err := uc.userService.GetUserByID(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "User retrieved successfully", user.UserPublic())
+}
+
+func (uc *UserController) UpdateUser(c *fiber.Ctx) error {
+	admin, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.AdminUpdateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := uc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	user, err := uc.userService.UpdateUser(&admin, c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "User updated successfully", user.UserPublic())
+}
+
+func (uc *UserController) DeactivateUser(c *fiber.Ctx) error {
+	admin, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+	if c.Params("id") == admin.ID.Hex() {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "You cannot deactivate your own account here")
+	}
+
+	if err := uc.userService.DeleteUser(c.Params("id")); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := uc.userService.GetUserByID(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+	uc.webhookService.Enqueue(models.WebhookUserDeactivated, primitive.NewObjectID().Hex(), user.UserPublic())
+
+	log.Printf("Account %s deactivated by admin %s", user.Email, admin.Email)
+	return utils.SuccessResponse(c, "User deactivated successfully", user.UserPublic())
+}
+
+func (uc *UserController) ActivateUser(c *fiber.Ctx) error {
+	if err := uc.userService.ActivateUser(c.Params("id")); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	user, err := uc.userService.GetUserByID(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "User activated successfully", user.UserPublic())
+}
+
+// ResetPassword password sementara hanya ditampilkan sekali di respons ini
+func (uc *UserController) ResetPassword(c *fiber.Ctx) error {
+	admin, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	user, password, err := uc.userService.ResetPassword(c.Params("id"))
+	if err != nil {
+		if services.IsAdminPasswordReset(err) {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Admin passwords cannot be reset by another admin")
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	log.Printf("Password of %s reset by admin %s", user.Email, admin.Email)
+	return utils.SuccessResponse(c, "Password reset, share the temporary password with the user", models.ResetPasswordResponse{
+		User:              user.UserPublic(),
+		TemporaryPassword: password,
+	})
+}
+
+// Impersonate membuat token singkat untuk login sebagai user lain. Token hanya untuk melihat:
+// ganti password, nonaktifkan akun, refresh token, ubah profil dan preferensi, serta aksi presensi, koreksi, izin dan
+// device ditolak, dan admin lain tidak bisa di-impersonate. Setiap sesi dicatat untuk audit.
+func (uc *UserController) Impersonate(c *fiber.Ctx) error {
+	admin, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+	if _, impersonating := c.Locals("impersonated_by").(string); impersonating {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Not allowed while impersonating")
+	}
+
+	user, err := uc.userService.GetUserByID(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+	if !user.IsActive {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "User account is inactive")
+	}
+	if user.HasRole(models.RoleAdmin) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Admin accounts cannot be impersonated")
+	}
+
+	// sesi dicatat dulu, tanpa jejak audit token tidak dikeluarkan
+	session, err := uc.impersonationService.StartSession(&admin, user, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	token, err := utils.GenerateImpersonationJWT(user.ID.Hex(), admin.ID.Hex(), session.ID.Hex(), session.ExpiresAt)
+	if err != nil {
+		log.Printf("Error generating JWT: %v", err)
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate token")
+	}
+
+	log.Printf("Admin %s is impersonating %s until %s (session %s)", admin.Email, user.Email, session.ExpiresAt.Format(time.RFC3339), session.ID.Hex())
+	return utils.SuccessResponse(c, "Impersonation token created", models.ImpersonationResponse{
+		SessionID: session.ID,
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+		User:      user.UserPublic(),
+	})
+}
+
+// ListImpersonations menampilkan jejak audit sesi impersonate, terbaru dulu
+func (uc *UserController) ListImpersonations(c *fiber.Ctx) error {
+	sessions, err := uc.impersonationService.ListSessions(c.Query("admin_id"), c.Query("user_id"), c.QueryInt("limit", 50))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Impersonation sessions retrieved", sessions)
+}
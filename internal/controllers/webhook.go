@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type WebhookController struct {
+	db             *mongo.Database
+	validator      *validator.Validate
+	webhookService services.WebhookServiceInterface
+}
+
+func NewWebhookController(db *mongo.Database, webhookService services.WebhookServiceInterface) *WebhookController {
+	return &WebhookController{
+		db:             db,
+		validator:      validator.New(),
+		webhookService: webhookService,
+	}
+}
+
+func (wc *WebhookController) CreateWebhook(c *fiber.Ctx) error {
+	user, ok := c.Locals("user").(models.User)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := wc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	credentials, err := wc.webhookService.CreateWebhook(user.ID, &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Webhook registered, store the secret now as it will not be shown again", credentials)
+}
+
+func (wc *WebhookController) ListWebhooks(c *fiber.Ctx) error {
+	webhooks, err := wc.webhookService.ListWebhooks()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Webhooks retrieved", fiber.Map{
+		"webhooks":         webhooks,
+		"available_events": models.WebhookEvents,
+	})
+}
+
+func (wc *WebhookController) GetWebhook(c *fiber.Ctx) error {
+	webhook, err := wc.webhookService.GetWebhook(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Webhook retrieved", webhook)
+}
+
+func (wc *WebhookController) UpdateWebhook(c *fiber.Ctx) error {
+	var req models.UpdateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := wc.validator.Struct(req); err != nil {
+		return utils.ValidationErrorResponse(c, utils.ValidatorErrors(err))
+	}
+
+	webhook, err := wc.webhookService.UpdateWebhook(c.Params("id"), &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Webhook updated", webhook)
+}
+
+func (wc *WebhookController) RotateSecret(c *fiber.Ctx) error {
+	credentials, err := wc.webhookService.RotateSecret(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Webhook secret rotated, store the secret now as it will not be shown again", credentials)
+}
+
+func (wc *WebhookController) DeleteWebhook(c *fiber.Ctx) error {
+	if err := wc.webhookService.DeleteWebhook(c.Params("id")); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Webhook deleted", nil)
+}
+
+func (wc *WebhookController) Ping(c *fiber.Ctx) error {
+	delivery, err := wc.webhookService.Ping(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Ping queued", delivery)
+}
+
+// ListDeliveries menampilkan log pengiriman, filter ?webhook_id=, ?status=, ?limit=
+func (wc *WebhookController) ListDeliveries(c *fiber.Ctx) error {
+	webhookID := c.Params("id", c.Query("webhook_id"))
+	deliveries, err := wc.webhookService.ListDeliveries(webhookID, c.Query("status"), c.QueryInt("limit", 50))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Webhook deliveries retrieved", deliveries)
+}
+
+func (wc *WebhookController) RetryDelivery(c *fiber.Ctx) error {
+	delivery, err := wc.webhookService.RetryDelivery(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, "Delivery queued for retry", delivery)
+}
@@ -0,0 +1,269 @@
+// Package export merender rekap absensi (matriks hari x siswa) ke CSV, XLSX dan PDF
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"ujikom-backend/internal/models"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/xuri/excelize/v2"
+)
+
+// Write merender matriks sesuai format (csv, xlsx, pdf)
+func Write(w io.Writer, format string, m *models.AttendanceMatrix) error {
+	switch format {
+	case models.ReportFormatCSV:
+		return WriteCSV(w, m)
+	case models.ReportFormatXLSX:
+		return WriteXLSX(w, m)
+	case models.ReportFormatPDF:
+		return WritePDF(w, m)
+	default:
+		return fmt.Errorf("unsupported report format: %s", format)
+	}
+}
+
+// ContentType mengembalikan MIME type untuk format laporan
+func ContentType(format string) string {
+	switch format {
+	case models.ReportFormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case models.ReportFormatPDF:
+		return "application/pdf"
+	default:
+		return "text/csv"
+	}
+}
+
+func header(m *models.AttendanceMatrix) []string {
+	columns := []string{"No", "NIS", "Nama", "Kelas"}
+	for _, day := range m.Days {
+		columns = append(columns, day.Format("02/01"))
+	}
+	columns = append(columns, m.Codes...)
+	return append(columns, "%")
+}
+
+func row(m *models.AttendanceMatrix, index int, r models.AttendanceRow) []string {
+	cells := []string{strconv.Itoa(index + 1), r.NIS, r.Name, r.Kelas}
+	cells = append(cells, r.Marks...)
+	for _, code := range m.Codes {
+		cells = append(cells, strconv.Itoa(r.Totals[code]))
+	}
+	return append(cells, strconv.FormatFloat(r.Percentage, 'f', 1, 64))
+}
+
+func period(m *models.AttendanceMatrix) string {
+	return "Periode: " + m.From.Format("02-01-2006") + " s/d " + m.To.Format("02-01-2006")
+}
+
+func legend(m *models.AttendanceMatrix) string {
+	text := "Keterangan:"
+	codes := append(append([]string{}, m.Codes...), "-")
+	for _, code := range codes {
+		text += " " + code + " = " + m.Legend[code] + ";"
+	}
+	return text
+}
+
+func WriteCSV(w io.Writer, m *models.AttendanceMatrix) error {
+	writer := csv.NewWriter(w)
+
+	records := [][]string{{m.Title}, {period(m)}, header(m)}
+	for i, r := range m.Rows {
+		records = append(records, row(m, i, r))
+	}
+	records = append(records, []string{legend(m)})
+
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteXLSX(w io.Writer, m *models.AttendanceMatrix) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := "Rekap"
+	f.SetSheetName("Sheet1", sheet)
+
+	columns := header(m)
+	lastColumn, _ := excelize.ColumnNumberToName(len(columns))
+
+	f.SetCellValue(sheet, "A1", m.Title)
+	f.SetCellValue(sheet, "A2", period(m))
+	f.MergeCell(sheet, "A1", lastColumn+"1")
+	f.MergeCell(sheet, "A2", lastColumn+"2")
+
+	titleStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	f.SetCellStyle(sheet, "A1", "A1", titleStyle)
+
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"D9E1F2"}},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+		Border:    cellBorder(),
+	})
+	cellStyle, _ := f.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+		Border:    cellBorder(),
+	})
+	textStyle, _ := f.NewStyle(&excelize.Style{Border: cellBorder()})
+
+	const headerRow = 4
+	for i, value := range columns {
+		cell, _ := excelize.CoordinatesToCellName(i+1, headerRow)
+		f.SetCellValue(sheet, cell, value)
+	}
+	f.SetCellStyle(sheet, "A4", lastColumn+"4", headerStyle)
+
+	for i, r := range m.Rows {
+		rowNumber := headerRow + 1 + i
+		for j, value := range row(m, i, r) {
+			cell, _ := excelize.CoordinatesToCellName(j+1, rowNumber)
+			// kolom angka disimpan sebagai angka supaya bisa dijumlah di Excel
+			if j == 0 || j >= 4+len(m.Days) {
+				if number, err := strconv.ParseFloat(value, 64); err == nil {
+					f.SetCellValue(sheet, cell, number)
+					continue
+				}
+			}
+			f.SetCellValue(sheet, cell, value)
+		}
+		first, _ := excelize.CoordinatesToCellName(1, rowNumber)
+		nameEnd, _ := excelize.CoordinatesToCellName(4, rowNumber)
+		marksStart, _ := excelize.CoordinatesToCellName(5, rowNumber)
+		last, _ := excelize.CoordinatesToCellName(len(columns), rowNumber)
+		f.SetCellStyle(sheet, first, nameEnd, textStyle)
+		f.SetCellStyle(sheet, marksStart, last, cellStyle)
+	}
+
+	legendCell, _ := excelize.CoordinatesToCellName(1, headerRow+len(m.Rows)+2)
+	f.SetCellValue(sheet, legendCell, legend(m))
+
+	f.SetColWidth(sheet, "A", "A", 5)
+	f.SetColWidth(sheet, "B", "B", 12)
+	f.SetColWidth(sheet, "C", "C", 28)
+	f.SetColWidth(sheet, "D", "D", 12)
+	if len(m.Days) > 0 {
+		firstDay, _ := excelize.ColumnNumberToName(5)
+		lastDay, _ := excelize.ColumnNumberToName(4 + len(m.Days))
+		f.SetColWidth(sheet, firstDay, lastDay, 6)
+	}
+	f.SetPanes(sheet, &excelize.Panes{
+		Freeze:      true,
+		XSplit:      4,
+		YSplit:      headerRow,
+		TopLeftCell: "E5",
+		ActivePane:  "bottomRight",
+	})
+
+	return f.Write(w)
+}
+
+func cellBorder() []excelize.Border {
+	return []excelize.Border{
+		{Type: "left", Color: "999999", Style: 1},
+		{Type: "right", Color: "999999", Style: 1},
+		{Type: "top", Color: "999999", Style: 1},
+		{Type: "bottom", Color: "999999", Style: 1},
+	}
+}
+
+// WritePDF membuat rekap landscape; kertas A3 dipakai jika kolom hari lebih dari 16
+func WritePDF(w io.Writer, m *models.AttendanceMatrix) error {
+	size := "A4"
+	if len(m.Days) > 16 {
+		size = "A3"
+	}
+
+	pdf := fpdf.New("L", "mm", size, "")
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(false, 10)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pageWidth, pageHeight := pdf.GetPageSize()
+	const (
+		noWidth    = 8.0
+		nisWidth   = 20.0
+		nameWidth  = 45.0
+		kelasWidth = 18.0
+		totalWidth = 9.0
+		rowHeight  = 5.0
+	)
+	fixed := noWidth + nisWidth + nameWidth + kelasWidth + totalWidth*float64(len(m.Codes)+1)
+	dayWidth := 6.0
+	if len(m.Days) > 0 {
+		dayWidth = (pageWidth - 20 - fixed) / float64(len(m.Days))
+		if dayWidth > 10 {
+			dayWidth = 10
+		}
+	}
+
+	widths := []float64{noWidth, nisWidth, nameWidth, kelasWidth}
+	for range m.Days {
+		widths = append(widths, dayWidth)
+	}
+	for range m.Codes {
+		widths = append(widths, totalWidth)
+	}
+	widths = append(widths, totalWidth)
+
+	drawHeader := func() {
+		pdf.SetFont("Helvetica", "B", 7)
+		pdf.SetFillColor(217, 225, 242)
+		for i, value := range header(m) {
+			pdf.CellFormat(widths[i], rowHeight, tr(value), "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 7)
+	}
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.CellFormat(0, 7, tr(m.Title), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(0, 5, tr(period(m)), "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+	drawHeader()
+
+	for i, r := range m.Rows {
+		if pdf.GetY()+rowHeight > pageHeight-15 {
+			pdf.AddPage()
+			drawHeader()
+		}
+		for j, value := range row(m, i, r) {
+			align := "C"
+			if j == 1 || j == 2 || j == 3 {
+				align = "L"
+			}
+			pdf.CellFormat(widths[j], rowHeight, tr(value), "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	if pdf.GetY()+30 > pageHeight-10 {
+		pdf.AddPage()
+	}
+	pdf.Ln(3)
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.MultiCell(0, 4, tr(legend(m)), "", "L", false)
+
+	// kolom tanda tangan untuk arsip
+	pdf.Ln(6)
+	signX := pageWidth - 80
+	pdf.SetX(signX)
+	pdf.CellFormat(70, 5, tr("Dicetak "+m.Created.Format("02-01-2006")), "", 1, "C", false, 0, "")
+	pdf.SetX(signX)
+	pdf.CellFormat(70, 5, "Mengetahui,", "", 1, "C", false, 0, "")
+	pdf.Ln(15)
+	pdf.SetX(signX)
+	pdf.CellFormat(70, 5, "(______________________)", "", 1, "C", false, 0, "")
+
+	return pdf.Output(w)
+}
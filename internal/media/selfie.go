@@ -0,0 +1,119 @@
+// Package media memproses foto unggahan: validasi format, buang metadata EXIF,
+// perbaiki orientasi, dan buat thumbnail
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	ContentTypeJPEG = "image/jpeg"
+	ContentTypePNG  = "image/png"
+
+	// batas pixel sebelum decode supaya gambar kecil berdimensi raksasa tidak menghabiskan memori
+	maxPixels = 40_000_000
+
+	fullQuality  = 85
+	thumbQuality = 75
+)
+
+var (
+	ErrUnsupportedImage = errors.New("selfie must be a JPEG or PNG image")
+	ErrImageTooLarge    = errors.New("selfie dimensions are too large")
+)
+
+type Image struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// ProcessSelfie memvalidasi foto lalu meng-encode ulang sebagai JPEG. Encode ulang
+// otomatis membuang seluruh metadata (EXIF, GPS, info kamera); orientasi EXIF
+// diterapkan dulu ke pixel supaya foto tetap tegak.
+func ProcessSelfie(data []byte, maxDimension, thumbSize int) (*Image, *Image, error) {
+	contentType := http.DetectContentType(data)
+	if contentType != ContentTypeJPEG && contentType != ContentTypePNG {
+		return nil, nil, ErrUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrUnsupportedImage
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, nil, ErrImageTooLarge
+	}
+
+	var src image.Image
+	if contentType == ContentTypeJPEG {
+		src, err = jpeg.Decode(bytes.NewReader(data))
+	} else {
+		src, err = png.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, nil, ErrUnsupportedImage
+	}
+
+	orientation := 1
+	if contentType == ContentTypeJPEG {
+		orientation = exifOrientation(data)
+	}
+
+	full := applyOrientation(fit(src, maxDimension), orientation)
+	fullImage, err := encodeJPEG(full, fullQuality)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	thumbImage, err := encodeJPEG(fit(full, thumbSize), thumbQuality)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fullImage, thumbImage, nil
+}
+
+// fit memperkecil gambar supaya sisi terpanjangnya maksimal size, gambar kecil tidak diperbesar
+func fit(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if size <= 0 || (width <= size && height <= size) {
+		return src
+	}
+
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+	return dst
+}
+
+func encodeJPEG(img image.Image, quality int) (*Image, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	return &Image{
+		Data:        buf.Bytes(),
+		ContentType: ContentTypeJPEG,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}, nil
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"strings"
+	"time"
 	"ujikom-backend/internal/models"
 	"ujikom-backend/internal/utils"
 
@@ -59,7 +60,36 @@ func AuthMiddleware(db *mongo.Database) fiber.Handler {
 
 		c.Locals("user", user)
 		c.Locals("user_id", userID)
+		if adminID, ok := claims["impersonated_by"].(string); ok && adminID != "" {
+			c.Locals("impersonated_by", adminID)
+		}
+
+		return c.Next()
+	}
+}
+
+// BlockImpersonation menolak aksi akun yang tidak boleh dilakukan admin saat login sebagai
+// user lain (ganti password, nonaktifkan akun, perpanjang token)
+func BlockImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("impersonated_by").(string); ok {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Not allowed while impersonating")
+		}
+		return c.Next()
+	}
+}
 
+// BlockImpersonatedWrites membuat token impersonate hanya-baca di grup route: selain
+// GET/HEAD/OPTIONS ditolak supaya admin tidak bisa presensi atau mengajukan atas nama user
+func BlockImpersonatedWrites() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		if _, ok := c.Locals("impersonated_by").(string); ok {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Not allowed while impersonating")
+		}
 		return c.Next()
 	}
 }
@@ -111,4 +141,59 @@ func OptionalAuthMiddleware(db *mongo.Database) fiber.Handler {
 
 		return c.Next()
 	}
-}
\ No newline at end of file
+}
+// StreamAuthMiddleware untuk endpoint SSE/WebSocket: menerima header Authorization seperti
+// AuthMiddleware, atau ?ticket= dari POST /events/ticket untuk client browser yang tidak
+// bisa mengirim header. Ticket dicatat di stream_tickets saat dipakai sehingga tidak bisa
+// dipakai dua kali walaupun ikut tersimpan di log URL.
+func StreamAuthMiddleware(db *mongo.Database, secret string) fiber.Handler {
+	auth := AuthMiddleware(db)
+
+	return func(c *fiber.Ctx) error {
+		ticket := c.Query("ticket")
+		if c.Get("Authorization") != "" || ticket == "" {
+			return auth(c)
+		}
+
+		verified, ok := utils.VerifyTicket(secret, ticket)
+		if !ok {
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid or expired ticket")
+		}
+
+		userID, err := primitive.ObjectIDFromHex(verified.Subject)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid or expired ticket")
+		}
+
+		// insert dengan _id = ID ticket bersifat atomik: hanya satu koneksi yang berhasil
+		_, err = db.Collection("stream_tickets").InsertOne(context.Background(), bson.M{
+			"_id":        verified.ID,
+			"user_id":    userID,
+			"used_at":    time.Now().UTC(),
+			"expires_at": verified.ExpiresAt,
+		})
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Ticket has already been used")
+			}
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Database error")
+		}
+
+		var user models.User
+		err = db.Collection("users").FindOne(context.Background(), bson.M{
+			"_id":       userID,
+			"is_active": true,
+		}).Decode(&user)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found or inactive")
+			}
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Database error")
+		}
+
+		c.Locals("user", user)
+		c.Locals("user_id", userID)
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBlockImpersonatedWrites(t *testing.T) {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if c.Get("X-Test-Impersonated-By") != "" {
+			c.Locals("impersonated_by", c.Get("X-Test-Impersonated-By"))
+		}
+		return c.Next()
+	})
+	app.Use(BlockImpersonatedWrites())
+	app.All("/attendance/checkin", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	tests := []struct {
+		name         string
+		method       string
+		impersonated bool
+		want         int
+	}{
+		{"own token can write", fiber.MethodPost, false, fiber.StatusOK},
+		{"impersonated read", fiber.MethodGet, true, fiber.StatusOK},
+		{"impersonated head", fiber.MethodHead, true, fiber.StatusOK},
+		{"impersonated post", fiber.MethodPost, true, fiber.StatusForbidden},
+		{"impersonated put", fiber.MethodPut, true, fiber.StatusForbidden},
+		{"impersonated delete", fiber.MethodDelete, true, fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/attendance/checkin", nil)
+		if tt.impersonated {
+			req.Header.Set("X-Test-Impersonated-By", "64b000000000000000000001")
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ClientIPMiddleware me-resolve IP client sekali di awal request supaya logging,
+// rate limiting dan network security memakai IP yang sama
+func ClientIPMiddleware(cfg *config.Config) fiber.Handler {
+	trusted := utils.ParseTrustedProxies(cfg.TrustedProxies)
+	header, ok := utils.ParseProxyHeader(cfg.TrustedProxyHeader)
+	if !ok {
+		log.Printf("Warning: unsupported TRUSTED_PROXY_HEADER %q, falling back to %s", cfg.TrustedProxyHeader, utils.ProxyHeaderXForwardedFor)
+		header = utils.ProxyHeaderXForwardedFor
+	}
+
+	return func(c *fiber.Ctx) error {
+		// header yang dikirim lebih dari sekali digabung seperti satu daftar
+		value := string(bytes.Join(c.Request().Header.PeekAll(header), []byte(",")))
+		c.Locals("client_ip", utils.ResolveClientIP(c.Context().RemoteIP().String(), header, value, trusted))
+		return c.Next()
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestClientIPMiddlewareReadsOnlyConfiguredHeader(t *testing.T) {
+	// app.Test memakai koneksi palsu dengan remote address 0.0.0.0
+	cfg := &config.Config{TrustedProxies: []string{"0.0.0.0/32"}, TrustedProxyHeader: "X-Forwarded-For"}
+
+	app := fiber.New()
+	app.Use(ClientIPMiddleware(cfg))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(utils.ClientIP(c))
+	})
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name: "spoofed Forwarded is ignored",
+			headers: map[string]string{
+				"Forwarded":       "for=10.0.0.1",
+				"X-Forwarded-For": "198.51.100.20",
+			},
+			want: "198.51.100.20",
+		},
+		{
+			name: "spoofed X-Real-IP is ignored",
+			headers: map[string]string{
+				"X-Real-IP":        "10.0.0.1",
+				"CF-Connecting-IP": "10.0.0.1",
+			},
+			want: "0.0.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.want {
+				t.Errorf("client IP = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
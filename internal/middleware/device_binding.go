@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"errors"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeviceBindingMiddleware mewajibkan request absensi ditandatangani key device yang terikat
+// ke akun siswa. Percobaan dari device lain selalu dicatat untuk laporan wali kelas,
+// dan ditolak jika enforced bernilai true. Nonce dicatat terpisah oleh DeviceNonceMiddleware.
+func DeviceBindingMiddleware(devices services.DeviceServiceInterface, enforced bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(primitive.ObjectID)
+		if !ok {
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+		}
+
+		signed := signedRequestFromContext(c)
+		device, err := devices.VerifySignedRequest(userID, signed)
+		if err != nil {
+			return rejectDevice(c, devices, userID, signed.DeviceID, err, enforced)
+		}
+
+		c.Locals("device", *device)
+		c.Locals("device_nonce", signed.Nonce)
+		c.Set("X-Device-Binding", "verified")
+		return c.Next()
+	}
+}
+
+// DeviceNonceMiddleware menolak nonce yang sudah pernah dipakai. Dipasang setelah
+// IdempotencyMiddleware: retry dengan Idempotency-Key yang sudah selesai dijawab dari
+// respons tersimpan sebelum sampai ke sini. Nonce dilepas lagi jika server gagal (5xx).
+func DeviceNonceMiddleware(devices services.DeviceServiceInterface, enforced bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		device, ok := c.Locals("device").(models.Device)
+		nonce, _ := c.Locals("device_nonce").(string)
+		if !ok || nonce == "" {
+			return c.Next()
+		}
+
+		if err := devices.ConsumeNonce(device.DeviceID, nonce); err != nil {
+			c.Locals("device", nil)
+			return rejectDevice(c, devices, device.UserID, device.DeviceID, err, enforced)
+		}
+
+		err := c.Next()
+		if err != nil || c.Response().StatusCode() >= fiber.StatusInternalServerError {
+			devices.ReleaseNonce(device.DeviceID, nonce)
+		}
+		return err
+	}
+}
+
+func rejectDevice(c *fiber.Ctx, devices services.DeviceServiceInterface, userID primitive.ObjectID, deviceID string, err error, enforced bool) error {
+	var verificationErr *services.DeviceVerificationError
+	if !errors.As(err, &verificationErr) {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	devices.RecordAttempt(&models.DeviceAttempt{
+		UserID:    userID,
+		DeviceID:  deviceID,
+		Reason:    verificationErr.Reason,
+		Path:      c.Path(),
+		ClientIP:  getClientIP(c),
+		UserAgent: c.Get("User-Agent"),
+	})
+
+	if enforced {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Access denied: "+verificationErr.Message)
+	}
+	c.Set("X-Device-Binding", "unverified")
+	return c.Next()
+}
+
+func signedRequestFromContext(c *fiber.Ctx) *services.SignedRequest {
+	return &services.SignedRequest{
+		DeviceID:  c.Get("X-Device-ID"),
+		Timestamp: c.Get("X-Device-Timestamp"),
+		Nonce:     c.Get("X-Device-Nonce"),
+		Signature: c.Get("X-Device-Signature"),
+		Method:    c.Method(),
+		Path:      c.Path(),
+		Body:      c.Body(),
+	}
+}
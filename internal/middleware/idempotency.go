@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	idempotencyHeader    = "Idempotency-Key"
+	idempotencyMaxKeyLen = 255
+	// request yang masih "processing" lebih lama dari ini dianggap gagal (server mati di tengah jalan)
+	idempotencyLockTimeout = time.Minute
+)
+
+// idempotencyStore menyimpan key dan respons, implementasinya collection idempotency_keys
+type idempotencyStore interface {
+	// Insert mengembalikan false tanpa error jika key sudah ada
+	Insert(ctx context.Context, record *models.IdempotencyRecord) (bool, error)
+	Find(ctx context.Context, id string) (*models.IdempotencyRecord, error)
+	// TakeOver mengambil alih key yang masih processing sejak sebelum staleBefore
+	TakeOver(ctx context.Context, id string, staleBefore, now, expiresAt time.Time) (bool, error)
+	Release(ctx context.Context, id string) error
+	Complete(ctx context.Context, id string, statusCode int, contentType string, body []byte) error
+}
+
+// IdempotencyMiddleware membuat request dengan header Idempotency-Key aman di-retry.
+// Request pertama diproses dan responsnya disimpan selama ttl; retry dengan key yang
+// sama mendapat respons yang sama tanpa diproses ulang. Dipasang setelah AuthMiddleware
+// dan sebelum DeviceNonceMiddleware.
+func IdempotencyMiddleware(db *mongo.Database, ttl time.Duration) fiber.Handler {
+	return idempotencyHandler(&mongoIdempotencyStore{collection: db.Collection("idempotency_keys")}, ttl)
+}
+
+func idempotencyHandler(store idempotencyStore, ttl time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(idempotencyHeader)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > idempotencyMaxKeyLen {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Idempotency-Key is too long")
+		}
+
+		userID, ok := c.Locals("user_id").(primitive.ObjectID)
+		if !ok {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		sum := sha256.Sum256(c.Body())
+		now := time.Now().UTC()
+		record := models.IdempotencyRecord{
+			ID:          userID.Hex() + ":" + key,
+			UserID:      userID,
+			Key:         key,
+			Method:      c.Method(),
+			Path:        c.Path(),
+			Fingerprint: hex.EncodeToString(sum[:]),
+			Status:      models.IdempotencyProcessing,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(ttl),
+		}
+
+		inserted, err := store.Insert(ctx, &record)
+		if err != nil {
+			// fail open, request tetap diproses tanpa perlindungan idempotency
+			log.Printf("Warning: failed to store idempotency key: %v", err)
+			return c.Next()
+		}
+
+		if !inserted {
+			existing, err := store.Find(ctx, record.ID)
+			if err != nil {
+				return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Database error")
+			}
+			if existing.Method != record.Method || existing.Path != record.Path || existing.Fingerprint != record.Fingerprint {
+				return utils.ErrorResponse(c, fiber.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+			}
+
+			if existing.Status == models.IdempotencyCompleted {
+				c.Set("Idempotent-Replayed", "true")
+				if existing.ContentType != "" {
+					c.Set(fiber.HeaderContentType, existing.ContentType)
+				}
+				return c.Status(existing.StatusCode).Send(existing.Body)
+			}
+
+			// ambil alih key yang macet; jika masih diproses request lain, minta client menunggu
+			taken, err := store.TakeOver(ctx, record.ID, now.Add(-idempotencyLockTimeout), now, record.ExpiresAt)
+			if err != nil || !taken {
+				c.Set(fiber.HeaderRetryAfter, "1")
+				return utils.ErrorResponse(c, fiber.StatusConflict, "A request with this Idempotency-Key is still being processed")
+			}
+		}
+
+		err = c.Next()
+
+		saveCtx, saveCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer saveCancel()
+
+		status := c.Response().StatusCode()
+		if err != nil || status >= fiber.StatusInternalServerError {
+			// error server tidak disimpan supaya retry bisa mencoba lagi
+			if delErr := store.Release(saveCtx, record.ID); delErr != nil {
+				log.Printf("Warning: failed to release idempotency key: %v", delErr)
+			}
+			return err
+		}
+
+		body := append([]byte(nil), c.Response().Body()...)
+		if err := store.Complete(saveCtx, record.ID, status, string(c.Response().Header.ContentType()), body); err != nil {
+			log.Printf("Warning: failed to save idempotent response: %v", err)
+		}
+
+		return nil
+	}
+}
+
+type mongoIdempotencyStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoIdempotencyStore) Insert(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	if _, err := s.collection.InsertOne(ctx, record); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *mongoIdempotencyStore) Find(ctx context.Context, id string) (*models.IdempotencyRecord, error) {
+	var record models.IdempotencyRecord
+	if err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (s *mongoIdempotencyStore) TakeOver(ctx context.Context, id string, staleBefore, now, expiresAt time.Time) (bool, error) {
+	result, err := s.collection.UpdateOne(ctx, bson.M{
+		"_id":        id,
+		"status":     models.IdempotencyProcessing,
+		"created_at": bson.M{"$lt": staleBefore},
+	}, bson.M{"$set": bson.M{"created_at": now, "expires_at": expiresAt}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (s *mongoIdempotencyStore) Release(ctx context.Context, id string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (s *mongoIdempotencyStore) Complete(ctx context.Context, id string, statusCode int, contentType string, body []byte) error {
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"status":       models.IdempotencyCompleted,
+		"status_code":  statusCode,
+		"content_type": contentType,
+		"body":         body,
+	}})
+	return err
+}
@@ -0,0 +1,256 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/security"
+	"ujikom-backend/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]models.IdempotencyRecord
+}
+
+func (s *memoryIdempotencyStore) Insert(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[record.ID]; ok {
+		return false, nil
+	}
+	s.records[record.ID] = *record
+	return true, nil
+}
+
+func (s *memoryIdempotencyStore) Find(ctx context.Context, id string) (*models.IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &record, nil
+}
+
+func (s *memoryIdempotencyStore) TakeOver(ctx context.Context, id string, staleBefore, now, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[id]
+	if !ok || record.Status != models.IdempotencyProcessing || !record.CreatedAt.Before(staleBefore) {
+		return false, nil
+	}
+	record.CreatedAt, record.ExpiresAt = now, expiresAt
+	s.records[id] = record
+	return true, nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, id)
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, id string, statusCode int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[id]
+	record.Status, record.StatusCode, record.ContentType, record.Body = models.IdempotencyCompleted, statusCode, contentType, body
+	s.records[id] = record
+	return nil
+}
+
+// fakeDevices memverifikasi tanda tangan sungguhan dan menyimpan nonce di memori
+type fakeDevices struct {
+	services.DeviceServiceInterface
+	device   models.Device
+	nonces   map[string]bool
+	attempts []string
+}
+
+func (f *fakeDevices) VerifySignedRequest(userID primitive.ObjectID, signed *services.SignedRequest) (*models.Device, error) {
+	message := security.CanonicalRequest(signed.Method, signed.Path, signed.Timestamp, signed.Nonce, signed.Body)
+	if err := security.VerifyDeviceSignature(f.device.PublicKey, message, signed.Signature); err != nil {
+		return nil, &services.DeviceVerificationError{Reason: services.AttemptInvalidSignature, Message: err.Error()}
+	}
+	device := f.device
+	return &device, nil
+}
+
+func (f *fakeDevices) ConsumeNonce(deviceID, nonce string) error {
+	if f.nonces[deviceID+":"+nonce] {
+		return &services.DeviceVerificationError{Reason: services.AttemptReplayedRequest, Message: "device request has already been used"}
+	}
+	f.nonces[deviceID+":"+nonce] = true
+	return nil
+}
+
+func (f *fakeDevices) ReleaseNonce(deviceID, nonce string) {
+	delete(f.nonces, deviceID+":"+nonce)
+}
+
+func (f *fakeDevices) RecordAttempt(attempt *models.DeviceAttempt) {
+	f.attempts = append(f.attempts, attempt.Reason)
+}
+
+type signedCheckInApp struct {
+	app     *fiber.App
+	devices *fakeDevices
+	private ed25519.PrivateKey
+	// timestamp tetap supaya request dengan nonce yang sama identik byte per byte
+	timestamp string
+	calls     int
+	status    int
+}
+
+func newSignedCheckInApp(t *testing.T) *signedCheckInApp {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID := primitive.NewObjectID()
+	s := &signedCheckInApp{
+		devices: &fakeDevices{
+			device: models.Device{UserID: userID, DeviceID: "device-1", PublicKey: base64.StdEncoding.EncodeToString(der)},
+			nonces: map[string]bool{},
+		},
+		private:   private,
+		timestamp: strconv.FormatInt(time.Now().Unix(), 10),
+		status:    fiber.StatusOK,
+	}
+
+	s.app = fiber.New()
+	s.app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		return c.Next()
+	})
+	store := &memoryIdempotencyStore{records: map[string]models.IdempotencyRecord{}}
+	s.app.Post("/api/v1/attendance/checkin",
+		DeviceBindingMiddleware(s.devices, true),
+		idempotencyHandler(store, time.Hour),
+		DeviceNonceMiddleware(s.devices, true),
+		func(c *fiber.Ctx) error {
+			s.calls++
+			return c.Status(s.status).JSON(fiber.Map{"call": s.calls})
+		},
+	)
+	return s
+}
+
+// request membuat check in bertanda tangan dengan nonce dan Idempotency-Key tertentu
+func (s *signedCheckInApp) request(nonce, key string) *http.Request {
+	path := "/api/v1/attendance/checkin"
+	body := `{"latitude":-7.946,"longitude":112.615}`
+	timestamp := s.timestamp
+	signature := ed25519.Sign(s.private, security.CanonicalRequest(fiber.MethodPost, path, timestamp, nonce, []byte(body)))
+
+	req := httptest.NewRequest(fiber.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Device-ID", "device-1")
+	req.Header.Set("X-Device-Timestamp", timestamp)
+	req.Header.Set("X-Device-Nonce", nonce)
+	req.Header.Set("X-Device-Signature", base64.StdEncoding.EncodeToString(signature))
+	if key != "" {
+		req.Header.Set(idempotencyHeader, key)
+	}
+	return req
+}
+
+func (s *signedCheckInApp) send(t *testing.T, req *http.Request) (int, string) {
+	t.Helper()
+	resp, err := s.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestIdempotentRetryOfSignedRequestReplaysResponse(t *testing.T) {
+	s := newSignedCheckInApp(t)
+
+	status, body := s.send(t, s.request("nonce-0123456789abcdef", "retry-key-1"))
+	if status != fiber.StatusOK {
+		t.Fatalf("first request status = %d (%s), want 200", status, body)
+	}
+
+	// retry yang sama persis (nonce dan tanda tangan sama) setelah koneksi putus
+	retryStatus, retryBody := s.send(t, s.request("nonce-0123456789abcdef", "retry-key-1"))
+	if retryStatus != fiber.StatusOK || retryBody != body {
+		t.Errorf("retry = %d %s, want 200 %s", retryStatus, retryBody, body)
+	}
+	if s.calls != 1 {
+		t.Errorf("handler ran %d times, want 1", s.calls)
+	}
+	if len(s.devices.attempts) != 0 {
+		t.Errorf("retry recorded device attempts %v, want none", s.devices.attempts)
+	}
+}
+
+func TestReplayedNonceWithoutCompletedKeyIsRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		firstKey  string
+		replayKey string
+	}{
+		{"no idempotency key", "", ""},
+		{"different idempotency key", "key-a", "key-b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSignedCheckInApp(t)
+			if status, body := s.send(t, s.request("nonce-0123456789abcdef", tt.firstKey)); status != fiber.StatusOK {
+				t.Fatalf("first request status = %d (%s), want 200", status, body)
+			}
+
+			status, _ := s.send(t, s.request("nonce-0123456789abcdef", tt.replayKey))
+			if status != fiber.StatusForbidden {
+				t.Errorf("replay status = %d, want 403", status)
+			}
+			if s.calls != 1 {
+				t.Errorf("handler ran %d times, want 1", s.calls)
+			}
+			if len(s.devices.attempts) != 1 || s.devices.attempts[0] != services.AttemptReplayedRequest {
+				t.Errorf("attempts = %v, want [%s]", s.devices.attempts, services.AttemptReplayedRequest)
+			}
+		})
+	}
+}
+
+func TestServerErrorReleasesNonceForRetry(t *testing.T) {
+	s := newSignedCheckInApp(t)
+	s.status = fiber.StatusInternalServerError
+	if status, _ := s.send(t, s.request("nonce-0123456789abcdef", "retry-key-1")); status != fiber.StatusInternalServerError {
+		t.Fatalf("first request status = %d, want 500", status)
+	}
+
+	s.status = fiber.StatusOK
+	if status, body := s.send(t, s.request("nonce-0123456789abcdef", "retry-key-1")); status != fiber.StatusOK {
+		t.Errorf("retry after server error = %d (%s), want 200", status, body)
+	}
+	if s.calls != 2 {
+		t.Errorf("handler ran %d times, want 2", s.calls)
+	}
+}
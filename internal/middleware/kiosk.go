@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// KioskAuthMiddleware mengautentikasi layar kiosk dengan header X-Kiosk-Key
+func KioskAuthMiddleware(kiosks services.KioskServiceInterface) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		kiosk, err := kiosks.AuthenticateKiosk(c.Get("X-Kiosk-Key"))
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, err.Error())
+		}
+
+		c.Locals("kiosk", *kiosk)
+		return c.Next()
+	}
+}
@@ -11,13 +11,16 @@ import (
 
 func LocationValidationMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		if c.Path() != "/api/v1/attendance/checkin" && c.Path() != "/api/v1/attendance/checkout" {
+		switch c.Path() {
+		case "/api/v1/attendance/checkin", "/api/v1/attendance/checkout",
+			"/api/v1/attendance/checkin/qr", "/api/v1/attendance/checkout/qr":
+		default:
 			return c.Next()
 		}
 
 		var requestBody struct {
-			Latitude  float64 `json:"latitude"`
-			Longitude float64 `json:"longitude"`
+			Latitude  float64 `json:"latitude" form:"latitude"`
+			Longitude float64 `json:"longitude" form:"longitude"`
 		}
 
 		if err := c.BodyParser(&requestBody); err != nil {
@@ -72,18 +75,6 @@ func DeviceValidationMiddleware() fiber.Handler {
 	}
 }
 
-func RateLimitMiddleware() fiber.Handler {
-	return func(c *fiber.Ctx) error {
-
-		clientIP := c.IP()
-		c.Set("X-Client-IP", clientIP)
-		
-		c.Set("X-Request-ID", generateRequestID())
-		
-		return c.Next()
-	}
-}
-
 func isValidCoordinates(lat, lng float64) bool {
 	if lat < -90 || lat > 90 {
 		return false
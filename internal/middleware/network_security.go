@@ -1,357 +1,191 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
-	"net"
-	"regexp"
+	"log"
 	"strconv"
 	"strings"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/security"
+	"ujikom-backend/internal/services"
 	"ujikom-backend/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func NetworkSecurityMiddleware() fiber.Handler {
+// NetworkSecurityMiddleware menilai risiko request dengan rule engine berbobot.
+// Request ditolak hanya jika skor melewati reject threshold, di atas flag threshold
+// request tetap diterima tetapi absensinya ditandai untuk direview.
+func NetworkSecurityMiddleware(db *mongo.Database, cfg *config.Config, policies services.NetworkPolicyServiceInterface) fiber.Handler {
+	engine := security.NewEngineFromConfig(cfg)
+
 	return func(c *fiber.Ctx) error {
 		if c.Path() == "/api/v1/health" || c.Path() == "/api/v1/auth/login" || c.Path() == "/api/v1/auth/register" {
 			return c.Next()
 		}
 
-		if adminOverride, ok := c.Locals("admin_override").(bool); ok && adminOverride {
-			c.Set("X-Network-Security", "admin-bypassed")
-			return c.Next()
-		}
-
-		adminKey := c.Get("X-Admin-Override")
-		if adminKey == "DIMAS-ANJAY-MABAR" {
-			c.Set("X-Network-Security", "admin-bypassed")
-			return c.Next()
+		// admin yang login sendiri (bukan impersonate) tidak dinilai risikonya
+		if user, ok := c.Locals("user").(models.User); ok && user.HasRole(models.RoleAdmin) {
+			if _, impersonating := c.Locals("impersonated_by").(string); !impersonating {
+				c.Set("X-Network-Security", "admin-bypassed")
+				return c.Next()
+			}
 		}
 
 		clientIP := getClientIP(c)
-		
-		if !isValidIPRange(clientIP) {
-			return utils.ErrorResponse(c, fiber.StatusForbidden, "Access denied: Invalid IP range")
+		riskRequest := buildRiskRequest(c, clientIP)
+		if userID, ok := c.Locals("user_id").(primitive.ObjectID); ok && recordsAttendance(c) {
+			loadRiskHistory(db, userID, riskRequest)
 		}
 
-		// if isVPNDetected(c) {
-		// 	return utils.ErrorResponse(c, fiber.StatusForbidden, "Access denied: VPN usage detected")
-		// }
-
-		// if !isValidNetworkType(c) {
-		// 	return utils.ErrorResponse(c, fiber.StatusForbidden, "Access denied: Invalid network type")
-		// }
-
-		if !isValidWiFiSSID(c) {
-			return utils.ErrorResponse(c, fiber.StatusForbidden, "Access denied: Invalid WiFi network")
-		}
-
-		if !isValidCellularNetwork(c) {
-			return utils.ErrorResponse(c, fiber.StatusForbidden, "Access denied: Insecure cellular network")
+		policy, _, err := policies.CurrentPolicy()
+		if err != nil {
+			log.Printf("Network security check unavailable: %v", err)
+			return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Network security check unavailable, please try again later")
+		}
+		assessment := engine.Evaluate(riskRequest, policy)
+		c.Locals("risk_request", riskRequest)
+		c.Locals("risk_assessment", assessment)
+		c.Set("X-Risk-Score", strconv.FormatFloat(assessment.Score, 'f', 1, 64))
+
+		if assessment.Decision == security.DecisionReject {
+			message := "Access denied: network security check failed"
+			if reasons := security.TopReasons(assessment, 3); len(reasons) > 0 {
+				message += " (" + strings.Join(reasons, "; ") + ")"
+			}
+			return utils.ErrorResponse(c, fiber.StatusForbidden, message)
 		}
 
-		if isFakeGPSDetected(c) {
-			return utils.ErrorResponse(c, fiber.StatusForbidden, "Access denied: Fake GPS detected")
+		if assessment.Decision == security.DecisionFlag {
+			c.Set("X-Network-Security", "flagged")
+		} else {
+			c.Set("X-Network-Security", "validated")
 		}
-
-		c.Set("X-Network-Security", "validated")
 		c.Set("X-Client-IP", clientIP)
-		c.Set("X-Security-Level", "high")
 
 		return c.Next()
 	}
 }
 
-// Mendapatkan IP address client yang sebenarnya
-func getClientIP(c *fiber.Ctx) string {
-	if xff := c.Get("X-Forwarded-For"); xff != "" {
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
-	}
-
-	if xri := c.Get("X-Real-IP"); xri != "" {
-		return xri
+func buildRiskRequest(c *fiber.Ctx, clientIP string) *security.Request {
+	req := &security.Request{
+		ClientIP:    clientIP,
+		UserAgent:   c.Get("User-Agent"),
+		NetworkType: c.Get("X-Network-Type"),
+		WiFiSSID:    c.Get("X-WiFi-SSID"),
+		Carrier:     c.Get("X-Carrier"),
+		DeviceID:    c.Get("X-Device-ID"),
+		Headers:     make(map[string]string),
+		Time:        time.Now().UTC(),
 	}
 
-	// Cek header CF-Connecting-IP (Cloudflare)
-	if cf := c.Get("CF-Connecting-IP"); cf != "" {
-		return cf
-	}
-
-	return c.IP()
-}
-
-// Validasi IP range yang diizinkan
-func isValidIPRange(ip string) bool {
-	allowedRanges := []string{
-		"10.0.0.0/8",       // Private network
-		"172.16.0.0/12",    // Private network
-		"192.168.0.0/16",   // Private network
-		"127.0.0.0/8",     
-		"103.0.0.0/8",     
-		"114.0.0.0/8",     
-		"202.0.0.0/8",     
-		"103.156.71.94",
-		"203.78.113.253",
-	}
-
-	clientIP := net.ParseIP(ip)
-	if clientIP == nil {
-		return false
-	}
-
-	for _, cidr := range allowedRanges {
-		_, network, err := net.ParseCIDR(cidr)
-		if err != nil {
-			continue
-		}
-		if network.Contains(clientIP) {
-			return true
+	for _, header := range security.InspectedHeaders {
+		if value := c.Get(header); value != "" {
+			req.Headers[header] = value
 		}
 	}
 
-	return false
-}
-
-// Deteksi penggunaan VPN
-func isVPNDetected(c *fiber.Ctx) bool {
-	vpnHeaders := []string{
-		"X-VPN-Client",
-		"X-Forwarded-Proto",
-		"X-Proxy-Authorization",
-		"Via",
-	}
-
-	for _, header := range vpnHeaders {
-		if c.Get(header) != "" {
-			return true
+	if accuracy := c.Get("X-GPS-Accuracy"); accuracy != "" {
+		if acc, err := strconv.ParseFloat(accuracy, 64); err == nil {
+			req.GPSAccuracy = &acc
 		}
 	}
 
-	// Deteksi berdasarkan User-Agent
-	userAgent := strings.ToLower(c.Get("User-Agent"))
-	vpnKeywords := []string{
-		"vpn",
-		"proxy",
-		"tunnel",
-		"nordvpn",
-		"expressvpn",
-		"cyberghost",
-		"protonvpn",
-	}
-
-	for _, keyword := range vpnKeywords {
-		if strings.Contains(userAgent, keyword) {
-			return true
+	if body := c.Body(); len(body) > 0 {
+		var locationData struct {
+			Latitude  *float64 `json:"latitude"`
+			Longitude *float64 `json:"longitude"`
 		}
-	}
-
-	// Deteksi berdasarkan hop count (TTL)
-	if ttl := c.Get("X-TTL"); ttl != "" {
-		if ttlValue, err := strconv.Atoi(ttl); err == nil {
-			// TTL yang terlalu rendah bisa menunjukkan VPN
-			if ttlValue < 50 {
-				return true
-			}
+		if err := json.Unmarshal(body, &locationData); err == nil && locationData.Latitude != nil && locationData.Longitude != nil {
+			req.Latitude = *locationData.Latitude
+			req.Longitude = *locationData.Longitude
+			req.HasLocation = true
 		}
 	}
 
-	return false
-}
-
-// Validasi tipe jaringan
-func isValidNetworkType(c *fiber.Ctx) bool {
-	networkType := c.Get("X-Network-Type")
-
-	allowedTypes := []string{
-		"wifi",
-		"cellular",
-		"4g",
-		"5g",
-		"lte",
-		"ethernet",
-	}
-
-	networkType = strings.ToLower(networkType)
-	for _, allowed := range allowedTypes {
-		if networkType == allowed {
-			return true
+	// check in dengan selfie dikirim sebagai multipart/form-data
+	if !req.HasLocation && strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		lat, latErr := strconv.ParseFloat(c.FormValue("latitude"), 64)
+		lng, lngErr := strconv.ParseFloat(c.FormValue("longitude"), 64)
+		if latErr == nil && lngErr == nil {
+			req.Latitude = lat
+			req.Longitude = lng
+			req.HasLocation = true
 		}
 	}
 
-	return false
+	return req
 }
 
-// Validasi SSID WiFi sekolah
-func isValidWiFiSSID(c *fiber.Ctx) bool {
-	ssid := c.Get("X-WiFi-SSID")
-	
-	// if ssid == "" {
-	// 	return true
-	// }
-
-	allowedSSIDs := []string{
-		"JTI-3.01",
-		"JTI-3.02",
-		"JTI-3.03",
-		"JTI-3.04",
-		"JTI-3.05",
-		"anjay",
-	}
-
-	for _, allowed := range allowedSSIDs {
-		if strings.EqualFold(ssid, allowed) {
-			return true
-		}
-	}
-
-	schoolPatterns := []string{
-		`^JTI-.*`,
-		`^UJIKOM-.*`,
-	}
-
-	for _, pattern := range schoolPatterns {
-		if matched, _ := regexp.MatchString(pattern, strings.ToUpper(ssid)); matched {
-			return true
-		}
-	}
-
-	return false
+// recordsAttendance true untuk route yang mencatat absensi (check in/out, lesson, sync offline).
+// Riwayat hanya dipakai rule device dan velocity, request baca tidak perlu query tambahan.
+func recordsAttendance(c *fiber.Ctx) bool {
+	return c.Method() == fiber.MethodPost
 }
 
-// Validasi keamanan jaringan seluler
-func isValidCellularNetwork(c *fiber.Ctx) bool {
-	// Ambil informasi carrier dari header
-	carrier := c.Get("X-Carrier")
-	networkType := c.Get("X-Network-Type")
-	
-	// if carrier == "" {
-	// 	return true
-	// }
+// Mengisi device terikat, device yang pernah dipakai dan lokasi terakhir dari riwayat absensi
+func loadRiskHistory(db *mongo.Database, userID primitive.ObjectID, req *security.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
-	allowedCarriers := []string{
-		"telkomsel",
-		"indosat",
-		"xl",
-		"axis",
-		"tri",
-		"smartfren",
-		"by.u",
+	cursor, err := db.Collection("attendances").Find(
+		ctx,
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "date", Value: -1}}).SetLimit(20),
+	)
+	if err != nil {
+		return
 	}
+	defer cursor.Close(ctx)
 
-	carrier = strings.ToLower(carrier)
-	for _, allowed := range allowedCarriers {
-		if strings.Contains(carrier, allowed) {
-			if isSecureCellularNetwork(networkType) {
-				return true
-			}
-		}
+	var attendances []models.Attendance
+	if err := cursor.All(ctx, &attendances); err != nil {
+		return
 	}
 
-	return false
-}
+	seen := make(map[string]bool)
 
-// Cek keamanan jaringan seluler
-func isSecureCellularNetwork(networkType string) bool {
-	secureNetworks := []string{
-		"4g",
-		"5g",
-		"lte",
-		"lte-a",
-	}
-
-	networkType = strings.ToLower(networkType)
-	for _, secure := range secureNetworks {
-		if networkType == secure {
-			return true
+	var devices []models.Device
+	deviceCursor, err := db.Collection("devices").Find(ctx, bson.M{"user_id": userID, "status": models.DeviceStatusActive})
+	if err == nil {
+		if err := deviceCursor.All(ctx, &devices); err == nil {
+			for _, device := range devices {
+				seen[device.DeviceID] = true
+				req.KnownDeviceIDs = append(req.KnownDeviceIDs, device.DeviceID)
+			}
 		}
 	}
 
-	return false
-}
-
-func isFakeGPSDetected(c *fiber.Ctx) bool {
-	body := c.Body()
-	if len(body) == 0 {
-		return false
-	}
-
-	var locationData struct {
-		Latitude  float64 `json:"latitude"`
-		Longitude float64 `json:"longitude"`
-	}
-
-	if err := json.Unmarshal(body, &locationData); err != nil {
-		return false
-	}
-
-	if isSuspiciousPrecision(locationData.Latitude, locationData.Longitude) {
-		return true
-	}
-
-	if hasUnrealisticPrecision(locationData.Latitude, locationData.Longitude) {
-		return true
-	}
-
-	mockHeaders := []string{
-		"X-Mock-Location",
-		"X-Fake-GPS",
-		"X-Location-Spoofed",
-	}
-
-	for _, header := range mockHeaders {
-		if c.Get(header) != "" {
-			return true
+	for _, attendance := range attendances {
+		if attendance.DeviceID != "" && !seen[attendance.DeviceID] {
+			seen[attendance.DeviceID] = true
+			req.KnownDeviceIDs = append(req.KnownDeviceIDs, attendance.DeviceID)
 		}
-	}
 
-	if accuracy := c.Get("X-GPS-Accuracy"); accuracy != "" {
-		if acc, err := strconv.ParseFloat(accuracy, 64); err == nil {
-			if acc == 0 || acc < 1 {
-				return true
+		if req.LastSample == nil && attendance.CheckIn != nil {
+			sampleTime := *attendance.CheckIn
+			if attendance.CheckOut != nil {
+				sampleTime = *attendance.CheckOut
+			}
+			req.LastSample = &security.LocationSample{
+				Latitude:  attendance.Location.Latitude,
+				Longitude: attendance.Location.Longitude,
+				Time:      sampleTime,
 			}
 		}
 	}
-
-	return false
-}
-
-func isSuspiciousPrecision(lat, lng float64) bool {
-	latPrecision := getDecimalPlaces(lat)
-	lngPrecision := getDecimalPlaces(lng)
-
-	if latPrecision > 10 || lngPrecision > 10 {
-		return true
-	}
-
-	if latPrecision > 12 || lngPrecision > 12 {
-		return true
-	}
-
-	return false
-}
-
-func hasUnrealisticPrecision(lat, lng float64) bool {
-	latStr := strconv.FormatFloat(lat, 'f', -1, 64)
-	lngStr := strconv.FormatFloat(lng, 'f', -1, 64)
-
-	if strings.Contains(latStr, "000") || strings.Contains(lngStr, "000") {
-		if getDecimalPlaces(lat) > 10 || getDecimalPlaces(lng) > 10 {
-			return true
-		}
-	}
-
-	return false
 }
 
-func getDecimalPlaces(num float64) int {
-	str := strconv.FormatFloat(num, 'f', -1, 64)
-	if dotIndex := strings.Index(str, "."); dotIndex != -1 {
-		return len(str) - dotIndex - 1
-	}
-	return 0
+// Mendapatkan IP address client yang sebenarnya (lihat ClientIPMiddleware)
+func getClientIP(c *fiber.Ctx) string {
+	return utils.ClientIP(c)
 }
 
 // Middleware untuk logging informasi jaringan
@@ -373,16 +207,3 @@ func NetworkInfoMiddleware() fiber.Handler {
 		return c.Next()
 	}
 }
-
-func AdminNetworkOverrideMiddleware() fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		adminKey := c.Get("X-Admin-Override")
-		
-		if adminKey == "DIMAS-ANJAY-MABAR" {
-			c.Set("X-Network-Security", "admin-override")
-			c.Locals("admin_override", true)
-		}
-
-		return c.Next()
-	}
-}
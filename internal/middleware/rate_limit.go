@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"ujikom-backend/internal/ratelimit"
+	"ujikom-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type rateLimitBucket struct {
+	key  string
+	rule ratelimit.Rule
+}
+
+// RateLimitMiddleware membatasi request per route class. Request yang sudah terautentikasi
+// dihitung per user karena banyak siswa berbagi satu IP NAT sekolah, sisanya per IP.
+func RateLimitMiddleware(limiter ratelimit.Limiter, class string, rule ratelimit.Rule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := class + ":ip:" + getClientIP(c)
+		if userID, ok := c.Locals("user_id").(primitive.ObjectID); ok {
+			key = class + ":user:" + userID.Hex()
+		}
+
+		return applyRateLimit(c, limiter, rateLimitBucket{key: key, rule: rule})
+	}
+}
+
+// LoginRateLimitMiddleware untuk endpoint login/registrasi yang belum punya user. Percobaan
+// dihitung per IP + email (accountRule) supaya satu siswa yang salah password tidak
+// mengunci seluruh sekolah di balik NAT yang sama, ditambah batas per IP yang lebih longgar
+// (ipRule) untuk menahan percobaan ke banyak email sekaligus.
+func LoginRateLimitMiddleware(limiter ratelimit.Limiter, accountRule, ipRule ratelimit.Rule) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		clientIP := getClientIP(c)
+		buckets := []rateLimitBucket{{key: "login:ip:" + clientIP, rule: ipRule}}
+		if email := loginEmail(c); email != "" {
+			buckets = append(buckets, rateLimitBucket{key: "login:account:" + clientIP + ":" + email, rule: accountRule})
+		}
+
+		return applyRateLimit(c, limiter, buckets...)
+	}
+}
+
+// loginEmail mengambil email yang dinormalisasi dari body JSON, kosong jika tidak ada
+func loginEmail(c *fiber.Ctx) string {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(body.Email))
+}
+
+// applyRateLimit memeriksa bucket berurutan dan berhenti di bucket pertama yang menolak.
+// Header RateLimit-* diambil dari bucket yang menolak, atau yang sisanya paling sedikit.
+func applyRateLimit(c *fiber.Ctx, limiter ratelimit.Limiter, buckets ...rateLimitBucket) error {
+	c.Set("X-Client-IP", getClientIP(c))
+	c.Set("X-Request-ID", generateRequestID())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var reported *ratelimit.Result
+	for _, b := range buckets {
+		result, err := limiter.Allow(ctx, b.key, b.rule)
+		if err != nil {
+			// fail open, lebih baik tidak membatasi daripada menolak semua request
+			log.Printf("Warning: rate limiter unavailable: %v", err)
+			return c.Next()
+		}
+
+		if reported == nil || !result.Allowed || result.Remaining < reported.Remaining {
+			reported = &result
+		}
+		if !result.Allowed {
+			break
+		}
+	}
+
+	if reported == nil {
+		return c.Next()
+	}
+
+	c.Set("RateLimit-Limit", strconv.Itoa(reported.Limit))
+	c.Set("RateLimit-Remaining", strconv.Itoa(reported.Remaining))
+	c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reported.ResetAfter)))
+
+	if !reported.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(reported.RetryAfter)))
+		return utils.ErrorResponse(c, fiber.StatusTooManyRequests, "Too many requests, please try again later")
+	}
+
+	return c.Next()
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"ujikom-backend/internal/ratelimit"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLoginRateLimitKeysByIPAndEmail(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter()
+	defer limiter.Close()
+
+	app := fiber.New()
+	app.Post("/login", LoginRateLimitMiddleware(limiter,
+		ratelimit.Rule{Limit: 2, Window: time.Minute, Algorithm: ratelimit.SlidingWindow},
+		ratelimit.Rule{Limit: 5, Window: time.Minute},
+	), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	login := func(email string) int {
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(`{"email":"`+email+`","password":"salah"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	// semua request datang dari IP yang sama (satu NAT sekolah)
+	steps := []struct {
+		email string
+		want  int
+	}{
+		{"andi@example.com", fiber.StatusOK},
+		{" Andi@Example.com", fiber.StatusOK},
+		{"andi@example.com", fiber.StatusTooManyRequests},
+		{"budi@example.com", fiber.StatusOK},
+		{"citra@example.com", fiber.StatusOK},
+		{"dewi@example.com", fiber.StatusTooManyRequests},
+	}
+
+	for i, step := range steps {
+		if got := login(step.email); got != step.want {
+			t.Errorf("step %d (%s): status %d, want %d", i+1, step.email, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RoleMiddleware membatasi akses hanya untuk role tertentu, dipasang setelah AuthMiddleware
+func RoleMiddleware(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals("user").(models.User)
+		if !ok {
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+		}
+
+		if !user.HasRole(roles...) {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Access denied: insufficient role")
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,76 @@
+// Package migrations berisi migrasi data sekali jalan yang dijalankan lewat cmd/migrate
+package migrations
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+	"ujikom-backend/internal/config"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Migration adalah satu migrasi data. Run harus aman dijalankan ulang dan tidak
+// menulis apa pun ke database saat dryRun bernilai true.
+type Migration struct {
+	Name        string
+	Description string
+	Run         func(ctx context.Context, db *mongo.Database, cfg *config.Config, dryRun bool) (*Report, error)
+}
+
+type Report struct {
+	Name     string         `json:"name" bson:"name"`
+	DryRun   bool           `json:"dry_run" bson:"dry_run"`
+	Changes  map[string]int `json:"changes" bson:"changes"`
+	Warnings []string       `json:"warnings" bson:"warnings"`
+	RanAt    time.Time      `json:"ran_at" bson:"ran_at"`
+}
+
+func newReport(name string, dryRun bool) *Report {
+	return &Report{
+		Name:     name,
+		DryRun:   dryRun,
+		Changes:  map[string]int{},
+		Warnings: []string{},
+	}
+}
+
+var registry = map[string]Migration{}
+
+func register(m Migration) {
+	registry[m.Name] = m
+}
+
+// All mengembalikan semua migrasi terdaftar urut nama
+func All() []Migration {
+	list := make([]Migration, 0, len(registry))
+	for _, m := range registry {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Run menjalankan migrasi berdasarkan nama dan mencatat hasilnya di schema_migrations
+func Run(ctx context.Context, db *mongo.Database, cfg *config.Config, name string, dryRun bool) (*Report, error) {
+	m, ok := registry[name]
+	if !ok {
+		return nil, errors.New("unknown migration: " + name)
+	}
+
+	report, err := m.Run(ctx, db, cfg, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	report.RanAt = time.Now().UTC()
+
+	if !dryRun {
+		if _, err := db.Collection("schema_migrations").InsertOne(ctx, report); err != nil {
+			log.Printf("Error recording migration %s: %v", name, err)
+		}
+	}
+
+	return report, nil
+}
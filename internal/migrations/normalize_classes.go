@@ -0,0 +1,416 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func init() {
+	register(Migration{
+		Name:        "normalize_classes",
+		Description: "convert free-text kelas/jurusan into classes and majors, link users, timetable and lesson sessions by class_id",
+		Run:         normalizeClasses,
+	})
+}
+
+var romanGrades = map[string]int{"X": 10, "XI": 11, "XII": 12, "XIII": 13}
+
+var gradeRomans = map[int]string{10: "X", 11: "XI", 12: "XII", 13: "XIII"}
+
+// parsedKelas adalah hasil parsing nama kelas bebas seperti "12 rpl1" atau "xii-rpl-1"
+type parsedKelas struct {
+	Grade   int
+	Major   string
+	Section string
+}
+
+func (p parsedKelas) Name() string {
+	name := gradeRomans[p.Grade] + " " + p.Major
+	if p.Section != "" {
+		name += " " + p.Section
+	}
+	return name
+}
+
+// parseKelas mengenali format "<tingkat> <kode jurusan> [rombel]", tingkat boleh
+// romawi (X-XIII) atau angka (10-13), rombel boleh angka atau satu huruf
+func parseKelas(raw string) (parsedKelas, bool) {
+	tokens := splitKelas(raw)
+	if len(tokens) < 2 {
+		return parsedKelas{}, false
+	}
+
+	var result parsedKelas
+	if grade, ok := romanGrades[tokens[0]]; ok {
+		result.Grade = grade
+	} else if grade, err := strconv.Atoi(tokens[0]); err == nil && grade >= 10 && grade <= 13 {
+		result.Grade = grade
+	} else {
+		return parsedKelas{}, false
+	}
+
+	rest := tokens[1:]
+	var major []string
+	for len(rest) > 0 && !isDigits(rest[0]) {
+		major = append(major, rest[0])
+		rest = rest[1:]
+	}
+	// huruf tunggal di akhir dianggap rombel, contoh "X TKJ B"
+	if len(rest) == 0 && len(major) > 1 && len(major[len(major)-1]) == 1 {
+		rest = major[len(major)-1:]
+		major = major[:len(major)-1]
+	}
+	if len(major) == 0 || len(rest) > 1 {
+		return parsedKelas{}, false
+	}
+	result.Major = strings.Join(major, "")
+	if len(result.Major) > 10 {
+		return parsedKelas{}, false
+	}
+
+	if len(rest) == 1 {
+		if number, err := strconv.Atoi(rest[0]); err == nil {
+			if number < 1 {
+				return parsedKelas{}, false
+			}
+			result.Section = strconv.Itoa(number)
+		} else {
+			result.Section = rest[0]
+		}
+	}
+
+	return result, true
+}
+
+// splitKelas memecah nama kelas di spasi/tanda baca dan di batas huruf-angka
+func splitKelas(raw string) []string {
+	var tokens []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			tokens = append(tokens, string(current))
+			current = current[:0]
+		}
+	}
+
+	for _, r := range strings.ToUpper(strings.TrimSpace(raw)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(current) > 0 && unicode.IsDigit(current[len(current)-1]) != unicode.IsDigit(r) {
+				flush()
+			}
+			current = append(current, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+type classNormalizer struct {
+	ctx     context.Context
+	db      *mongo.Database
+	dryRun  bool
+	report  *Report
+	now     time.Time
+	majors  map[string]*models.Major // per kode
+	classes map[string]*models.Class // per nama kelas (huruf besar)
+	// nama jurusan yang paling sering dipakai siswa per kode jurusan
+	majorNames map[string]map[string]int
+}
+
+func normalizeClasses(ctx context.Context, db *mongo.Database, _ *config.Config, dryRun bool) (*Report, error) {
+	n := &classNormalizer{
+		ctx:        ctx,
+		db:         db,
+		dryRun:     dryRun,
+		report:     newReport("normalize_classes", dryRun),
+		now:        time.Now().UTC(),
+		majors:     map[string]*models.Major{},
+		classes:    map[string]*models.Class{},
+		majorNames: map[string]map[string]int{},
+	}
+
+	if err := n.loadExisting(); err != nil {
+		return nil, err
+	}
+
+	users, err := n.pendingUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	// kumpulkan nama jurusan dulu supaya jurusan baru memakai nama lengkap, bukan kode
+	for _, user := range users {
+		if parsed, ok := parseKelas(user.Kelas); ok && strings.TrimSpace(user.Jurusan) != "" {
+			names := n.majorNames[parsed.Major]
+			if names == nil {
+				names = map[string]int{}
+				n.majorNames[parsed.Major] = names
+			}
+			names[strings.TrimSpace(user.Jurusan)]++
+		}
+	}
+
+	unparsed := map[string]bool{}
+	var writes []mongo.WriteModel
+
+	for _, user := range users {
+		if user.HasRole(models.RoleAdmin) {
+			continue
+		}
+
+		parsed, ok := parseKelas(user.Kelas)
+		if !ok {
+			unparsed[user.Kelas] = true
+			continue
+		}
+
+		class, major, err := n.ensureClass(parsed)
+		if err != nil {
+			return nil, err
+		}
+
+		update := bson.M{"class_id": class.ID, "kelas": class.Name, "updated_at": n.now}
+		if user.HasRole(models.RoleTeacher) {
+			// kelas milik guru adalah kelas perwaliannya
+			if class.HomeroomTeacherID != nil && *class.HomeroomTeacherID != user.ID {
+				n.warn("class %s already has a homeroom teacher, skipped teacher %s", class.Name, user.Email)
+				continue
+			}
+			if class.HomeroomTeacherID == nil {
+				if err := n.assignHomeroom(class, user.ID); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			update["major_id"] = major.ID
+			update["jurusan"] = major.Name
+		}
+
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": user.ID}).
+			SetUpdate(bson.M{"$set": update}))
+		n.report.Changes["users_updated"]++
+	}
+
+	if !dryRun && len(writes) > 0 {
+		if _, err := db.Collection("users").BulkWrite(ctx, writes); err != nil {
+			return nil, fmt.Errorf("failed to update users: %v", err)
+		}
+	}
+
+	for _, collection := range []string{"timetable_periods", "lesson_sessions"} {
+		if err := n.migrateCollection(collection, unparsed); err != nil {
+			return nil, err
+		}
+	}
+
+	if !dryRun {
+		// indeks lama berbasis nama kelas digantikan class_day_period_unique
+		if _, err := db.Collection("timetable_periods").Indexes().DropOne(ctx, "kelas_day_period_unique"); err == nil {
+			n.report.Changes["indexes_dropped"]++
+		}
+	}
+
+	var names []string
+	for kelas := range unparsed {
+		names = append(names, kelas)
+	}
+	sort.Strings(names)
+	for _, kelas := range names {
+		n.warn("unrecognized class name %q, fix it manually", kelas)
+	}
+
+	return n.report, nil
+}
+
+func (n *classNormalizer) loadExisting() error {
+	var majors []models.Major
+	cursor, err := n.db.Collection("majors").Find(n.ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch majors: %v", err)
+	}
+	if err := cursor.All(n.ctx, &majors); err != nil {
+		return fmt.Errorf("failed to decode majors: %v", err)
+	}
+	for i := range majors {
+		n.majors[majors[i].Code] = &majors[i]
+	}
+
+	var classes []models.Class
+	cursor, err = n.db.Collection("classes").Find(n.ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch classes: %v", err)
+	}
+	if err := cursor.All(n.ctx, &classes); err != nil {
+		return fmt.Errorf("failed to decode classes: %v", err)
+	}
+	for i := range classes {
+		n.classes[strings.ToUpper(classes[i].Name)] = &classes[i]
+	}
+
+	return nil
+}
+
+func (n *classNormalizer) pendingUsers() ([]models.User, error) {
+	cursor, err := n.db.Collection("users").Find(n.ctx, bson.M{
+		"kelas":    bson.M{"$exists": true, "$ne": ""},
+		"class_id": bson.M{"$exists": false},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %v", err)
+	}
+
+	var users []models.User
+	if err := cursor.All(n.ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode users: %v", err)
+	}
+	return users, nil
+}
+
+func (n *classNormalizer) ensureClass(parsed parsedKelas) (*models.Class, *models.Major, error) {
+	major, err := n.ensureMajor(parsed.Major)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	name := parsed.Name()
+	if class, ok := n.classes[strings.ToUpper(name)]; ok {
+		return class, major, nil
+	}
+
+	class := &models.Class{
+		ID:        primitive.NewObjectID(),
+		Name:      name,
+		Grade:     parsed.Grade,
+		MajorID:   major.ID,
+		IsActive:  true,
+		CreatedAt: n.now,
+		UpdatedAt: n.now,
+	}
+	if !n.dryRun {
+		if _, err := n.db.Collection("classes").InsertOne(n.ctx, class); err != nil {
+			return nil, nil, fmt.Errorf("failed to create class %s: %v", name, err)
+		}
+	}
+	n.classes[strings.ToUpper(name)] = class
+	n.report.Changes["classes_created"]++
+
+	return class, major, nil
+}
+
+func (n *classNormalizer) ensureMajor(code string) (*models.Major, error) {
+	if major, ok := n.majors[code]; ok {
+		return major, nil
+	}
+
+	name, best := code, 0
+	for candidate, count := range n.majorNames[code] {
+		if count > best || (count == best && candidate < name) {
+			name, best = candidate, count
+		}
+	}
+
+	major := &models.Major{
+		ID:        primitive.NewObjectID(),
+		Code:      code,
+		Name:      name,
+		IsActive:  true,
+		CreatedAt: n.now,
+		UpdatedAt: n.now,
+	}
+	if !n.dryRun {
+		if _, err := n.db.Collection("majors").InsertOne(n.ctx, major); err != nil {
+			return nil, fmt.Errorf("failed to create major %s: %v", code, err)
+		}
+	}
+	n.majors[code] = major
+	n.report.Changes["majors_created"]++
+
+	return major, nil
+}
+
+func (n *classNormalizer) assignHomeroom(class *models.Class, teacherID primitive.ObjectID) error {
+	if !n.dryRun {
+		if _, err := n.db.Collection("classes").UpdateOne(n.ctx, bson.M{"_id": class.ID}, bson.M{"$set": bson.M{
+			"homeroom_teacher_id": teacherID,
+			"updated_at":          n.now,
+		}}); err != nil {
+			return fmt.Errorf("failed to assign homeroom teacher for %s: %v", class.Name, err)
+		}
+	}
+	class.HomeroomTeacherID = &teacherID
+	n.report.Changes["homerooms_assigned"]++
+
+	return nil
+}
+
+// migrateCollection mengisi class_id pada dokumen yang masih hanya menyimpan nama kelas
+func (n *classNormalizer) migrateCollection(collection string, unparsed map[string]bool) error {
+	values, err := n.db.Collection(collection).Distinct(n.ctx, "kelas", bson.M{"class_id": bson.M{"$exists": false}})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", collection, err)
+	}
+
+	for _, value := range values {
+		kelas, _ := value.(string)
+		parsed, ok := parseKelas(kelas)
+		if !ok {
+			unparsed[kelas] = true
+			continue
+		}
+
+		class, _, err := n.ensureClass(parsed)
+		if err != nil {
+			return err
+		}
+
+		filter := bson.M{"kelas": kelas, "class_id": bson.M{"$exists": false}}
+		if n.dryRun {
+			count, err := n.db.Collection(collection).CountDocuments(n.ctx, filter)
+			if err != nil {
+				return fmt.Errorf("failed to count %s: %v", collection, err)
+			}
+			n.report.Changes[collection+"_updated"] += int(count)
+			continue
+		}
+
+		result, err := n.db.Collection(collection).UpdateMany(n.ctx, filter, bson.M{"$set": bson.M{
+			"class_id": class.ID,
+			"kelas":    class.Name,
+		}})
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %v", collection, err)
+		}
+		n.report.Changes[collection+"_updated"] += int(result.ModifiedCount)
+	}
+
+	return nil
+}
+
+func (n *classNormalizer) warn(format string, args ...interface{}) {
+	n.report.Warnings = append(n.report.Warnings, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKelas(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want parsedKelas
+		ok   bool
+	}{
+		{"XII RPL 1", parsedKelas{Grade: 12, Major: "RPL", Section: "1"}, true},
+		{"12 RPL1", parsedKelas{Grade: 12, Major: "RPL", Section: "1"}, true},
+		{"xii-rpl-1", parsedKelas{Grade: 12, Major: "RPL", Section: "1"}, true},
+		{"X TKJ B", parsedKelas{Grade: 10, Major: "TKJ", Section: "B"}, true},
+		{"XI  TKJ 02", parsedKelas{Grade: 11, Major: "TKJ", Section: "2"}, true},
+		{"10 AK", parsedKelas{Grade: 10, Major: "AK"}, true},
+		{"XIII TEI 3", parsedKelas{Grade: 13, Major: "TEI", Section: "3"}, true},
+		{"xi.multi media.2", parsedKelas{Grade: 11, Major: "MULTIMEDIA", Section: "2"}, true},
+		{"", parsedKelas{}, false},
+		{"XII", parsedKelas{}, false},
+		{"RPL 1", parsedKelas{}, false},
+		{"9 RPL 1", parsedKelas{}, false},
+		{"14 RPL 1", parsedKelas{}, false},
+		{"XII 1", parsedKelas{}, false},
+		{"XII RPL 0", parsedKelas{}, false},
+		{"XII RPL 1 2", parsedKelas{}, false},
+		{"X ABCDEFGHIJK 1", parsedKelas{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseKelas(tt.raw)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseKelas(%q) = %+v, %v, want %+v, %v", tt.raw, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParsedKelasName(t *testing.T) {
+	tests := []struct {
+		kelas parsedKelas
+		want  string
+	}{
+		{parsedKelas{Grade: 12, Major: "RPL", Section: "1"}, "XII RPL 1"},
+		{parsedKelas{Grade: 10, Major: "TKJ", Section: "B"}, "X TKJ B"},
+		{parsedKelas{Grade: 11, Major: "AK"}, "XI AK"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.kelas.Name(); got != tt.want {
+			t.Errorf("%+v.Name() = %q, want %q", tt.kelas, got, tt.want)
+		}
+	}
+}
+
+func TestSplitKelas(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"XII RPL 1", []string{"XII", "RPL", "1"}},
+		{"12 RPL1", []string{"12", "RPL", "1"}},
+		{"xii-rpl-1", []string{"XII", "RPL", "1"}},
+		{"  x_tkj/b  ", []string{"X", "TKJ", "B"}},
+		{"12rpl1", []string{"12", "RPL", "1"}},
+		{"--", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := splitKelas(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitKelas(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
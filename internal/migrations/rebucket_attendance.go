@@ -0,0 +1,176 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func init() {
+	register(Migration{
+		Name:        "rebucket_attendance_dates",
+		Description: "recompute attendance date from check-in time in SCHOOL_TIMEZONE and fill date_key",
+		Run:         rebucketAttendanceDates,
+	})
+}
+
+type bucketRecord struct {
+	ID                 primitive.ObjectID  `bson:"_id"`
+	UserID             primitive.ObjectID  `bson:"user_id"`
+	Date               time.Time           `bson:"date"`
+	DateKey            string              `bson:"date_key"`
+	TermID             *primitive.ObjectID `bson:"term_id"`
+	CheckIn            *time.Time          `bson:"check_in"`
+	VerificationMethod string              `bson:"verification_method"`
+
+	target time.Time
+}
+
+// Record lama menyimpan date sebagai tanggal UTC dari waktu check-in, sehingga
+// check-in 06:30 WIB tercatat di hari sebelumnya. Migrasi ini menghitung ulang
+// tanggal dari check_in menurut zona waktu sekolah. Record manual dan turunan
+// absensi per jam pelajaran sudah memakai tanggal kalender, jadi hanya diberi date_key.
+func rebucketAttendanceDates(ctx context.Context, db *mongo.Database, cfg *config.Config, dryRun bool) (*Report, error) {
+	report := newReport("rebucket_attendance_dates", dryRun)
+	collection := db.Collection("attendances")
+
+	semesters, err := loadSemesters(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.D{{Key: "user_id", Value: 1}, {Key: "date", Value: 1}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"user_id": 1, "date": 1, "date_key": 1, "term_id": 1, "check_in": 1, "verification_method": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch attendances: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var writes []mongo.WriteModel
+	var batch []*bucketRecord
+
+	flush := func() {
+		writes = append(writes, rebucketUser(batch, semesters, report)...)
+		batch = batch[:0]
+	}
+
+	for cursor.Next(ctx) {
+		record := &bucketRecord{}
+		if err := cursor.Decode(record); err != nil {
+			return nil, fmt.Errorf("failed to decode attendance: %v", err)
+		}
+		record.Date = record.Date.UTC()
+		record.target = time.Date(record.Date.Year(), record.Date.Month(), record.Date.Day(), 0, 0, 0, 0, time.UTC)
+		if record.CheckIn != nil && record.VerificationMethod != models.VerificationManual && record.VerificationMethod != models.VerificationLesson {
+			record.target = cfg.SchoolDate(*record.CheckIn)
+		}
+
+		if len(batch) > 0 && batch[0].UserID != record.UserID {
+			flush()
+		}
+		batch = append(batch, record)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read attendances: %v", err)
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+
+	if !dryRun && len(writes) > 0 {
+		if _, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return nil, fmt.Errorf("failed to update attendances: %v", err)
+		}
+	}
+
+	return report, nil
+}
+
+// rebucketUser memproses semua record satu siswa. Record yang tanggalnya tidak
+// berubah didahulukan; jika dua record jatuh di tanggal yang sama, record yang
+// kalah dibiarkan tanpa date_key dan dilaporkan sebagai warning untuk dicek manual.
+func rebucketUser(records []*bucketRecord, semesters []models.Semester, report *Report) []mongo.WriteModel {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].target.Equal(records[i].Date) && !records[j].target.Equal(records[j].Date)
+	})
+
+	var writes []mongo.WriteModel
+	taken := map[string]primitive.ObjectID{}
+
+	for _, record := range records {
+		key := record.target.Format("2006-01-02")
+		if owner, ok := taken[key]; ok {
+			report.Changes["attendances_conflicted"]++
+			report.Warnings = append(report.Warnings, fmt.Sprintf(
+				"user %s has more than one attendance on %s (%s kept, %s skipped)",
+				record.UserID.Hex(), key, owner.Hex(), record.ID.Hex()))
+			continue
+		}
+		taken[key] = record.ID
+
+		update := bson.M{}
+		if !record.target.Equal(record.Date) {
+			update["date"] = record.target
+			report.Changes["attendances_rebucketed"]++
+
+			termID := semesterFor(semesters, record.target)
+			if !sameTerm(termID, record.TermID) {
+				update["term_id"] = termID
+				report.Changes["attendances_term_changed"]++
+			}
+		}
+		if record.DateKey != key {
+			update["date_key"] = key
+			report.Changes["attendances_date_key_set"]++
+		}
+		if len(update) == 0 {
+			continue
+		}
+
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": record.ID}).
+			SetUpdate(bson.M{"$set": update}))
+	}
+
+	return writes
+}
+
+func loadSemesters(ctx context.Context, db *mongo.Database) ([]models.Semester, error) {
+	cursor, err := db.Collection("semesters").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch semesters: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var semesters []models.Semester
+	if err := cursor.All(ctx, &semesters); err != nil {
+		return nil, fmt.Errorf("failed to decode semesters: %v", err)
+	}
+	return semesters, nil
+}
+
+func semesterFor(semesters []models.Semester, date time.Time) *primitive.ObjectID {
+	for _, semester := range semesters {
+		if !date.Before(semester.StartDate) && !date.After(semester.EndDate) {
+			id := semester.ID
+			return &id
+		}
+	}
+	return nil
+}
+
+func sameTerm(a, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AcademicYear struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name         string             `json:"name" bson:"name"` // contoh: 2025/2026
+	StartDate    time.Time          `json:"start_date" bson:"start_date"`
+	EndDate      time.Time          `json:"end_date" bson:"end_date"`
+	RolledOverAt *time.Time         `json:"rolled_over_at,omitempty" bson:"rolled_over_at,omitempty"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// Semester adalah satu term dalam tahun ajaran, record absensi ditandai term_id sesuai tanggalnya
+type Semester struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	AcademicYearID primitive.ObjectID `json:"academic_year_id" bson:"academic_year_id"`
+	Name           string             `json:"name" bson:"name"`     // Ganjil, Genap
+	Number         int                `json:"number" bson:"number"` // 1, 2
+	StartDate      time.Time          `json:"start_date" bson:"start_date"`
+	EndDate        time.Time          `json:"end_date" bson:"end_date"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type AcademicYearDetail struct {
+	AcademicYear `bson:",inline"`
+	Semesters    []Semester `json:"semesters"`
+}
+
+type CreateAcademicYearRequest struct {
+	Name      string `json:"name" validate:"required,min=4,max=20"`
+	StartDate string `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate   string `json:"end_date" validate:"required,datetime=2006-01-02"`
+}
+
+type UpdateAcademicYearRequest struct {
+	Name      string `json:"name,omitempty" validate:"omitempty,min=4,max=20"`
+	StartDate string `json:"start_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	EndDate   string `json:"end_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type CreateSemesterRequest struct {
+	Name      string `json:"name" validate:"required,min=2,max=20"`
+	Number    int    `json:"number" validate:"required,min=1,max=4"`
+	StartDate string `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate   string `json:"end_date" validate:"required,datetime=2006-01-02"`
+}
+
+type UpdateSemesterRequest struct {
+	Name      string `json:"name,omitempty" validate:"omitempty,min=2,max=20"`
+	StartDate string `json:"start_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+	EndDate   string `json:"end_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// RolloverRequest memetakan kelas lama ke kelas baru (berdasarkan ID) saat kenaikan kelas,
+// siswa di kelas Graduate dinonaktifkan sebagai alumni
+type RolloverRequest struct {
+	Promotions []ClassPromotionRule `json:"promotions" validate:"omitempty,max=200,dive"`
+	Graduate   []string             `json:"graduate" validate:"omitempty,max=100,dive,len=24,hexadecimal"`
+	DryRun     bool                 `json:"dry_run"`
+}
+
+type ClassPromotionRule struct {
+	FromClassID string `json:"from_class_id" validate:"required,len=24,hexadecimal"`
+	ToClassID   string `json:"to_class_id" validate:"required,len=24,hexadecimal"`
+}
+
+// ClassPromotion mencatat perpindahan kelas satu siswa saat rollover
+type ClassPromotion struct {
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	AcademicYearID primitive.ObjectID  `json:"academic_year_id" bson:"academic_year_id"`
+	UserID         primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	FromClassID    primitive.ObjectID  `json:"from_class_id" bson:"from_class_id"`
+	FromKelas      string              `json:"from_kelas" bson:"from_kelas"`
+	ToClassID      *primitive.ObjectID `json:"to_class_id,omitempty" bson:"to_class_id,omitempty"`
+	ToKelas        string              `json:"to_kelas,omitempty" bson:"to_kelas,omitempty"`
+	Graduated      bool                `json:"graduated" bson:"graduated"`
+	CreatedAt      time.Time           `json:"created_at" bson:"created_at"`
+}
+
+type RolloverResult struct {
+	AcademicYear AcademicYear `json:"academic_year"`
+	Promoted     int          `json:"promoted"`
+	Graduated    int          `json:"graduated"`
+	Unmapped     []string     `json:"unmapped_classes"`
+	DryRun       bool         `json:"dry_run"`
+}
+
+// AttendancePeriod membatasi statistik/riwayat ke satu semester atau rentang tanggal
+type AttendancePeriod struct {
+	TermID *primitive.ObjectID `json:"term_id,omitempty"`
+	From   *time.Time          `json:"from,omitempty"`
+	To     *time.Time          `json:"to,omitempty"`
+}
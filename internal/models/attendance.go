@@ -10,28 +10,167 @@ type Attendance struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
 	Date      time.Time          `json:"date" bson:"date"`
+	DateKey   string             `json:"date_key" bson:"date_key,omitempty"` // YYYY-MM-DD menurut zona waktu sekolah
+	TermID    *primitive.ObjectID `json:"term_id,omitempty" bson:"term_id,omitempty"`
 	CheckIn   *time.Time         `json:"check_in,omitempty" bson:"check_in,omitempty"`
 	CheckOut  *time.Time         `json:"check_out,omitempty" bson:"check_out,omitempty"`
-	Status    string             `json:"status" bson:"status"` // present, late, absent
+	Status    string             `json:"status" bson:"status"` // present, late, very_late, absent
 	Location  Location           `json:"location" bson:"location"`
+	DeviceID  string             `json:"device_id,omitempty" bson:"device_id,omitempty"`
+
+	VerificationMethod string              `json:"verification_method,omitempty" bson:"verification_method,omitempty"` // gps, qr_kiosk
+	KioskID            *primitive.ObjectID `json:"kiosk_id,omitempty" bson:"kiosk_id,omitempty"`
+	CheckOutMethod     string              `json:"check_out_method,omitempty" bson:"check_out_method,omitempty"`
+	CheckOutKioskID    *primitive.ObjectID `json:"check_out_kiosk_id,omitempty" bson:"check_out_kiosk_id,omitempty"`
+
+	Flagged   bool               `json:"flagged" bson:"flagged"`
+	Risk      *RiskAssessment    `json:"risk,omitempty" bson:"risk,omitempty"`
+
+	CheckOutRisk *RiskAssessment `json:"check_out_risk,omitempty" bson:"check_out_risk,omitempty"`
+
+	CheckInSelfie  *Selfie `json:"check_in_selfie,omitempty" bson:"check_in_selfie,omitempty"`
+	CheckOutSelfie *Selfie `json:"check_out_selfie,omitempty" bson:"check_out_selfie,omitempty"`
+
+	// SubmittedLate berarti check in direkam offline dan baru diterima server pada SubmittedAt;
+	// CheckIn tetap berisi jam asli saat direkam
+	SubmittedLate bool       `json:"submitted_late" bson:"submitted_late,omitempty"`
+	SubmittedAt   *time.Time `json:"submitted_at,omitempty" bson:"submitted_at,omitempty"`
+
+	// MinutesLate dihitung dari jam masuk sekolah. DepartureStatus dinilai saat
+	// check out: on_time, early_leave, atau no_checkout
+	MinutesLate     int    `json:"minutes_late" bson:"minutes_late,omitempty"`
+	DepartureStatus string `json:"departure_status,omitempty" bson:"departure_status,omitempty"`
+	MinutesEarly    int    `json:"minutes_early" bson:"minutes_early,omitempty"`
+
+	// Voided berarti record dibatalkan guru/admin dan tidak dihitung di statistik
+	Voided   bool                 `json:"voided" bson:"voided"`
+	VoidedAt *time.Time           `json:"voided_at,omitempty" bson:"voided_at,omitempty"`
+	History  []AttendanceRevision `json:"history,omitempty" bson:"history,omitempty"`
+
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
+const (
+	StatusPresent  = "present"
+	StatusLate     = "late"
+	StatusVeryLate = "very_late"
+	StatusAbsent   = "absent"
+	StatusSick     = "sick"    // sakit, dari pengajuan izin yang disetujui wali kelas
+	StatusExcused  = "excused" // izin
+
+	DepartureOnTime     = "on_time"
+	DepartureEarlyLeave = "early_leave"
+	DepartureNoCheckout = "no_checkout"
+
+	VerificationGPS     = "gps"
+	VerificationKiosk   = "qr_kiosk"
+	VerificationManual  = "manual"
+	VerificationOffline = "offline" // dicatat aplikasi tanpa sinyal, dikirim belakangan
+	VerificationSystem  = "system"  // alpa otomatis, tidak ada record sampai jendela check in tutup
+
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionVoid    = "void"
+	RevisionRestore = "restore"
+
+	CorrectionSourceManual  = "manual"
+	CorrectionSourceRequest = "correction_request"
+	CorrectionSourceLeave   = "leave_request"
+)
+
+// AttendanceRevision mencatat satu perubahan record absensi oleh guru/admin
+// beserta kondisi sebelum dan sesudahnya
+type AttendanceRevision struct {
+	Action        string              `json:"action" bson:"action"` // create, update, void, restore
+	Before        *AttendanceSnapshot `json:"before,omitempty" bson:"before,omitempty"`
+	After         *AttendanceSnapshot `json:"after" bson:"after"`
+	Reason        string              `json:"reason" bson:"reason"`
+	Source        string              `json:"source" bson:"source"` // manual, correction_request
+	RequestID     *primitive.ObjectID `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	ChangedBy     primitive.ObjectID  `json:"changed_by" bson:"changed_by"`
+	ChangedByName string              `json:"changed_by_name" bson:"changed_by_name"`
+	ChangedByRole string              `json:"changed_by_role" bson:"changed_by_role"`
+	ChangedAt     time.Time           `json:"changed_at" bson:"changed_at"`
+}
+
+type AttendanceSnapshot struct {
+	Status   string     `json:"status" bson:"status"`
+	CheckIn  *time.Time `json:"check_in,omitempty" bson:"check_in,omitempty"`
+	CheckOut *time.Time `json:"check_out,omitempty" bson:"check_out,omitempty"`
+	Voided   bool       `json:"voided" bson:"voided"`
+}
+
+func (a Attendance) Snapshot() *AttendanceSnapshot {
+	return &AttendanceSnapshot{
+		Status:   a.Status,
+		CheckIn:  a.CheckIn,
+		CheckOut: a.CheckOut,
+		Voided:   a.Voided,
+	}
+}
+
+// ManualAttendanceRequest dipakai guru/admin untuk membuat record absensi siswa
+type ManualAttendanceRequest struct {
+	StudentID string     `json:"student_id" validate:"required,len=24,hexadecimal"`
+	Date      string     `json:"date" validate:"required,datetime=2006-01-02"`
+	Status    string     `json:"status" validate:"required,oneof=present late very_late absent sick excused"`
+	CheckIn   *time.Time `json:"check_in,omitempty"`
+	CheckOut  *time.Time `json:"check_out,omitempty"`
+	Reason    string     `json:"reason" validate:"required,min=5,max=500"`
+}
+
+type UpdateAttendanceRequest struct {
+	Status   string     `json:"status,omitempty" validate:"omitempty,oneof=present late very_late absent sick excused"`
+	CheckIn  *time.Time `json:"check_in,omitempty"`
+	CheckOut *time.Time `json:"check_out,omitempty"`
+	Reason   string     `json:"reason" validate:"required,min=5,max=500"`
+}
+
+type VoidAttendanceRequest struct {
+	Reason string `json:"reason" validate:"required,min=5,max=500"`
+}
+
 type Location struct {
 	Latitude  float64 `json:"latitude" bson:"latitude"`
 	Longitude float64 `json:"longitude" bson:"longitude"`
 	Address   string  `json:"address,omitempty" bson:"address,omitempty"`
 }
 
+// AttendanceRequest bisa dikirim sebagai JSON atau multipart/form-data (jika ada file selfie)
 type AttendanceRequest struct {
-	Latitude  float64 `json:"latitude" validate:"required"`
-	Longitude float64 `json:"longitude" validate:"required"`
-	Address   string  `json:"address,omitempty"`
+	Latitude  float64 `json:"latitude" form:"latitude" validate:"required"`
+	Longitude float64 `json:"longitude" form:"longitude" validate:"required"`
+	Address   string  `json:"address,omitempty" form:"address"`
+
+	// diisi oleh controller dari middleware, bukan dari body request
+	DeviceID string              `json:"-" form:"-"`
+	Risk     *RiskAssessment     `json:"-" form:"-"`
+	KioskID  *primitive.ObjectID `json:"-" form:"-"` // terisi jika absen lewat scan QR kiosk
+	Selfie   *Selfie             `json:"-" form:"-"` // sudah diproses dan disimpan di storage
+}
+
+// QRAttendanceRequest dikirim aplikasi setelah scan QR di kiosk, beserta lokasi GPS HP siswa
+// sebagai bukti berada di dekat kiosk
+type QRAttendanceRequest struct {
+	Token     string  `json:"token" form:"token" validate:"required,max=200"`
+	Latitude  float64 `json:"latitude" form:"latitude" validate:"required,latitude"`
+	Longitude float64 `json:"longitude" form:"longitude" validate:"required,longitude"`
+}
+
+func (ar AttendanceRequest) VerificationMethod() string {
+	if ar.KioskID != nil {
+		return VerificationKiosk
+	}
+	return VerificationGPS
 }
 
 func (ar AttendanceRequest) ToLocation() Location {
-	return Location(ar)
+	return Location{
+		Latitude:  ar.Latitude,
+		Longitude: ar.Longitude,
+		Address:   ar.Address,
+	}
 }
 
 type AttendanceResponse struct {
@@ -42,32 +181,66 @@ type AttendanceResponse struct {
 	CheckOut  *time.Time         `json:"check_out"`
 	Status    string             `json:"status"`
 	Location  Location           `json:"location"`
+	Flagged   bool               `json:"flagged"`
+
+	MinutesLate     int    `json:"minutes_late"`
+	DepartureStatus string `json:"departure_status,omitempty"`
+	MinutesEarly    int    `json:"minutes_early"`
+
+	VerificationMethod string              `json:"verification_method,omitempty"`
+	KioskID            *primitive.ObjectID `json:"kiosk_id,omitempty"`
+	SubmittedLate      bool                 `json:"submitted_late"`
+	SubmittedAt        *time.Time           `json:"submitted_at,omitempty"`
+	CheckInSelfie      *Selfie              `json:"check_in_selfie,omitempty"`
+	CheckOutSelfie     *Selfie              `json:"check_out_selfie,omitempty"`
+	Voided             bool                 `json:"voided"`
+	History            []AttendanceRevision `json:"history,omitempty"`
 	User      UserPublic         `json:"user"`
 	CreatedAt time.Time          `json:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at"`
 }
 
 type UserPublic struct {
-	ID      primitive.ObjectID `json:"id"`
-	NIS     string             `json:"nis"`
-	Name    string             `json:"name"`
-	Kelas   string             `json:"kelas"`
-	Jurusan string             `json:"jurusan"`
-	Email   string             `json:"email"`
-	Phone   string             `json:"phone"`
+	ID      primitive.ObjectID  `json:"id"`
+	NIS     string              `json:"nis"`
+	Name    string              `json:"name"`
+	Kelas   string              `json:"kelas"`
+	Jurusan string              `json:"jurusan"`
+	ClassID *primitive.ObjectID `json:"class_id,omitempty"`
+	Email   string              `json:"email"`
+	Phone   string              `json:"phone"`
 }
 
 type AttendanceStats struct {
 	TotalPresent int     `json:"total_present"`
 	TotalLate    int     `json:"total_late"`
 	TotalAbsent  int     `json:"total_absent"`
+	TotalSick    int     `json:"total_sick"`
+	TotalExcused int     `json:"total_excused"`
 	Percentage   float64 `json:"percentage"`
+
+	TotalVeryLate     int `json:"total_very_late"`
+	TotalEarlyLeave   int `json:"total_early_leave"`
+	TotalNoCheckout   int `json:"total_no_checkout"`
+	TotalMinutesLate  int `json:"total_minutes_late"`
+	TotalMinutesEarly int `json:"total_minutes_early"`
+
+	Period *AttendancePeriod `json:"period,omitempty"`
+	Term   *Semester         `json:"term,omitempty"`
 }
 
 type AttendanceHistory struct {
 	Date     time.Time `json:"date"`
+	TermID   *primitive.ObjectID `json:"term_id,omitempty"`
 	CheckIn  *time.Time `json:"check_in"`
 	CheckOut *time.Time `json:"check_out"`
 	Status   string    `json:"status"`
 	Location Location  `json:"location"`
+
+	VerificationMethod string `json:"verification_method,omitempty"`
+	SubmittedLate      bool                 `json:"submitted_late"`
+	CheckInSelfie      *Selfie              `json:"check_in_selfie,omitempty"`
+	CheckOutSelfie     *Selfie              `json:"check_out_selfie,omitempty"`
+	Voided             bool                 `json:"voided"`
+	History            []AttendanceRevision `json:"history,omitempty"`
 }
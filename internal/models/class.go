@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Major adalah jurusan/kompetensi keahlian, contoh RPL - Rekayasa Perangkat Lunak
+type Major struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Code      string             `json:"code" bson:"code"`
+	Name      string             `json:"name" bson:"name"`
+	IsActive  bool               `json:"is_active" bson:"is_active"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateMajorRequest struct {
+	Code string `json:"code" validate:"required,min=2,max=10,alphanum"`
+	Name string `json:"name" validate:"required,min=2,max=100"`
+}
+
+type UpdateMajorRequest struct {
+	Name     string `json:"name,omitempty" validate:"omitempty,min=2,max=100"`
+	IsActive *bool  `json:"is_active,omitempty"`
+}
+
+// Class adalah rombongan belajar, contoh XII RPL 1. Nama kelas disalin ke
+// users.kelas supaya response lama tetap berisi nama kelas.
+type Class struct {
+	ID                primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Name              string              `json:"name" bson:"name"`
+	Grade             int                 `json:"grade" bson:"grade"` // 10, 11, 12, 13
+	MajorID           primitive.ObjectID  `json:"major_id" bson:"major_id"`
+	HomeroomTeacherID *primitive.ObjectID `json:"homeroom_teacher_id,omitempty" bson:"homeroom_teacher_id,omitempty"`
+	IsActive          bool                `json:"is_active" bson:"is_active"`
+	CreatedAt         time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateClassRequest struct {
+	Name    string `json:"name" validate:"required,min=1,max=50"`
+	Grade   int    `json:"grade" validate:"required,min=10,max=13"`
+	MajorID string `json:"major_id" validate:"required,len=24,hexadecimal"`
+}
+
+type UpdateClassRequest struct {
+	Name     string `json:"name,omitempty" validate:"omitempty,min=1,max=50"`
+	Grade    int    `json:"grade,omitempty" validate:"omitempty,min=10,max=13"`
+	MajorID  string `json:"major_id,omitempty" validate:"omitempty,len=24,hexadecimal"`
+	IsActive *bool  `json:"is_active,omitempty"`
+}
+
+// AssignHomeroomRequest mengatur wali kelas, teacher_id kosong berarti melepas wali kelas
+type AssignHomeroomRequest struct {
+	TeacherID string `json:"teacher_id" validate:"omitempty,len=24,hexadecimal"`
+}
+
+type ClassDetail struct {
+	Class           `bson:",inline"`
+	Major           *Major      `json:"major,omitempty"`
+	HomeroomTeacher *UserPublic `json:"homeroom_teacher,omitempty"`
+	StudentCount    int64       `json:"student_count"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CorrectionStatusPending    = "pending"
+	CorrectionStatusProcessing = "processing" // sedang diproses satu reviewer, sementara
+	CorrectionStatusApproved   = "approved"
+	CorrectionStatusRejected   = "rejected"
+)
+
+// CorrectionRequest diajukan siswa ketika absen gagal (misal GPS meleset),
+// ditinjau wali kelas dan jika disetujui mengubah record absensi
+type CorrectionRequest struct {
+	ID              primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID          primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	Date            time.Time           `json:"date" bson:"date"`
+	ClaimedCheckIn  time.Time           `json:"claimed_check_in" bson:"claimed_check_in"`
+	ClaimedCheckOut *time.Time          `json:"claimed_check_out,omitempty" bson:"claimed_check_out,omitempty"`
+	Reason          string              `json:"reason" bson:"reason"`
+	EvidenceURL     string              `json:"evidence_url,omitempty" bson:"evidence_url,omitempty"` // hanya pengajuan lama
+	Evidence        *Selfie             `json:"evidence,omitempty" bson:"evidence,omitempty"`
+	Status          string              `json:"status" bson:"status"`
+	AttendanceID    *primitive.ObjectID `json:"attendance_id,omitempty" bson:"attendance_id,omitempty"`
+	ReviewedBy      *primitive.ObjectID `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time          `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+	ReviewNote      string              `json:"review_note,omitempty" bson:"review_note,omitempty"`
+	CreatedAt       time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+type CorrectionRequestInput struct {
+	Date            string     `json:"date" validate:"required,datetime=2006-01-02"`
+	ClaimedCheckIn  time.Time  `json:"claimed_check_in" validate:"required"`
+	ClaimedCheckOut *time.Time `json:"claimed_check_out,omitempty"`
+	Reason          string     `json:"reason" validate:"required,min=5,max=500"`
+}
+
+type CorrectionRequestReport struct {
+	CorrectionRequest `bson:",inline"`
+	User              UserPublic `json:"user"`
+}
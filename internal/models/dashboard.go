@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StatusNotCheckedIn dipakai dashboard kelas untuk siswa yang belum punya record absensi hari itu
+const StatusNotCheckedIn = "not_checked_in"
+
+// ClassAttendanceEntry adalah baris satu siswa di dashboard harian kelas
+type ClassAttendanceEntry struct {
+	User               UserPublic          `json:"user"`
+	AttendanceID       *primitive.ObjectID `json:"attendance_id,omitempty"`
+	Status             string              `json:"status"`
+	CheckIn            *time.Time          `json:"check_in,omitempty"`
+	CheckOut           *time.Time          `json:"check_out,omitempty"`
+	MinutesLate        int                 `json:"minutes_late"`
+	DepartureStatus    string              `json:"departure_status,omitempty"`
+	MinutesEarly       int                 `json:"minutes_early"`
+	VerificationMethod string              `json:"verification_method,omitempty"`
+	Flagged            bool                `json:"flagged"`
+}
+
+// ClassDaySummary rekap satu hari untuk satu kelas. Percentage = (present + late + very_late) / total siswa.
+type ClassDaySummary struct {
+	Date          time.Time `json:"date"`
+	TotalStudents int       `json:"total_students"`
+	Present       int       `json:"present"`
+	Late          int       `json:"late"`
+	VeryLate      int       `json:"very_late"`
+	Absent        int       `json:"absent"`
+	Sick          int       `json:"sick"`
+	Excused       int       `json:"excused"`
+	EarlyLeave    int       `json:"early_leave"`
+	NoCheckout    int       `json:"no_checkout"`
+	NotCheckedIn  int       `json:"not_checked_in"`
+	CheckedOut    int       `json:"checked_out"`
+	Flagged       int       `json:"flagged"`
+	Percentage    float64   `json:"percentage"`
+}
+
+type ClassDailyAttendance struct {
+	Class        Class                  `json:"class"`
+	Date         time.Time              `json:"date"`
+	Summary      ClassDaySummary        `json:"summary"`
+	Students     []ClassAttendanceEntry `json:"students"`
+	NotCheckedIn []UserPublic           `json:"not_checked_in"`
+}
+
+// ClassStudentSummary rekap bulanan satu siswa di dashboard kelas
+type ClassStudentSummary struct {
+	User       UserPublic `json:"user"`
+	Present    int        `json:"present"`
+	Late       int        `json:"late"`
+	VeryLate   int        `json:"very_late"`
+	Absent     int        `json:"absent"`
+	Sick       int        `json:"sick"`
+	Excused    int        `json:"excused"`
+	EarlyLeave int        `json:"early_leave"`
+	NoCheckout int        `json:"no_checkout"`
+	Missing    int        `json:"missing"` // hari sekolah tanpa record absensi
+	Flagged    int        `json:"flagged"`
+	Percentage float64    `json:"percentage"`
+}
+
+// ClassMonthlyAttendance rekap bulanan kelas. Hari sekolah dihitung dari tanggal
+// yang punya minimal satu record absensi di kelas tersebut.
+type ClassMonthlyAttendance struct {
+	Class         Class                 `json:"class"`
+	Month         string                `json:"month"`
+	From          time.Time             `json:"from"`
+	To            time.Time             `json:"to"`
+	TotalStudents int                   `json:"total_students"`
+	SchoolDays    int                   `json:"school_days"`
+	Percentage    float64               `json:"percentage"`
+	Days          []ClassDaySummary     `json:"days"`
+	Students      []ClassStudentSummary `json:"students"`
+}
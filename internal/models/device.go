@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DeviceStatusActive  = "active"
+	DeviceStatusRevoked = "revoked"
+
+	ResetStatusPending  = "pending"
+	ResetStatusApproved = "approved"
+	ResetStatusRejected = "rejected"
+)
+
+// Device adalah perangkat terpercaya milik siswa beserta public key dari aplikasi
+type Device struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	DeviceID   string             `json:"device_id" bson:"device_id"`
+	PublicKey  string             `json:"public_key" bson:"public_key"` // base64 DER (PKIX), ECDSA P-256 atau Ed25519
+	Name       string             `json:"name,omitempty" bson:"name,omitempty"`
+	Platform   string             `json:"platform,omitempty" bson:"platform,omitempty"`
+	Status     string             `json:"status" bson:"status"`
+	Slot       *int               `json:"-" bson:"slot,omitempty"` // 0..MAX_DEVICES_PER_USER-1 selama aktif
+	LastUsedAt *time.Time         `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	RevokedAt  *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type RegisterDeviceRequest struct {
+	PublicKey string `json:"public_key" validate:"required,base64"`
+	Name      string `json:"name,omitempty" validate:"omitempty,max=100"`
+	Platform  string `json:"platform,omitempty" validate:"omitempty,oneof=android ios"`
+}
+
+// DeviceResetRequest diajukan siswa ketika ganti HP, disetujui wali kelas
+type DeviceResetRequest struct {
+	ID         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	Reason     string              `json:"reason" bson:"reason"`
+	Status     string              `json:"status" bson:"status"`
+	ReviewedBy *primitive.ObjectID `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time          `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+	ReviewNote string              `json:"review_note,omitempty" bson:"review_note,omitempty"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+type DeviceResetRequestInput struct {
+	Reason string `json:"reason" validate:"required,min=5,max=500"`
+}
+
+type ReviewRequest struct {
+	Note string `json:"note,omitempty" validate:"omitempty,max=500"`
+}
+
+// DeviceAttempt mencatat absensi yang dicoba dari perangkat yang tidak terikat
+type DeviceAttempt struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	DeviceID  string             `json:"device_id,omitempty" bson:"device_id,omitempty"`
+	Reason    string             `json:"reason" bson:"reason"` // missing_signature, unbound_device, invalid_signature, expired_signature, replayed_request
+	Path      string             `json:"path" bson:"path"`
+	ClientIP  string             `json:"client_ip" bson:"client_ip"`
+	UserAgent string             `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type DeviceAttemptReport struct {
+	DeviceAttempt `bson:",inline"`
+	User          UserPublic `json:"user"`
+}
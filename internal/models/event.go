@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EventCheckIn    = "attendance.checkin"
+	EventCheckOut   = "attendance.checkout"
+	EventCorrection = "attendance.correction"
+	EventAbsent     = "attendance.absent" // ditandai job alpa setelah jendela check in tutup
+
+	EventScopeSelf   = "self"
+	EventScopeClass  = "class"
+	EventScopeSchool = "school"
+)
+
+// AttendanceEvent dikirim ke feed real-time dan disimpan sementara di collection
+// attendance_events untuk replay lewat Last-Event-ID
+type AttendanceEvent struct {
+	ID        primitive.ObjectID  `json:"id" bson:"_id"`
+	Type      string              `json:"type" bson:"type"`
+	Instance  string              `json:"-" bson:"instance"` // instance API yang mem-publish
+	UserID    primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	ClassID   *primitive.ObjectID `json:"class_id,omitempty" bson:"class_id,omitempty"`
+	Student   EventStudent        `json:"student" bson:"student"`
+	Record    EventAttendance     `json:"attendance" bson:"attendance"`
+	CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+}
+
+type EventStudent struct {
+	Name string `json:"name" bson:"name"`
+	NIS  string `json:"nis" bson:"nis"`
+}
+
+type EventAttendance struct {
+	ID                 primitive.ObjectID `json:"id" bson:"id"`
+	Date               time.Time          `json:"date" bson:"date"`
+	Status             string             `json:"status" bson:"status"`
+	DepartureStatus    string             `json:"departure_status,omitempty" bson:"departure_status,omitempty"`
+	CheckIn            *time.Time         `json:"check_in,omitempty" bson:"check_in,omitempty"`
+	CheckOut           *time.Time         `json:"check_out,omitempty" bson:"check_out,omitempty"`
+	VerificationMethod string             `json:"verification_method,omitempty" bson:"verification_method,omitempty"`
+	MinutesLate        int                `json:"minutes_late,omitempty" bson:"minutes_late,omitempty"`
+	Flagged            bool               `json:"flagged" bson:"flagged"`
+	Voided             bool               `json:"voided" bson:"voided"`
+	SubmittedLate      bool               `json:"submitted_late" bson:"submitted_late"`
+}
+
+// EventTicket dipakai EventSource/WebSocket di browser yang tidak bisa mengirim header Authorization
+type EventTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GuardianInvite adalah kode undangan dari sekolah (wali kelas/admin) untuk menautkan akun
+// orang tua/wali ke satu siswa. Kode hanya ditampilkan sekali, yang disimpan hash-nya.
+type GuardianInvite struct {
+	ID        primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	StudentID primitive.ObjectID  `json:"student_id" bson:"student_id"`
+	CodeHash  string              `json:"-" bson:"code_hash"`
+	Relation  string              `json:"relation,omitempty" bson:"relation,omitempty"` // ayah, ibu, wali
+	CreatedBy primitive.ObjectID  `json:"created_by" bson:"created_by"`
+	ExpiresAt time.Time           `json:"expires_at" bson:"expires_at"`
+	UsedBy    *primitive.ObjectID `json:"used_by,omitempty" bson:"used_by,omitempty"`
+	UsedAt    *time.Time          `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// GuardianInviteCode hanya dikembalikan sekali saat kode dibuat
+type GuardianInviteCode struct {
+	Invite GuardianInvite `json:"invite"`
+	Code   string         `json:"code"`
+}
+
+// GuardianLink menautkan akun orang tua/wali ke siswa, satu orang tua bisa punya beberapa anak
+type GuardianLink struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	GuardianID primitive.ObjectID `json:"guardian_id" bson:"guardian_id"`
+	StudentID  primitive.ObjectID `json:"student_id" bson:"student_id"`
+	Relation   string             `json:"relation,omitempty" bson:"relation,omitempty"`
+	InviteID   primitive.ObjectID `json:"invite_id" bson:"invite_id"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type CreateGuardianInviteRequest struct {
+	Relation string `json:"relation,omitempty" validate:"omitempty,max=50"`
+}
+
+type RegisterGuardianRequest struct {
+	Name       string `json:"name" validate:"required,min=2,max=100"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required,min=6"`
+	Phone      string `json:"phone,omitempty" validate:"omitempty,min=10,max=15"`
+	InviteCode string `json:"invite_code" validate:"required,min=8,max=20"`
+}
+
+type LinkGuardianRequest struct {
+	InviteCode string `json:"invite_code" validate:"required,min=8,max=20"`
+}
+
+// GuardianChild adalah anak yang ditautkan, dilihat dari sisi orang tua
+type GuardianChild struct {
+	User     UserPublic `json:"user"`
+	Relation string     `json:"relation,omitempty"`
+	LinkedAt time.Time  `json:"linked_at"`
+}
+
+// StudentGuardian adalah orang tua yang ditautkan, dilihat dari sisi sekolah
+type StudentGuardian struct {
+	LinkID   primitive.ObjectID `json:"link_id"`
+	User     UserPublic         `json:"user"`
+	Relation string             `json:"relation,omitempty"`
+	LinkedAt time.Time          `json:"linked_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	IdempotencyProcessing = "processing"
+	IdempotencyCompleted  = "completed"
+)
+
+// IdempotencyRecord menyimpan hasil request yang membawa header Idempotency-Key,
+// _id berisi "<user_id>:<key>" sehingga key hanya berlaku per user
+type IdempotencyRecord struct {
+	ID          string             `bson:"_id"`
+	UserID      primitive.ObjectID `bson:"user_id"`
+	Key         string             `bson:"key"`
+	Method      string             `bson:"method"`
+	Path        string             `bson:"path"`
+	Fingerprint string             `bson:"fingerprint"` // sha256 body request
+	Status      string             `bson:"status"`
+	StatusCode  int                `bson:"status_code,omitempty"`
+	ContentType string             `bson:"content_type,omitempty"`
+	Body        []byte             `bson:"body,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at"`
+	ExpiresAt   time.Time          `bson:"expires_at"`
+}
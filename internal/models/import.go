@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// ImportModePassword membuat password awal yang dikembalikan di laporan import
+	ImportModePassword = "password"
+	// ImportModeInvite mengirim email undangan berisi link untuk membuat password sendiri
+	ImportModeInvite = "invite"
+
+	ImportActionCreate = "create"
+	ImportActionUpdate = "update"
+	ImportActionError  = "error"
+)
+
+// StudentImportRecord adalah satu baris file roster siswa
+type StudentImportRecord struct {
+	Line  int    `json:"line"`
+	NIS   string `json:"nis" validate:"required,min=3,max=20"`
+	Name  string `json:"name" validate:"required,min=2,max=100"`
+	Class string `json:"class" validate:"required,max=50"`
+	Major string `json:"major,omitempty" validate:"omitempty,max=100"`
+	Email string `json:"email" validate:"required,email"`
+	Phone string `json:"phone,omitempty" validate:"omitempty,min=10,max=15"`
+}
+
+type StudentImportOptions struct {
+	DryRun bool   `json:"dry_run"`
+	Mode   string `json:"mode" validate:"required,oneof=password invite"`
+}
+
+type StudentImportResult struct {
+	Line   int                 `json:"line"`
+	NIS    string              `json:"nis"`
+	Name   string              `json:"name"`
+	Class  string              `json:"class"`
+	Action string              `json:"action"`
+	UserID *primitive.ObjectID `json:"user_id,omitempty"`
+	Errors []string            `json:"errors,omitempty"`
+	// InitialPassword hanya diisi untuk siswa baru pada mode password
+	InitialPassword string `json:"initial_password,omitempty"`
+	Invited         bool   `json:"invited,omitempty"`
+}
+
+// StudentImportReport hasil import. Jika ada baris yang tidak valid, tidak ada data yang
+// ditulis sama sekali (Applied false) dan error per baris ada di Results.
+type StudentImportReport struct {
+	DryRun  bool                  `json:"dry_run"`
+	Mode    string                `json:"mode"`
+	Applied bool                  `json:"applied"`
+	Total   int                   `json:"total"`
+	Created int                   `json:"created"`
+	Updated int                   `json:"updated"`
+	Failed  int                   `json:"failed"`
+	Results []StudentImportResult `json:"results"`
+}
+
+// PasswordSetupToken link sekali pakai untuk siswa hasil import membuat password sendiri
+type PasswordSetupToken struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	TokenHash string             `json:"-" bson:"token_hash"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	UsedAt    *time.Time         `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type SetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6,max=50"`
+}
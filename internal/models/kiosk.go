@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	KioskTypeGate      = "gate"
+	KioskTypeClassroom = "classroom"
+)
+
+// Kiosk adalah layar di gerbang atau kelas yang menampilkan QR code absensi
+type Kiosk struct {
+	ID         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Name       string              `json:"name" bson:"name"`
+	Type       string              `json:"type" bson:"type"`
+	Location   Location            `json:"location" bson:"location"`
+	Secret     string              `json:"-" bson:"secret"`       // kunci HMAC untuk QR
+	APIKeyHash string              `json:"-" bson:"api_key_hash"` // sha256 dari API key kiosk
+	IsActive   bool                `json:"is_active" bson:"is_active"`
+	CreatedBy  *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	LastSeenAt *time.Time          `json:"last_seen_at,omitempty" bson:"last_seen_at,omitempty"`
+	CreatedAt  time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateKioskRequest struct {
+	Name      string  `json:"name" validate:"required,min=2,max=100"`
+	Type      string  `json:"type" validate:"required,oneof=gate classroom"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+	Address   string  `json:"address,omitempty" validate:"omitempty,max=255"`
+}
+
+type UpdateKioskRequest struct {
+	Name      string   `json:"name,omitempty" validate:"omitempty,min=2,max=100"`
+	Type      string   `json:"type,omitempty" validate:"omitempty,oneof=gate classroom"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	Address   string   `json:"address,omitempty" validate:"omitempty,max=255"`
+	IsActive  *bool    `json:"is_active,omitempty"`
+}
+
+// KioskCredentials hanya dikembalikan sekali saat kiosk dibuat atau key dirotasi
+type KioskCredentials struct {
+	Kiosk  Kiosk  `json:"kiosk"`
+	APIKey string `json:"api_key"`
+}
+
+type KioskQRCode struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RefreshIn int       `json:"refresh_in"` // detik
+}
+
+// KioskScan mencatat token QR yang sudah dipakai untuk mencegah replay
+type KioskScan struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	KioskID   primitive.ObjectID `bson:"kiosk_id"`
+	Window    int64              `bson:"window"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	Action    string             `bson:"action"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
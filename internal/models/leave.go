@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	LeaveTypeSick    = "sick"
+	LeaveTypeExcused = "excused"
+
+	LeaveStatusPending    = "pending"
+	LeaveStatusProcessing = "processing" // sedang diproses satu reviewer, sementara
+	LeaveStatusApproved   = "approved"
+	LeaveStatusRejected   = "rejected"
+)
+
+// LeaveRequest adalah pengajuan sakit/izin untuk rentang tanggal, diajukan siswa atau
+// orang tuanya (khusus surat sakit). Jika disetujui wali kelas, setiap hari sekolah di
+// rentang tersebut dicatat dengan status sick/excused.
+type LeaveRequest struct {
+	ID              primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	StudentID       primitive.ObjectID   `json:"student_id" bson:"student_id"`
+	Type            string               `json:"type" bson:"type"`
+	From            time.Time            `json:"from" bson:"from"`
+	To              time.Time            `json:"to" bson:"to"`
+	Reason          string               `json:"reason" bson:"reason"`
+	EvidenceURL     string               `json:"evidence_url,omitempty" bson:"evidence_url,omitempty"`
+	Status          string               `json:"status" bson:"status"`
+	SubmittedBy     primitive.ObjectID   `json:"submitted_by" bson:"submitted_by"`
+	SubmittedByRole string               `json:"submitted_by_role" bson:"submitted_by_role"`
+	AttendanceIDs   []primitive.ObjectID `json:"attendance_ids,omitempty" bson:"attendance_ids,omitempty"`
+	FailedDates     []LeaveDayFailure    `json:"failed_dates,omitempty" bson:"failed_dates,omitempty"`
+	ReviewedBy      *primitive.ObjectID  `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time           `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+	ReviewNote      string               `json:"review_note,omitempty" bson:"review_note,omitempty"`
+	CreatedAt       time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at" bson:"updated_at"`
+}
+
+// LeaveDayFailure adalah hari sekolah yang absensinya gagal dicatat saat pengajuan disetujui
+type LeaveDayFailure struct {
+	Date  string `json:"date" bson:"date"`
+	Error string `json:"error" bson:"error"`
+}
+
+type LeaveRequestInput struct {
+	Type        string `json:"type" validate:"required,oneof=sick excused"`
+	From        string `json:"from" validate:"required,datetime=2006-01-02"`
+	To          string `json:"to" validate:"required,datetime=2006-01-02"`
+	Reason      string `json:"reason" validate:"required,min=5,max=500"`
+	EvidenceURL string `json:"evidence_url,omitempty" validate:"omitempty,url,max=500"`
+}
+
+// SickNoteInput adalah surat sakit yang diajukan orang tua atas nama anaknya
+type SickNoteInput struct {
+	From        string `json:"from" validate:"required,datetime=2006-01-02"`
+	To          string `json:"to" validate:"required,datetime=2006-01-02"`
+	Reason      string `json:"reason" validate:"required,min=5,max=500"`
+	EvidenceURL string `json:"evidence_url,omitempty" validate:"omitempty,url,max=500"`
+}
+
+type LeaveRequestReport struct {
+	LeaveRequest `bson:",inline"`
+	User         UserPublic `json:"user"`
+}
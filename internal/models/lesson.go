@@ -0,0 +1,161 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	SessionStatusOpen   = "open"
+	SessionStatusClosed = "closed"
+
+	LessonStatusPresent = "present"
+	LessonStatusLate    = "late"
+	LessonStatusExcused = "excused" // izin/sakit
+	LessonStatusAbsent  = "absent"
+
+	LessonMarkSelf    = "self"
+	LessonMarkTeacher = "teacher"
+
+	// VerificationLesson dipakai record harian yang diturunkan dari absensi per jam pelajaran
+	VerificationLesson = "lesson"
+)
+
+type Subject struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Code      string             `json:"code" bson:"code"`
+	Name      string             `json:"name" bson:"name"`
+	IsActive  bool               `json:"is_active" bson:"is_active"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateSubjectRequest struct {
+	Code string `json:"code" validate:"required,min=2,max=20"`
+	Name string `json:"name" validate:"required,min=2,max=100"`
+}
+
+type UpdateSubjectRequest struct {
+	Code     string `json:"code,omitempty" validate:"omitempty,min=2,max=20"`
+	Name     string `json:"name,omitempty" validate:"omitempty,min=2,max=100"`
+	IsActive *bool  `json:"is_active,omitempty"`
+}
+
+// TimetablePeriod adalah satu jam pelajaran di jadwal mingguan sebuah kelas.
+// StartTime/EndTime dalam jam lokal sekolah (HH:MM).
+type TimetablePeriod struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ClassID      primitive.ObjectID `json:"class_id" bson:"class_id"`
+	Kelas        string             `json:"kelas" bson:"kelas"` // nama kelas, salinan untuk tampilan
+	SubjectID    primitive.ObjectID `json:"subject_id" bson:"subject_id"`
+	TeacherID    primitive.ObjectID `json:"teacher_id" bson:"teacher_id"`
+	DayOfWeek    int                `json:"day_of_week" bson:"day_of_week"` // 1 = Senin ... 7 = Minggu
+	PeriodNumber int                `json:"period_number" bson:"period_number"`
+	StartTime    string             `json:"start_time" bson:"start_time"`
+	EndTime      string             `json:"end_time" bson:"end_time"`
+	IsActive     bool               `json:"is_active" bson:"is_active"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type CreatePeriodRequest struct {
+	ClassID      string `json:"class_id" validate:"required,len=24,hexadecimal"`
+	SubjectID    string `json:"subject_id" validate:"required,len=24,hexadecimal"`
+	TeacherID    string `json:"teacher_id" validate:"required,len=24,hexadecimal"`
+	DayOfWeek    int    `json:"day_of_week" validate:"required,min=1,max=7"`
+	PeriodNumber int    `json:"period_number" validate:"required,min=1,max=20"`
+	StartTime    string `json:"start_time" validate:"required,datetime=15:04"`
+	EndTime      string `json:"end_time" validate:"required,datetime=15:04"`
+}
+
+type UpdatePeriodRequest struct {
+	SubjectID    string `json:"subject_id,omitempty" validate:"omitempty,len=24,hexadecimal"`
+	TeacherID    string `json:"teacher_id,omitempty" validate:"omitempty,len=24,hexadecimal"`
+	DayOfWeek    int    `json:"day_of_week,omitempty" validate:"omitempty,min=1,max=7"`
+	PeriodNumber int    `json:"period_number,omitempty" validate:"omitempty,min=1,max=20"`
+	StartTime    string `json:"start_time,omitempty" validate:"omitempty,datetime=15:04"`
+	EndTime      string `json:"end_time,omitempty" validate:"omitempty,datetime=15:04"`
+	IsActive     *bool  `json:"is_active,omitempty"`
+}
+
+// LessonSession dibuka guru di awal jam pelajaran, siswa absen ke sesi ini
+type LessonSession struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PeriodID  primitive.ObjectID `json:"period_id" bson:"period_id"`
+	ClassID   primitive.ObjectID `json:"class_id" bson:"class_id"`
+	Kelas     string             `json:"kelas" bson:"kelas"`
+	SubjectID primitive.ObjectID `json:"subject_id" bson:"subject_id"`
+	TeacherID primitive.ObjectID `json:"teacher_id" bson:"teacher_id"`
+	Date      time.Time          `json:"date" bson:"date"`
+	StartTime string             `json:"start_time" bson:"start_time"`
+	EndTime   string             `json:"end_time" bson:"end_time"`
+	Status    string             `json:"status" bson:"status"`
+	OpenedAt  time.Time          `json:"opened_at" bson:"opened_at"`
+	ClosedAt  *time.Time         `json:"closed_at,omitempty" bson:"closed_at,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type OpenSessionRequest struct {
+	PeriodID string `json:"period_id" validate:"required,len=24,hexadecimal"`
+	Date     string `json:"date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// LessonAttendance adalah kehadiran satu siswa di satu sesi jam pelajaran
+type LessonAttendance struct {
+	ID          primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	SessionID   primitive.ObjectID  `json:"session_id" bson:"session_id"`
+	PeriodID    primitive.ObjectID  `json:"period_id" bson:"period_id"`
+	SubjectID   primitive.ObjectID  `json:"subject_id" bson:"subject_id"`
+	UserID      primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	Date        time.Time           `json:"date" bson:"date"`
+	Status      string              `json:"status" bson:"status"` // present, late, excused, absent
+	Method      string              `json:"method" bson:"method"` // self, teacher
+	CheckedInAt *time.Time          `json:"checked_in_at,omitempty" bson:"checked_in_at,omitempty"`
+	MarkedBy    *primitive.ObjectID `json:"marked_by,omitempty" bson:"marked_by,omitempty"`
+	Note        string              `json:"note,omitempty" bson:"note,omitempty"`
+	CreatedAt   time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+type BulkMarkRequest struct {
+	Records []LessonMark `json:"records" validate:"required,min=1,max=100,dive"`
+}
+
+type LessonMark struct {
+	StudentID string `json:"student_id" validate:"required,len=24,hexadecimal"`
+	Status    string `json:"status" validate:"required,oneof=present late excused absent"`
+	Note      string `json:"note,omitempty" validate:"omitempty,max=200"`
+}
+
+type LessonRosterEntry struct {
+	User       UserPublic        `json:"user"`
+	Attendance *LessonAttendance `json:"attendance"`
+}
+
+type LessonSessionDetail struct {
+	Session LessonSession       `json:"session"`
+	Roster  []LessonRosterEntry `json:"roster"`
+}
+
+// SubjectReportRow rekap kehadiran satu siswa untuk satu mata pelajaran
+type SubjectReportRow struct {
+	User       UserPublic `json:"user"`
+	Sessions   int        `json:"sessions"`
+	Present    int        `json:"present"`
+	Late       int        `json:"late"`
+	Excused    int        `json:"excused"`
+	Absent     int        `json:"absent"`
+	Percentage float64    `json:"percentage"`
+}
+
+type SubjectReport struct {
+	Subject  Subject             `json:"subject"`
+	ClassID  *primitive.ObjectID `json:"class_id,omitempty"`
+	Kelas    string              `json:"kelas,omitempty"`
+	From     time.Time           `json:"from"`
+	To       time.Time           `json:"to"`
+	Sessions int64               `json:"sessions"`
+	Students []SubjectReportRow  `json:"students"`
+}
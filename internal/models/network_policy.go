@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	PolicyTypeIPRange       = "ip_range"
+	PolicyTypeSSID          = "ssid"
+	PolicyTypeSSIDPattern   = "ssid_pattern"
+	PolicyTypeCarrier       = "carrier"
+	PolicyTypeSecureNetwork = "secure_network_type"
+)
+
+// NetworkPolicyEntry adalah satu item allowlist jaringan (CIDR, SSID, pola SSID, carrier)
+type NetworkPolicyEntry struct {
+	ID          primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Type        string              `json:"type" bson:"type"`
+	Value       string              `json:"value" bson:"value"`
+	Description string              `json:"description,omitempty" bson:"description,omitempty"`
+	IsActive    bool                `json:"is_active" bson:"is_active"`
+	CreatedBy   *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	UpdatedBy   *primitive.ObjectID `json:"updated_by,omitempty" bson:"updated_by,omitempty"`
+	CreatedAt   time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateNetworkPolicyRequest struct {
+	Type        string `json:"type" validate:"required,oneof=ip_range ssid ssid_pattern carrier secure_network_type"`
+	Value       string `json:"value" validate:"required,max=200"`
+	Description string `json:"description,omitempty" validate:"omitempty,max=255"`
+}
+
+type UpdateNetworkPolicyRequest struct {
+	Value       string `json:"value,omitempty" validate:"omitempty,max=200"`
+	Description string `json:"description,omitempty" validate:"omitempty,max=255"`
+	IsActive    *bool  `json:"is_active,omitempty"`
+}
+
+// NetworkPolicyDryRunRequest adalah contoh request absensi yang dievaluasi tanpa disimpan
+type NetworkPolicyDryRunRequest struct {
+	ClientIP       string            `json:"client_ip" validate:"required,ip"`
+	UserAgent      string            `json:"user_agent,omitempty"`
+	NetworkType    string            `json:"network_type,omitempty"`
+	WiFiSSID       string            `json:"wifi_ssid,omitempty"`
+	Carrier        string            `json:"carrier,omitempty"`
+	DeviceID       string            `json:"device_id,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Latitude       *float64          `json:"latitude,omitempty"`
+	Longitude      *float64          `json:"longitude,omitempty"`
+	GPSAccuracy    *float64          `json:"gps_accuracy,omitempty"`
+	KnownDeviceIDs []string          `json:"known_device_ids,omitempty"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ChannelEmail    = "email"
+	ChannelWhatsApp = "whatsapp"
+	ChannelSMS      = "sms"
+	ChannelPush     = "push"
+
+	NotifyArrival    = "arrival"
+	NotifyLate       = "late"
+	NotifyAbsent     = "absent"
+	NotifyDeparture  = "departure"
+	NotifyInvitation = "invitation" // undangan akun hasil import, bukan bagian preferensi
+
+	LanguageIndonesian = "id"
+	LanguageEnglish    = "en"
+
+	NotificationPending = "pending"
+	NotificationSending = "sending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// NotificationPreference adalah pengaturan notifikasi milik satu user. Untuk siswa, notifikasi
+// dikirim ke siswa sendiri dan ke kontak orang tua/wali yang didaftarkan di sini. Orang tua
+// yang ditautkan lewat guardian_links punya preferensi sendiri dan dikirimi di alamat akunnya.
+type NotificationPreference struct {
+	ID         primitive.ObjectID    `json:"-" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID    `json:"user_id" bson:"user_id"`
+	Language   string                `json:"language" bson:"language"`
+	Channels   []string              `json:"channels" bson:"channels"`
+	Events     []string              `json:"events" bson:"events"`
+	QuietHours *QuietHours           `json:"quiet_hours,omitempty" bson:"quiet_hours,omitempty"`
+	Contacts   []NotificationContact `json:"contacts" bson:"contacts"`
+	PushTokens []string              `json:"push_tokens" bson:"push_tokens"`
+	UpdatedAt  time.Time             `json:"updated_at" bson:"updated_at"`
+}
+
+// QuietHours dalam waktu lokal sekolah (HH:MM), boleh melewati tengah malam (21:00-06:00).
+// Notifikasi di jam tenang ditunda sampai jam tenang selesai.
+type QuietHours struct {
+	Start string `json:"start" bson:"start" validate:"required,datetime=15:04"`
+	End   string `json:"end" bson:"end" validate:"required,datetime=15:04"`
+}
+
+type NotificationContact struct {
+	Name     string `json:"name" bson:"name" validate:"required,min=2,max=100"`
+	Relation string `json:"relation,omitempty" bson:"relation,omitempty" validate:"omitempty,max=50"` // ayah, ibu, wali
+	Email    string `json:"email,omitempty" bson:"email,omitempty" validate:"omitempty,email"`
+	Phone    string `json:"phone,omitempty" bson:"phone,omitempty" validate:"omitempty,min=10,max=15"`
+}
+
+type UpdateNotificationPreferenceRequest struct {
+	Language   string                `json:"language" validate:"required,oneof=id en"`
+	Channels   []string              `json:"channels" validate:"dive,oneof=email whatsapp sms push"`
+	Events     []string              `json:"events" validate:"dive,oneof=arrival late absent departure"`
+	QuietHours *QuietHours           `json:"quiet_hours,omitempty"`
+	Contacts   []NotificationContact `json:"contacts" validate:"max=3,dive"`
+	PushTokens []string              `json:"push_tokens" validate:"max=5,dive,min=1,max=4096"`
+}
+
+// Notification adalah outbox notifikasi, satu dokumen per alamat tujuan per channel
+type Notification struct {
+	ID           primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	StudentID    primitive.ObjectID  `json:"student_id" bson:"student_id"`
+	AttendanceID *primitive.ObjectID `json:"attendance_id,omitempty" bson:"attendance_id,omitempty"`
+	Event        string              `json:"event" bson:"event"`
+	Channel      string              `json:"channel" bson:"channel"`
+	Recipient    string              `json:"recipient" bson:"recipient"` // nama penerima
+	To           string              `json:"to" bson:"to"`
+	Language     string              `json:"language" bson:"language"`
+	Subject      string              `json:"subject" bson:"subject"`
+	Body         string              `json:"body" bson:"body"`
+	Status       string              `json:"status" bson:"status"`
+	Attempts     int                 `json:"attempts" bson:"attempts"`
+	SendAfter    time.Time           `json:"send_after" bson:"send_after"`
+	LockedAt     *time.Time          `json:"-" bson:"locked_at,omitempty"`
+	Error        string              `json:"error,omitempty" bson:"error,omitempty"`
+	SentAt       *time.Time          `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+	CreatedAt    time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	OfflineResultAccepted  = "accepted"
+	OfflineResultDuplicate = "duplicate"
+	OfflineResultRejected  = "rejected"
+)
+
+// OfflineNonce dikeluarkan server per siswa, device, dan hari sekolah sebelum jendela
+// check in hari itu dibuka. Aplikasi mengambilnya selagi online lalu menyertakannya
+// di check in offline hari itu.
+type OfflineNonce struct {
+	Date      string    `json:"date"`
+	DeviceID  string    `json:"device_id"`
+	Nonce     string    `json:"nonce"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// OfflineNonceRecord disimpan di collection offline_nonces. Hanya hash nonce yang
+// disimpan, dan nonce hanya bisa dipakai untuk satu check in.
+type OfflineNonceRecord struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	DeviceID  string             `bson:"device_id"`
+	DateKey   string             `bson:"date_key"`
+	NonceHash string             `bson:"nonce_hash"`
+	IssuedAt  time.Time          `bson:"issued_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty"`
+	ExpiresAt time.Time          `bson:"expires_at"`
+}
+
+// OfflineRecord satu check in offline. Payload adalah JSON OfflineCheckIn apa adanya
+// (string yang sama persis dengan yang ditandatangani), Signature base64 dari
+// tanda tangan key device atas security.OfflineMessage(payload).
+type OfflineRecord struct {
+	DeviceID  string `json:"device_id" validate:"required,max=100"`
+	Payload   string `json:"payload" validate:"required,max=2000"`
+	Signature string `json:"signature" validate:"required,base64"`
+}
+
+type OfflineCheckIn struct {
+	Action     string  `json:"action"`      // saat ini hanya checkin
+	Date       string  `json:"date"`        // YYYY-MM-DD menurut zona waktu sekolah
+	RecordedAt int64   `json:"recorded_at"` // unix detik saat siswa check in
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Address    string  `json:"address,omitempty"`
+	Nonce      string  `json:"nonce"`
+}
+
+type OfflineSyncRequest struct {
+	Records []OfflineRecord `json:"records" validate:"required,min=1,max=20,dive"`
+}
+
+type OfflineSyncResult struct {
+	Index      int         `json:"index"`
+	Result     string      `json:"result"` // accepted, duplicate, rejected
+	Message    string      `json:"message,omitempty"`
+	Attendance *Attendance `json:"attendance,omitempty"`
+}
+
+type OfflineSyncResponse struct {
+	Accepted  int                 `json:"accepted"`
+	Duplicate int                 `json:"duplicate"`
+	Rejected  int                 `json:"rejected"`
+	Results   []OfflineSyncResult `json:"results"`
+}
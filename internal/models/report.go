@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ReportScopeStudent = "student"
+	ReportScopeClass   = "class"
+	ReportScopeSchool  = "school"
+
+	ReportFormatCSV  = "csv"
+	ReportFormatXLSX = "xlsx"
+	ReportFormatPDF  = "pdf"
+
+	ReportStatusPending    = "pending"
+	ReportStatusProcessing = "processing"
+	ReportStatusDone       = "done"
+	ReportStatusFailed     = "failed"
+	ReportStatusExpired    = "expired"
+)
+
+// ReportJob adalah permintaan export rekap absensi. Laporan kecil langsung dibuat,
+// laporan besar diproses worker di background lalu bisa diunduh lewat DownloadURL.
+type ReportJob struct {
+	ID          primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Scope       string              `json:"scope" bson:"scope"`
+	TargetID    *primitive.ObjectID `json:"target_id,omitempty" bson:"target_id,omitempty"`
+	Format      string              `json:"format" bson:"format"`
+	From        time.Time           `json:"from" bson:"from"`
+	To          time.Time           `json:"to" bson:"to"`
+	Status      string              `json:"status" bson:"status"`
+	FileName    string              `json:"file_name,omitempty" bson:"file_name,omitempty"`
+	FileKey     string              `json:"-" bson:"file_key,omitempty"`
+	Size        int64               `json:"size,omitempty" bson:"size,omitempty"`
+	Error       string              `json:"error,omitempty" bson:"error,omitempty"`
+	RequestedBy primitive.ObjectID  `json:"requested_by" bson:"requested_by"`
+	CreatedAt   time.Time           `json:"created_at" bson:"created_at"`
+	StartedAt   *time.Time          `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	ExpiresAt   *time.Time          `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+
+	DownloadURL string `json:"download_url,omitempty" bson:"-"`
+}
+
+type CreateReportRequest struct {
+	Scope    string `json:"scope" validate:"required,oneof=student class school"`
+	TargetID string `json:"target_id,omitempty" validate:"required_unless=Scope school,omitempty,len=24,hexadecimal"`
+	Format   string `json:"format" validate:"required,oneof=csv xlsx pdf"`
+	From     string `json:"from" validate:"required,datetime=2006-01-02"`
+	To       string `json:"to" validate:"required,datetime=2006-01-02"`
+}
+
+// AttendanceMatrix adalah rekap absensi hari x siswa yang dirender ke CSV/XLSX/PDF
+type AttendanceMatrix struct {
+	Title   string            `json:"title"`
+	From    time.Time         `json:"from"`
+	To      time.Time         `json:"to"`
+	Days    []time.Time       `json:"days"`
+	Rows    []AttendanceRow   `json:"rows"`
+	Legend  map[string]string `json:"legend"`
+	Codes   []string          `json:"codes"` // urutan kolom total
+	Created time.Time         `json:"created"`
+}
+
+type AttendanceRow struct {
+	NIS        string         `json:"nis"`
+	Name       string         `json:"name"`
+	Kelas      string         `json:"kelas"`
+	Marks      []string       `json:"marks"` // satu kode per hari, "-" jika tidak ada record
+	Totals     map[string]int `json:"totals"`
+	Percentage float64        `json:"percentage"`
+}
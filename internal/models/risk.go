@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// RiskAssessment menyimpan hasil penilaian risiko jaringan/perangkat saat absensi
+type RiskAssessment struct {
+	Score           float64      `json:"score" bson:"score"`
+	Decision        string       `json:"decision" bson:"decision"` // accept, flag, reject
+	FlagThreshold   float64      `json:"flag_threshold" bson:"flag_threshold"`
+	RejectThreshold float64      `json:"reject_threshold" bson:"reject_threshold"`
+	Signals         []RiskSignal `json:"signals" bson:"signals"`
+	EvaluatedAt     time.Time    `json:"evaluated_at" bson:"evaluated_at"`
+}
+
+type RiskSignal struct {
+	Name    string  `json:"name" bson:"name"`
+	Weight  float64 `json:"weight" bson:"weight"`
+	Risk    float64 `json:"risk" bson:"risk"`
+	Score   float64 `json:"score" bson:"score"`
+	Reason  string  `json:"reason,omitempty" bson:"reason,omitempty"`
+	Skipped bool    `json:"skipped,omitempty" bson:"skipped,omitempty"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	SelfieCheckIn  = "checkin"
+	SelfieCheckOut = "checkout"
+
+	SelfieVariantFull      = "full"
+	SelfieVariantThumbnail = "thumb"
+)
+
+// Selfie foto bukti saat check in/out. Key menunjuk objek di storage dan tidak
+// pernah dikirim ke client; URL dan ThumbnailURL diisi saat response dengan
+// link bertanda tangan yang cepat kedaluwarsa.
+type Selfie struct {
+	Key          string    `json:"-" bson:"key"`
+	ThumbnailKey string    `json:"-" bson:"thumbnail_key"`
+	ContentType  string    `json:"content_type" bson:"content_type"`
+	Size         int       `json:"size" bson:"size"`
+	Width        int       `json:"width" bson:"width"`
+	Height       int       `json:"height" bson:"height"`
+	UploadedAt   time.Time `json:"uploaded_at" bson:"uploaded_at"`
+
+	URL          string `json:"url,omitempty" bson:"-"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty" bson:"-"`
+}
@@ -7,41 +7,74 @@ import (
 )
 
 type User struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	NIS       string             `json:"nis,omitempty" bson:"nis,omitempty"`
-	Name      string             `json:"name" bson:"name"`
-	Kelas     string             `json:"kelas,omitempty" bson:"kelas,omitempty"`
-	Jurusan   string             `json:"jurusan,omitempty" bson:"jurusan,omitempty"`
-	Email     string             `json:"email" bson:"email"`
-	Password  string             `json:"-" bson:"password"`
-	Phone     string             `json:"phone,omitempty" bson:"phone,omitempty"`
-	Avatar    string             `json:"avatar,omitempty" bson:"avatar,omitempty"`
-	IsActive  bool               `json:"is_active" bson:"is_active"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+	ID      primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	NIS     string             `json:"nis,omitempty" bson:"nis,omitempty"`
+	Name    string             `json:"name" bson:"name"`
+	Kelas   string             `json:"kelas,omitempty" bson:"kelas,omitempty"`
+	Jurusan string             `json:"jurusan,omitempty" bson:"jurusan,omitempty"`
+	// ClassID untuk siswa adalah kelasnya, untuk guru adalah kelas perwaliannya
+	ClassID   *primitive.ObjectID `json:"class_id,omitempty" bson:"class_id,omitempty"`
+	MajorID   *primitive.ObjectID `json:"major_id,omitempty" bson:"major_id,omitempty"`
+	Email     string              `json:"email" bson:"email"`
+	Password  string              `json:"-" bson:"password"`
+	Phone     string              `json:"phone,omitempty" bson:"phone,omitempty"`
+	Avatar    string              `json:"avatar,omitempty" bson:"avatar,omitempty"`
+	Role      string              `json:"role" bson:"role,omitempty"` // student, teacher, admin, guardian
+	IsActive  bool                `json:"is_active" bson:"is_active"`
+	CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+const (
+	RoleStudent  = "student"
+	RoleTeacher  = "teacher"
+	RoleAdmin    = "admin"
+	RoleGuardian = "guardian" // orang tua/wali, hanya bisa melihat data anak yang ditautkan
+
+	UserStatusActive   = "active"
+	UserStatusInactive = "inactive"
+	UserStatusAll      = "all"
+)
+
+// GetRole mengembalikan role user, akun lama tanpa role dianggap siswa
+func (u *User) GetRole() string {
+	if u.Role == "" {
+		return RoleStudent
+	}
+	return u.Role
+}
+
+func (u *User) HasRole(roles ...string) bool {
+	role := u.GetRole()
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
 
 type LoginRequest struct {
-	Email 		string `json:"email" validate:"required,email"`
-	Password	string `json:"password" validate:"required,min=6"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=6"`
 }
 
 type RegisterRequest struct {
-	NIS       string `json:"nis" validate:"required,min=3,max=20"`
-	Name      string `json:"name" validate:"required,min=2,max=100"`
-	Kelas     string `json:"kelas" validate:"required,min=1,max=50"`
-	Jurusan   string `json:"jurusan" validate:"required,min=2,max=100"`
-	Email     string `json:"email" validate:"required,email"`
-	Password  string `json:"password" validate:"required,min=6"`
-	Phone     string `json:"phone,omitempty" validate:"omitempty,min=10,max=15"`
+	NIS      string `json:"nis" validate:"required,min=3,max=20"`
+	Name     string `json:"name" validate:"required,min=2,max=100"`
+	ClassID  string `json:"class_id" validate:"required,len=24,hexadecimal"`
+	MajorID  string `json:"major_id,omitempty" validate:"omitempty,len=24,hexadecimal"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=6"`
+	Phone    string `json:"phone,omitempty" validate:"omitempty,min=10,max=15"`
 }
 
 type UpdateProfileRequest struct {
-	Name     string `json:"name" validate:"required,min=2,max=100"`
-	Kelas    string `json:"kelas" validate:"required,min=1,max=50"`
-	Jurusan  string `json:"jurusan" validate:"required,min=2,max=100"`
-	Phone    string `json:"phone,omitempty" validate:"omitempty,min=10,max=15"`
-	Avatar   string `json:"avatar,omitempty" validate:"omitempty,url"`
+	Name    string `json:"name" validate:"required,min=2,max=100"`
+	ClassID string `json:"class_id,omitempty" validate:"omitempty,len=24,hexadecimal"`
+	MajorID string `json:"major_id,omitempty" validate:"omitempty,len=24,hexadecimal"`
+	Phone   string `json:"phone,omitempty" validate:"omitempty,min=10,max=15"`
+	Avatar  string `json:"avatar,omitempty" validate:"omitempty,url"`
 }
 
 type LoginResponse struct {
@@ -54,6 +87,54 @@ type ChangePasswordRequest struct {
 	NewPassword     string `json:"new_password" validate:"required,min=6,max=50"`
 }
 
+// UserFilter filter daftar user di panel admin
+type UserFilter struct {
+	Role    string
+	ClassID *primitive.ObjectID
+	MajorID *primitive.ObjectID
+	Status  string // active (default), inactive, all
+	Sort    string // created_at, name, nis, kelas, email; awalan "-" untuk urutan menurun
+}
+
+// AdminUpdateUserRequest field kosong tidak diubah. Kelas hanya untuk siswa, wali kelas guru
+// diatur lewat /admin/classes/:id/homeroom.
+type AdminUpdateUserRequest struct {
+	NIS     string `json:"nis,omitempty" validate:"omitempty,min=3,max=20"`
+	Name    string `json:"name,omitempty" validate:"omitempty,min=2,max=100"`
+	Email   string `json:"email,omitempty" validate:"omitempty,email"`
+	Phone   string `json:"phone,omitempty" validate:"omitempty,min=10,max=15"`
+	ClassID string `json:"class_id,omitempty" validate:"omitempty,len=24,hexadecimal"`
+	MajorID string `json:"major_id,omitempty" validate:"omitempty,len=24,hexadecimal"`
+	Role    string `json:"role,omitempty" validate:"omitempty,oneof=student teacher admin"`
+}
+
+type ResetPasswordResponse struct {
+	User              User   `json:"user"`
+	TemporaryPassword string `json:"temporary_password"`
+}
+
+type ImpersonationResponse struct {
+	SessionID primitive.ObjectID `json:"session_id"`
+	Token     string             `json:"token"`
+	ExpiresAt time.Time          `json:"expires_at"`
+	User      User               `json:"user"`
+}
+
+// ImpersonationSession jejak audit setiap token impersonate, disimpan di
+// collection impersonation_sessions. ID-nya dipakai sebagai jti token.
+type ImpersonationSession struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	AdminID    primitive.ObjectID `json:"admin_id" bson:"admin_id"`
+	AdminEmail string             `json:"admin_email" bson:"admin_email"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	UserEmail  string             `json:"user_email" bson:"user_email"`
+	UserRole   string             `json:"user_role" bson:"user_role"`
+	ClientIP   string             `json:"client_ip" bson:"client_ip"`
+	UserAgent  string             `json:"user_agent,omitempty" bson:"user_agent,omitempty"`
+	StartedAt  time.Time          `json:"started_at" bson:"started_at"`
+	ExpiresAt  time.Time          `json:"expires_at" bson:"expires_at"`
+}
+
 func (u *User) UserPublic() User {
 	return User{
 		ID:        u.ID,
@@ -61,11 +142,27 @@ func (u *User) UserPublic() User {
 		Name:      u.Name,
 		Kelas:     u.Kelas,
 		Jurusan:   u.Jurusan,
+		ClassID:   u.ClassID,
+		MajorID:   u.MajorID,
 		Email:     u.Email,
 		Phone:     u.Phone,
 		Avatar:    u.Avatar,
+		Role:      u.GetRole(),
 		IsActive:  u.IsActive,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
 	}
-}
\ No newline at end of file
+}
+
+func (u *User) ToPublic() UserPublic {
+	return UserPublic{
+		ID:      u.ID,
+		NIS:     u.NIS,
+		Name:    u.Name,
+		Kelas:   u.Kelas,
+		Jurusan: u.Jurusan,
+		ClassID: u.ClassID,
+		Email:   u.Email,
+		Phone:   u.Phone,
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	WebhookUserRegistered  = "user.registered"
+	WebhookUserDeactivated = "user.deactivated"
+	WebhookPing            = "webhook.ping"
+
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSending   = "sending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusDead      = "dead" // gagal setelah semua percobaan, bisa dikirim ulang manual
+)
+
+// WebhookEvents adalah event yang bisa dilanggan webhook
+var WebhookEvents = []string{
+	EventCheckIn,
+	EventCheckOut,
+	EventCorrection,
+	EventAbsent,
+	WebhookUserRegistered,
+	WebhookUserDeactivated,
+}
+
+// Webhook adalah langganan sistem luar (aplikasi orang tua, ERP sekolah) ke event absensi dan akun
+type Webhook struct {
+	ID        primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Name      string              `json:"name" bson:"name"`
+	URL       string              `json:"url" bson:"url"`
+	Secret    string              `json:"-" bson:"secret"` // kunci HMAC untuk header X-Webhook-Signature
+	Events    []string            `json:"events" bson:"events"`
+	IsActive  bool                `json:"is_active" bson:"is_active"`
+	CreatedBy *primitive.ObjectID `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+type CreateWebhookRequest struct {
+	Name   string   `json:"name" validate:"required,min=2,max=100"`
+	URL    string   `json:"url" validate:"required,url,max=500"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=attendance.checkin attendance.checkout attendance.correction attendance.absent user.registered user.deactivated"`
+}
+
+type UpdateWebhookRequest struct {
+	Name     string   `json:"name,omitempty" validate:"omitempty,min=2,max=100"`
+	URL      string   `json:"url,omitempty" validate:"omitempty,url,max=500"`
+	Events   []string `json:"events,omitempty" validate:"omitempty,min=1,dive,oneof=attendance.checkin attendance.checkout attendance.correction attendance.absent user.registered user.deactivated"`
+	IsActive *bool    `json:"is_active,omitempty"`
+}
+
+// WebhookCredentials hanya dikembalikan sekali saat webhook dibuat atau secret dirotasi
+type WebhookCredentials struct {
+	Webhook Webhook `json:"webhook"`
+	Secret  string  `json:"secret"`
+}
+
+// WebhookPayload adalah body JSON yang dikirim ke URL webhook
+type WebhookPayload struct {
+	ID        string      `json:"id"` // sama untuk setiap percobaan, dipakai penerima untuk dedup
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDelivery adalah satu event untuk satu webhook di antrean pengiriman,
+// sekaligus log percobaannya
+type WebhookDelivery struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WebhookID     primitive.ObjectID `json:"webhook_id" bson:"webhook_id"`
+	EventID       string             `json:"event_id" bson:"event_id"`
+	Event         string             `json:"event" bson:"event"`
+	Payload       string             `json:"payload" bson:"payload"`
+	Status        string             `json:"status" bson:"status"`
+	Attempts      int                `json:"attempts" bson:"attempts"`
+	NextAttemptAt *time.Time         `json:"next_attempt_at,omitempty" bson:"next_attempt_at,omitempty"`
+	LockedAt      *time.Time         `json:"-" bson:"locked_at,omitempty"`
+	LastError     string             `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	History       []WebhookAttempt   `json:"history" bson:"history"`
+	DeliveredAt   *time.Time         `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+type WebhookAttempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms" bson:"duration_ms"`
+}
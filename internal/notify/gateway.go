@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const gatewayTimeout = 10 * time.Second
+
+// GatewaySender adalah adapter HTTP untuk gateway WhatsApp/SMS. Body yang dikirim:
+//
+//	{"channel":"whatsapp","to":"62812...","message":"..."}
+//
+// dengan header Authorization: Bearer <token>. Status 2xx dianggap terkirim.
+type GatewaySender struct {
+	channel string
+	url     string
+	token   string
+	client  *http.Client
+}
+
+func NewGatewaySender(channel, url, token string) *GatewaySender {
+	return &GatewaySender{
+		channel: channel,
+		url:     url,
+		token:   token,
+		client:  &http.Client{Timeout: gatewayTimeout},
+	}
+}
+
+func (s *GatewaySender) Send(ctx context.Context, msg Message) error {
+	return postJSON(ctx, s.client, s.url, s.token, map[string]string{
+		"channel": s.channel,
+		"to":      msg.To,
+		"message": msg.Body,
+	})
+}
+
+func postJSON(ctx context.Context, client *http.Client, url, token string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("gateway returned status %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
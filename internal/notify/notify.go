@@ -0,0 +1,58 @@
+// Package notify mengirim notifikasi ke orang tua dan siswa lewat email (SMTP),
+// gateway WhatsApp/SMS berbasis HTTP, dan push notification
+package notify
+
+import (
+	"context"
+	"log"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+)
+
+// Message adalah satu notifikasi yang sudah dirender untuk satu alamat tujuan.
+// To berisi email, nomor telepon, atau token push sesuai channel.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SendersFromConfig mengembalikan sender untuk setiap channel yang dikonfigurasi,
+// key-nya nama channel (models.ChannelEmail, dst.)
+func SendersFromConfig(cfg *config.Config) map[string]Sender {
+	senders := make(map[string]Sender)
+
+	if cfg.SMTPHost != "" && cfg.SMTPFrom != "" {
+		senders[models.ChannelEmail] = NewSMTPSender(SMTPOptions{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		})
+	}
+	if cfg.WhatsAppGatewayURL != "" {
+		senders[models.ChannelWhatsApp] = NewGatewaySender(models.ChannelWhatsApp, cfg.WhatsAppGatewayURL, cfg.WhatsAppGatewayToken)
+	}
+	if cfg.SMSGatewayURL != "" {
+		senders[models.ChannelSMS] = NewGatewaySender(models.ChannelSMS, cfg.SMSGatewayURL, cfg.SMSGatewayToken)
+	}
+
+	switch cfg.PushProvider {
+	case "":
+	case "log":
+		senders[models.ChannelPush] = NewPushSender(LogPushProvider{})
+	case "http":
+		if cfg.PushGatewayURL != "" {
+			senders[models.ChannelPush] = NewPushSender(NewHTTPPushProvider(cfg.PushGatewayURL, cfg.PushGatewayToken))
+		}
+	default:
+		log.Printf("Warning: unknown push provider %s, push notifications disabled", cfg.PushProvider)
+	}
+
+	return senders
+}
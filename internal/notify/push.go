@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"net/http"
+)
+
+// PushProvider mengirim push notification ke satu token device. Provider lain
+// (mis. FCM langsung) cukup mengimplementasikan interface ini.
+type PushProvider interface {
+	Push(ctx context.Context, token, title, body string) error
+}
+
+type PushSender struct {
+	provider PushProvider
+}
+
+func NewPushSender(provider PushProvider) *PushSender {
+	return &PushSender{provider: provider}
+}
+
+func (s *PushSender) Send(ctx context.Context, msg Message) error {
+	return s.provider.Push(ctx, msg.To, msg.Subject, msg.Body)
+}
+
+// LogPushProvider hanya mencatat push ke log, untuk development
+type LogPushProvider struct{}
+
+func (LogPushProvider) Push(ctx context.Context, token, title, body string) error {
+	log.Printf("Push notification to %s: %s - %s", token, title, body)
+	return nil
+}
+
+// HTTPPushProvider meneruskan push ke relay HTTP (mis. layanan yang memegang
+// kredensial FCM/APNs) dengan body {"token","title","body"}
+type HTTPPushProvider struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewHTTPPushProvider(url, token string) *HTTPPushProvider {
+	return &HTTPPushProvider{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: gatewayTimeout},
+	}
+}
+
+func (p *HTTPPushProvider) Push(ctx context.Context, token, title, body string) error {
+	return postJSON(ctx, p.client, p.url, p.token, map[string]string{
+		"token": token,
+		"title": title,
+		"body":  body,
+	})
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string // boleh dengan nama, mis. "Absensi Sekolah <noreply@sekolah.sch.id>"
+}
+
+// SMTPSender mengirim email teks biasa lewat SMTP dengan STARTTLS jika server mendukung
+type SMTPSender struct {
+	opts SMTPOptions
+}
+
+func NewSMTPSender(opts SMTPOptions) *SMTPSender {
+	return &SMTPSender{opts: opts}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(s.opts.From)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_FROM address: %v", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return errors.New("invalid recipient email")
+	}
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return errors.New("invalid email subject")
+	}
+
+	var data bytes.Buffer
+	fmt.Fprintf(&data, "From: %s\r\n", from.String())
+	fmt.Fprintf(&data, "To: %s\r\n", to.String())
+	fmt.Fprintf(&data, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&data, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	data.WriteString("MIME-Version: 1.0\r\n")
+	data.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	data.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	data.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	data.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if s.opts.Username != "" {
+		auth = smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host)
+	}
+
+	addr := net.JoinHostPort(s.opts.Host, strconv.Itoa(s.opts.Port))
+	return smtp.SendMail(addr, auth, from.Address, []string{to.Address}, data.Bytes())
+}
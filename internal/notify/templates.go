@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+	"ujikom-backend/internal/models"
+)
+
+// TemplateData adalah variabel yang bisa dipakai di template, tanggal dan jam
+// sudah diformat sesuai bahasa dan zona waktu sekolah
+type TemplateData struct {
+	Name        string
+	NIS         string
+	Kelas       string
+	Date        string
+	Time        string
+	MinutesLate int
+	Email       string
+	Link        string
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+var templates = map[string]map[string]messageTemplate{
+	models.LanguageIndonesian: {
+		models.NotifyArrival: parse(
+			"{{.Name}} sudah tiba di sekolah",
+			"{{.Name}}{{with .Kelas}} ({{.}}){{end}} tercatat hadir di sekolah pada {{.Date}} pukul {{.Time}}.",
+		),
+		models.NotifyLate: parse(
+			"{{.Name}} terlambat masuk sekolah",
+			"{{.Name}}{{with .Kelas}} ({{.}}){{end}} tercatat terlambat{{if .MinutesLate}} {{.MinutesLate}} menit{{end}} pada {{.Date}}, tiba pukul {{.Time}}.",
+		),
+		models.NotifyAbsent: parse(
+			"{{.Name}} tidak hadir hari ini",
+			"{{.Name}}{{with .Kelas}} ({{.}}){{end}} tidak tercatat hadir di sekolah pada {{.Date}} dan ditandai alpa. Jika ada keterangan izin atau sakit, silakan hubungi wali kelas.",
+		),
+		models.NotifyDeparture: parse(
+			"{{.Name}} sudah pulang sekolah",
+			"{{.Name}}{{with .Kelas}} ({{.}}){{end}} tercatat pulang dari sekolah pada {{.Date}} pukul {{.Time}}.",
+		),
+		models.NotifyInvitation: parse(
+			"Aktivasi akun absensi {{.Name}}",
+			"Halo {{.Name}}, akun absensi sekolah kamu sudah dibuat dengan email {{.Email}}{{with .NIS}} (NIS {{.}}){{end}}. Buat password lewat link berikut sebelum {{.Date}}: {{.Link}}",
+		),
+	},
+	models.LanguageEnglish: {
+		models.NotifyArrival: parse(
+			"{{.Name}} has arrived at school",
+			"{{.Name}}{{with .Kelas}} ({{.}}){{end}} checked in at school on {{.Date}} at {{.Time}}.",
+		),
+		models.NotifyLate: parse(
+			"{{.Name}} arrived late",
+			"{{.Name}}{{with .Kelas}} ({{.}}){{end}} arrived late{{if .MinutesLate}} by {{.MinutesLate}} minutes{{end}} on {{.Date}}, checking in at {{.Time}}.",
+		),
+		models.NotifyAbsent: parse(
+			"{{.Name}} is absent today",
+			"{{.Name}}{{with .Kelas}} ({{.}}){{end}} has not checked in at school on {{.Date}} and was marked absent. If there is a leave or sick note, please contact the homeroom teacher.",
+		),
+		models.NotifyDeparture: parse(
+			"{{.Name}} has left school",
+			"{{.Name}}{{with .Kelas}} ({{.}}){{end}} checked out of school on {{.Date}} at {{.Time}}.",
+		),
+		models.NotifyInvitation: parse(
+			"Activate {{.Name}}'s attendance account",
+			"Hi {{.Name}}, your school attendance account has been created with the email {{.Email}}{{with .NIS}} (NIS {{.}}){{end}}. Set your password using this link before {{.Date}}: {{.Link}}",
+		),
+	},
+}
+
+func parse(subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+// Render mengisi template event dalam bahasa lang, bahasa yang tidak dikenal memakai bahasa Indonesia
+func Render(lang, event string, data TemplateData) (string, string, error) {
+	byEvent, ok := templates[lang]
+	if !ok {
+		byEvent = templates[models.LanguageIndonesian]
+	}
+	tmpl, ok := byEvent[event]
+	if !ok {
+		return "", "", fmt.Errorf("no template for notification event %s", event)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	return subject.String(), body.String(), nil
+}
+
+var (
+	indonesianDays   = []string{"Minggu", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu"}
+	indonesianMonths = []string{"Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli", "Agustus", "September", "Oktober", "November", "Desember"}
+)
+
+// FormatDate memformat tanggal untuk template, mis. "Senin, 3 Maret 2025" atau "Monday, 3 March 2025"
+func FormatDate(lang string, t time.Time) string {
+	if lang == models.LanguageEnglish {
+		return t.Format("Monday, 2 January 2006")
+	}
+	return fmt.Sprintf("%s, %d %s %d", indonesianDays[t.Weekday()], t.Day(), indonesianMonths[t.Month()-1], t.Year())
+}
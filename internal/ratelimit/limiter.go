@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+	"ujikom-backend/internal/config"
+)
+
+const (
+	// TokenBucket mengizinkan burst sampai Limit lalu diisi ulang merata sepanjang Window
+	TokenBucket = "token_bucket"
+	// SlidingWindow menghitung request di Window terakhir (perkiraan dari jendela saat ini
+	// dan jendela sebelumnya), tanpa burst di pergantian jendela
+	SlidingWindow = "sliding_window"
+)
+
+// Rule mendefinisikan Limit request per Window. Algorithm kosong berarti TokenBucket.
+type Rule struct {
+	Limit     int
+	Window    time.Duration
+	Algorithm string
+}
+
+func (r Rule) refillPerSecond() float64 {
+	return float64(r.Limit) / r.Window.Seconds()
+}
+
+func (r Rule) slidingWindow() bool {
+	return r.Algorithm == SlidingWindow
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // waktu sampai kuota penuh lagi
+	RetryAfter time.Duration // waktu sampai request berikutnya diizinkan
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, rule Rule) (Result, error)
+	Close() error
+}
+
+// NewFromConfig memilih backend sesuai RATE_LIMIT_BACKEND (memory atau redis)
+func NewFromConfig(cfg *config.Config) (Limiter, error) {
+	switch cfg.RateLimitBackend {
+	case "redis":
+		limiter, err := NewRedisLimiter(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize redis rate limiter: %v", err)
+		}
+		log.Printf("Rate limiter: redis")
+		return limiter, nil
+	case "", "memory":
+		log.Printf("Rate limiter: in-memory")
+		return NewMemoryLimiter(), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend: %s", cfg.RateLimitBackend)
+	}
+}
+
+// buildResult menghitung header dari sisa token setelah request diproses
+func buildResult(rule Rule, allowed bool, tokens float64) Result {
+	refill := rule.refillPerSecond()
+
+	result := Result{
+		Allowed:    allowed,
+		Limit:      rule.Limit,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(rule.Limit) - tokens) / refill * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / refill * float64(time.Second))
+	}
+	return result
+}
+
+// slidingWindowAllowed memperkirakan jumlah request di Window terakhir dari hitungan jendela
+// sebelumnya (prev) yang dibobot sisa waktunya ditambah jendela saat ini (curr)
+func slidingWindowAllowed(rule Rule, prev, curr int, elapsed time.Duration) bool {
+	return slidingEstimate(rule, prev, curr, elapsed)+1 <= float64(rule.Limit)
+}
+
+func slidingEstimate(rule Rule, prev, curr int, elapsed time.Duration) float64 {
+	weight := 1 - elapsed.Seconds()/rule.Window.Seconds()
+	return float64(prev)*math.Max(0, weight) + float64(curr)
+}
+
+// buildSlidingResult menghitung header sliding window, curr sudah termasuk request ini
+// jika diizinkan
+func buildSlidingResult(rule Rule, allowed bool, prev, curr int, elapsed time.Duration) Result {
+	window := rule.Window.Seconds()
+	limit := float64(rule.Limit)
+	estimate := slidingEstimate(rule, prev, curr, elapsed)
+
+	result := Result{
+		Allowed:   allowed,
+		Limit:     rule.Limit,
+		Remaining: int(math.Max(0, math.Floor(limit-estimate))),
+	}
+
+	switch {
+	case curr > 0:
+		result.ResetAfter = 2*rule.Window - elapsed
+	case prev > 0:
+		result.ResetAfter = rule.Window - elapsed
+	}
+
+	if !allowed {
+		var wait float64
+		if float64(curr) <= limit-1 && prev > 0 {
+			// cukup menunggu bobot jendela sebelumnya turun
+			wait = window*(1-(limit-1-float64(curr))/float64(prev)) - elapsed.Seconds()
+		} else {
+			// jendela saat ini sudah penuh, tunggu jendela berikutnya lalu bobotnya turun
+			wait = window - elapsed.Seconds()
+			if curr > 0 {
+				wait += math.Max(0, window*(1-(limit-1)/float64(curr)))
+			}
+		}
+		result.RetryAfter = time.Duration(math.Max(0, wait) * float64(time.Second))
+	}
+
+	return result
+}
@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// clock jam palsu yang bisa dimajukan dari test
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// backend menyamakan cara memajukan waktu untuk memory dan Redis
+type backend struct {
+	limiter Limiter
+	advance func(time.Duration)
+}
+
+func newMemoryBackend(t *testing.T, start time.Time) backend {
+	clk := &clock{now: start}
+	limiter := newMemoryLimiter(clk.Now)
+	t.Cleanup(func() { limiter.Close() })
+	return backend{limiter: limiter, advance: clk.Advance}
+}
+
+func newRedisBackend(t *testing.T, start time.Time) backend {
+	server := miniredis.RunT(t)
+	now := start
+	server.SetTime(now)
+
+	limiter, err := NewRedisLimiter("redis://" + server.Addr())
+	if err != nil {
+		t.Fatalf("NewRedisLimiter: %v", err)
+	}
+	t.Cleanup(func() { limiter.Close() })
+
+	return backend{limiter: limiter, advance: func(d time.Duration) {
+		now = now.Add(d)
+		server.SetTime(now)
+		server.FastForward(d)
+	}}
+}
+
+func forEachBackend(t *testing.T, fn func(t *testing.T, b backend)) {
+	// awal jendela menit supaya perhitungan sliding window mudah dibaca
+	start := time.Date(2026, 7, 14, 7, 0, 0, 0, time.UTC)
+
+	t.Run("memory", func(t *testing.T) { fn(t, newMemoryBackend(t, start)) })
+	t.Run("redis", func(t *testing.T) { fn(t, newRedisBackend(t, start)) })
+}
+
+func allow(t *testing.T, b backend, key string, rule Rule) Result {
+	t.Helper()
+	result, err := b.limiter.Allow(context.Background(), key, rule)
+	if err != nil {
+		t.Fatalf("Allow(%q): %v", key, err)
+	}
+	return result
+}
+
+func TestTokenBucket(t *testing.T) {
+	rule := Rule{Limit: 3, Window: 3 * time.Second}
+
+	forEachBackend(t, func(t *testing.T, b backend) {
+		for i := 0; i < 3; i++ {
+			result := allow(t, b, "api:ip:1.2.3.4", rule)
+			if !result.Allowed {
+				t.Fatalf("request %d denied, want allowed", i+1)
+			}
+			if result.Remaining != 2-i {
+				t.Errorf("request %d remaining = %d, want %d", i+1, result.Remaining, 2-i)
+			}
+		}
+
+		denied := allow(t, b, "api:ip:1.2.3.4", rule)
+		if denied.Allowed {
+			t.Fatal("4th request allowed, want denied")
+		}
+		if denied.RetryAfter <= 0 || denied.RetryAfter > time.Second {
+			t.Errorf("retry after = %v, want (0, 1s]", denied.RetryAfter)
+		}
+
+		// bucket lain tidak terpengaruh
+		if !allow(t, b, "api:ip:5.6.7.8", rule).Allowed {
+			t.Error("other key denied, want allowed")
+		}
+
+		// satu token terisi ulang tiap detik
+		b.advance(time.Second)
+		if !allow(t, b, "api:ip:1.2.3.4", rule).Allowed {
+			t.Error("request after refill denied, want allowed")
+		}
+		if allow(t, b, "api:ip:1.2.3.4", rule).Allowed {
+			t.Error("second request after single refill allowed, want denied")
+		}
+	})
+}
+
+func TestSlidingWindow(t *testing.T) {
+	rule := Rule{Limit: 4, Window: time.Minute, Algorithm: SlidingWindow}
+
+	forEachBackend(t, func(t *testing.T, b backend) {
+		key := "login:account:1.2.3.4:siswa@example.com"
+
+		for i := 0; i < 4; i++ {
+			if !allow(t, b, key, rule).Allowed {
+				t.Fatalf("request %d denied, want allowed", i+1)
+			}
+		}
+		denied := allow(t, b, key, rule)
+		if denied.Allowed {
+			t.Fatal("5th request allowed, want denied")
+		}
+		if denied.RetryAfter != time.Minute+15*time.Second {
+			t.Errorf("retry after = %v, want 1m15s", denied.RetryAfter)
+		}
+
+		// di awal jendela berikutnya 4 request sebelumnya masih terhitung penuh,
+		// token bucket akan mengizinkan burst baru di sini
+		b.advance(time.Minute)
+		if allow(t, b, key, rule).Allowed {
+			t.Fatal("request at window boundary allowed, want denied")
+		}
+
+		// setelah 30 detik bobot jendela sebelumnya tinggal 2
+		b.advance(30 * time.Second)
+		for i := 0; i < 2; i++ {
+			if !allow(t, b, key, rule).Allowed {
+				t.Fatalf("request %d after half window denied, want allowed", i+1)
+			}
+		}
+		if allow(t, b, key, rule).Allowed {
+			t.Fatal("request over estimated limit allowed, want denied")
+		}
+
+		// dua jendela tanpa request, hitungan kembali nol
+		b.advance(2 * time.Minute)
+		result := allow(t, b, key, rule)
+		if !result.Allowed || result.Remaining != 3 {
+			t.Errorf("after idle got allowed=%v remaining=%d, want true 3", result.Allowed, result.Remaining)
+		}
+	})
+}
+
+func TestMemoryLimiterEvictsIdleBuckets(t *testing.T) {
+	clk := &clock{now: time.Date(2026, 7, 14, 7, 0, 0, 0, time.UTC)}
+	limiter := newMemoryLimiter(clk.Now)
+	defer limiter.Close()
+
+	ctx := context.Background()
+	limiter.Allow(ctx, "a", Rule{Limit: 1, Window: time.Minute})
+	limiter.Allow(ctx, "b", Rule{Limit: 1, Window: time.Minute, Algorithm: SlidingWindow})
+
+	clk.Advance(90 * time.Second)
+	limiter.evict()
+	if _, ok := limiter.buckets["a"]; ok {
+		t.Error("idle token bucket not evicted")
+	}
+	if _, ok := limiter.buckets["b"]; !ok {
+		t.Error("sliding window evicted while previous window still counts")
+	}
+
+	clk.Advance(time.Minute)
+	limiter.evict()
+	if len(limiter.buckets) != 0 {
+		t.Errorf("%d buckets left, want 0", len(limiter.buckets))
+	}
+}
+
+func TestMemoryLimiterCloseStopsCleanup(t *testing.T) {
+	limiter := newMemoryLimiter(time.Now)
+	done := make(chan struct{})
+	go func() {
+		limiter.cleanup(time.Millisecond)
+		close(done)
+	}()
+
+	limiter.Close()
+	limiter.Close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cleanup goroutine still running after Close")
+	}
+}
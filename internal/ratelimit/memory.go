@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	// token bucket
+	tokens  float64
+	updated time.Time
+
+	// sliding window
+	windowStart time.Time
+	prev        int
+	curr        int
+
+	expiresAt time.Time
+}
+
+// MemoryLimiter menyimpan bucket di memori proses, cocok untuk single instance
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+	stop    chan struct{}
+	once    sync.Once
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	limiter := newMemoryLimiter(time.Now)
+	go limiter.cleanup(time.Minute)
+	return limiter
+}
+
+func newMemoryLimiter(now func() time.Time) *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     now,
+		stop:    make(chan struct{}),
+	}
+}
+
+func (m *MemoryLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Limit), updated: now}
+		m.buckets[key] = b
+	}
+
+	if rule.slidingWindow() {
+		b.expiresAt = now.Add(2 * rule.Window)
+		return m.allowSliding(b, rule, now), nil
+	}
+	b.expiresAt = now.Add(rule.Window)
+
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(rule.Limit), b.tokens+elapsed*rule.refillPerSecond())
+		b.updated = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	return buildResult(rule, allowed, b.tokens), nil
+}
+
+func (m *MemoryLimiter) allowSliding(b *bucket, rule Rule, now time.Time) Result {
+	start := now.Truncate(rule.Window)
+	switch {
+	case start.Equal(b.windowStart):
+	case start.Sub(b.windowStart) == rule.Window:
+		b.prev, b.curr = b.curr, 0
+	default:
+		b.prev, b.curr = 0, 0
+	}
+	b.windowStart = start
+
+	elapsed := now.Sub(start)
+	allowed := slidingWindowAllowed(rule, b.prev, b.curr, elapsed)
+	if allowed {
+		b.curr++
+	}
+
+	return buildSlidingResult(rule, allowed, b.prev, b.curr, elapsed)
+}
+
+// Close menghentikan goroutine cleanup
+func (m *MemoryLimiter) Close() error {
+	m.once.Do(func() { close(m.stop) })
+	return nil
+}
+
+// Hapus bucket yang sudah tidak dipakai supaya memori tidak terus bertambah
+func (m *MemoryLimiter) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.evict()
+		}
+	}
+}
+
+func (m *MemoryLimiter) evict() {
+	now := m.now()
+	m.mu.Lock()
+	for key, b := range m.buckets {
+		if now.After(b.expiresAt) {
+			delete(m.buckets, key)
+		}
+	}
+	m.mu.Unlock()
+}
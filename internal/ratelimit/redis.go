@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Token bucket atomik di Redis. Waktu diambil dari server Redis supaya
+// semua replica memakai jam yang sama.
+var tokenBucketScript = redis.NewScript(`
+local key = KEYS[1]
+local capacity = tonumber(ARGV[1])
+local refill_per_ms = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local data = redis.call("HMGET", key, "tokens", "ts")
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+local elapsed = math.max(0, now - ts)
+tokens = math.min(capacity, tokens + elapsed * refill_per_ms)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", key, "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("PEXPIRE", key, ttl)
+
+return {allowed, tostring(tokens)}
+`)
+
+// Sliding window counter atomik di Redis: satu counter per jendela, counter jendela
+// sebelumnya dibobot sisa waktunya. Perhitungan perkiraan sama dengan MemoryLimiter.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local index = math.floor(now / window)
+local elapsed = now - index * window
+local curr_key = key .. ":" .. index
+local prev = tonumber(redis.call("GET", key .. ":" .. (index - 1))) or 0
+local curr = tonumber(redis.call("GET", curr_key)) or 0
+
+local estimate = prev * math.max(0, 1 - elapsed / window) + curr
+local allowed = 0
+if estimate + 1 <= limit then
+	curr = redis.call("INCR", curr_key)
+	redis.call("PEXPIRE", curr_key, window * 2)
+	allowed = 1
+end
+
+return {allowed, prev, curr, elapsed}
+`)
+
+// RedisLimiter membagi bucket antar replica lewat Redis
+type RedisLimiter struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisLimiter(redisURL string) (*RedisLimiter, error) {
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(options)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, err
+	}
+
+	return &RedisLimiter{client: client, prefix: "ratelimit:"}, nil
+}
+
+func (r *RedisLimiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	if rule.slidingWindow() {
+		return r.allowSliding(ctx, key, rule)
+	}
+
+	refillPerMs := rule.refillPerSecond() / 1000
+	ttl := rule.Window.Milliseconds() * 2
+
+	values, err := tokenBucketScript.Run(ctx, r.client, []string{r.prefix + key},
+		rule.Limit,
+		strconv.FormatFloat(refillPerMs, 'f', -1, 64),
+		ttl,
+	).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := values[0].(int64)
+	tokensStr, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return buildResult(rule, allowed == 1, tokens), nil
+}
+
+func (r *RedisLimiter) allowSliding(ctx context.Context, key string, rule Rule) (Result, error) {
+	values, err := slidingWindowScript.Run(ctx, r.client, []string{r.prefix + "sw:" + key},
+		rule.Limit,
+		rule.Window.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(values) != 4 {
+		return Result{}, fmt.Errorf("unexpected sliding window reply: %v", values)
+	}
+
+	elapsed := time.Duration(values[3]) * time.Millisecond
+	return buildSlidingResult(rule, values[0] == 1, int(values[1]), int(values[2]), elapsed), nil
+}
+
+func (r *RedisLimiter) Close() error {
+	return r.client.Close()
+}
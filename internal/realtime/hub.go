@@ -0,0 +1,218 @@
+// Package realtime menyebarkan event absensi ke client SSE/WebSocket yang sedang terhubung
+package realtime
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"sync"
+	"time"
+	"ujikom-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// ukuran buffer per client, client yang tertinggal lebih jauh diputus dan harus
+	// reconnect dengan Last-Event-ID
+	subscriberBuffer = 64
+	publishTimeout   = 3 * time.Second
+)
+
+// Scope membatasi event yang diterima satu client. Field kosong berarti tidak dibatasi.
+type Scope struct {
+	UserID  *primitive.ObjectID
+	ClassID *primitive.ObjectID
+}
+
+func (s Scope) Matches(event *models.AttendanceEvent) bool {
+	if s.UserID != nil && event.UserID != *s.UserID {
+		return false
+	}
+	if s.ClassID != nil && (event.ClassID == nil || *event.ClassID != *s.ClassID) {
+		return false
+	}
+	return true
+}
+
+func (s Scope) filter() bson.M {
+	filter := bson.M{}
+	if s.UserID != nil {
+		filter["user_id"] = *s.UserID
+	}
+	if s.ClassID != nil {
+		filter["class_id"] = *s.ClassID
+	}
+	return filter
+}
+
+type Subscription struct {
+	Events chan models.AttendanceEvent
+	scope  Scope
+}
+
+// eventStore menyimpan event untuk replay, implementasinya collection attendance_events
+type eventStore interface {
+	Insert(ctx context.Context, event *models.AttendanceEvent) error
+	// Find mengembalikan event yang cocok dengan filter, urut _id naik
+	Find(ctx context.Context, filter bson.M, limit int) ([]models.AttendanceEvent, error)
+}
+
+// Hub menyimpan event di collection attendance_events lalu meneruskannya ke subscriber
+// di proses ini. Event dari instance lain diterima lewat change stream (Watch).
+type Hub struct {
+	db          *mongo.Database
+	store       eventStore
+	instance    string
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+}
+
+func NewHub(db *mongo.Database) *Hub {
+	return &Hub{
+		db:          db,
+		store:       &mongoEventStore{collection: db.Collection("attendance_events")},
+		instance:    primitive.NewObjectID().Hex(),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+func (h *Hub) Publish(event *models.AttendanceEvent) {
+	event.ID = primitive.NewObjectID()
+	event.Instance = h.instance
+	event.CreatedAt = time.Now().UTC()
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := h.store.Insert(ctx, event); err != nil {
+		// tetap dikirim ke client yang terhubung, hanya tidak bisa di-replay
+		log.Printf("Warning: failed to store attendance event: %v", err)
+	}
+
+	h.broadcast(*event)
+}
+
+func (h *Hub) Subscribe(scope Scope) *Subscription {
+	sub := &Subscription{
+		Events: make(chan models.AttendanceEvent, subscriberBuffer),
+		scope:  scope,
+	}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.Events)
+	}
+}
+
+// Replay mengembalikan event setelah lastID yang cocok dengan scope, urut dari yang terlama
+func (h *Hub) Replay(lastID primitive.ObjectID, scope Scope, limit int) ([]models.AttendanceEvent, error) {
+	filter := scope.filter()
+	filter["_id"] = bson.M{"$gt": lastID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	return h.store.Find(ctx, filter, limit)
+}
+
+// Watch meneruskan event yang di-publish instance API lain. Tanpa change stream
+// (MongoDB standalone) feed tetap jalan untuk event dari instance ini saja.
+func (h *Hub) Watch(ctx context.Context) {
+	go func() {
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{
+				"operationType":         "insert",
+				"fullDocument.instance": bson.M{"$ne": h.instance},
+			}}},
+		}
+
+		for {
+			stream, err := h.collection().Watch(ctx, pipeline)
+			if err != nil {
+				log.Printf("Warning: attendance event change stream unavailable, real-time feed is local to this instance: %v", err)
+				return
+			}
+
+			for stream.Next(ctx) {
+				var change struct {
+					FullDocument models.AttendanceEvent `bson:"fullDocument"`
+				}
+				if err := stream.Decode(&change); err != nil {
+					log.Printf("Warning: failed to decode attendance event: %v", err)
+					continue
+				}
+				h.broadcast(change.FullDocument)
+			}
+			stream.Close(context.Background())
+
+			if ctx.Err() != nil {
+				return
+			}
+			time.Sleep(5 * time.Second)
+		}
+	}()
+}
+
+func (h *Hub) broadcast(event models.AttendanceEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers {
+		if !sub.scope.Matches(&event) {
+			continue
+		}
+		select {
+		case sub.Events <- event:
+		default:
+			// client terlalu lambat, putus supaya reconnect dan replay dari Last-Event-ID
+			delete(h.subscribers, sub)
+			close(sub.Events)
+		}
+	}
+}
+
+func (h *Hub) collection() *mongo.Collection {
+	return h.db.Collection("attendance_events")
+}
+
+type mongoEventStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoEventStore) Insert(ctx context.Context, event *models.AttendanceEvent) error {
+	_, err := s.collection.InsertOne(ctx, event)
+	return err
+}
+
+func (s *mongoEventStore) Find(ctx context.Context, filter bson.M, limit int) ([]models.AttendanceEvent, error) {
+	cursor, err := s.collection.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.AttendanceEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// After mengecek apakah event lebih baru dari lastID, dipakai untuk membuang
+// event live yang sudah terkirim lewat replay
+func After(event *models.AttendanceEvent, lastID primitive.ObjectID) bool {
+	return bytes.Compare(event.ID[:], lastID[:]) > 0
+}
@@ -0,0 +1,158 @@
+package realtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"ujikom-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryEventStore menjalankan filter dari Scope.filter dengan aturan yang sama seperti MongoDB
+type memoryEventStore struct {
+	events []models.AttendanceEvent
+}
+
+func (s *memoryEventStore) Insert(ctx context.Context, event *models.AttendanceEvent) error {
+	s.events = append(s.events, *event)
+	return nil
+}
+
+func (s *memoryEventStore) Find(ctx context.Context, filter bson.M, limit int) ([]models.AttendanceEvent, error) {
+	for key := range filter {
+		if key != "_id" && key != "user_id" && key != "class_id" {
+			return nil, fmt.Errorf("unexpected filter field %s", key)
+		}
+	}
+
+	matched := []models.AttendanceEvent{}
+	for _, event := range s.events {
+		if userID, ok := filter["user_id"]; ok && event.UserID != userID {
+			continue
+		}
+		if classID, ok := filter["class_id"]; ok && (event.ClassID == nil || *event.ClassID != classID) {
+			continue
+		}
+		if gt, ok := filter["_id"].(bson.M)["$gt"].(primitive.ObjectID); ok && bytes.Compare(event.ID[:], gt[:]) <= 0 {
+			continue
+		}
+		matched = append(matched, event)
+	}
+
+	sort.Slice(matched, func(i, j int) bool { return bytes.Compare(matched[i].ID[:], matched[j].ID[:]) < 0 })
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+	return matched, nil
+}
+
+func testHub() *Hub {
+	return &Hub{
+		store:       &memoryEventStore{},
+		instance:    "test",
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+func TestScopeMatches(t *testing.T) {
+	student, other := primitive.NewObjectID(), primitive.NewObjectID()
+	class, otherClass := primitive.NewObjectID(), primitive.NewObjectID()
+
+	tests := []struct {
+		name  string
+		scope Scope
+		event models.AttendanceEvent
+		want  bool
+	}{
+		{"school sees everything", Scope{}, models.AttendanceEvent{UserID: other}, true},
+		{"self own event", Scope{UserID: &student}, models.AttendanceEvent{UserID: student, ClassID: &class}, true},
+		{"self other student", Scope{UserID: &student}, models.AttendanceEvent{UserID: other, ClassID: &class}, false},
+		{"class same class", Scope{ClassID: &class}, models.AttendanceEvent{UserID: other, ClassID: &class}, true},
+		{"class other class", Scope{ClassID: &class}, models.AttendanceEvent{UserID: other, ClassID: &otherClass}, false},
+		{"class event without class", Scope{ClassID: &class}, models.AttendanceEvent{UserID: other}, false},
+		{"user and class both required", Scope{UserID: &student, ClassID: &class}, models.AttendanceEvent{UserID: student, ClassID: &otherClass}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.scope.Matches(&tt.event); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHubReplay(t *testing.T) {
+	hub := testHub()
+	student, other := primitive.NewObjectID(), primitive.NewObjectID()
+	class, otherClass := primitive.NewObjectID(), primitive.NewObjectID()
+
+	var published []primitive.ObjectID
+	for _, event := range []models.AttendanceEvent{
+		{Type: models.EventCheckIn, UserID: student, ClassID: &class},
+		{Type: models.EventCheckIn, UserID: other, ClassID: &otherClass},
+		{Type: models.EventCheckOut, UserID: student, ClassID: &class},
+		{Type: models.EventCheckIn, UserID: other, ClassID: &class},
+	} {
+		event := event
+		hub.Publish(&event)
+		published = append(published, event.ID)
+	}
+
+	tests := []struct {
+		name   string
+		lastID primitive.ObjectID
+		scope  Scope
+		limit  int
+		want   []int
+	}{
+		{"school from start", primitive.NilObjectID, Scope{}, 10, []int{0, 1, 2, 3}},
+		{"school after second", published[1], Scope{}, 10, []int{2, 3}},
+		{"self", primitive.NilObjectID, Scope{UserID: &student}, 10, []int{0, 2}},
+		{"self after own check in", published[0], Scope{UserID: &student}, 10, []int{2}},
+		{"class", primitive.NilObjectID, Scope{ClassID: &class}, 10, []int{0, 2, 3}},
+		{"class limited", primitive.NilObjectID, Scope{ClassID: &class}, 2, []int{0, 2}},
+		{"nothing newer", published[3], Scope{}, 10, []int{}},
+	}
+
+	for _, tt := range tests {
+		events, err := hub.Replay(tt.lastID, tt.scope, tt.limit)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []int
+		for _, event := range events {
+			for i, id := range published {
+				if event.ID == id {
+					got = append(got, i)
+				}
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: replayed events %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHubBroadcastsToMatchingSubscribers(t *testing.T) {
+	hub := testHub()
+	student := primitive.NewObjectID()
+	class := primitive.NewObjectID()
+
+	own := hub.Subscribe(Scope{UserID: &student})
+	school := hub.Subscribe(Scope{})
+	defer hub.Unsubscribe(own)
+	defer hub.Unsubscribe(school)
+
+	hub.Publish(&models.AttendanceEvent{Type: models.EventCheckIn, UserID: primitive.NewObjectID(), ClassID: &class})
+	hub.Publish(&models.AttendanceEvent{Type: models.EventCheckIn, UserID: student, ClassID: &class})
+
+	if len(own.Events) != 1 || len(school.Events) != 2 {
+		t.Fatalf("buffered events = self %d, school %d, want 1 and 2", len(own.Events), len(school.Events))
+	}
+	if event := <-own.Events; event.UserID != student {
+		t.Errorf("self subscriber received event for %s", event.UserID.Hex())
+	}
+}
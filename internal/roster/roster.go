@@ -0,0 +1,159 @@
+// Package roster membaca file daftar siswa (CSV/XLSX) untuk import massal
+package roster
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"ujikom-backend/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// MaxRows batas baris data dalam satu file
+const MaxRows = 5000
+
+// headerAliases memetakan judul kolom (huruf kecil, tanpa spasi/tanda baca) ke field
+var headerAliases = map[string]string{
+	"nis":         "nis",
+	"name":        "name",
+	"nama":        "name",
+	"namalengkap": "name",
+	"class":       "class",
+	"kelas":       "class",
+	"major":       "major",
+	"jurusan":     "major",
+	"email":       "email",
+	"phone":       "phone",
+	"nohp":        "phone",
+	"telepon":     "phone",
+	"hp":          "phone",
+}
+
+var requiredColumns = []string{"nis", "name", "class", "email"}
+
+// Parse membaca file berdasarkan ekstensinya (.csv atau .xlsx). Baris pertama wajib
+// berisi judul kolom, urutan kolom bebas, baris kosong dilewati.
+func Parse(filename string, r io.Reader) ([]models.StudentImportRecord, error) {
+	var rows [][]string
+	var err error
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		rows, err = readCSV(r)
+	case ".xlsx":
+		rows, err = readXLSX(r)
+	default:
+		return nil, errors.New("unsupported file type, use .csv or .xlsx")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	columns := map[string]int{}
+	for i, title := range rows[0] {
+		if field, ok := headerAliases[normalizeHeader(title)]; ok {
+			if _, exists := columns[field]; !exists {
+				columns[field] = i
+			}
+		}
+	}
+	for _, field := range requiredColumns {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("missing required column: %s", field)
+		}
+	}
+
+	records := []models.StudentImportRecord{}
+	for i, row := range rows[1:] {
+		cell := func(field string) string {
+			index, ok := columns[field]
+			if !ok || index >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[index])
+		}
+
+		record := models.StudentImportRecord{
+			Line:  i + 2,
+			NIS:   cell("nis"),
+			Name:  cell("name"),
+			Class: cell("class"),
+			Major: cell("major"),
+			Email: strings.ToLower(cell("email")),
+			Phone: cell("phone"),
+		}
+		if record.NIS == "" && record.Name == "" && record.Class == "" && record.Email == "" && record.Phone == "" {
+			continue
+		}
+		records = append(records, record)
+		if len(records) > MaxRows {
+			return nil, fmt.Errorf("file has more than %d rows, split it into smaller files", MaxRows)
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("file has no data rows")
+	}
+	return records, nil
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.New("failed to read file")
+	}
+	// BOM dari Excel "CSV UTF-8"
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	// Excel versi Indonesia menyimpan CSV dengan pemisah titik koma
+	if line, _, _ := bytes.Cut(data, []byte("\n")); bytes.Count(line, []byte(";")) > bytes.Count(line, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	return rows, nil
+}
+
+// readXLSX membaca sheet pertama
+func readXLSX(r io.Reader) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, errors.New("invalid XLSX file")
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("XLSX file has no sheets")
+	}
+
+	rows, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, errors.New("failed to read XLSX sheet")
+	}
+	return rows, nil
+}
+
+func normalizeHeader(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -1,9 +1,17 @@
 package routes
 
 import (
+	"context"
+	"log"
+	"time"
 	"ujikom-backend/internal/config"
 	"ujikom-backend/internal/controllers"
 	"ujikom-backend/internal/middleware"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/ratelimit"
+	"ujikom-backend/internal/services"
+	"ujikom-backend/internal/storage"
+	"ujikom-backend/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -11,13 +19,71 @@ import (
 
 func Setup(app *fiber.App, db *mongo.Database) {
 	cfg := config.Load()
-	authController := controllers.NewAuthController(db)
-	userController := controllers.NewUserController(db)
-	attendanceController := controllers.NewAttendanceController(db, cfg)
+	authController := controllers.NewAuthController(db, cfg)
+	userController := controllers.NewUserController(db, cfg)
 
-	api := app.Group("/api/v1")
+	fileStorage, err := storage.NewFromConfig(cfg)
+	if err != nil {
+		log.Printf("Warning: %v, falling back to local storage", err)
+		fileStorage = storage.NewLocalStorage(cfg.StorageDir)
+	}
+	selfieService := services.NewSelfieService(db, cfg, fileStorage)
+	attendanceController := controllers.NewAttendanceController(db, cfg, selfieService)
+
+	policyService := services.NewNetworkPolicyService(db)
+	policyService.Watch(context.Background())
+	networkPolicyController := controllers.NewNetworkPolicyController(db, cfg, policyService)
+	deviceController := controllers.NewDeviceController(db, cfg)
+	kioskController := controllers.NewKioskController(db, cfg)
+	correctionController := controllers.NewCorrectionController(db, cfg, selfieService)
+	leaveController := controllers.NewLeaveController(db, cfg)
+	guardianController := controllers.NewGuardianController(db, cfg, selfieService)
+	timetableController := controllers.NewTimetableController(db)
+	lessonController := controllers.NewLessonController(db, cfg)
+	academicController := controllers.NewAcademicController(db, cfg)
+	classController := controllers.NewClassController(db, cfg)
+	offlineController := controllers.NewOfflineController(db, cfg, policyService)
+	importController := controllers.NewImportController(db, cfg)
+
+	eventHub := services.EventHub(db)
+	eventHub.Watch(context.Background())
+	eventController := controllers.NewEventController(db, cfg, eventHub)
+
+	services.NewAttendanceService(db, cfg).StartAutoCheckout(context.Background())
+
+	reportService := services.NewReportService(db, cfg, fileStorage)
+	reportService.Start(context.Background())
+	reportController := controllers.NewReportController(db, cfg, reportService)
+
+	webhookService := services.NewWebhookService(db, cfg)
+	webhookService.Start(context.Background())
+	webhookController := controllers.NewWebhookController(db, webhookService)
 
-	api.Use(middleware.AdminNetworkOverrideMiddleware())
+	notificationService := services.NewNotificationService(db, cfg)
+	notificationService.Start(context.Background())
+	notificationController := controllers.NewNotificationController(db, notificationService)
+	deviceService := services.NewDeviceService(db, cfg)
+	deviceBinding := middleware.DeviceBindingMiddleware(deviceService, cfg.DeviceBindingEnforced)
+	// nonce dicatat setelah idempotent supaya retry yang sama persis mendapat respons tersimpan
+	deviceNonce := middleware.DeviceNonceMiddleware(deviceService, cfg.DeviceBindingEnforced)
+
+	limiter, err := ratelimit.NewFromConfig(cfg)
+	if err != nil {
+		log.Printf("Warning: %v, falling back to in-memory rate limiter", err)
+		limiter = ratelimit.NewMemoryLimiter()
+	}
+	app.Hooks().OnShutdown(limiter.Close)
+	loginLimit := middleware.LoginRateLimitMiddleware(limiter,
+		ratelimit.Rule{Limit: cfg.LoginRateLimit, Window: time.Minute, Algorithm: ratelimit.SlidingWindow},
+		ratelimit.Rule{Limit: cfg.LoginIPRateLimit, Window: time.Minute},
+	)
+	checkInLimit := middleware.RateLimitMiddleware(limiter, "checkin", ratelimit.Rule{Limit: cfg.CheckInRateLimit, Window: time.Minute})
+	apiLimit := middleware.RateLimitMiddleware(limiter, "api", ratelimit.Rule{Limit: cfg.APIRateLimit, Window: time.Minute})
+	idempotent := middleware.IdempotencyMiddleware(db, 24*time.Hour)
+	// akun orang tua hanya memakai endpoint /guardian
+	notGuardian := middleware.RoleMiddleware(models.RoleStudent, models.RoleTeacher, models.RoleAdmin)
+
+	api := app.Group("/api/v1")
 
 	api.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -30,8 +96,10 @@ func Setup(app *fiber.App, db *mongo.Database) {
 	})
 
 	auth := api.Group("/auth")
-	auth.Post("/register", authController.Register)
-	auth.Post("/login", authController.Login)
+	auth.Post("/register", loginLimit, authController.Register)
+	auth.Post("/register/guardian", loginLimit, guardianController.Register)
+	auth.Post("/login", loginLimit, authController.Login)
+	auth.Post("/set-password", loginLimit, authController.SetPassword)
 	auth.Get("/test", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
 			"success": true,
@@ -42,36 +110,228 @@ func Setup(app *fiber.App, db *mongo.Database) {
 		})
 	})
 
+	// daftar kelas & jurusan publik untuk form registrasi
+	api.Get("/classes", apiLimit, classController.ListClasses)
+	api.Get("/majors", apiLimit, classController.ListMajors)
+
+	// link unduhan laporan ditandatangani, tidak memakai header Authorization
+	api.Get("/downloads/reports/:id", apiLimit, reportController.Download)
+	api.Get("/downloads/selfies/:id/:kind/:variant", apiLimit, attendanceController.DownloadSelfie)
+	api.Get("/downloads/corrections/:id/evidence/:variant", apiLimit, correctionController.DownloadEvidence)
+
 	protected := api.Group("/user")
 	protected.Use(middleware.AuthMiddleware(db))
-	// protected.Use(middleware.NetworkSecurityMiddleware())
+	protected.Use(apiLimit)
+	// protected.Use(middleware.NetworkSecurityMiddleware(db, cfg, policyService))
 	protected.Use(middleware.NetworkInfoMiddleware())
 	
 	protected.Get("/profile", userController.GetProfile)
-	protected.Put("/profile", userController.UpdateProfile)
-	protected.Post("/change-password", userController.ChangePassword)
-	protected.Post("/deactivate", userController.DeactivateAccount)
+	protected.Put("/profile", middleware.BlockImpersonation(), userController.UpdateProfile)
+	protected.Post("/change-password", middleware.BlockImpersonation(), userController.ChangePassword)
+	protected.Post("/deactivate", middleware.BlockImpersonation(), userController.DeactivateAccount)
+	
+	protected.Get("/notifications", notificationController.ListMyNotifications)
+	protected.Get("/notification-preferences", notificationController.GetPreferences)
+	protected.Put("/notification-preferences", middleware.BlockImpersonation(), notificationController.UpdatePreferences)
 	
 	protected.Post("/logout", authController.Logout)
-	protected.Post("/refresh-token", authController.RefreshToken)
+	protected.Post("/refresh-token", middleware.BlockImpersonation(), authController.RefreshToken)
 
 	attendance := api.Group("/attendance")
 	attendance.Use(middleware.AuthMiddleware(db))
-	attendance.Use(middleware.NetworkSecurityMiddleware())
+	attendance.Use(middleware.BlockImpersonatedWrites())
+	attendance.Use(notGuardian)
+	attendance.Use(apiLimit)
+	attendance.Use(middleware.NetworkSecurityMiddleware(db, cfg, policyService))
 	attendance.Use(middleware.NetworkInfoMiddleware())
 	attendance.Use(middleware.SecurityHeadersMiddleware())
 	attendance.Use(middleware.DeviceValidationMiddleware())
 	attendance.Use(middleware.LocationValidationMiddleware())
 	
-	attendance.Post("/checkin", attendanceController.CheckIn)
-	attendance.Post("/checkout", attendanceController.CheckOut)
+	attendance.Post("/checkin", checkInLimit, deviceBinding, idempotent, deviceNonce, attendanceController.CheckIn)
+	attendance.Post("/checkout", checkInLimit, deviceBinding, idempotent, deviceNonce, attendanceController.CheckOut)
+	attendance.Post("/checkin/qr", checkInLimit, deviceBinding, idempotent, deviceNonce, attendanceController.CheckInQR)
+	attendance.Post("/checkout/qr", checkInLimit, deviceBinding, idempotent, deviceNonce, attendanceController.CheckOutQR)
 	attendance.Get("/today", attendanceController.GetTodayAttendance)
 	attendance.Get("/history", attendanceController.GetAttendanceHistory)
 	attendance.Get("/stats", attendanceController.GetAttendanceStats)
+	attendance.Get("/lessons", lessonController.ListOpenSessions)
+	attendance.Get("/lessons/history", lessonController.GetLessonHistory)
+	attendance.Post("/lessons/:id/checkin", checkInLimit, deviceBinding, idempotent, deviceNonce, lessonController.CheckIn)
+	attendance.Get("/offline/nonce", middleware.BlockImpersonation(), deviceBinding, deviceNonce, offlineController.GetNonce)
+	attendance.Post("/offline/sync", checkInLimit, deviceBinding, idempotent, deviceNonce, offlineController.Sync)
+
+	// feed real-time; EventSource/WebSocket di browser memakai ?ticket= dari POST /events/ticket
+	events := api.Group("/events")
+	events.Post("/ticket", middleware.AuthMiddleware(db), apiLimit, eventController.CreateTicket)
+	events.Get("/attendance", middleware.StreamAuthMiddleware(db, utils.SigningKey(cfg, utils.SigningPurposeTicket)), apiLimit, eventController.Authorize, eventController.Stream)
+	events.Get("/attendance/ws", eventController.RequireWebSocket, middleware.StreamAuthMiddleware(db, utils.SigningKey(cfg, utils.SigningPurposeTicket)), apiLimit, eventController.Authorize, eventController.WebSocket())
+
+	kiosk := api.Group("/kiosk")
+	kiosk.Use(middleware.KioskAuthMiddleware(services.NewKioskService(db, cfg)))
+	kiosk.Use(apiLimit)
+
+	kiosk.Get("/qr", kioskController.GetQRCode)
+
+	devices := api.Group("/devices")
+	devices.Use(middleware.AuthMiddleware(db))
+	devices.Use(middleware.BlockImpersonatedWrites())
+	devices.Use(notGuardian)
+	devices.Use(apiLimit)
+
+	devices.Post("/", deviceController.RegisterDevice)
+	devices.Get("/", deviceController.ListDevices)
+	devices.Post("/reset-requests", deviceController.RequestReset)
+	devices.Get("/reset-requests", deviceController.ListOwnResetRequests)
+
+	academic := api.Group("/academic-years")
+	academic.Use(middleware.AuthMiddleware(db))
+	academic.Use(apiLimit)
+
+	academic.Get("/", academicController.ListAcademicYears)
+	academic.Get("/current-semester", academicController.GetCurrentSemester)
+
+	corrections := api.Group("/corrections")
+	corrections.Use(middleware.AuthMiddleware(db))
+	corrections.Use(middleware.BlockImpersonatedWrites())
+	corrections.Use(notGuardian)
+	corrections.Use(apiLimit)
+
+	corrections.Post("/", correctionController.SubmitRequest)
+	corrections.Get("/", correctionController.ListOwnRequests)
+	corrections.Post("/:id/evidence", correctionController.UploadEvidence)
+
+	leaves := api.Group("/leave-requests")
+	leaves.Use(middleware.AuthMiddleware(db))
+	leaves.Use(middleware.BlockImpersonatedWrites())
+	leaves.Use(notGuardian)
+	leaves.Use(apiLimit)
 
-	testing := api.Group("/testing")
-	testing.Use(middleware.OptionalAuthMiddleware(db))
-	testing.Get("/users", userController.GetAllUsers)
+	leaves.Post("/", leaveController.SubmitRequest)
+	leaves.Get("/", leaveController.ListOwnRequests)
+
+	guardian := api.Group("/guardian")
+	guardian.Use(middleware.AuthMiddleware(db))
+	guardian.Use(middleware.BlockImpersonatedWrites())
+	guardian.Use(middleware.RoleMiddleware(models.RoleGuardian))
+	guardian.Use(apiLimit)
+
+	guardian.Get("/children", guardianController.ListChildren)
+	guardian.Post("/children", guardianController.LinkChild)
+	guardian.Get("/children/:id/attendance", guardianController.GetChildAttendance)
+	guardian.Get("/children/:id/attendance/stats", guardianController.GetChildStats)
+	guardian.Get("/children/:id/leave-requests", guardianController.ListChildLeaveRequests)
+	guardian.Post("/children/:id/sick-notes", guardianController.SubmitSickNote)
+	guardian.Get("/notification-preferences", notificationController.GetPreferences)
+	guardian.Put("/notification-preferences", notificationController.UpdatePreferences)
+
+	teacher := api.Group("/teacher")
+	teacher.Use(middleware.AuthMiddleware(db))
+	teacher.Use(middleware.BlockImpersonatedWrites())
+	teacher.Use(middleware.RoleMiddleware(models.RoleTeacher, models.RoleAdmin))
+	teacher.Use(apiLimit)
+
+	teacher.Get("/device-reset-requests", deviceController.ListResetRequests)
+	teacher.Post("/device-reset-requests/:id/approve", deviceController.ApproveResetRequest)
+	teacher.Post("/device-reset-requests/:id/reject", deviceController.RejectResetRequest)
+	teacher.Get("/device-attempts", deviceController.ListAttempts)
+	teacher.Get("/students/:id/attendances", attendanceController.GetStudentAttendance)
+	teacher.Post("/attendances", attendanceController.CreateManualAttendance)
+	teacher.Get("/attendances/:id", attendanceController.GetAttendance)
+	teacher.Put("/attendances/:id", attendanceController.UpdateAttendance)
+	teacher.Post("/attendances/:id/void", attendanceController.VoidAttendance)
+	teacher.Get("/subjects", timetableController.ListSubjects)
+	teacher.Get("/timetable", timetableController.ListOwnPeriods)
+	teacher.Get("/lessons", lessonController.ListSessions)
+	teacher.Post("/lessons", lessonController.OpenSession)
+	teacher.Get("/lessons/:id", lessonController.GetSession)
+	teacher.Post("/lessons/:id/marks", lessonController.BulkMark)
+	teacher.Post("/lessons/:id/close", lessonController.CloseSession)
+	teacher.Get("/reports/subjects/:id", lessonController.GetSubjectReport)
+	teacher.Get("/classes", classController.ListTeacherClasses)
+	teacher.Post("/reports/attendance", reportController.CreateReport)
+	teacher.Get("/reports/attendance", reportController.ListReports)
+	teacher.Get("/reports/attendance/:id", reportController.GetReport)
+	teacher.Get("/classes/:id/attendance", classController.GetDailyAttendance)
+	teacher.Get("/classes/:id/attendance/monthly", classController.GetMonthlyAttendance)
+	teacher.Get("/corrections", correctionController.ListRequests)
+	teacher.Post("/corrections/:id/approve", correctionController.ApproveRequest)
+	teacher.Post("/corrections/:id/reject", correctionController.RejectRequest)
+	teacher.Get("/leave-requests", leaveController.ListRequests)
+	teacher.Post("/leave-requests/:id/approve", leaveController.ApproveRequest)
+	teacher.Post("/leave-requests/:id/reject", leaveController.RejectRequest)
+	teacher.Get("/students/:id/guardians", guardianController.ListGuardians)
+	teacher.Post("/students/:id/guardian-invites", guardianController.CreateInvite)
+	teacher.Delete("/students/:id/guardians/:linkId", guardianController.Unlink)
+
+	admin := api.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(db))
+	admin.Use(middleware.RoleMiddleware(models.RoleAdmin))
+	admin.Use(apiLimit)
+
+	admin.Get("/users", userController.ListUsers)
+	admin.Get("/users/:id", userController.GetUser)
+	admin.Put("/users/:id", userController.UpdateUser)
+	admin.Post("/users/:id/deactivate", userController.DeactivateUser)
+	admin.Post("/users/:id/activate", userController.ActivateUser)
+	admin.Post("/users/:id/reset-password", userController.ResetPassword)
+	admin.Post("/users/:id/impersonate", userController.Impersonate)
+	admin.Get("/impersonations", userController.ListImpersonations)
+
+	admin.Get("/kiosks", kioskController.ListKiosks)
+	admin.Post("/kiosks", kioskController.CreateKiosk)
+	admin.Get("/kiosks/:id", kioskController.GetKiosk)
+	admin.Put("/kiosks/:id", kioskController.UpdateKiosk)
+	admin.Post("/kiosks/:id/rotate-key", kioskController.RotateKey)
+	admin.Delete("/kiosks/:id", kioskController.DeleteKiosk)
+
+	admin.Post("/academic-years", academicController.CreateAcademicYear)
+	admin.Get("/academic-years/:id", academicController.GetAcademicYear)
+	admin.Put("/academic-years/:id", academicController.UpdateAcademicYear)
+	admin.Post("/academic-years/:id/semesters", academicController.CreateSemester)
+	admin.Post("/academic-years/:id/rollover", academicController.Rollover)
+	admin.Put("/semesters/:id", academicController.UpdateSemester)
+
+	admin.Get("/majors", classController.ListMajors)
+	admin.Post("/majors", classController.CreateMajor)
+	admin.Put("/majors/:id", classController.UpdateMajor)
+	admin.Get("/classes", classController.ListClasses)
+	admin.Post("/classes", classController.CreateClass)
+	admin.Get("/classes/:id", classController.GetClass)
+	admin.Put("/classes/:id", classController.UpdateClass)
+	admin.Put("/classes/:id/homeroom", classController.AssignHomeroom)
+
+	admin.Get("/subjects", timetableController.ListSubjects)
+	admin.Post("/subjects", timetableController.CreateSubject)
+	admin.Put("/subjects/:id", timetableController.UpdateSubject)
+	admin.Delete("/subjects/:id", timetableController.DeleteSubject)
+	admin.Get("/timetable", timetableController.ListPeriods)
+	admin.Post("/timetable", timetableController.CreatePeriod)
+	admin.Put("/timetable/:id", timetableController.UpdatePeriod)
+	admin.Delete("/timetable/:id", timetableController.DeletePeriod)
+
+	admin.Get("/webhooks", webhookController.ListWebhooks)
+	admin.Post("/webhooks", webhookController.CreateWebhook)
+	admin.Get("/webhooks/:id", webhookController.GetWebhook)
+	admin.Put("/webhooks/:id", webhookController.UpdateWebhook)
+	admin.Delete("/webhooks/:id", webhookController.DeleteWebhook)
+	admin.Post("/webhooks/:id/rotate-secret", webhookController.RotateSecret)
+	admin.Post("/webhooks/:id/ping", webhookController.Ping)
+	admin.Get("/webhooks/:id/deliveries", webhookController.ListDeliveries)
+	admin.Get("/webhook-deliveries", webhookController.ListDeliveries)
+	admin.Post("/webhook-deliveries/:id/retry", webhookController.RetryDelivery)
+
+	admin.Get("/notifications", notificationController.ListNotifications)
+
+	admin.Post("/students/import", importController.ImportStudents)
+
+	admin.Get("/network-policies", networkPolicyController.ListEntries)
+	admin.Get("/network-policies/current", networkPolicyController.GetCurrentPolicy)
+	admin.Post("/network-policies/dry-run", networkPolicyController.DryRun)
+	admin.Get("/network-policies/:id", networkPolicyController.GetEntry)
+	admin.Post("/network-policies", networkPolicyController.CreateEntry)
+	admin.Put("/network-policies/:id", networkPolicyController.UpdateEntry)
+	admin.Delete("/network-policies/:id", networkPolicyController.DeleteEntry)
 
 	api.Get("/docs", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -81,14 +341,24 @@ func Setup(app *fiber.App, db *mongo.Database) {
 				"public": []string{
 					"GET /api/v1/health",
 					"POST /api/v1/auth/register",
+					"POST /api/v1/auth/register/guardian",
 					"POST /api/v1/auth/login",
+					"POST /api/v1/auth/set-password",
 					"GET /api/v1/auth/test",
+					"GET /api/v1/classes",
+					"GET /api/v1/majors",
+					"GET /api/v1/downloads/reports/:id",
+					"GET /api/v1/downloads/selfies/:id/:kind/:variant",
+					"GET /api/v1/downloads/corrections/:id/evidence/:variant",
 				},
 				"protected": []string{
 					"GET /api/v1/user/profile",
 					"PUT /api/v1/user/profile",
 					"POST /api/v1/user/change-password",
 					"POST /api/v1/user/deactivate",
+					"GET /api/v1/user/notifications",
+					"GET /api/v1/user/notification-preferences",
+					"PUT /api/v1/user/notification-preferences",
 					"POST /api/v1/user/logout",
 					"POST /api/v1/user/refresh-token",
 				},
@@ -98,9 +368,141 @@ func Setup(app *fiber.App, db *mongo.Database) {
 					"GET /api/v1/attendance/today",
 					"GET /api/v1/attendance/history",
 					"GET /api/v1/attendance/stats",
+					"POST /api/v1/attendance/checkin/qr",
+					"POST /api/v1/attendance/checkout/qr",
+					"GET /api/v1/attendance/lessons",
+					"GET /api/v1/attendance/lessons/history",
+					"POST /api/v1/attendance/lessons/:id/checkin",
+					"GET /api/v1/attendance/offline/nonce",
+					"POST /api/v1/attendance/offline/sync",
+				},
+				"events": []string{
+					"POST /api/v1/events/ticket",
+					"GET /api/v1/events/attendance",
+					"GET /api/v1/events/attendance/ws",
+				},
+				"kiosk": []string{
+					"GET /api/v1/kiosk/qr",
+				},
+				"devices": []string{
+					"POST /api/v1/devices",
+					"GET /api/v1/devices",
+					"POST /api/v1/devices/reset-requests",
+					"GET /api/v1/devices/reset-requests",
+				},
+				"academic": []string{
+					"GET /api/v1/academic-years",
+					"GET /api/v1/academic-years/current-semester",
+				},
+				"corrections": []string{
+					"POST /api/v1/corrections",
+					"GET /api/v1/corrections",
+					"POST /api/v1/corrections/:id/evidence",
+				},
+				"leave_requests": []string{
+					"POST /api/v1/leave-requests",
+					"GET /api/v1/leave-requests",
+				},
+				"guardian": []string{
+					"GET /api/v1/guardian/children",
+					"POST /api/v1/guardian/children",
+					"GET /api/v1/guardian/children/:id/attendance",
+					"GET /api/v1/guardian/children/:id/attendance/stats",
+					"GET /api/v1/guardian/children/:id/leave-requests",
+					"POST /api/v1/guardian/children/:id/sick-notes",
+					"GET /api/v1/guardian/notification-preferences",
+					"PUT /api/v1/guardian/notification-preferences",
+				},
+				"teacher": []string{
+					"GET /api/v1/teacher/device-reset-requests",
+					"POST /api/v1/teacher/device-reset-requests/:id/approve",
+					"POST /api/v1/teacher/device-reset-requests/:id/reject",
+					"GET /api/v1/teacher/device-attempts",
+					"GET /api/v1/teacher/students/:id/attendances",
+					"POST /api/v1/teacher/attendances",
+					"GET /api/v1/teacher/attendances/:id",
+					"PUT /api/v1/teacher/attendances/:id",
+					"POST /api/v1/teacher/attendances/:id/void",
+					"GET /api/v1/teacher/subjects",
+					"GET /api/v1/teacher/timetable",
+					"GET /api/v1/teacher/lessons",
+					"POST /api/v1/teacher/lessons",
+					"GET /api/v1/teacher/lessons/:id",
+					"POST /api/v1/teacher/lessons/:id/marks",
+					"POST /api/v1/teacher/lessons/:id/close",
+					"GET /api/v1/teacher/reports/subjects/:id",
+					"GET /api/v1/teacher/classes",
+					"POST /api/v1/teacher/reports/attendance",
+					"GET /api/v1/teacher/reports/attendance",
+					"GET /api/v1/teacher/reports/attendance/:id",
+					"GET /api/v1/teacher/classes/:id/attendance",
+					"GET /api/v1/teacher/classes/:id/attendance/monthly",
+					"GET /api/v1/teacher/corrections",
+					"POST /api/v1/teacher/corrections/:id/approve",
+					"POST /api/v1/teacher/corrections/:id/reject",
+					"GET /api/v1/teacher/leave-requests",
+					"POST /api/v1/teacher/leave-requests/:id/approve",
+					"POST /api/v1/teacher/leave-requests/:id/reject",
+					"GET /api/v1/teacher/students/:id/guardians",
+					"POST /api/v1/teacher/students/:id/guardian-invites",
+					"DELETE /api/v1/teacher/students/:id/guardians/:linkId",
 				},
-				"testing": []string{
-					"GET /api/v1/testing/users",
+				"admin": []string{
+					"GET /api/v1/admin/users",
+					"GET /api/v1/admin/users/:id",
+					"PUT /api/v1/admin/users/:id",
+					"POST /api/v1/admin/users/:id/deactivate",
+					"POST /api/v1/admin/users/:id/activate",
+					"POST /api/v1/admin/users/:id/reset-password",
+					"POST /api/v1/admin/users/:id/impersonate",
+					"GET /api/v1/admin/impersonations",
+					"GET /api/v1/admin/kiosks",
+					"POST /api/v1/admin/kiosks",
+					"GET /api/v1/admin/kiosks/:id",
+					"PUT /api/v1/admin/kiosks/:id",
+					"POST /api/v1/admin/kiosks/:id/rotate-key",
+					"DELETE /api/v1/admin/kiosks/:id",
+					"POST /api/v1/admin/academic-years",
+					"GET /api/v1/admin/academic-years/:id",
+					"PUT /api/v1/admin/academic-years/:id",
+					"POST /api/v1/admin/academic-years/:id/semesters",
+					"POST /api/v1/admin/academic-years/:id/rollover",
+					"PUT /api/v1/admin/semesters/:id",
+					"GET /api/v1/admin/majors",
+					"POST /api/v1/admin/majors",
+					"PUT /api/v1/admin/majors/:id",
+					"GET /api/v1/admin/classes",
+					"POST /api/v1/admin/classes",
+					"GET /api/v1/admin/classes/:id",
+					"PUT /api/v1/admin/classes/:id",
+					"PUT /api/v1/admin/classes/:id/homeroom",
+					"GET /api/v1/admin/subjects",
+					"POST /api/v1/admin/subjects",
+					"PUT /api/v1/admin/subjects/:id",
+					"DELETE /api/v1/admin/subjects/:id",
+					"GET /api/v1/admin/timetable",
+					"POST /api/v1/admin/timetable",
+					"PUT /api/v1/admin/timetable/:id",
+					"DELETE /api/v1/admin/timetable/:id",
+					"GET /api/v1/admin/webhooks",
+					"POST /api/v1/admin/webhooks",
+					"GET /api/v1/admin/webhooks/:id",
+					"PUT /api/v1/admin/webhooks/:id",
+					"DELETE /api/v1/admin/webhooks/:id",
+					"POST /api/v1/admin/webhooks/:id/rotate-secret",
+					"POST /api/v1/admin/webhooks/:id/ping",
+					"GET /api/v1/admin/webhooks/:id/deliveries",
+					"GET /api/v1/admin/webhook-deliveries",
+					"POST /api/v1/admin/webhook-deliveries/:id/retry",
+					"GET /api/v1/admin/notifications",
+					"POST /api/v1/admin/students/import",
+					"GET /api/v1/admin/network-policies",
+					"GET /api/v1/admin/network-policies/current",
+					"POST /api/v1/admin/network-policies/dry-run",
+					"GET /api/v1/admin/network-policies/:id",
+					"POST /api/v1/admin/network-policies",
+					"PUT /api/v1/admin/network-policies/:id",
+					"DELETE /api/v1/admin/network-policies/:id",
 				},
 			},
 			"authentication": "Bearer token required for protected routes",
@@ -0,0 +1,119 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// MaxSignatureSkew adalah selisih maksimal timestamp request dengan jam server
+const MaxSignatureSkew = 5 * time.Minute
+
+// NonceRetention adalah lama pasangan device + nonce disimpan untuk menolak replay,
+// lebih lama dari seluruh rentang timestamp yang diterima (2 x MaxSignatureSkew)
+const NonceRetention = 3 * MaxSignatureSkew
+
+var (
+	ErrInvalidPublicKey = errors.New("invalid device public key")
+	ErrInvalidSignature = errors.New("invalid device signature")
+	ErrExpiredSignature = errors.New("device signature timestamp out of range")
+	ErrInvalidNonce     = errors.New("device nonce must be 16-128 characters of A-Z, a-z, 0-9, - or _")
+)
+
+// CanonicalRequest adalah pesan yang ditandatangani aplikasi:
+// METHOD \n PATH \n TIMESTAMP \n NONCE \n hex(sha256(body))
+func CanonicalRequest(method, path, timestamp, nonce string, body []byte) []byte {
+	hash := sha256.Sum256(body)
+	return []byte(method + "\n" + path + "\n" + timestamp + "\n" + nonce + "\n" + hex.EncodeToString(hash[:]))
+}
+
+// CheckNonce memastikan format nonce acak dari aplikasi, keunikannya dicek oleh pemanggil
+func CheckNonce(nonce string) error {
+	if len(nonce) < 16 || len(nonce) > 128 {
+		return ErrInvalidNonce
+	}
+	for _, r := range nonce {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return ErrInvalidNonce
+		}
+	}
+	return nil
+}
+
+// OfflineMessage adalah pesan yang ditandatangani aplikasi untuk check in offline.
+// Prefix membedakannya dari CanonicalRequest supaya tanda tangan tidak bisa dipakai ulang.
+func OfflineMessage(payload string) []byte {
+	return []byte("OFFLINE\n" + payload)
+}
+
+// ParseDevicePublicKey menerima public key base64 DER (PKIX) ECDSA P-256 atau Ed25519
+func ParseDevicePublicKey(encoded string) (interface{}, error) {
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if k.Curve.Params().Name != "P-256" {
+			return nil, ErrInvalidPublicKey
+		}
+		return k, nil
+	case ed25519.PublicKey:
+		return k, nil
+	default:
+		return nil, ErrInvalidPublicKey
+	}
+}
+
+func VerifyDeviceSignature(publicKey string, message []byte, signature string) error {
+	key, err := ParseDevicePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		hash := sha256.Sum256(message)
+		if !ecdsa.VerifyASN1(k, hash[:], sig) {
+			return ErrInvalidSignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, message, sig) {
+			return ErrInvalidSignature
+		}
+	}
+
+	return nil
+}
+
+// CheckSignatureTimestamp memastikan timestamp (unix detik) tidak terlalu jauh dari now
+func CheckSignatureTimestamp(timestamp string, now time.Time) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrExpiredSignature
+	}
+
+	diff := now.Sub(time.Unix(unix, 0))
+	if diff > MaxSignatureSkew || diff < -MaxSignatureSkew {
+		return ErrExpiredSignature
+	}
+
+	return nil
+}
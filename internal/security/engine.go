@@ -0,0 +1,184 @@
+package security
+
+import (
+	"sort"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+)
+
+const (
+	DecisionAccept = "accept"
+	DecisionFlag   = "flag"
+	DecisionReject = "reject"
+)
+
+// Request berisi semua sinyal yang dikumpulkan dari satu request absensi
+type Request struct {
+	ClientIP    string
+	UserAgent   string
+	NetworkType string
+	WiFiSSID    string
+	Carrier     string
+	DeviceID    string
+	Headers     map[string]string
+
+	Latitude    float64
+	Longitude   float64
+	HasLocation bool
+	GPSAccuracy *float64
+
+	Time           time.Time
+	KnownDeviceIDs []string
+	LastSample     *LocationSample
+
+	// Excluded berisi rule yang tidak dinilai untuk request ini beserta alasannya
+	Excluded map[string]string
+}
+
+// LocationSample adalah posisi terakhir yang tercatat untuk user (dipakai untuk velocity check)
+type LocationSample struct {
+	Latitude  float64
+	Longitude float64
+	Time      time.Time
+}
+
+func (r *Request) Header(name string) string {
+	if r.Headers == nil {
+		return ""
+	}
+	return r.Headers[name]
+}
+
+// Result adalah hasil evaluasi satu rule. Risk bernilai 0 (aman) sampai 1 (sangat mencurigakan).
+type Result struct {
+	Risk    float64
+	Reason  string
+	Skipped bool
+}
+
+type Rule interface {
+	Name() string
+	Evaluate(req *Request, policy *Policy) Result
+}
+
+type Thresholds struct {
+	Flag   float64
+	Reject float64
+}
+
+type Engine struct {
+	rules      []Rule
+	weights    map[string]float64
+	thresholds Thresholds
+}
+
+func NewEngine(rules []Rule, weights map[string]float64, thresholds Thresholds) *Engine {
+	return &Engine{
+		rules:      rules,
+		weights:    weights,
+		thresholds: thresholds,
+	}
+}
+
+// NewEngineFromConfig membuat engine dengan rule bawaan, bobot dan threshold dari config
+func NewEngineFromConfig(cfg *config.Config) *Engine {
+	flagThreshold, rejectThreshold := cfg.GetRiskThresholds()
+	return NewEngine(DefaultRules(), cfg.RiskWeights, Thresholds{
+		Flag:   flagThreshold,
+		Reject: rejectThreshold,
+	})
+}
+
+func (e *Engine) Thresholds() Thresholds {
+	return e.thresholds
+}
+
+// Evaluate menjalankan semua rule dan menjumlahkan skor berbobot
+func (e *Engine) Evaluate(req *Request, policy *Policy) *models.RiskAssessment {
+	if policy == nil {
+		policy = DefaultPolicy()
+	}
+	if req.Time.IsZero() {
+		req.Time = time.Now().UTC()
+	}
+
+	assessment := &models.RiskAssessment{
+		FlagThreshold:   e.thresholds.Flag,
+		RejectThreshold: e.thresholds.Reject,
+		EvaluatedAt:     req.Time,
+	}
+
+	for _, rule := range e.rules {
+		weight, ok := e.weights[rule.Name()]
+		if !ok {
+			continue
+		}
+
+		var result Result
+		if reason, excluded := req.Excluded[rule.Name()]; excluded {
+			result = Result{Reason: reason, Skipped: true}
+		} else {
+			result = rule.Evaluate(req, policy)
+		}
+		risk := clamp(result.Risk)
+
+		signal := models.RiskSignal{
+			Name:    rule.Name(),
+			Weight:  weight,
+			Risk:    risk,
+			Reason:  result.Reason,
+			Skipped: result.Skipped,
+		}
+		if !result.Skipped {
+			signal.Score = weight * risk
+			assessment.Score += signal.Score
+		}
+
+		assessment.Signals = append(assessment.Signals, signal)
+	}
+
+	switch {
+	case assessment.Score >= e.thresholds.Reject:
+		assessment.Decision = DecisionReject
+	case assessment.Score >= e.thresholds.Flag:
+		assessment.Decision = DecisionFlag
+	default:
+		assessment.Decision = DecisionAccept
+	}
+
+	return assessment
+}
+
+// TopReasons mengembalikan alasan dari sinyal dengan skor tertinggi
+func TopReasons(assessment *models.RiskAssessment, limit int) []string {
+	signals := make([]models.RiskSignal, 0, len(assessment.Signals))
+	for _, signal := range assessment.Signals {
+		if !signal.Skipped && signal.Score > 0 && signal.Reason != "" {
+			signals = append(signals, signal)
+		}
+	}
+
+	sort.SliceStable(signals, func(i, j int) bool {
+		return signals[i].Score > signals[j].Score
+	})
+
+	var reasons []string
+	for i, signal := range signals {
+		if i >= limit {
+			break
+		}
+		reasons = append(reasons, signal.Reason)
+	}
+	return reasons
+}
+
+func clamp(value float64) float64 {
+	if value < 0 {
+		return 0
+	}
+	if value > 1 {
+		return 1
+	}
+	return value
+}
@@ -0,0 +1,179 @@
+package security
+
+import (
+	"testing"
+	"time"
+	"ujikom-backend/internal/config"
+)
+
+// cleanRequest adalah request dari jaringan sekolah tanpa sinyal mencurigakan
+func cleanRequest() *Request {
+	return &Request{
+		ClientIP:       "10.1.2.3",
+		UserAgent:      "UjikomApp/1.0 (Android 14)",
+		NetworkType:    "wifi",
+		WiFiSSID:       "JTI-3.01",
+		DeviceID:       "device-1",
+		Headers:        map[string]string{},
+		Latitude:       -7.946,
+		Longitude:      112.615,
+		HasLocation:    true,
+		Time:           time.Date(2026, 10, 19, 0, 30, 0, 0, time.UTC),
+		KnownDeviceIDs: []string{"device-1"},
+	}
+}
+
+func productionEngine(t *testing.T) *Engine {
+	t.Setenv("APP_ENV", "production")
+	return NewEngineFromConfig(config.Load())
+}
+
+func TestDefaultThresholdsPerEnvironment(t *testing.T) {
+	tests := []struct {
+		env  string
+		want Thresholds
+	}{
+		{"production", Thresholds{Flag: 25, Reject: 50}},
+		{"staging", Thresholds{Flag: 35, Reject: 70}},
+		{"development", Thresholds{Flag: 50, Reject: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("APP_ENV", tt.env)
+		if got := NewEngineFromConfig(config.Load()).Thresholds(); got != tt.want {
+			t.Errorf("%s thresholds = %+v, want %+v", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestEngineDecisionsAtProductionThresholds(t *testing.T) {
+	tests := []struct {
+		name      string
+		modify    func(req *Request)
+		wantScore float64
+		want      string
+	}{
+		{
+			name:      "school network",
+			modify:    func(req *Request) {},
+			wantScore: 0,
+			want:      DecisionAccept,
+		},
+		{
+			name: "unknown device only",
+			modify: func(req *Request) {
+				req.DeviceID = "device-2"
+			},
+			wantScore: 14,
+			want:      DecisionAccept,
+		},
+		{
+			name: "outside IP range reaches flag threshold",
+			modify: func(req *Request) {
+				req.ClientIP = "8.8.8.8"
+			},
+			wantScore: 25,
+			want:      DecisionFlag,
+		},
+		{
+			name: "mock location",
+			modify: func(req *Request) {
+				req.Headers["X-Mock-Location"] = "1"
+			},
+			wantScore: 40,
+			want:      DecisionFlag,
+		},
+		{
+			name: "home network on unknown device",
+			modify: func(req *Request) {
+				req.ClientIP = "8.8.8.8"
+				req.WiFiSSID = "IndiHome-123"
+				req.DeviceID = "device-2"
+			},
+			wantScore: 54,
+			want:      DecisionReject,
+		},
+		{
+			name: "outside IP range with mock location",
+			modify: func(req *Request) {
+				req.ClientIP = "8.8.8.8"
+				req.Headers["X-Fake-GPS"] = "1"
+			},
+			wantScore: 65,
+			want:      DecisionReject,
+		},
+	}
+
+	engine := productionEngine(t)
+	for _, tt := range tests {
+		req := cleanRequest()
+		tt.modify(req)
+
+		assessment := engine.Evaluate(req, DefaultPolicy())
+		if assessment.Score != tt.wantScore {
+			t.Errorf("%s: score = %v, want %v", tt.name, assessment.Score, tt.wantScore)
+		}
+		if assessment.Decision != tt.want {
+			t.Errorf("%s: decision = %s, want %s", tt.name, assessment.Decision, tt.want)
+		}
+		if assessment.FlagThreshold != 25 || assessment.RejectThreshold != 50 {
+			t.Errorf("%s: thresholds = %v/%v, want 25/50", tt.name, assessment.FlagThreshold, assessment.RejectThreshold)
+		}
+	}
+}
+
+func TestEngineWeightsAndClampsRuleRisk(t *testing.T) {
+	engine := productionEngine(t)
+	req := cleanRequest()
+	// tiga indikator VPN sekaligus bernilai lebih dari 1 dan harus dibatasi ke bobot penuh
+	req.UserAgent = "NordVPN"
+	req.Headers["X-VPN-Client"] = "1"
+	req.Headers["Via"] = "1.1 proxy"
+
+	assessment := engine.Evaluate(req, DefaultPolicy())
+	if len(assessment.Signals) != len(DefaultRules()) {
+		t.Fatalf("signals = %d, want one per rule (%d)", len(assessment.Signals), len(DefaultRules()))
+	}
+	for _, signal := range assessment.Signals {
+		if signal.Name != RuleVPN {
+			continue
+		}
+		if signal.Risk != 1 || signal.Score != 20 {
+			t.Errorf("vpn signal = risk %v score %v, want risk 1 score 20", signal.Risk, signal.Score)
+		}
+	}
+	if reasons := TopReasons(assessment, 3); len(reasons) != 1 {
+		t.Errorf("TopReasons = %v, want only the vpn reason", reasons)
+	}
+}
+
+func TestEngineSkipsRulesWithoutWeight(t *testing.T) {
+	engine := NewEngine(DefaultRules(), map[string]float64{RuleIPRange: 25}, Thresholds{Flag: 25, Reject: 50})
+	req := cleanRequest()
+	req.ClientIP = "8.8.8.8"
+	req.Headers["X-Mock-Location"] = "1"
+
+	assessment := engine.Evaluate(req, nil)
+	if len(assessment.Signals) != 1 || assessment.Score != 25 {
+		t.Errorf("assessment = %d signals score %v, want only ip_range scoring 25", len(assessment.Signals), assessment.Score)
+	}
+}
+
+func TestEngineExcludedRulesAreSkippedWithReason(t *testing.T) {
+	engine := productionEngine(t)
+	req := cleanRequest()
+	req.ClientIP = ""
+	req.WiFiSSID, req.NetworkType = "", ""
+	req.Excluded = map[string]string{RuleIPRange: "not captured", RuleCarrier: "not captured"}
+
+	assessment := engine.Evaluate(req, DefaultPolicy())
+	for _, signal := range assessment.Signals {
+		_, excluded := req.Excluded[signal.Name]
+		if excluded && (!signal.Skipped || signal.Reason != "not captured" || signal.Score != 0) {
+			t.Errorf("%s = %+v, want skipped with the exclusion reason", signal.Name, signal)
+		}
+	}
+	if assessment.Score != 0 || assessment.Decision != DecisionAccept {
+		t.Errorf("assessment = score %v %s, want 0 accept", assessment.Score, assessment.Decision)
+	}
+}
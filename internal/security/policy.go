@@ -0,0 +1,157 @@
+package security
+
+import (
+	"log"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Policy berisi daftar jaringan yang diizinkan untuk absensi
+type Policy struct {
+	AllowedIPRanges    []string `json:"allowed_ip_ranges"`
+	AllowedSSIDs       []string `json:"allowed_ssids"`
+	SSIDPatterns       []string `json:"ssid_patterns"`
+	AllowedCarriers    []string `json:"allowed_carriers"`
+	SecureNetworkTypes []string `json:"secure_network_types"`
+
+	// ssidMatchers hasil Compile dari SSIDPatterns
+	ssidMatchers []*regexp.Regexp
+}
+
+// DefaultPolicy dipakai sebagai seed awal collection network_policies dan
+// fallback jika policy belum pernah berhasil dimuat dari database
+func DefaultPolicy() *Policy {
+	policy := &Policy{
+		AllowedIPRanges: []string{
+			"10.0.0.0/8",     // Private network
+			"172.16.0.0/12",  // Private network
+			"192.168.0.0/16", // Private network
+			"127.0.0.0/8",
+			"103.0.0.0/8",
+			"114.0.0.0/8",
+			"202.0.0.0/8",
+			"103.156.71.94",
+			"203.78.113.253",
+		},
+		AllowedSSIDs: []string{
+			"JTI-3.01",
+			"JTI-3.02",
+			"JTI-3.03",
+			"JTI-3.04",
+			"JTI-3.05",
+		},
+		SSIDPatterns: []string{
+			`^JTI-.*`,
+			`^UJIKOM-.*`,
+		},
+		AllowedCarriers: []string{
+			"telkomsel",
+			"indosat",
+			"xl",
+			"axis",
+			"tri",
+			"smartfren",
+			"by.u",
+		},
+		SecureNetworkTypes: []string{
+			"4g",
+			"5g",
+			"lte",
+			"lte-a",
+		},
+	}
+	return policy.Compile()
+}
+
+// CompileSSIDPattern mengompilasi pola SSID tanpa membedakan huruf besar/kecil
+func CompileSSIDPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// Compile mengompilasi SSIDPatterns sekali saat policy dimuat, pola yang tidak valid dilewati
+func (p *Policy) Compile() *Policy {
+	p.ssidMatchers = nil
+	for _, pattern := range p.SSIDPatterns {
+		matcher, err := CompileSSIDPattern(pattern)
+		if err != nil {
+			log.Printf("Warning: skipping invalid SSID pattern %q: %v", pattern, err)
+			continue
+		}
+		p.ssidMatchers = append(p.ssidMatchers, matcher)
+	}
+	return p
+}
+
+// AllowsIP mengecek apakah IP masuk ke salah satu range (CIDR atau IP tunggal)
+func (p *Policy) AllowsIP(ip string) bool {
+	clientIP := net.ParseIP(ip)
+	if clientIP == nil {
+		return false
+	}
+
+	for _, cidr := range p.AllowedIPRanges {
+		if !strings.Contains(cidr, "/") {
+			if allowed := net.ParseIP(cidr); allowed != nil && allowed.Equal(clientIP) {
+				return true
+			}
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+		if network.Contains(clientIP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Policy) AllowsSSID(ssid string) bool {
+	if ssid == "" {
+		return false
+	}
+
+	for _, allowed := range p.AllowedSSIDs {
+		if strings.EqualFold(ssid, allowed) {
+			return true
+		}
+	}
+
+	for _, matcher := range p.ssidMatchers {
+		if matcher.MatchString(ssid) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Policy) AllowsCarrier(carrier string) bool {
+	if carrier == "" {
+		return false
+	}
+
+	carrier = strings.ToLower(carrier)
+	for _, allowed := range p.AllowedCarriers {
+		if strings.Contains(carrier, strings.ToLower(allowed)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Policy) IsSecureNetworkType(networkType string) bool {
+	networkType = strings.ToLower(networkType)
+	for _, secure := range p.SecureNetworkTypes {
+		if networkType == strings.ToLower(secure) {
+			return true
+		}
+	}
+
+	return false
+}
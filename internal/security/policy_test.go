@@ -0,0 +1,41 @@
+package security
+
+import "testing"
+
+func TestPolicyAllowsSSID(t *testing.T) {
+	policy := (&Policy{
+		AllowedSSIDs: []string{"Ruang-Guru"},
+		SSIDPatterns: []string{`^sekolah-`, `^JTI-\d`, `[invalid`},
+	}).Compile()
+
+	tests := []struct {
+		ssid    string
+		allowed bool
+	}{
+		{"Ruang-Guru", true},
+		{"ruang-guru", true},
+		{"sekolah-lab1", true},
+		{"SEKOLAH-LAB1", true},
+		{"JTI-3", true},
+		{"jti-4", true},
+		{"JTI-lab", false},
+		{"wifi-sekolah-rumah", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := policy.AllowsSSID(tt.ssid); got != tt.allowed {
+			t.Errorf("AllowsSSID(%q) = %v, want %v", tt.ssid, got, tt.allowed)
+		}
+	}
+}
+
+func TestPolicyCompileSkipsInvalidPatterns(t *testing.T) {
+	policy := (&Policy{SSIDPatterns: []string{`[invalid`, `^UJIKOM-`}}).Compile()
+	if len(policy.ssidMatchers) != 1 {
+		t.Errorf("compiled %d patterns, want 1", len(policy.ssidMatchers))
+	}
+	if !DefaultPolicy().AllowsSSID("ujikom-lab") {
+		t.Error("DefaultPolicy must be compiled and match patterns case-insensitively")
+	}
+}
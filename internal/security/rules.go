@@ -0,0 +1,261 @@
+package security
+
+import (
+	"strconv"
+	"strings"
+	"ujikom-backend/internal/utils"
+)
+
+const (
+	RuleIPRange      = "ip_range"
+	RuleWiFiSSID     = "wifi_ssid"
+	RuleCarrier      = "carrier"
+	RuleVPN          = "vpn"
+	RuleGPSPrecision = "gps_precision"
+	RuleDevice       = "device_binding"
+	RuleVelocity     = "velocity"
+)
+
+// InspectedHeaders adalah header request yang dibaca oleh rule
+var InspectedHeaders = []string{
+	"X-VPN-Client",
+	"X-Proxy-Authorization",
+	"Via",
+	"X-TTL",
+	"X-Mock-Location",
+	"X-Fake-GPS",
+	"X-Location-Spoofed",
+}
+
+// DefaultRules mengembalikan semua rule bawaan sesuai urutan evaluasi
+func DefaultRules() []Rule {
+	return []Rule{
+		IPRangeRule{},
+		WiFiSSIDRule{},
+		CarrierRule{},
+		VPNRule{},
+		GPSPrecisionRule{},
+		DeviceRule{},
+		VelocityRule{MaxSpeedKmh: 120},
+	}
+}
+
+// Validasi IP range yang diizinkan
+type IPRangeRule struct{}
+
+func (IPRangeRule) Name() string { return RuleIPRange }
+
+func (IPRangeRule) Evaluate(req *Request, policy *Policy) Result {
+	if req.ClientIP == "" {
+		return Result{Risk: 1, Reason: "client IP unknown"}
+	}
+	if !policy.AllowsIP(req.ClientIP) {
+		return Result{Risk: 1, Reason: "IP address outside allowed ranges"}
+	}
+	return Result{}
+}
+
+// Validasi SSID WiFi sekolah
+type WiFiSSIDRule struct{}
+
+func (WiFiSSIDRule) Name() string { return RuleWiFiSSID }
+
+func (WiFiSSIDRule) Evaluate(req *Request, policy *Policy) Result {
+	networkType := strings.ToLower(req.NetworkType)
+
+	if req.WiFiSSID == "" {
+		if networkType == "wifi" {
+			return Result{Risk: 0.5, Reason: "connected to WiFi without reporting SSID"}
+		}
+		return Result{Skipped: true, Reason: "not on WiFi"}
+	}
+
+	if !policy.AllowsSSID(req.WiFiSSID) {
+		return Result{Risk: 1, Reason: "WiFi network is not a school network"}
+	}
+	return Result{}
+}
+
+// Validasi keamanan jaringan seluler
+type CarrierRule struct{}
+
+func (CarrierRule) Name() string { return RuleCarrier }
+
+func (CarrierRule) Evaluate(req *Request, policy *Policy) Result {
+	networkType := strings.ToLower(req.NetworkType)
+
+	if req.Carrier == "" {
+		if networkType == "" && req.WiFiSSID == "" {
+			return Result{Risk: 0.5, Reason: "no network information provided"}
+		}
+		if networkType != "" && networkType != "wifi" && networkType != "ethernet" {
+			return Result{Risk: 0.5, Reason: "cellular connection without carrier information"}
+		}
+		return Result{Skipped: true, Reason: "not on cellular"}
+	}
+
+	if !policy.AllowsCarrier(req.Carrier) {
+		return Result{Risk: 1, Reason: "unknown cellular carrier"}
+	}
+	if !policy.IsSecureNetworkType(networkType) {
+		return Result{Risk: 0.5, Reason: "insecure cellular network type"}
+	}
+	return Result{}
+}
+
+// Deteksi penggunaan VPN
+type VPNRule struct{}
+
+func (VPNRule) Name() string { return RuleVPN }
+
+func (VPNRule) Evaluate(req *Request, policy *Policy) Result {
+	var risk float64
+	var reasons []string
+
+	vpnHeaders := []string{
+		"X-VPN-Client",
+		"X-Proxy-Authorization",
+	}
+	for _, header := range vpnHeaders {
+		if req.Header(header) != "" {
+			risk += 0.6
+			reasons = append(reasons, header+" header present")
+		}
+	}
+
+	if req.Header("Via") != "" {
+		risk += 0.3
+		reasons = append(reasons, "request passed through a proxy")
+	}
+
+	userAgent := strings.ToLower(req.UserAgent)
+	vpnKeywords := []string{
+		"vpn",
+		"proxy",
+		"tunnel",
+		"nordvpn",
+		"expressvpn",
+		"cyberghost",
+		"protonvpn",
+	}
+	for _, keyword := range vpnKeywords {
+		if strings.Contains(userAgent, keyword) {
+			risk += 1
+			reasons = append(reasons, "VPN client user agent")
+			break
+		}
+	}
+
+	// TTL yang terlalu rendah bisa menunjukkan VPN
+	if ttl := req.Header("X-TTL"); ttl != "" {
+		if ttlValue, err := strconv.Atoi(ttl); err == nil && ttlValue < 50 {
+			risk += 0.4
+			reasons = append(reasons, "low TTL")
+		}
+	}
+
+	if risk == 0 {
+		return Result{}
+	}
+	return Result{Risk: risk, Reason: "VPN suspected: " + strings.Join(reasons, ", ")}
+}
+
+// Deteksi fake GPS dari presisi koordinat, header mock location dan akurasi
+type GPSPrecisionRule struct{}
+
+func (GPSPrecisionRule) Name() string { return RuleGPSPrecision }
+
+func (GPSPrecisionRule) Evaluate(req *Request, policy *Policy) Result {
+	mockHeaders := []string{
+		"X-Mock-Location",
+		"X-Fake-GPS",
+		"X-Location-Spoofed",
+	}
+	for _, header := range mockHeaders {
+		if req.Header(header) != "" {
+			return Result{Risk: 1, Reason: "mock location reported by device"}
+		}
+	}
+
+	if !req.HasLocation {
+		return Result{Skipped: true, Reason: "no coordinates in request"}
+	}
+
+	if hasSuspiciousPrecision(req.Latitude, req.Longitude) {
+		return Result{Risk: 0.8, Reason: "unrealistic coordinate precision"}
+	}
+
+	if req.GPSAccuracy != nil && *req.GPSAccuracy < 1 {
+		return Result{Risk: 0.7, Reason: "GPS accuracy too perfect"}
+	}
+
+	return Result{}
+}
+
+func hasSuspiciousPrecision(lat, lng float64) bool {
+	return getDecimalPlaces(lat) > 10 || getDecimalPlaces(lng) > 10
+}
+
+func getDecimalPlaces(num float64) int {
+	str := strconv.FormatFloat(num, 'f', -1, 64)
+	if dotIndex := strings.Index(str, "."); dotIndex != -1 {
+		return len(str) - dotIndex - 1
+	}
+	return 0
+}
+
+// Cek apakah device yang dipakai pernah dipakai absen sebelumnya
+type DeviceRule struct{}
+
+func (DeviceRule) Name() string { return RuleDevice }
+
+func (DeviceRule) Evaluate(req *Request, policy *Policy) Result {
+	if req.DeviceID == "" {
+		return Result{Risk: 0.5, Reason: "no device ID provided"}
+	}
+
+	if len(req.KnownDeviceIDs) == 0 {
+		return Result{}
+	}
+
+	for _, known := range req.KnownDeviceIDs {
+		if known == req.DeviceID {
+			return Result{}
+		}
+	}
+
+	return Result{Risk: 0.7, Reason: "device not previously used by this student"}
+}
+
+// Deteksi perpindahan yang tidak mungkin antara absensi terakhir dan sekarang
+type VelocityRule struct {
+	MaxSpeedKmh float64
+}
+
+func (VelocityRule) Name() string { return RuleVelocity }
+
+func (r VelocityRule) Evaluate(req *Request, policy *Policy) Result {
+	if req.LastSample == nil || !req.HasLocation {
+		return Result{Skipped: true, Reason: "no previous location"}
+	}
+
+	elapsed := req.Time.Sub(req.LastSample.Time).Hours()
+	distance := utils.CalculateDistance(req.LastSample.Latitude, req.LastSample.Longitude, req.Latitude, req.Longitude)
+
+	// jarak di bawah 1 km dianggap noise GPS
+	if distance < 1 {
+		return Result{}
+	}
+	if elapsed <= 0 {
+		return Result{Risk: 1, Reason: "location changed with no time elapsed"}
+	}
+
+	speed := distance / elapsed
+	if speed > r.MaxSpeedKmh {
+		return Result{Risk: 1, Reason: "impossible travel speed since last attendance"}
+	}
+	if speed > r.MaxSpeedKmh/2 {
+		return Result{Risk: 0.5, Reason: "unusually fast travel since last attendance"}
+	}
+	return Result{}
+}
@@ -0,0 +1,82 @@
+package security
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRuleContributions(t *testing.T) {
+	accuracy := 0.5
+	now := time.Date(2026, 10, 19, 0, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		rule        Rule
+		modify      func(req *Request)
+		wantRisk    float64
+		wantSkipped bool
+	}{
+		{"ip allowed", IPRangeRule{}, func(req *Request) {}, 0, false},
+		{"ip single address allowed", IPRangeRule{}, func(req *Request) { req.ClientIP = "203.78.113.253" }, 0, false},
+		{"ip outside ranges", IPRangeRule{}, func(req *Request) { req.ClientIP = "8.8.8.8" }, 1, false},
+		{"ip unknown", IPRangeRule{}, func(req *Request) { req.ClientIP = "" }, 1, false},
+
+		{"ssid exact", WiFiSSIDRule{}, func(req *Request) {}, 0, false},
+		{"ssid pattern", WiFiSSIDRule{}, func(req *Request) { req.WiFiSSID = "UJIKOM-Lab" }, 0, false},
+		{"ssid unknown", WiFiSSIDRule{}, func(req *Request) { req.WiFiSSID = "IndiHome-123" }, 1, false},
+		{"wifi without ssid", WiFiSSIDRule{}, func(req *Request) { req.WiFiSSID = "" }, 0.5, false},
+		{"not on wifi", WiFiSSIDRule{}, func(req *Request) { req.WiFiSSID, req.NetworkType = "", "4g" }, 0, true},
+
+		{"carrier skipped on wifi", CarrierRule{}, func(req *Request) {}, 0, true},
+		{"carrier known on lte", CarrierRule{}, func(req *Request) { req.NetworkType, req.Carrier = "lte", "Telkomsel" }, 0, false},
+		{"carrier known on 3g", CarrierRule{}, func(req *Request) { req.NetworkType, req.Carrier = "3g", "Telkomsel" }, 0.5, false},
+		{"carrier unknown", CarrierRule{}, func(req *Request) { req.NetworkType, req.Carrier = "4g", "Unknown Mobile" }, 1, false},
+		{"cellular without carrier", CarrierRule{}, func(req *Request) { req.WiFiSSID, req.NetworkType = "", "4g" }, 0.5, false},
+		{"no network information", CarrierRule{}, func(req *Request) { req.WiFiSSID, req.NetworkType = "", "" }, 0.5, false},
+
+		{"no vpn", VPNRule{}, func(req *Request) {}, 0, false},
+		{"vpn header", VPNRule{}, func(req *Request) { req.Headers["X-VPN-Client"] = "1" }, 0.6, false},
+		{"via header", VPNRule{}, func(req *Request) { req.Headers["Via"] = "1.1 proxy" }, 0.3, false},
+		{"low ttl", VPNRule{}, func(req *Request) { req.Headers["X-TTL"] = "40" }, 0.4, false},
+		{"vpn user agent", VPNRule{}, func(req *Request) { req.UserAgent = "ProtonVPN/3.0" }, 1, false},
+
+		{"gps normal", GPSPrecisionRule{}, func(req *Request) {}, 0, false},
+		{"gps mock header", GPSPrecisionRule{}, func(req *Request) { req.Headers["X-Location-Spoofed"] = "true" }, 1, false},
+		{"gps too precise", GPSPrecisionRule{}, func(req *Request) { req.Latitude = -7.94612345678901 }, 0.8, false},
+		{"gps accuracy too perfect", GPSPrecisionRule{}, func(req *Request) { req.GPSAccuracy = &accuracy }, 0.7, false},
+		{"gps no coordinates", GPSPrecisionRule{}, func(req *Request) { req.HasLocation = false }, 0, true},
+
+		{"device known", DeviceRule{}, func(req *Request) {}, 0, false},
+		{"device first use", DeviceRule{}, func(req *Request) { req.KnownDeviceIDs = nil }, 0, false},
+		{"device unknown", DeviceRule{}, func(req *Request) { req.DeviceID = "device-2" }, 0.7, false},
+		{"device missing", DeviceRule{}, func(req *Request) { req.DeviceID = "" }, 0.5, false},
+
+		{"velocity no history", VelocityRule{MaxSpeedKmh: 120}, func(req *Request) {}, 0, true},
+		{"velocity same place", VelocityRule{MaxSpeedKmh: 120}, func(req *Request) {
+			req.LastSample = &LocationSample{Latitude: req.Latitude, Longitude: req.Longitude, Time: now.Add(-time.Minute)}
+		}, 0, false},
+		{"velocity fast", VelocityRule{MaxSpeedKmh: 120}, func(req *Request) {
+			// sekitar 111 km dalam 1 jam
+			req.LastSample = &LocationSample{Latitude: req.Latitude + 1, Longitude: req.Longitude, Time: now.Add(-time.Hour)}
+		}, 0.5, false},
+		{"velocity impossible", VelocityRule{MaxSpeedKmh: 120}, func(req *Request) {
+			req.LastSample = &LocationSample{Latitude: req.Latitude + 1, Longitude: req.Longitude, Time: now.Add(-10 * time.Minute)}
+		}, 1, false},
+	}
+
+	policy := DefaultPolicy()
+	for _, tt := range tests {
+		req := cleanRequest()
+		req.Time = now
+		tt.modify(req)
+
+		result := tt.rule.Evaluate(req, policy)
+		if result.Risk != tt.wantRisk || result.Skipped != tt.wantSkipped {
+			t.Errorf("%s: %s = risk %v skipped %v (%s), want risk %v skipped %v",
+				tt.name, tt.rule.Name(), result.Risk, result.Skipped, result.Reason, tt.wantRisk, tt.wantSkipped)
+		}
+		if result.Risk > 0 && result.Reason == "" {
+			t.Errorf("%s: risky result must carry a reason", tt.name)
+		}
+	}
+}
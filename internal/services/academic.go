@@ -0,0 +1,600 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AcademicService struct {
+	db     *mongo.Database
+	ctx    context.Context
+	config *config.Config
+}
+
+type AcademicServiceInterface interface {
+	CreateAcademicYear(req *models.CreateAcademicYearRequest) (*models.AcademicYear, error)
+	ListAcademicYears() ([]models.AcademicYearDetail, error)
+	GetAcademicYear(id string) (*models.AcademicYearDetail, error)
+	UpdateAcademicYear(id string, req *models.UpdateAcademicYearRequest) (*models.AcademicYear, error)
+	CreateSemester(yearID string, req *models.CreateSemesterRequest) (*models.Semester, error)
+	UpdateSemester(id string, req *models.UpdateSemesterRequest) (*models.Semester, error)
+	GetSemester(id string) (*models.Semester, error)
+	CurrentSemester() (*models.Semester, error)
+	Rollover(actor *models.User, yearID string, req *models.RolloverRequest) (*models.RolloverResult, error)
+}
+
+func NewAcademicService(db *mongo.Database, cfg *config.Config) AcademicServiceInterface {
+	return &AcademicService{
+		db:     db,
+		ctx:    context.Background(),
+		config: cfg,
+	}
+}
+
+func (s *AcademicService) CreateAcademicYear(req *models.CreateAcademicYearRequest) (*models.AcademicYear, error) {
+	start, end, err := parseDateRange(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkOverlap("academic_years", bson.M{}, start, end, primitive.NilObjectID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	year := models.AcademicYear{
+		Name:      utils.SanitizeInput(req.Name),
+		StartDate: start,
+		EndDate:   end,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	result, err := s.db.Collection("academic_years").InsertOne(s.ctx, year)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("academic year already exists")
+		}
+		log.Printf("Error creating academic year: %v", err)
+		return nil, errors.New("failed to create academic year")
+	}
+	year.ID = result.InsertedID.(primitive.ObjectID)
+
+	return &year, nil
+}
+
+func (s *AcademicService) ListAcademicYears() ([]models.AcademicYearDetail, error) {
+	cursor, err := s.db.Collection("academic_years").Find(s.ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "start_date", Value: -1}}))
+	if err != nil {
+		return nil, errors.New("failed to fetch academic years")
+	}
+	defer cursor.Close(s.ctx)
+
+	var years []models.AcademicYear
+	if err = cursor.All(s.ctx, &years); err != nil {
+		return nil, errors.New("failed to decode academic years")
+	}
+
+	semesters, err := s.findSemesters(bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	byYear := make(map[primitive.ObjectID][]models.Semester)
+	for _, semester := range semesters {
+		byYear[semester.AcademicYearID] = append(byYear[semester.AcademicYearID], semester)
+	}
+
+	details := make([]models.AcademicYearDetail, 0, len(years))
+	for _, year := range years {
+		yearSemesters := byYear[year.ID]
+		if yearSemesters == nil {
+			yearSemesters = []models.Semester{}
+		}
+		details = append(details, models.AcademicYearDetail{AcademicYear: year, Semesters: yearSemesters})
+	}
+
+	return details, nil
+}
+
+func (s *AcademicService) GetAcademicYear(id string) (*models.AcademicYearDetail, error) {
+	year, err := s.findYear(id)
+	if err != nil {
+		return nil, err
+	}
+
+	semesters, err := s.findSemesters(bson.M{"academic_year_id": year.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AcademicYearDetail{AcademicYear: *year, Semesters: semesters}, nil
+}
+
+func (s *AcademicService) UpdateAcademicYear(id string, req *models.UpdateAcademicYearRequest) (*models.AcademicYear, error) {
+	year, err := s.findYear(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updateDoc := bson.M{"updated_at": time.Now().UTC()}
+	if req.Name != "" {
+		updateDoc["name"] = utils.SanitizeInput(req.Name)
+	}
+
+	start, end := year.StartDate, year.EndDate
+	if req.StartDate != "" {
+		if start, err = time.Parse("2006-01-02", req.StartDate); err != nil {
+			return nil, errors.New("invalid start date, use YYYY-MM-DD")
+		}
+	}
+	if req.EndDate != "" {
+		if end, err = time.Parse("2006-01-02", req.EndDate); err != nil {
+			return nil, errors.New("invalid end date, use YYYY-MM-DD")
+		}
+	}
+	if !end.After(start) {
+		return nil, errors.New("end date must be after start date")
+	}
+	if err := s.checkOverlap("academic_years", bson.M{}, start, end, year.ID); err != nil {
+		return nil, err
+	}
+
+	// semester yang sudah ada harus tetap berada di dalam tahun ajaran
+	outside, err := s.db.Collection("semesters").CountDocuments(s.ctx, bson.M{
+		"academic_year_id": year.ID,
+		"$or": []bson.M{
+			{"start_date": bson.M{"$lt": start}},
+			{"end_date": bson.M{"$gt": end}},
+		},
+	})
+	if err != nil {
+		return nil, errors.New("database error")
+	}
+	if outside > 0 {
+		return nil, errors.New("existing semesters fall outside the new date range")
+	}
+
+	updateDoc["start_date"] = start
+	updateDoc["end_date"] = end
+
+	if _, err := s.db.Collection("academic_years").UpdateOne(s.ctx, bson.M{"_id": year.ID}, bson.M{"$set": updateDoc}); err != nil {
+		log.Printf("Error updating academic year: %v", err)
+		return nil, errors.New("failed to update academic year")
+	}
+
+	return s.findYear(id)
+}
+
+func (s *AcademicService) CreateSemester(yearID string, req *models.CreateSemesterRequest) (*models.Semester, error) {
+	year, err := s.findYear(yearID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := parseDateRange(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if start.Before(year.StartDate) || end.After(year.EndDate) {
+		return nil, errors.New("semester must fall within the academic year")
+	}
+	if err := s.checkOverlap("semesters", bson.M{}, start, end, primitive.NilObjectID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	semester := models.Semester{
+		AcademicYearID: year.ID,
+		Name:           utils.SanitizeInput(req.Name),
+		Number:         req.Number,
+		StartDate:      start,
+		EndDate:        end,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	result, err := s.db.Collection("semesters").InsertOne(s.ctx, semester)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("semester number already exists in this academic year")
+		}
+		log.Printf("Error creating semester: %v", err)
+		return nil, errors.New("failed to create semester")
+	}
+	semester.ID = result.InsertedID.(primitive.ObjectID)
+
+	s.tagAttendances(&semester)
+
+	return &semester, nil
+}
+
+func (s *AcademicService) UpdateSemester(id string, req *models.UpdateSemesterRequest) (*models.Semester, error) {
+	semester, err := s.GetSemester(id)
+	if err != nil {
+		return nil, err
+	}
+
+	year, err := s.findYear(semester.AcademicYearID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		semester.Name = utils.SanitizeInput(req.Name)
+	}
+	if req.StartDate != "" {
+		if semester.StartDate, err = time.Parse("2006-01-02", req.StartDate); err != nil {
+			return nil, errors.New("invalid start date, use YYYY-MM-DD")
+		}
+	}
+	if req.EndDate != "" {
+		if semester.EndDate, err = time.Parse("2006-01-02", req.EndDate); err != nil {
+			return nil, errors.New("invalid end date, use YYYY-MM-DD")
+		}
+	}
+	if !semester.EndDate.After(semester.StartDate) {
+		return nil, errors.New("end date must be after start date")
+	}
+	if semester.StartDate.Before(year.StartDate) || semester.EndDate.After(year.EndDate) {
+		return nil, errors.New("semester must fall within the academic year")
+	}
+	if err := s.checkOverlap("semesters", bson.M{}, semester.StartDate, semester.EndDate, semester.ID); err != nil {
+		return nil, err
+	}
+
+	semester.UpdatedAt = time.Now().UTC()
+	_, err = s.db.Collection("semesters").UpdateOne(s.ctx, bson.M{"_id": semester.ID}, bson.M{"$set": bson.M{
+		"name":       semester.Name,
+		"start_date": semester.StartDate,
+		"end_date":   semester.EndDate,
+		"updated_at": semester.UpdatedAt,
+	}})
+	if err != nil {
+		log.Printf("Error updating semester: %v", err)
+		return nil, errors.New("failed to update semester")
+	}
+
+	s.tagAttendances(semester)
+
+	return semester, nil
+}
+
+func (s *AcademicService) GetSemester(id string) (*models.Semester, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid semester ID")
+	}
+
+	var semester models.Semester
+	if err := s.db.Collection("semesters").FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&semester); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("semester not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	return &semester, nil
+}
+
+// CurrentSemester mengembalikan semester yang berlangsung hari ini, nil jika belum diatur
+func (s *AcademicService) CurrentSemester() (*models.Semester, error) {
+	return semesterForDate(s.ctx, s.db, s.config.Today())
+}
+
+// Rollover menaikkan kelas siswa sesuai pemetaan untuk tahun ajaran baru.
+// Pemetaan dihitung dari kelas awal semua siswa sekaligus sehingga X -> XI dan XI -> XII
+// tidak saling berantai.
+func (s *AcademicService) Rollover(actor *models.User, yearID string, req *models.RolloverRequest) (*models.RolloverResult, error) {
+	year, err := s.findYear(yearID)
+	if err != nil {
+		return nil, err
+	}
+	if year.RolledOverAt != nil && !req.DryRun {
+		return nil, errors.New("class promotion has already been run for this academic year")
+	}
+	if len(req.Promotions) == 0 && len(req.Graduate) == 0 {
+		return nil, errors.New("no promotion rules given")
+	}
+
+	classes, err := s.loadClasses()
+	if err != nil {
+		return nil, err
+	}
+
+	promotions := make(map[primitive.ObjectID]models.Class)
+	for _, rule := range req.Promotions {
+		from, _ := primitive.ObjectIDFromHex(rule.FromClassID)
+		to, _ := primitive.ObjectIDFromHex(rule.ToClassID)
+		if _, ok := classes[from]; !ok {
+			return nil, errors.New("class " + rule.FromClassID + " not found")
+		}
+		target, ok := classes[to]
+		if !ok {
+			return nil, errors.New("class " + rule.ToClassID + " not found")
+		}
+		promotions[from] = target
+	}
+	majorNames, err := s.loadMajorNames(promotions)
+	if err != nil {
+		return nil, err
+	}
+	graduate := make(map[primitive.ObjectID]bool)
+	for _, id := range req.Graduate {
+		classID, _ := primitive.ObjectIDFromHex(id)
+		if _, ok := classes[classID]; !ok {
+			return nil, errors.New("class " + id + " not found")
+		}
+		graduate[classID] = true
+	}
+
+	cursor, err := s.db.Collection("users").Find(s.ctx, bson.M{
+		"role":      bson.M{"$nin": []string{models.RoleTeacher, models.RoleAdmin, models.RoleGuardian}},
+		"is_active": true,
+		"class_id":  bson.M{"$exists": true},
+	})
+	if err != nil {
+		return nil, errors.New("failed to fetch students")
+	}
+	defer cursor.Close(s.ctx)
+
+	var students []models.User
+	if err = cursor.All(s.ctx, &students); err != nil {
+		return nil, errors.New("failed to decode students")
+	}
+
+	now := time.Now().UTC()
+	result := &models.RolloverResult{AcademicYear: *year, DryRun: req.DryRun, Unmapped: []string{}}
+	unmapped := make(map[primitive.ObjectID]bool)
+	var writes []mongo.WriteModel
+	var history []interface{}
+
+	for _, student := range students {
+		key := *student.ClassID
+		promotion := models.ClassPromotion{
+			AcademicYearID: year.ID,
+			UserID:         student.ID,
+			FromClassID:    key,
+			FromKelas:      classes[key].Name,
+			CreatedAt:      now,
+		}
+
+		target, promoted := promotions[key]
+		switch {
+		case graduate[key]:
+			promotion.Graduated = true
+			result.Graduated++
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": student.ID}).
+				SetUpdate(bson.M{"$set": bson.M{"is_active": false, "updated_at": now}}))
+		case promoted:
+			promotion.ToClassID = &target.ID
+			promotion.ToKelas = target.Name
+			result.Promoted++
+			update := bson.M{"class_id": target.ID, "kelas": target.Name, "updated_at": now}
+			if target.MajorID != classes[key].MajorID {
+				update["major_id"] = target.MajorID
+				update["jurusan"] = majorNames[target.MajorID]
+			}
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": student.ID}).
+				SetUpdate(bson.M{"$set": update}))
+		default:
+			if !unmapped[key] {
+				unmapped[key] = true
+				result.Unmapped = append(result.Unmapped, promotion.FromKelas)
+			}
+			continue
+		}
+		history = append(history, promotion)
+	}
+
+	if req.DryRun || len(writes) == 0 {
+		return result, nil
+	}
+
+	// klaim tahun ajaran dulu agar dua rollover bersamaan tidak menaikkan kelas dua kali
+	claim, err := s.db.Collection("academic_years").UpdateOne(s.ctx,
+		bson.M{"_id": year.ID, "rolled_over_at": nil},
+		bson.M{"$set": bson.M{"rolled_over_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		log.Printf("Error marking academic year rollover: %v", err)
+		return nil, errors.New("failed to start class promotion")
+	}
+	if claim.MatchedCount == 0 {
+		return nil, errors.New("class promotion has already been run for this academic year")
+	}
+
+	bulk, err := s.db.Collection("users").BulkWrite(s.ctx, writes)
+	if err != nil {
+		log.Printf("Error promoting students: %v", err)
+		if bulk == nil || bulk.ModifiedCount == 0 {
+			// belum ada siswa yang berubah, tandai ulang agar rollover bisa dicoba lagi
+			if _, err := s.db.Collection("academic_years").UpdateOne(s.ctx, bson.M{"_id": year.ID, "rolled_over_at": now}, bson.M{
+				"$set":   bson.M{"updated_at": time.Now().UTC()},
+				"$unset": bson.M{"rolled_over_at": ""},
+			}); err != nil {
+				log.Printf("Error releasing academic year rollover: %v", err)
+			}
+			return nil, errors.New("failed to promote students")
+		}
+		return nil, errors.New("class promotion was only partially applied, please check student classes")
+	}
+	if _, err := s.db.Collection("class_promotions").InsertMany(s.ctx, history); err != nil {
+		log.Printf("Error saving promotion history: %v", err)
+	}
+	result.AcademicYear.RolledOverAt = &now
+
+	log.Printf("Academic year %s rollover by %s: %d promoted, %d graduated", year.Name, actor.Email, result.Promoted, result.Graduated)
+	return result, nil
+}
+
+func (s *AcademicService) loadClasses() (map[primitive.ObjectID]models.Class, error) {
+	cursor, err := s.db.Collection("classes").Find(s.ctx, bson.M{})
+	if err != nil {
+		return nil, errors.New("failed to fetch classes")
+	}
+	defer cursor.Close(s.ctx)
+
+	var list []models.Class
+	if err = cursor.All(s.ctx, &list); err != nil {
+		return nil, errors.New("failed to decode classes")
+	}
+
+	classes := make(map[primitive.ObjectID]models.Class)
+	for _, class := range list {
+		classes[class.ID] = class
+	}
+	return classes, nil
+}
+
+// loadMajorNames memuat nama jurusan kelas tujuan untuk disalin ke users.jurusan
+func (s *AcademicService) loadMajorNames(promotions map[primitive.ObjectID]models.Class) (map[primitive.ObjectID]string, error) {
+	var ids []primitive.ObjectID
+	for _, target := range promotions {
+		ids = append(ids, target.MajorID)
+	}
+
+	names := make(map[primitive.ObjectID]string)
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	cursor, err := s.db.Collection("majors").Find(s.ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, errors.New("failed to fetch majors")
+	}
+	defer cursor.Close(s.ctx)
+
+	var majors []models.Major
+	if err = cursor.All(s.ctx, &majors); err != nil {
+		return nil, errors.New("failed to decode majors")
+	}
+	for _, major := range majors {
+		names[major.ID] = major.Name
+	}
+	for _, target := range promotions {
+		if _, ok := names[target.MajorID]; !ok {
+			return nil, errors.New("major of class " + target.Name + " not found")
+		}
+	}
+	return names, nil
+}
+
+// tagAttendances menandai ulang record absensi dalam rentang tanggal semester
+func (s *AcademicService) tagAttendances(semester *models.Semester) {
+	collection := s.db.Collection("attendances")
+
+	if _, err := collection.UpdateMany(s.ctx, bson.M{"term_id": semester.ID}, bson.M{"$unset": bson.M{"term_id": ""}}); err != nil {
+		log.Printf("Error clearing attendance term: %v", err)
+		return
+	}
+
+	_, err := collection.UpdateMany(s.ctx, bson.M{
+		"date": bson.M{"$gte": semester.StartDate, "$lt": semester.EndDate.Add(24 * time.Hour)},
+	}, bson.M{"$set": bson.M{"term_id": semester.ID}})
+	if err != nil {
+		log.Printf("Error tagging attendance term: %v", err)
+	}
+}
+
+func (s *AcademicService) findYear(id string) (*models.AcademicYear, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid academic year ID")
+	}
+
+	var year models.AcademicYear
+	if err := s.db.Collection("academic_years").FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&year); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("academic year not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	return &year, nil
+}
+
+func (s *AcademicService) findSemesters(filter bson.M) ([]models.Semester, error) {
+	cursor, err := s.db.Collection("semesters").Find(s.ctx, filter, options.Find().SetSort(bson.D{{Key: "start_date", Value: 1}}))
+	if err != nil {
+		return nil, errors.New("failed to fetch semesters")
+	}
+	defer cursor.Close(s.ctx)
+
+	semesters := []models.Semester{}
+	if err = cursor.All(s.ctx, &semesters); err != nil {
+		return nil, errors.New("failed to decode semesters")
+	}
+	return semesters, nil
+}
+
+func (s *AcademicService) checkOverlap(collection string, filter bson.M, start, end time.Time, exclude primitive.ObjectID) error {
+	filter["start_date"] = bson.M{"$lte": end}
+	filter["end_date"] = bson.M{"$gte": start}
+	if !exclude.IsZero() {
+		filter["_id"] = bson.M{"$ne": exclude}
+	}
+
+	count, err := s.db.Collection(collection).CountDocuments(s.ctx, filter)
+	if err != nil {
+		return errors.New("database error")
+	}
+	if count > 0 {
+		return errors.New("date range overlaps an existing period")
+	}
+	return nil
+}
+
+// semesterForDate mencari semester yang mencakup tanggal tersebut
+func semesterForDate(ctx context.Context, db *mongo.Database, date time.Time) (*models.Semester, error) {
+	day := startOfDayUTC(date)
+
+	var semester models.Semester
+	err := db.Collection("semesters").FindOne(ctx, bson.M{
+		"start_date": bson.M{"$lte": day},
+		"end_date":   bson.M{"$gte": day},
+	}).Decode(&semester)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, errors.New("failed to fetch semester")
+	}
+
+	return &semester, nil
+}
+
+// termIDForDate dipakai saat membuat record absensi, nil jika semester belum diatur
+func termIDForDate(ctx context.Context, db *mongo.Database, date time.Time) *primitive.ObjectID {
+	semester, err := semesterForDate(ctx, db, date)
+	if err != nil || semester == nil {
+		return nil
+	}
+	return &semester.ID
+}
+
+func parseDateRange(startValue, endValue string) (time.Time, time.Time, error) {
+	start, err := time.Parse("2006-01-02", startValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid start date, use YYYY-MM-DD")
+	}
+	end, err := time.Parse("2006-01-02", endValue)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid end date, use YYYY-MM-DD")
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, errors.New("end date must be after start date")
+	}
+	return start, end, nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"ujikom-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CanManageStudent mengecek apakah actor boleh mengelola data siswa:
+// admin boleh semua, guru hanya siswa di kelas perwaliannya
+func CanManageStudent(actor *models.User, student *models.User) bool {
+	if actor.HasRole(models.RoleAdmin) {
+		return true
+	}
+
+	if !actor.HasRole(models.RoleTeacher) || actor.ClassID == nil || student.ClassID == nil {
+		return false
+	}
+
+	return *actor.ClassID == *student.ClassID && !student.HasRole(models.RoleTeacher, models.RoleAdmin, models.RoleGuardian)
+}
+
+// homeroomStudentFilter mengembalikan filter user untuk siswa yang bisa dikelola actor,
+// nil berarti tidak ada batasan (admin)
+func homeroomStudentFilter(actor *models.User) bson.M {
+	if actor.HasRole(models.RoleAdmin) {
+		return nil
+	}
+
+	if actor.ClassID == nil {
+		// guru tanpa kelas perwalian tidak mengelola siswa mana pun
+		return bson.M{"_id": bson.M{"$exists": false}}
+	}
+
+	return classStudentFilter(*actor.ClassID)
+}
+
+// classStudentFilter memilih siswa sebuah kelas; guru ikut menyimpan class_id
+// (kelas perwalian) sehingga perlu dikecualikan
+func classStudentFilter(classID primitive.ObjectID) bson.M {
+	return bson.M{
+		"class_id": classID,
+		"role":     bson.M{"$nin": []string{models.RoleTeacher, models.RoleAdmin, models.RoleGuardian}},
+	}
+}
+
+// loadClassStudents mengambil siswa aktif sebuah kelas urut nama
+func loadClassStudents(ctx context.Context, db *mongo.Database, classID primitive.ObjectID) ([]models.User, error) {
+	filter := classStudentFilter(classID)
+	filter["is_active"] = true
+
+	cursor, err := db.Collection("users").Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, errors.New("failed to fetch students")
+	}
+	defer cursor.Close(ctx)
+
+	var students []models.User
+	if err = cursor.All(ctx, &students); err != nil {
+		return nil, errors.New("failed to decode students")
+	}
+	return students, nil
+}
+
+// manageableStudentIDs mengembalikan ID siswa yang bisa dikelola actor, nil untuk admin
+func manageableStudentIDs(ctx context.Context, db *mongo.Database, actor *models.User) (map[primitive.ObjectID]bool, error) {
+	filter := homeroomStudentFilter(actor)
+	if filter == nil {
+		return nil, nil
+	}
+
+	cursor, err := db.Collection("users").Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, errors.New("failed to fetch students")
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, errors.New("failed to decode students")
+	}
+
+	ids := make(map[primitive.ObjectID]bool)
+	for _, user := range users {
+		ids[user.ID] = true
+	}
+	return ids, nil
+}
+
+func loadUsersByID(ctx context.Context, db *mongo.Database, ids []primitive.ObjectID) (map[primitive.ObjectID]models.User, error) {
+	users := make(map[primitive.ObjectID]models.User)
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	cursor, err := db.Collection("users").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, errors.New("failed to fetch users")
+	}
+	defer cursor.Close(ctx)
+
+	var list []models.User
+	if err = cursor.All(ctx, &list); err != nil {
+		return nil, errors.New("failed to decode users")
+	}
+	for _, user := range list {
+		users[user.ID] = user
+	}
+	return users, nil
+}
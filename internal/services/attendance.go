@@ -7,6 +7,8 @@ import (
 	"time"
 	"ujikom-backend/internal/config"
 	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/realtime"
+	"ujikom-backend/internal/security"
 	"ujikom-backend/internal/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -15,28 +17,68 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// errAttendanceChanged dikembalikan ApplyCorrection jika record berubah setelah dibaca
+var errAttendanceChanged = errors.New("attendance record was changed by another request, reload and try again")
+
+// IsAttendanceConflict true jika koreksi gagal karena record diubah request lain
+func IsAttendanceConflict(err error) bool {
+	return errors.Is(err, errAttendanceChanged)
+}
+
 type AttendanceService struct {
-	db     *mongo.Database
-	ctx    context.Context
-	config *config.Config
+	db       *mongo.Database
+	ctx      context.Context
+	config   *config.Config
+	events   *realtime.Hub
+	webhooks WebhookServiceInterface
+	notifier NotificationServiceInterface
 }
 
 type AttendanceServiceInterface interface {
 	CheckIn(userID string, req *models.AttendanceRequest) (*models.Attendance, error)
 	CheckOut(userID string, req *models.AttendanceRequest) (*models.Attendance, error)
+	CheckInOffline(userID primitive.ObjectID, device *models.Device, checkIn *models.OfflineCheckIn, nonceIssuedAt time.Time, risk *models.RiskAssessment) (*models.Attendance, error)
 	GetTodayAttendance(userID string) (*models.Attendance, error)
-	GetAttendanceHistory(userID string, limit, offset int) ([]models.Attendance, int64, error)
-	GetAttendanceStats(userID string) (*models.AttendanceStats, error)
+	GetAttendanceHistory(userID string, period *models.AttendancePeriod, limit, offset int) ([]models.Attendance, int64, error)
+	GetAttendanceStats(userID string, period *models.AttendancePeriod) (*models.AttendanceStats, error)
 	GetAttendanceByDate(userID string, date time.Time) (*models.Attendance, error)
 	IsValidLocation(lat, lng float64) bool
 	DetermineStatus(checkInTime time.Time) string
+	StartAutoCheckout(ctx context.Context)
+	CloseOpenAttendances(now time.Time) (int64, error)
+	PublishEvent(eventType string, attendance *models.Attendance)
+
+	GetAttendance(actor *models.User, attendanceID string) (*models.Attendance, error)
+	GetStudentAttendanceHistory(actor *models.User, studentID string, period *models.AttendancePeriod, limit, offset int) ([]models.Attendance, int64, error)
+	CreateManualAttendance(actor *models.User, req *models.ManualAttendanceRequest) (*models.Attendance, error)
+	UpdateAttendance(actor *models.User, attendanceID string, req *models.UpdateAttendanceRequest) (*models.Attendance, error)
+	VoidAttendance(actor *models.User, attendanceID string, reason string) (*models.Attendance, error)
+	ApplyCorrection(actor *models.User, correction *AttendanceCorrection) (*models.Attendance, error)
+}
+
+// AttendanceCorrection adalah perubahan record absensi oleh guru/admin. Input manual,
+// edit, void, dan persetujuan pengajuan koreksi siswa semuanya lewat ApplyCorrection.
+type AttendanceCorrection struct {
+	AttendanceID *primitive.ObjectID // record yang diubah, jika nil dicari dari StudentID + Date
+	StudentID    primitive.ObjectID
+	Date         time.Time
+	Status       string
+	CheckIn      *time.Time
+	CheckOut     *time.Time
+	Void         bool
+	Reason       string
+	Source       string
+	RequestID    *primitive.ObjectID
 }
 
 func NewAttendanceService(db *mongo.Database, cfg *config.Config) AttendanceServiceInterface {
 	return &AttendanceService{
-		db:     db,
-		ctx:    context.Background(),
-		config: cfg,
+		db:       db,
+		ctx:      context.Background(),
+		config:   cfg,
+		events:   EventHub(db),
+		webhooks: NewWebhookService(db, cfg),
+		notifier: NewNotificationService(db, cfg),
 	}
 }
 
@@ -46,53 +88,70 @@ func (s *AttendanceService) CheckIn(userID string, req *models.AttendanceRequest
 		return nil, errors.New("invalid user ID")
 	}
 
+	if err := s.checkWindow(ActionCheckIn, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// scan QR kiosk juga membawa lokasi GPS HP siswa, jadi tetap dicek
 	if !s.IsValidLocation(req.Latitude, req.Longitude) {
 		return nil, errors.New("location is outside school area")
 	}
 
-	today := time.Now().UTC()
-	startOfDay := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	today := s.config.Today()
 
 	collection := s.db.Collection("attendances")
 	var existingAttendance models.Attendance
 	err = collection.FindOne(s.ctx, bson.M{
 		"user_id": objectID,
-		"date": bson.M{
-			"$gte": startOfDay,
-			"$lt":  endOfDay,
-		},
+		"date":    dayRange(today),
 	}).Decode(&existingAttendance)
 
 	if err == nil {
+		if existingAttendance.Voided {
+			return nil, errors.New("today's attendance was voided, contact your homeroom teacher")
+		}
+		if existingAttendance.Status == models.StatusSick || existingAttendance.Status == models.StatusExcused {
+			return nil, errors.New("you have approved leave for today, contact your homeroom teacher")
+		}
 		return nil, errors.New("already checked in today")
 	}
 
 	now := time.Now().UTC()
-	status := s.DetermineStatus(now)
+	status, minutesLate := s.evaluateArrival(now)
 
 	attendance := models.Attendance{
-		UserID:  objectID,
-		Date:    startOfDay,
-		CheckIn: &now,
-		Status:  status,
-		Location: models.Location{
-			Latitude:  req.Latitude,
-			Longitude: req.Longitude,
-			Address:   req.Address,
-		},
+		UserID:    objectID,
+		Date:      today,
+		DateKey:   dateKey(today),
+		TermID:    termIDForDate(s.ctx, s.db, today),
+		CheckIn:   &now,
+		Status:    status,
+		Location:  req.ToLocation(),
+		DeviceID:  req.DeviceID,
+		Flagged:   isFlagged(req.Risk),
+		Risk:      req.Risk,
 		CreatedAt: now,
 		UpdatedAt: now,
+
+		MinutesLate:        minutesLate,
+		VerificationMethod: req.VerificationMethod(),
+		KioskID:            req.KioskID,
+		CheckInSelfie:      req.Selfie,
 	}
 
+	// unique index user_id + date_key menolak request ganda yang lolos FindOne di atas
 	result, err := collection.InsertOne(s.ctx, attendance)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("already checked in today")
+		}
 		log.Printf("Error creating attendance: %v", err)
 		return nil, errors.New("failed to create attendance")
 	}
 
 	attendance.ID = result.InsertedID.(primitive.ObjectID)
 	log.Printf("Check in successful for user %s at %s", userID, now.Format("15:04:05"))
+	s.publishEvent(models.EventCheckIn, &attendance)
 	
 	return &attendance, nil
 }
@@ -103,53 +162,85 @@ func (s *AttendanceService) CheckOut(userID string, req *models.AttendanceReques
 		return nil, errors.New("invalid user ID")
 	}
 
+	if err := s.checkWindow(ActionCheckOut, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// scan QR kiosk juga membawa lokasi GPS HP siswa, jadi tetap dicek
 	if !s.IsValidLocation(req.Latitude, req.Longitude) {
 		return nil, errors.New("location is outside school area")
 	}
 
-	today := time.Now().UTC()
-	startOfDay := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	today := s.config.Today()
 
 	collection := s.db.Collection("attendances")
 	var attendance models.Attendance
 	err = collection.FindOne(s.ctx, bson.M{
 		"user_id": objectID,
-		"date": bson.M{
-			"$gte": startOfDay,
-			"$lt":  endOfDay,
-		},
+		"date":    dayRange(today),
 	}).Decode(&attendance)
 
 	if err != nil {
 		return nil, errors.New("no check in record found for today")
 	}
 
+	if attendance.Voided {
+		return nil, errors.New("today's attendance was voided, contact your homeroom teacher")
+	}
+
 	if attendance.CheckOut != nil {
 		return nil, errors.New("already checked out today")
 	}
 
 	now := time.Now().UTC()
-	_, err = collection.UpdateOne(
+	departure, minutesEarly := s.evaluateDeparture(attendance.Date, now)
+	update := bson.M{
+		"check_out":        now,
+		"check_out_method": req.VerificationMethod(),
+		"departure_status": departure,
+		"minutes_early":    minutesEarly,
+		"updated_at":       now,
+	}
+	if req.KioskID != nil {
+		update["check_out_kiosk_id"] = req.KioskID
+	}
+	if req.Selfie != nil {
+		update["check_out_selfie"] = req.Selfie
+	}
+	if req.Risk != nil {
+		update["check_out_risk"] = req.Risk
+		if isFlagged(req.Risk) {
+			update["flagged"] = true
+			attendance.Flagged = true
+		}
+		attendance.CheckOutRisk = req.Risk
+	}
+
+	// filter check_out kosong supaya dua request check out bersamaan hanya satu yang menang
+	result, err := collection.UpdateOne(
 		s.ctx,
-		bson.M{"_id": attendance.ID},
-		bson.M{
-			"$set": bson.M{
-				"check_out":  now,
-				"updated_at": now,
-			},
-		},
+		bson.M{"_id": attendance.ID, "check_out": nil, "voided": bson.M{"$ne": true}},
+		bson.M{"$set": update},
 	)
 
 	if err != nil {
 		log.Printf("Error updating attendance: %v", err)
 		return nil, errors.New("failed to update attendance")
 	}
+	if result.MatchedCount == 0 {
+		return nil, errors.New("already checked out today")
+	}
 
 	attendance.CheckOut = &now
+	attendance.CheckOutMethod = req.VerificationMethod()
+	attendance.DepartureStatus = departure
+	attendance.MinutesEarly = minutesEarly
+	attendance.CheckOutKioskID = req.KioskID
+	attendance.CheckOutSelfie = req.Selfie
 	attendance.UpdatedAt = now
 
 	log.Printf("Check out successful for user %s at %s", userID, now.Format("15:04:05"))
+	s.publishEvent(models.EventCheckOut, &attendance)
 	return &attendance, nil
 }
 
@@ -159,18 +250,13 @@ func (s *AttendanceService) GetTodayAttendance(userID string) (*models.Attendanc
 		return nil, errors.New("invalid user ID")
 	}
 
-	today := time.Now().UTC()
-	startOfDay := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
-	endOfDay := startOfDay.Add(24 * time.Hour)
+	today := s.config.Today()
 
 	collection := s.db.Collection("attendances")
 	var attendance models.Attendance
 	err = collection.FindOne(s.ctx, bson.M{
 		"user_id": objectID,
-		"date": bson.M{
-			"$gte": startOfDay,
-			"$lt":  endOfDay,
-		},
+		"date":    dayRange(today),
 	}).Decode(&attendance)
 
 	if err != nil {
@@ -183,22 +269,23 @@ func (s *AttendanceService) GetTodayAttendance(userID string) (*models.Attendanc
 	return &attendance, nil
 }
 
-func (s *AttendanceService) GetAttendanceHistory(userID string, limit, offset int) ([]models.Attendance, int64, error) {
+func (s *AttendanceService) GetAttendanceHistory(userID string, period *models.AttendancePeriod, limit, offset int) ([]models.Attendance, int64, error) {
 	objectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, 0, errors.New("invalid user ID")
 	}
 
 	collection := s.db.Collection("attendances")
+	filter := periodFilter(bson.M{"user_id": objectID}, period)
 	
-	total, err := collection.CountDocuments(s.ctx, bson.M{"user_id": objectID})
+	total, err := collection.CountDocuments(s.ctx, filter)
 	if err != nil {
 		return nil, 0, errors.New("failed to count attendance records")
 	}
 
 	cursor, err := collection.Find(
 		s.ctx,
-		bson.M{"user_id": objectID},
+		filter,
 		options.Find().SetSort(bson.D{{Key: "date", Value: -1}}).SetSkip(int64(offset)).SetLimit(int64(limit)),
 	)
 	if err != nil {
@@ -214,7 +301,7 @@ func (s *AttendanceService) GetAttendanceHistory(userID string, limit, offset in
 	return attendances, total, nil
 }
 
-func (s *AttendanceService) GetAttendanceStats(userID string) (*models.AttendanceStats, error) {
+func (s *AttendanceService) GetAttendanceStats(userID string, period *models.AttendancePeriod) (*models.AttendanceStats, error) {
 	objectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, errors.New("invalid user ID")
@@ -223,11 +310,8 @@ func (s *AttendanceService) GetAttendanceStats(userID string) (*models.Attendanc
 	collection := s.db.Collection("attendances")
 	
 	pipeline := []bson.M{
-		{"$match": bson.M{"user_id": objectID}},
-		{"$group": bson.M{
-			"_id": "$status",
-			"count": bson.M{"$sum": 1},
-		}},
+		{"$match": periodFilter(bson.M{"user_id": objectID, "voided": bson.M{"$ne": true}}, period)},
+		{"$group": attendanceCountGroup(nil, s.config.Today())},
 	}
 
 	cursor, err := collection.Aggregate(s.ctx, pipeline)
@@ -236,31 +320,30 @@ func (s *AttendanceService) GetAttendanceStats(userID string) (*models.Attendanc
 	}
 	defer cursor.Close(s.ctx)
 
-	var results []bson.M
+	var results []attendanceCounts
 	if err = cursor.All(s.ctx, &results); err != nil {
 		return nil, errors.New("failed to decode stats")
 	}
 
-	stats := &models.AttendanceStats{}
-	var totalDays int
-
-	for _, result := range results {
-		status := result["_id"].(string)
-		count := int(result["count"].(int32))
-		totalDays += count
-
-		switch status {
-		case "present":
-			stats.TotalPresent = count
-		case "late":
-			stats.TotalLate = count
-		case "absent":
-			stats.TotalAbsent = count
-		}
+	stats := &models.AttendanceStats{Period: period}
+	if len(results) == 0 {
+		return stats, nil
 	}
 
-	if totalDays > 0 {
-		stats.Percentage = float64(stats.TotalPresent) / float64(totalDays) * 100
+	counts := results[0]
+	stats.TotalPresent = counts.Present
+	stats.TotalLate = counts.Late
+	stats.TotalVeryLate = counts.VeryLate
+	stats.TotalAbsent = counts.Absent
+	stats.TotalSick = counts.Sick
+	stats.TotalExcused = counts.Excused
+	stats.TotalEarlyLeave = counts.EarlyLeave
+	stats.TotalNoCheckout = counts.NoCheckout
+	stats.TotalMinutesLate = counts.MinutesLate
+	stats.TotalMinutesEarly = counts.MinutesEarly
+
+	if counts.Recorded > 0 {
+		stats.Percentage = float64(stats.TotalPresent) / float64(counts.Recorded) * 100
 	}
 
 	return stats, nil
@@ -272,17 +355,11 @@ func (s *AttendanceService) GetAttendanceByDate(userID string, date time.Time) (
 		return nil, errors.New("invalid user ID")
 	}
 
-	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
-	endOfDay := startOfDay.Add(24 * time.Hour)
-
 	collection := s.db.Collection("attendances")
 	var attendance models.Attendance
 	err = collection.FindOne(s.ctx, bson.M{
 		"user_id": objectID,
-		"date": bson.M{
-			"$gte": startOfDay,
-			"$lt":  endOfDay,
-		},
+		"date":    dayRange(date),
 	}).Decode(&attendance)
 
 	if err != nil {
@@ -306,19 +383,421 @@ func (s *AttendanceService) calculateDistance(lat1, lng1, lat2, lng2 float64) fl
 }
 
 func (s *AttendanceService) DetermineStatus(checkInTime time.Time) string {
+	status, _ := s.evaluateArrival(checkInTime)
+	return status
+}
+
+// evaluateArrival: sampai jam masuk + LateGraceMinutes present, sampai jam masuk +
+// LateThreshold late, setelahnya very_late. Siswa yang datang tetap dihitung hadir,
+// status absent hanya untuk yang tidak datang.
+func (s *AttendanceService) evaluateArrival(checkIn time.Time) (string, int) {
 	startHour, startMinute, _, _ := s.config.GetSchoolHours()
-	lateThreshold := s.config.GetLateThreshold()
-	
-	loc, _ := time.LoadLocation("Asia/Jakarta")
-	localTime := checkInTime.In(loc)
-	
+
+	loc := s.config.Location()
+	localTime := checkIn.In(loc)
 	schoolStartTime := time.Date(localTime.Year(), localTime.Month(), localTime.Day(), startHour, startMinute, 0, 0, loc)
-	
-	if localTime.Before(schoolStartTime) || localTime.Equal(schoolStartTime) {
-		return "present"
-	} else if localTime.Before(schoolStartTime.Add(time.Duration(lateThreshold) * time.Minute)) {
-		return "late"
+
+	minutesLate := int(localTime.Sub(schoolStartTime).Minutes())
+	switch {
+	case minutesLate <= s.config.LateGraceMinutes:
+		return models.StatusPresent, 0
+	case minutesLate <= s.config.GetLateThreshold():
+		return models.StatusLate, minutesLate
+	default:
+		return models.StatusVeryLate, minutesLate
+	}
+}
+
+// evaluateDeparture menilai jam pulang terhadap jam pulang sekolah pada tanggal absensi
+func (s *AttendanceService) evaluateDeparture(date, checkOut time.Time) (string, int) {
+	_, _, endHour, endMinute := s.config.GetSchoolHours()
+
+	schoolEnd := s.config.DayStart(date).Add(time.Duration(endHour)*time.Hour + time.Duration(endMinute)*time.Minute)
+	minutesEarly := int(schoolEnd.Sub(checkOut).Minutes())
+	if minutesEarly > s.config.EarlyLeaveGraceMinutes {
+		return models.DepartureEarlyLeave, minutesEarly
+	}
+	return models.DepartureOnTime, 0
+}
+
+func (s *AttendanceService) GetAttendance(actor *models.User, attendanceID string) (*models.Attendance, error) {
+	objectID, err := primitive.ObjectIDFromHex(attendanceID)
+	if err != nil {
+		return nil, errors.New("invalid attendance ID")
+	}
+
+	attendance, err := s.findAttendance(bson.M{"_id": objectID})
+	if err != nil {
+		return nil, err
+	}
+	if attendance == nil {
+		return nil, errors.New("attendance record not found")
+	}
+
+	if _, err := s.manageableStudent(actor, attendance.UserID); err != nil {
+		return nil, err
+	}
+
+	return attendance, nil
+}
+
+func (s *AttendanceService) GetStudentAttendanceHistory(actor *models.User, studentID string, period *models.AttendancePeriod, limit, offset int) ([]models.Attendance, int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil, 0, errors.New("invalid student ID")
+	}
+
+	if _, err := s.manageableStudent(actor, objectID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.GetAttendanceHistory(studentID, period, limit, offset)
+}
+
+func (s *AttendanceService) CreateManualAttendance(actor *models.User, req *models.ManualAttendanceRequest) (*models.Attendance, error) {
+	studentID, err := primitive.ObjectIDFromHex(req.StudentID)
+	if err != nil {
+		return nil, errors.New("invalid student ID")
+	}
+
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return nil, errors.New("invalid date format, use YYYY-MM-DD")
+	}
+
+	existing, err := s.findAttendance(bson.M{"user_id": studentID, "date": dayRange(date)})
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && !existing.Voided {
+		return nil, errors.New("attendance record already exists for this date, edit it instead")
+	}
+
+	return s.ApplyCorrection(actor, &AttendanceCorrection{
+		StudentID: studentID,
+		Date:      date,
+		Status:    req.Status,
+		CheckIn:   req.CheckIn,
+		CheckOut:  req.CheckOut,
+		Reason:    req.Reason,
+		Source:    models.CorrectionSourceManual,
+	})
+}
+
+func (s *AttendanceService) UpdateAttendance(actor *models.User, attendanceID string, req *models.UpdateAttendanceRequest) (*models.Attendance, error) {
+	objectID, err := primitive.ObjectIDFromHex(attendanceID)
+	if err != nil {
+		return nil, errors.New("invalid attendance ID")
+	}
+
+	if req.Status == "" && req.CheckIn == nil && req.CheckOut == nil {
+		return nil, errors.New("nothing to update")
+	}
+
+	return s.ApplyCorrection(actor, &AttendanceCorrection{
+		AttendanceID: &objectID,
+		Status:       req.Status,
+		CheckIn:      req.CheckIn,
+		CheckOut:     req.CheckOut,
+		Reason:       req.Reason,
+		Source:       models.CorrectionSourceManual,
+	})
+}
+
+func (s *AttendanceService) VoidAttendance(actor *models.User, attendanceID string, reason string) (*models.Attendance, error) {
+	objectID, err := primitive.ObjectIDFromHex(attendanceID)
+	if err != nil {
+		return nil, errors.New("invalid attendance ID")
+	}
+
+	return s.ApplyCorrection(actor, &AttendanceCorrection{
+		AttendanceID: &objectID,
+		Void:         true,
+		Reason:       reason,
+		Source:       models.CorrectionSourceManual,
+	})
+}
+
+func (s *AttendanceService) ApplyCorrection(actor *models.User, correction *AttendanceCorrection) (*models.Attendance, error) {
+	reason := utils.SanitizeInput(correction.Reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	var existing *models.Attendance
+	var err error
+	if correction.AttendanceID != nil {
+		existing, err = s.findAttendance(bson.M{"_id": *correction.AttendanceID})
+		if err == nil && existing == nil {
+			return nil, errors.New("attendance record not found")
+		}
 	} else {
-		return "absent"
+		existing, err = s.findAttendance(bson.M{"user_id": correction.StudentID, "date": dayRange(correction.Date)})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	studentID := correction.StudentID
+	if existing != nil {
+		studentID = existing.UserID
+	}
+	if _, err := s.manageableStudent(actor, studentID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	revision := models.AttendanceRevision{
+		Reason:        reason,
+		Source:        correction.Source,
+		RequestID:     correction.RequestID,
+		ChangedBy:     actor.ID,
+		ChangedByName: actor.Name,
+		ChangedByRole: actor.GetRole(),
+		ChangedAt:     now,
+	}
+
+	collection := s.db.Collection("attendances")
+
+	if existing == nil {
+		if correction.Void {
+			return nil, errors.New("attendance record not found")
+		}
+
+		date := startOfDayUTC(correction.Date)
+		status := correction.Status
+		if status == "" && correction.CheckIn != nil {
+			status = s.DetermineStatus(*correction.CheckIn)
+		}
+		if status == "" {
+			return nil, errors.New("status is required")
+		}
+		if err := validateCorrectionTimes(s.config, date, correction.CheckIn, correction.CheckOut); err != nil {
+			return nil, err
+		}
+
+		attendance := models.Attendance{
+			UserID:    studentID,
+			Date:      date,
+			DateKey:   dateKey(date),
+			TermID:    termIDForDate(s.ctx, s.db, date),
+			CheckIn:   utcTime(correction.CheckIn),
+			CheckOut:  utcTime(correction.CheckOut),
+			Status:    status,
+			CreatedAt: now,
+			UpdatedAt: now,
+
+			VerificationMethod: models.VerificationManual,
+		}
+		s.applyTimeRules(&attendance)
+		revision.Action = models.RevisionCreate
+		revision.After = attendance.Snapshot()
+		attendance.History = []models.AttendanceRevision{revision}
+
+		result, err := collection.InsertOne(s.ctx, attendance)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, errors.New("attendance record already exists for this date")
+			}
+			log.Printf("Error creating manual attendance: %v", err)
+			return nil, errors.New("failed to create attendance")
+		}
+		attendance.ID = result.InsertedID.(primitive.ObjectID)
+
+		log.Printf("Attendance %s created for user %s by %s: %s", attendance.ID.Hex(), studentID.Hex(), actor.Email, reason)
+		s.publishEvent(models.EventCorrection, &attendance)
+		return &attendance, nil
+	}
+
+	updated := *existing
+	revision.Before = existing.Snapshot()
+	update := bson.M{"updated_at": now}
+
+	switch {
+	case correction.Void:
+		if existing.Voided {
+			return nil, errors.New("attendance record is already voided")
+		}
+		revision.Action = models.RevisionVoid
+		updated.Voided = true
+		updated.VoidedAt = &now
+		update["voided"] = true
+		update["voided_at"] = now
+
+	case existing.Voided && correction.AttendanceID != nil:
+		return nil, errors.New("attendance record is voided, create a new record for this date instead")
+
+	default:
+		revision.Action = models.RevisionUpdate
+		if existing.Voided {
+			// input ulang untuk tanggal yang sudah di-void menghidupkan record lama
+			revision.Action = models.RevisionRestore
+			updated.Voided = false
+			updated.VoidedAt = nil
+			update["voided"] = false
+			update["voided_at"] = nil
+		}
+
+		if correction.Status != "" {
+			updated.Status = correction.Status
+		}
+		if correction.CheckIn != nil {
+			updated.CheckIn = utcTime(correction.CheckIn)
+		}
+		if correction.CheckOut != nil {
+			updated.CheckOut = utcTime(correction.CheckOut)
+		}
+		if err := validateCorrectionTimes(s.config, existing.Date, updated.CheckIn, updated.CheckOut); err != nil {
+			return nil, err
+		}
+
+		s.applyTimeRules(&updated)
+
+		update["status"] = updated.Status
+		update["check_in"] = updated.CheckIn
+		update["check_out"] = updated.CheckOut
+		update["minutes_late"] = updated.MinutesLate
+		update["departure_status"] = updated.DepartureStatus
+		update["minutes_early"] = updated.MinutesEarly
+	}
+
+	updated.UpdatedAt = now
+	revision.After = updated.Snapshot()
+	updated.History = append(updated.History, revision)
+
+	// record hanya diubah jika belum berubah sejak dibaca (misalnya siswa check out di antaranya),
+	// supaya jam yang baru masuk tidak tertimpa dan revision.Before tetap benar
+	filter := bson.M{"_id": existing.ID, "updated_at": existing.UpdatedAt}
+	if existing.UpdatedAt.IsZero() {
+		filter["updated_at"] = bson.M{"$in": bson.A{nil, existing.UpdatedAt}}
+	}
+	result, err := collection.UpdateOne(
+		s.ctx,
+		filter,
+		bson.M{
+			"$set":  update,
+			"$push": bson.M{"history": revision},
+		},
+	)
+	if err != nil {
+		log.Printf("Error correcting attendance: %v", err)
+		return nil, errors.New("failed to update attendance")
+	}
+	if result.MatchedCount == 0 {
+		return nil, errAttendanceChanged
+	}
+
+	log.Printf("Attendance %s %s by %s: %s", existing.ID.Hex(), revision.Action, actor.Email, reason)
+	s.publishEvent(models.EventCorrection, &updated)
+	return &updated, nil
+}
+
+// applyTimeRules menghitung ulang menit terlambat dan status pulang setelah jam
+// masuk/pulang diubah guru. Status kedatangan tetap mengikuti input guru.
+func (s *AttendanceService) applyTimeRules(attendance *models.Attendance) {
+	attendance.MinutesLate = 0
+	if attendance.CheckIn != nil && (attendance.Status == models.StatusLate || attendance.Status == models.StatusVeryLate) {
+		_, attendance.MinutesLate = s.evaluateArrival(*attendance.CheckIn)
 	}
+	if attendance.CheckOut != nil {
+		attendance.DepartureStatus, attendance.MinutesEarly = s.evaluateDeparture(attendance.Date, *attendance.CheckOut)
+	}
+}
+
+func (s *AttendanceService) findAttendance(filter bson.M) (*models.Attendance, error) {
+	var attendance models.Attendance
+	err := s.db.Collection("attendances").FindOne(s.ctx, filter).Decode(&attendance)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, errors.New("failed to fetch attendance")
+	}
+	return &attendance, nil
+}
+
+// manageableStudent memuat siswa dan memastikan actor adalah wali kelasnya (atau admin)
+func (s *AttendanceService) manageableStudent(actor *models.User, studentID primitive.ObjectID) (*models.User, error) {
+	var student models.User
+	if err := s.db.Collection("users").FindOne(s.ctx, bson.M{"_id": studentID}).Decode(&student); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("student not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	if !CanManageStudent(actor, &student) {
+		return nil, errors.New("you are not the homeroom teacher of this student")
+	}
+
+	return &student, nil
+}
+
+func validateCorrectionTimes(cfg *config.Config, date time.Time, checkIn, checkOut *time.Time) error {
+	if checkIn != nil && !cfg.SchoolDate(*checkIn).Equal(startOfDayUTC(date)) {
+		return errors.New("check in time must be on the attendance date")
+	}
+	if checkOut != nil && checkIn == nil {
+		return errors.New("check out requires a check in time")
+	}
+	if checkIn != nil && checkOut != nil && !checkOut.After(*checkIn) {
+		return errors.New("check out time must be after check in time")
+	}
+	return nil
+}
+
+// periodFilter menambahkan batas semester atau rentang tanggal ke filter absensi
+func periodFilter(filter bson.M, period *models.AttendancePeriod) bson.M {
+	if period == nil {
+		return filter
+	}
+
+	if period.TermID != nil {
+		filter["term_id"] = *period.TermID
+	}
+
+	date := bson.M{}
+	if period.From != nil {
+		date["$gte"] = startOfDayUTC(*period.From)
+	}
+	if period.To != nil {
+		date["$lt"] = startOfDayUTC(*period.To).Add(24 * time.Hour)
+	}
+	if len(date) > 0 {
+		filter["date"] = date
+	}
+
+	return filter
+}
+
+// startOfDayUTC menormalkan tanggal kalender (hasil parsing YYYY-MM-DD atau field date)
+// ke tengah malam UTC. Untuk waktu kejadian seperti time.Now() pakai config.SchoolDate.
+func startOfDayUTC(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func dayRange(t time.Time) bson.M {
+	start := startOfDayUTC(t)
+	return bson.M{
+		"$gte": start,
+		"$lt":  start.Add(24 * time.Hour),
+	}
+}
+
+// dateKey kunci tanggal sekolah YYYY-MM-DD dari tanggal absensi
+func dateKey(date time.Time) string {
+	return startOfDayUTC(date).Format("2006-01-02")
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+func isFlagged(risk *models.RiskAssessment) bool {
+	return risk != nil && risk.Decision == security.DecisionFlag
 }
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/realtime"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	eventHubOnce sync.Once
+	eventHub     *realtime.Hub
+)
+
+// EventHub mengembalikan hub event absensi yang dipakai bersama oleh seluruh service
+// di proses ini (AttendanceService dibuat di banyak controller)
+func EventHub(db *mongo.Database) *realtime.Hub {
+	eventHubOnce.Do(func() {
+		eventHub = realtime.NewHub(db)
+	})
+	return eventHub
+}
+
+// PublishEvent dipakai service lain yang menulis record harian (misalnya absensi per jam)
+func (s *AttendanceService) PublishEvent(eventType string, attendance *models.Attendance) {
+	s.publishEvent(eventType, attendance)
+}
+
+// publishEvent dijalankan di background supaya check in tidak menunggu feed real-time
+// maupun antrean webhook dan notifikasi
+func (s *AttendanceService) publishEvent(eventType string, attendance *models.Attendance) {
+	record := models.EventAttendance{
+		ID:                 attendance.ID,
+		Date:               attendance.Date,
+		Status:             attendance.Status,
+		DepartureStatus:    attendance.DepartureStatus,
+		CheckIn:            attendance.CheckIn,
+		CheckOut:           attendance.CheckOut,
+		VerificationMethod: attendance.VerificationMethod,
+		MinutesLate:        attendance.MinutesLate,
+		Flagged:            attendance.Flagged,
+		Voided:             attendance.Voided,
+		SubmittedLate:      attendance.SubmittedLate,
+	}
+	userID := attendance.UserID
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		var student models.User
+		err := s.db.Collection("users").FindOne(ctx, bson.M{"_id": userID},
+			options.FindOne().SetProjection(bson.M{"name": 1, "nis": 1, "class_id": 1})).Decode(&student)
+		if err != nil {
+			log.Printf("Warning: failed to load student for attendance event: %v", err)
+		}
+
+		event := &models.AttendanceEvent{
+			Type:    eventType,
+			UserID:  userID,
+			ClassID: student.ClassID,
+			Student: models.EventStudent{Name: student.Name, NIS: student.NIS},
+			Record:  record,
+		}
+		s.events.Publish(event)
+		s.webhooks.Enqueue(eventType, event.ID.Hex(), event)
+		s.notifier.NotifyAttendance(event)
+	}()
+}
+
+// ResolveEventScope menentukan event yang boleh diikuti actor: siswa hanya absensinya
+// sendiri, wali kelas kelas perwaliannya, admin kelas mana pun atau seluruh sekolah.
+// Tanpa parameter, admin mendapat seluruh sekolah dan guru kelas perwaliannya.
+func ResolveEventScope(actor *models.User, classID, scope string) (*realtime.Scope, error) {
+	if !actor.HasRole(models.RoleTeacher, models.RoleAdmin) {
+		if classID != "" || (scope != "" && scope != models.EventScopeSelf) {
+			return nil, errors.New("students can only follow their own attendance")
+		}
+		return &realtime.Scope{UserID: &actor.ID}, nil
+	}
+
+	if classID != "" {
+		objectID, err := primitive.ObjectIDFromHex(classID)
+		if err != nil {
+			return nil, errors.New("invalid class ID")
+		}
+		if !actor.HasRole(models.RoleAdmin) && (actor.ClassID == nil || *actor.ClassID != objectID) {
+			return nil, errors.New("you are not the homeroom teacher of this class")
+		}
+		return &realtime.Scope{ClassID: &objectID}, nil
+	}
+
+	switch scope {
+	case models.EventScopeSelf:
+		return &realtime.Scope{UserID: &actor.ID}, nil
+	case models.EventScopeSchool:
+		if !actor.HasRole(models.RoleAdmin) {
+			return nil, errors.New("only admin can follow the whole school")
+		}
+		return &realtime.Scope{}, nil
+	case "", models.EventScopeClass:
+		if actor.HasRole(models.RoleAdmin) && scope == "" {
+			return &realtime.Scope{}, nil
+		}
+		if actor.ClassID == nil {
+			return nil, errors.New("no homeroom class assigned, pass class_id")
+		}
+		return &realtime.Scope{ClassID: actor.ClassID}, nil
+	default:
+		return nil, errors.New("invalid scope, use self, class or school")
+	}
+}
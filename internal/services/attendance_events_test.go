@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/realtime"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResolveEventScope(t *testing.T) {
+	homeroom, otherClass := primitive.NewObjectID(), primitive.NewObjectID()
+	student := &models.User{ID: primitive.NewObjectID(), Role: models.RoleStudent, ClassID: &homeroom}
+	teacher := &models.User{ID: primitive.NewObjectID(), Role: models.RoleTeacher, ClassID: &homeroom}
+	teacherWithoutClass := &models.User{ID: primitive.NewObjectID(), Role: models.RoleTeacher}
+	admin := &models.User{ID: primitive.NewObjectID(), Role: models.RoleAdmin}
+
+	tests := []struct {
+		name    string
+		actor   *models.User
+		classID string
+		scope   string
+		want    realtime.Scope
+		wantErr string
+	}{
+		{name: "student default", actor: student, want: realtime.Scope{UserID: &student.ID}},
+		{name: "student self", actor: student, scope: models.EventScopeSelf, want: realtime.Scope{UserID: &student.ID}},
+		{name: "student class", actor: student, scope: models.EventScopeClass, wantErr: "students can only follow their own attendance"},
+		{name: "student class id", actor: student, classID: homeroom.Hex(), wantErr: "students can only follow their own attendance"},
+
+		{name: "teacher default", actor: teacher, want: realtime.Scope{ClassID: &homeroom}},
+		{name: "teacher own class id", actor: teacher, classID: homeroom.Hex(), want: realtime.Scope{ClassID: &homeroom}},
+		{name: "teacher other class", actor: teacher, classID: otherClass.Hex(), wantErr: "you are not the homeroom teacher of this class"},
+		{name: "teacher self", actor: teacher, scope: models.EventScopeSelf, want: realtime.Scope{UserID: &teacher.ID}},
+		{name: "teacher school", actor: teacher, scope: models.EventScopeSchool, wantErr: "only admin can follow the whole school"},
+		{name: "teacher without class", actor: teacherWithoutClass, wantErr: "no homeroom class assigned, pass class_id"},
+		{name: "invalid class id", actor: teacher, classID: "abc", wantErr: "invalid class ID"},
+
+		{name: "admin default", actor: admin, want: realtime.Scope{}},
+		{name: "admin school", actor: admin, scope: models.EventScopeSchool, want: realtime.Scope{}},
+		{name: "admin any class", actor: admin, classID: otherClass.Hex(), want: realtime.Scope{ClassID: &otherClass}},
+		{name: "admin class scope without class", actor: admin, scope: models.EventScopeClass, wantErr: "no homeroom class assigned, pass class_id"},
+		{name: "unknown scope", actor: admin, scope: "everyone", wantErr: "invalid scope, use self, class or school"},
+	}
+
+	for _, tt := range tests {
+		got, err := ResolveEventScope(tt.actor, tt.classID, tt.scope)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if !sameObjectID(got.UserID, tt.want.UserID) || !sameObjectID(got.ClassID, tt.want.ClassID) {
+			t.Errorf("%s: scope = user %v class %v, want user %v class %v", tt.name, got.UserID, got.ClassID, tt.want.UserID, tt.want.ClassID)
+		}
+	}
+}
+
+func sameObjectID(a, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
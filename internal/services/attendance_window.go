@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ActionCheckIn  = "checkin"
+	ActionCheckOut = "checkout"
+
+	autoCheckoutInterval = 10 * time.Minute
+)
+
+// AttendanceWindowError dikembalikan saat check in/out di luar jendela waktu,
+// isinya ikut dikirim ke client supaya bisa menampilkan jam buka dan jam server
+type AttendanceWindowError struct {
+	Action     string                  `json:"action"`
+	Window     config.AttendanceWindow `json:"window"`
+	ServerTime time.Time               `json:"server_time"`
+	Timezone   string                  `json:"timezone"`
+	Message    string                  `json:"-"`
+}
+
+func (e *AttendanceWindowError) Error() string {
+	return e.Message
+}
+
+// checkWindow memastikan waktu now berada di jendela check in/out hari itu
+func (s *AttendanceService) checkWindow(action string, now time.Time) error {
+	local := now.In(s.config.Location())
+	window := s.config.WindowFor(local.Weekday())
+	clock := local.Format("15:04")
+
+	opens, closes := window.CheckInOpen, window.CheckInClose
+	if action == ActionCheckOut {
+		opens, closes = window.CheckOutOpen, window.CheckOutClose
+	}
+	if clock >= opens && clock < closes {
+		return nil
+	}
+
+	verb := "check in"
+	if action == ActionCheckOut {
+		verb = "check out"
+	}
+	return &AttendanceWindowError{
+		Action:     action,
+		Window:     window,
+		ServerTime: local,
+		Timezone:   local.Location().String(),
+		Message:    fmt.Sprintf("%s is only allowed between %s and %s", verb, opens, closes),
+	}
+}
+
+// StartAutoCheckout menjalankan CloseOpenAttendances dan MarkAbsentees secara berkala
+// sampai ctx selesai
+func (s *AttendanceService) StartAutoCheckout(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(autoCheckoutInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.CloseOpenAttendances(time.Now()); err != nil {
+				log.Printf("Error closing open attendances: %v", err)
+			}
+			if _, err := s.MarkAbsentees(time.Now()); err != nil {
+				log.Printf("Error marking absent students: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// CloseOpenAttendances menandai record yang sudah check in tapi tidak check out
+// sebagai no_checkout: semua hari sebelumnya, dan hari ini jika jendela check out sudah tutup
+func (s *AttendanceService) CloseOpenAttendances(now time.Time) (int64, error) {
+	today := s.config.SchoolDate(now)
+	local := now.In(s.config.Location())
+
+	dateFilter := bson.M{"$lt": today}
+	if local.Format("15:04") >= s.config.WindowFor(local.Weekday()).CheckOutClose {
+		dateFilter = bson.M{"$lte": today}
+	}
+
+	result, err := s.db.Collection("attendances").UpdateMany(s.ctx, bson.M{
+		"date":             dateFilter,
+		"check_in":         bson.M{"$ne": nil},
+		"check_out":        nil,
+		"departure_status": bson.M{"$in": []interface{}{nil, ""}},
+		"voided":           bson.M{"$ne": true},
+	}, bson.M{"$set": bson.M{
+		"departure_status": models.DepartureNoCheckout,
+		"updated_at":       now.UTC(),
+	}})
+	if err != nil {
+		return 0, err
+	}
+
+	if result.ModifiedCount > 0 {
+		log.Printf("Auto checkout marked %d attendance records as no_checkout", result.ModifiedCount)
+	}
+	return result.ModifiedCount, nil
+}
+
+// MarkAbsentees membuat record absent untuk siswa aktif yang belum punya record hari ini
+// setelah jendela check in tutup. Hanya berjalan di hari sekolah dalam semester aktif;
+// record-nya bisa ditimpa absensi per jam pelajaran atau koreksi wali kelas.
+func (s *AttendanceService) MarkAbsentees(now time.Time) (int, error) {
+	local := now.In(s.config.Location())
+	if !utils.IsSchoolDay(local) || local.Format("15:04") < s.config.WindowFor(local.Weekday()).CheckInClose {
+		return 0, nil
+	}
+
+	today := s.config.SchoolDate(now)
+	termID := termIDForDate(s.ctx, s.db, today)
+	if termID == nil {
+		return 0, nil
+	}
+
+	recorded, err := s.db.Collection("attendances").Distinct(s.ctx, "user_id", bson.M{"date": dayRange(today)})
+	if err != nil {
+		return 0, err
+	}
+
+	filter := schoolStudentFilter()
+	filter["_id"] = bson.M{"$nin": recorded}
+	filter["created_at"] = bson.M{"$lt": s.config.DayStart(today)}
+	cursor, err := s.db.Collection("users").Find(s.ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var students []models.User
+	if err := cursor.All(s.ctx, &students); err != nil {
+		return 0, err
+	}
+
+	marked := 0
+	for _, student := range students {
+		utcNow := now.UTC()
+		attendance := models.Attendance{
+			UserID:             student.ID,
+			Date:               today,
+			DateKey:            dateKey(today),
+			TermID:             termID,
+			Status:             models.StatusAbsent,
+			VerificationMethod: models.VerificationSystem,
+			CreatedAt:          utcNow,
+			UpdatedAt:          utcNow,
+		}
+
+		result, err := s.db.Collection("attendances").InsertOne(s.ctx, attendance)
+		if err != nil {
+			if !mongo.IsDuplicateKeyError(err) {
+				log.Printf("Error marking %s absent: %v", student.ID.Hex(), err)
+			}
+			continue
+		}
+		attendance.ID = result.InsertedID.(primitive.ObjectID)
+		s.publishEvent(models.EventAbsent, &attendance)
+		marked++
+	}
+
+	if marked > 0 {
+		log.Printf("Marked %d students absent for %s", marked, dateKey(today))
+	}
+	return marked, nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"log"
+	"strings"
 	"time"
+	"ujikom-backend/internal/config"
 	"ujikom-backend/internal/models"
 	"ujikom-backend/internal/utils"
 
@@ -256,4 +258,65 @@ func (s *AuthService) GetUserStats() (map[string]interface{}, error) {
 	}
 
 	return stats, nil
-}
\ No newline at end of file
+}
+
+// BootstrapAdmins membuat akun admin untuk email di ADMIN_EMAILS yang belum terdaftar, tanpa
+// password, dan mencetak link set password sekali pakai ke log server. Akun yang sudah ada
+// tidak pernah dipromosikan: registrasi mandiri tidak memverifikasi email, jadi siapa pun
+// bisa mendaftar lebih dulu memakai alamat admin. Admin tambahan diangkat oleh admin lain
+// lewat PUT /admin/users/:id.
+func BootstrapAdmins(db *mongo.Database, cfg *config.Config) {
+	ctx := context.Background()
+	users := db.Collection("users")
+	passwordSetup := NewPasswordSetupService(db, cfg)
+
+	for _, email := range cfg.AdminEmails {
+		email = strings.TrimSpace(email)
+		if email == "" {
+			continue
+		}
+
+		var user models.User
+		err := users.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+		if err != nil && err != mongo.ErrNoDocuments {
+			log.Printf("Warning: failed to check bootstrap admin %s: %v", email, err)
+			continue
+		}
+
+		if err == mongo.ErrNoDocuments {
+			now := time.Now().UTC()
+			user = models.User{
+				ID:        primitive.NewObjectID(),
+				Name:      "Administrator",
+				Email:     email,
+				Role:      models.RoleAdmin,
+				IsActive:  true,
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			if _, err := users.InsertOne(ctx, user); err != nil {
+				// replica lain bisa membuat akun yang sama bersamaan
+				if !mongo.IsDuplicateKeyError(err) {
+					log.Printf("Warning: failed to create bootstrap admin %s: %v", email, err)
+				}
+				continue
+			}
+			log.Printf("Bootstrap admin account created: %s", email)
+		}
+
+		if !user.HasRole(models.RoleAdmin) {
+			log.Printf("Warning: %s is already registered as %s and was not promoted; ask an existing admin to change the role", email, user.GetRole())
+			continue
+		}
+
+		// link dibuat ulang setiap start selama password belum diisi
+		if user.Password == "" {
+			link, expiresAt, err := passwordSetup.Issue(user.ID)
+			if err != nil {
+				log.Printf("Warning: failed to issue setup link for bootstrap admin %s: %v", email, err)
+				continue
+			}
+			log.Printf("Set the password for admin %s before %s: %s", email, expiresAt.Format(time.RFC3339), link)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ClassService struct {
+	db  *mongo.Database
+	ctx context.Context
+}
+
+type ClassServiceInterface interface {
+	CreateMajor(req *models.CreateMajorRequest) (*models.Major, error)
+	ListMajors(includeInactive bool) ([]models.Major, error)
+	GetMajor(id string) (*models.Major, error)
+	UpdateMajor(id string, req *models.UpdateMajorRequest) (*models.Major, error)
+
+	CreateClass(req *models.CreateClassRequest) (*models.Class, error)
+	ListClasses(majorID string, grade int, includeInactive bool) ([]models.Class, error)
+	GetClass(id string) (*models.Class, error)
+	GetClassDetail(id string) (*models.ClassDetail, error)
+	UpdateClass(id string, req *models.UpdateClassRequest) (*models.Class, error)
+	AssignHomeroom(id string, req *models.AssignHomeroomRequest) (*models.Class, error)
+
+	ResolveEnrollment(classID, majorID string) (*models.Class, *models.Major, error)
+}
+
+func NewClassService(db *mongo.Database) ClassServiceInterface {
+	return &ClassService{
+		db:  db,
+		ctx: context.Background(),
+	}
+}
+
+func (s *ClassService) CreateMajor(req *models.CreateMajorRequest) (*models.Major, error) {
+	now := time.Now().UTC()
+	major := models.Major{
+		Code:      strings.ToUpper(strings.TrimSpace(req.Code)),
+		Name:      utils.SanitizeInput(req.Name),
+		IsActive:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	result, err := s.db.Collection("majors").InsertOne(s.ctx, major)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("major code already exists")
+		}
+		log.Printf("Error creating major: %v", err)
+		return nil, errors.New("failed to create major")
+	}
+	major.ID = result.InsertedID.(primitive.ObjectID)
+
+	return &major, nil
+}
+
+func (s *ClassService) ListMajors(includeInactive bool) ([]models.Major, error) {
+	filter := bson.M{}
+	if !includeInactive {
+		filter["is_active"] = true
+	}
+
+	cursor, err := s.db.Collection("majors").Find(s.ctx, filter, options.Find().SetSort(bson.D{{Key: "code", Value: 1}}))
+	if err != nil {
+		return nil, errors.New("failed to fetch majors")
+	}
+	defer cursor.Close(s.ctx)
+
+	majors := []models.Major{}
+	if err = cursor.All(s.ctx, &majors); err != nil {
+		return nil, errors.New("failed to decode majors")
+	}
+
+	return majors, nil
+}
+
+func (s *ClassService) GetMajor(id string) (*models.Major, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid major ID")
+	}
+
+	var major models.Major
+	if err := s.db.Collection("majors").FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&major); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("major not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	return &major, nil
+}
+
+func (s *ClassService) UpdateMajor(id string, req *models.UpdateMajorRequest) (*models.Major, error) {
+	major, err := s.GetMajor(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	updateDoc := bson.M{"updated_at": now}
+	if req.Name != "" {
+		updateDoc["name"] = utils.SanitizeInput(req.Name)
+	}
+	if req.IsActive != nil {
+		updateDoc["is_active"] = *req.IsActive
+	}
+
+	if _, err := s.db.Collection("majors").UpdateOne(s.ctx, bson.M{"_id": major.ID}, bson.M{"$set": updateDoc}); err != nil {
+		log.Printf("Error updating major: %v", err)
+		return nil, errors.New("failed to update major")
+	}
+
+	// nama jurusan disalin ke user, ikut diperbarui
+	if name, ok := updateDoc["name"]; ok {
+		if _, err := s.db.Collection("users").UpdateMany(s.ctx, bson.M{"major_id": major.ID}, bson.M{"$set": bson.M{
+			"jurusan":    name,
+			"updated_at": now,
+		}}); err != nil {
+			log.Printf("Error syncing major name to users: %v", err)
+		}
+	}
+
+	return s.GetMajor(id)
+}
+
+func (s *ClassService) CreateClass(req *models.CreateClassRequest) (*models.Class, error) {
+	major, err := s.GetMajor(req.MajorID)
+	if err != nil {
+		return nil, err
+	}
+	if !major.IsActive {
+		return nil, errors.New("major is not active")
+	}
+
+	now := time.Now().UTC()
+	class := models.Class{
+		Name:      utils.SanitizeInput(req.Name),
+		Grade:     req.Grade,
+		MajorID:   major.ID,
+		IsActive:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	result, err := s.db.Collection("classes").InsertOne(s.ctx, class)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("class name already exists")
+		}
+		log.Printf("Error creating class: %v", err)
+		return nil, errors.New("failed to create class")
+	}
+	class.ID = result.InsertedID.(primitive.ObjectID)
+
+	return &class, nil
+}
+
+func (s *ClassService) ListClasses(majorID string, grade int, includeInactive bool) ([]models.Class, error) {
+	filter := bson.M{}
+	if !includeInactive {
+		filter["is_active"] = true
+	}
+	if majorID != "" {
+		objectID, err := primitive.ObjectIDFromHex(majorID)
+		if err != nil {
+			return nil, errors.New("invalid major ID")
+		}
+		filter["major_id"] = objectID
+	}
+	if grade > 0 {
+		filter["grade"] = grade
+	}
+
+	cursor, err := s.db.Collection("classes").Find(
+		s.ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "grade", Value: 1}, {Key: "name", Value: 1}}),
+	)
+	if err != nil {
+		return nil, errors.New("failed to fetch classes")
+	}
+	defer cursor.Close(s.ctx)
+
+	classes := []models.Class{}
+	if err = cursor.All(s.ctx, &classes); err != nil {
+		return nil, errors.New("failed to decode classes")
+	}
+
+	return classes, nil
+}
+
+func (s *ClassService) GetClass(id string) (*models.Class, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid class ID")
+	}
+
+	return findClass(s.ctx, s.db, objectID)
+}
+
+func (s *ClassService) GetClassDetail(id string) (*models.ClassDetail, error) {
+	class, err := s.GetClass(id)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &models.ClassDetail{Class: *class}
+	if major, err := s.GetMajor(class.MajorID.Hex()); err == nil {
+		detail.Major = major
+	}
+	if class.HomeroomTeacherID != nil {
+		var teacher models.User
+		if err := s.db.Collection("users").FindOne(s.ctx, bson.M{"_id": *class.HomeroomTeacherID}).Decode(&teacher); err == nil {
+			public := teacher.ToPublic()
+			detail.HomeroomTeacher = &public
+		}
+	}
+
+	detail.StudentCount, err = s.db.Collection("users").CountDocuments(s.ctx, bson.M{
+		"class_id":  class.ID,
+		"role":      bson.M{"$nin": []string{models.RoleTeacher, models.RoleAdmin, models.RoleGuardian}},
+		"is_active": true,
+	})
+	if err != nil {
+		return nil, errors.New("failed to count students")
+	}
+
+	return detail, nil
+}
+
+func (s *ClassService) UpdateClass(id string, req *models.UpdateClassRequest) (*models.Class, error) {
+	class, err := s.GetClass(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	updateDoc := bson.M{"updated_at": now}
+	userDoc := bson.M{}
+
+	if req.Name != "" {
+		updateDoc["name"] = utils.SanitizeInput(req.Name)
+		userDoc["kelas"] = updateDoc["name"]
+	}
+	if req.Grade != 0 {
+		updateDoc["grade"] = req.Grade
+	}
+	if req.MajorID != "" {
+		major, err := s.GetMajor(req.MajorID)
+		if err != nil {
+			return nil, err
+		}
+		updateDoc["major_id"] = major.ID
+		userDoc["major_id"] = major.ID
+		userDoc["jurusan"] = major.Name
+	}
+	if req.IsActive != nil {
+		updateDoc["is_active"] = *req.IsActive
+	}
+
+	if _, err := s.db.Collection("classes").UpdateOne(s.ctx, bson.M{"_id": class.ID}, bson.M{"$set": updateDoc}); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("class name already exists")
+		}
+		log.Printf("Error updating class: %v", err)
+		return nil, errors.New("failed to update class")
+	}
+
+	if len(userDoc) > 0 {
+		userDoc["updated_at"] = now
+		if _, err := s.db.Collection("users").UpdateMany(s.ctx, bson.M{"class_id": class.ID}, bson.M{"$set": userDoc}); err != nil {
+			log.Printf("Error syncing class to users: %v", err)
+		}
+		if name, ok := userDoc["kelas"]; ok {
+			for _, collection := range []string{"timetable_periods", "lesson_sessions"} {
+				if _, err := s.db.Collection(collection).UpdateMany(s.ctx, bson.M{"class_id": class.ID}, bson.M{"$set": bson.M{"kelas": name}}); err != nil {
+					log.Printf("Error syncing class name to %s: %v", collection, err)
+				}
+			}
+		}
+	}
+
+	return s.GetClass(id)
+}
+
+// AssignHomeroom mengatur wali kelas. Kelas perwalian disimpan juga di users.class_id
+// milik guru sehingga CanManageStudent cukup membandingkan class_id.
+func (s *ClassService) AssignHomeroom(id string, req *models.AssignHomeroomRequest) (*models.Class, error) {
+	class, err := s.GetClass(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	users := s.db.Collection("users")
+
+	var teacher *models.User
+	if req.TeacherID != "" {
+		teacherID, err := primitive.ObjectIDFromHex(req.TeacherID)
+		if err != nil {
+			return nil, errors.New("invalid teacher ID")
+		}
+		var found models.User
+		if err := users.FindOne(s.ctx, bson.M{"_id": teacherID, "is_active": true}).Decode(&found); err != nil {
+			return nil, errors.New("teacher not found")
+		}
+		if !found.HasRole(models.RoleTeacher) {
+			return nil, errors.New("assigned user is not a teacher")
+		}
+		if found.ClassID != nil && *found.ClassID != class.ID {
+			return nil, errors.New("teacher is already homeroom teacher of another class")
+		}
+		teacher = &found
+	}
+
+	// lepas wali kelas lama
+	if class.HomeroomTeacherID != nil && (teacher == nil || *class.HomeroomTeacherID != teacher.ID) {
+		if _, err := users.UpdateOne(s.ctx, bson.M{"_id": *class.HomeroomTeacherID}, bson.M{
+			"$unset": bson.M{"class_id": "", "kelas": ""},
+			"$set":   bson.M{"updated_at": now},
+		}); err != nil {
+			log.Printf("Error releasing homeroom teacher: %v", err)
+			return nil, errors.New("failed to update homeroom teacher")
+		}
+	}
+
+	classUpdate := bson.M{"$set": bson.M{"updated_at": now}}
+	if teacher == nil {
+		classUpdate["$unset"] = bson.M{"homeroom_teacher_id": ""}
+	} else {
+		classUpdate["$set"].(bson.M)["homeroom_teacher_id"] = teacher.ID
+		if _, err := users.UpdateOne(s.ctx, bson.M{"_id": teacher.ID}, bson.M{"$set": bson.M{
+			"class_id":   class.ID,
+			"kelas":      class.Name,
+			"updated_at": now,
+		}}); err != nil {
+			log.Printf("Error assigning homeroom teacher: %v", err)
+			return nil, errors.New("failed to update homeroom teacher")
+		}
+	}
+
+	if _, err := s.db.Collection("classes").UpdateOne(s.ctx, bson.M{"_id": class.ID}, classUpdate); err != nil {
+		log.Printf("Error updating class homeroom: %v", err)
+		return nil, errors.New("failed to update homeroom teacher")
+	}
+
+	return s.GetClass(id)
+}
+
+// ResolveEnrollment memvalidasi class_id (dan major_id jika dikirim) dari registrasi/profil
+func (s *ClassService) ResolveEnrollment(classID, majorID string) (*models.Class, *models.Major, error) {
+	class, err := s.GetClass(classID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !class.IsActive {
+		return nil, nil, errors.New("class is not active")
+	}
+
+	if majorID != "" && majorID != class.MajorID.Hex() {
+		return nil, nil, errors.New("major does not match the selected class")
+	}
+
+	major, err := s.GetMajor(class.MajorID.Hex())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return class, major, nil
+}
+
+func findClass(ctx context.Context, db *mongo.Database, id primitive.ObjectID) (*models.Class, error) {
+	var class models.Class
+	if err := db.Collection("classes").FindOne(ctx, bson.M{"_id": id}).Decode(&class); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("class not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	return &class, nil
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ClassAttendanceService menyediakan dashboard absensi per kelas untuk wali kelas dan admin
+type ClassAttendanceService struct {
+	db     *mongo.Database
+	ctx    context.Context
+	config *config.Config
+}
+
+type ClassAttendanceServiceInterface interface {
+	ListViewableClasses(actor *models.User) ([]models.Class, error)
+	GetDailyAttendance(actor *models.User, classID string, date time.Time) (*models.ClassDailyAttendance, error)
+	GetMonthlyAttendance(actor *models.User, classID string, month time.Time) (*models.ClassMonthlyAttendance, error)
+}
+
+func NewClassAttendanceService(db *mongo.Database, cfg *config.Config) ClassAttendanceServiceInterface {
+	return &ClassAttendanceService{
+		db:     db,
+		ctx:    context.Background(),
+		config: cfg,
+	}
+}
+
+// attendanceCounts hasil attendanceCountGroup
+type attendanceCounts struct {
+	Recorded     int `bson:"recorded"`
+	Present      int `bson:"present"`
+	Late         int `bson:"late"`
+	VeryLate     int `bson:"very_late"`
+	Absent       int `bson:"absent"`
+	Sick         int `bson:"sick"`
+	Excused      int `bson:"excused"`
+	EarlyLeave   int `bson:"early_leave"`
+	NoCheckout   int `bson:"no_checkout"`
+	MinutesLate  int `bson:"minutes_late"`
+	MinutesEarly int `bson:"minutes_early"`
+	CheckedOut   int `bson:"checked_out"`
+	Flagged      int `bson:"flagged"`
+}
+
+// attended siswa yang datang, terlambat atau tidak
+func (a attendanceCounts) attended() int {
+	return a.Present + a.Late + a.VeryLate
+}
+
+type classDayAggregate struct {
+	Day    string           `bson:"_id"`
+	Counts attendanceCounts `bson:",inline"`
+}
+
+type classStudentAggregate struct {
+	UserID primitive.ObjectID `bson:"_id"`
+	Counts attendanceCounts   `bson:",inline"`
+}
+
+func (s *ClassAttendanceService) ListViewableClasses(actor *models.User) ([]models.Class, error) {
+	if actor.HasRole(models.RoleAdmin) {
+		return NewClassService(s.db).ListClasses("", 0, false)
+	}
+
+	if actor.ClassID == nil {
+		return []models.Class{}, nil
+	}
+	class, err := findClass(s.ctx, s.db, *actor.ClassID)
+	if err != nil {
+		return nil, err
+	}
+	return []models.Class{*class}, nil
+}
+
+func (s *ClassAttendanceService) GetDailyAttendance(actor *models.User, classID string, date time.Time) (*models.ClassDailyAttendance, error) {
+	class, err := s.viewableClass(actor, classID)
+	if err != nil {
+		return nil, err
+	}
+
+	students, ids, err := s.roster(class.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	date = startOfDayUTC(date)
+	result := &models.ClassDailyAttendance{
+		Class:        *class,
+		Date:         date,
+		Summary:      daySummary(date, len(students), attendanceCounts{}),
+		Students:     []models.ClassAttendanceEntry{},
+		NotCheckedIn: []models.UserPublic{},
+	}
+	if len(students) == 0 {
+		return result, nil
+	}
+
+	cursor, err := s.db.Collection("attendances").Aggregate(s.ctx, []bson.M{
+		{"$match": bson.M{
+			"user_id": bson.M{"$in": ids},
+			"date":    dayRange(date),
+			"voided":  bson.M{"$ne": true},
+		}},
+		{"$facet": bson.M{
+			"records": []bson.M{
+				{"$project": bson.M{
+					"user_id":             1,
+					"status":              1,
+					"check_in":            1,
+					"check_out":           1,
+					"minutes_late":        1,
+					"departure_status":    1,
+					"minutes_early":       1,
+					"verification_method": 1,
+					"flagged":             1,
+				}},
+			},
+			"summary": []bson.M{
+				{"$group": attendanceCountGroup(nil, s.config.Today())},
+			},
+		}},
+	})
+	if err != nil {
+		log.Printf("Error aggregating class attendance: %v", err)
+		return nil, errors.New("failed to calculate class attendance")
+	}
+	defer cursor.Close(s.ctx)
+
+	var facets []struct {
+		Records []models.Attendance `bson:"records"`
+		Summary []classDayAggregate `bson:"summary"`
+	}
+	if err = cursor.All(s.ctx, &facets); err != nil {
+		return nil, errors.New("failed to decode class attendance")
+	}
+
+	byUser := make(map[primitive.ObjectID]models.Attendance)
+	if len(facets) > 0 {
+		for _, record := range facets[0].Records {
+			byUser[record.UserID] = record
+		}
+		if len(facets[0].Summary) > 0 {
+			result.Summary = daySummary(date, len(students), facets[0].Summary[0].Counts)
+		}
+	}
+
+	for _, student := range students {
+		public := student.ToPublic()
+		entry := models.ClassAttendanceEntry{User: public, Status: models.StatusNotCheckedIn}
+		if record, ok := byUser[student.ID]; ok {
+			recordID := record.ID
+			entry.AttendanceID = &recordID
+			entry.Status = record.Status
+			entry.CheckIn = record.CheckIn
+			entry.CheckOut = record.CheckOut
+			entry.MinutesLate = record.MinutesLate
+			entry.DepartureStatus = record.DepartureStatus
+			entry.MinutesEarly = record.MinutesEarly
+			entry.VerificationMethod = record.VerificationMethod
+			entry.Flagged = record.Flagged
+		} else {
+			result.NotCheckedIn = append(result.NotCheckedIn, public)
+		}
+		result.Students = append(result.Students, entry)
+	}
+
+	return result, nil
+}
+
+func (s *ClassAttendanceService) GetMonthlyAttendance(actor *models.User, classID string, month time.Time) (*models.ClassMonthlyAttendance, error) {
+	class, err := s.viewableClass(actor, classID)
+	if err != nil {
+		return nil, err
+	}
+
+	students, ids, err := s.roster(class.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	result := &models.ClassMonthlyAttendance{
+		Class:         *class,
+		Month:         from.Format("2006-01"),
+		From:          from,
+		To:            to.Add(-time.Nanosecond),
+		TotalStudents: len(students),
+		Days:          []models.ClassDaySummary{},
+		Students:      []models.ClassStudentSummary{},
+	}
+	if len(students) == 0 {
+		return result, nil
+	}
+
+	cursor, err := s.db.Collection("attendances").Aggregate(s.ctx, []bson.M{
+		{"$match": bson.M{
+			"user_id": bson.M{"$in": ids},
+			"date":    bson.M{"$gte": from, "$lt": to},
+			"voided":  bson.M{"$ne": true},
+		}},
+		{"$facet": bson.M{
+			"days": []bson.M{
+				{"$group": attendanceCountGroup(bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$date"}}, s.config.Today())},
+				{"$sort": bson.M{"_id": 1}},
+			},
+			"students": []bson.M{
+				{"$group": attendanceCountGroup("$user_id", s.config.Today())},
+			},
+		}},
+	})
+	if err != nil {
+		log.Printf("Error aggregating monthly class attendance: %v", err)
+		return nil, errors.New("failed to calculate class attendance")
+	}
+	defer cursor.Close(s.ctx)
+
+	var facets []struct {
+		Days     []classDayAggregate     `bson:"days"`
+		Students []classStudentAggregate `bson:"students"`
+	}
+	if err = cursor.All(s.ctx, &facets); err != nil {
+		return nil, errors.New("failed to decode class attendance")
+	}
+
+	byUser := make(map[primitive.ObjectID]classStudentAggregate)
+	attended := 0
+	if len(facets) > 0 {
+		for _, day := range facets[0].Days {
+			date, err := time.Parse("2006-01-02", day.Day)
+			if err != nil {
+				continue
+			}
+			result.Days = append(result.Days, daySummary(date, len(students), day.Counts))
+			attended += day.Counts.attended()
+		}
+		for _, row := range facets[0].Students {
+			byUser[row.UserID] = row
+		}
+	}
+	result.SchoolDays = len(result.Days)
+	if result.SchoolDays > 0 {
+		result.Percentage = float64(attended) / float64(result.SchoolDays*len(students)) * 100
+	}
+
+	for _, student := range students {
+		row := byUser[student.ID].Counts
+		summary := models.ClassStudentSummary{
+			User:       student.ToPublic(),
+			Present:    row.Present,
+			Late:       row.Late,
+			VeryLate:   row.VeryLate,
+			Absent:     row.Absent,
+			Sick:       row.Sick,
+			Excused:    row.Excused,
+			EarlyLeave: row.EarlyLeave,
+			NoCheckout: row.NoCheckout,
+			Missing:    result.SchoolDays - row.Recorded,
+			Flagged:    row.Flagged,
+		}
+		if summary.Missing < 0 {
+			summary.Missing = 0
+		}
+		if result.SchoolDays > 0 {
+			summary.Percentage = float64(row.attended()) / float64(result.SchoolDays) * 100
+		}
+		result.Students = append(result.Students, summary)
+	}
+
+	return result, nil
+}
+
+// viewableClass hanya mengizinkan admin dan wali kelas dari kelas tersebut
+func (s *ClassAttendanceService) viewableClass(actor *models.User, classID string) (*models.Class, error) {
+	objectID, err := primitive.ObjectIDFromHex(classID)
+	if err != nil {
+		return nil, errors.New("invalid class ID")
+	}
+
+	if !actor.HasRole(models.RoleAdmin) && (actor.ClassID == nil || *actor.ClassID != objectID) {
+		return nil, errors.New("you are not the homeroom teacher of this class")
+	}
+
+	return findClass(s.ctx, s.db, objectID)
+}
+
+func (s *ClassAttendanceService) roster(classID primitive.ObjectID) ([]models.User, []primitive.ObjectID, error) {
+	students, err := loadClassStudents(s.ctx, s.db, classID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(students))
+	for _, student := range students {
+		ids = append(ids, student.ID)
+	}
+	return students, ids, nil
+}
+
+// attendanceCountGroup membuat stage $group yang menghitung record per status.
+// Record yang sudah check in tapi tidak check out sebelum hari ini dihitung no_checkout.
+func attendanceCountGroup(id interface{}, today time.Time) bson.M {
+	countIf := func(cond interface{}) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": []interface{}{cond, 1, 0}}}
+	}
+	statusIs := func(field, status string) bson.M {
+		return bson.M{"$eq": []interface{}{"$" + field, status}}
+	}
+
+	return bson.M{
+		"_id":           id,
+		"recorded":      bson.M{"$sum": 1},
+		"present":       countIf(statusIs("status", models.StatusPresent)),
+		"late":          countIf(statusIs("status", models.StatusLate)),
+		"very_late":     countIf(statusIs("status", models.StatusVeryLate)),
+		"absent":        countIf(statusIs("status", models.StatusAbsent)),
+		"sick":          countIf(statusIs("status", models.StatusSick)),
+		"excused":       countIf(statusIs("status", models.StatusExcused)),
+		"early_leave":   countIf(statusIs("departure_status", models.DepartureEarlyLeave)),
+		"minutes_late":  bson.M{"$sum": bson.M{"$ifNull": []interface{}{"$minutes_late", 0}}},
+		"minutes_early": bson.M{"$sum": bson.M{"$ifNull": []interface{}{"$minutes_early", 0}}},
+		"checked_out":   countIf(bson.M{"$gt": []interface{}{"$check_out", nil}}),
+		"flagged":       countIf(bson.M{"$eq": []interface{}{"$flagged", true}}),
+		"no_checkout": countIf(bson.M{"$or": []interface{}{
+			statusIs("departure_status", models.DepartureNoCheckout),
+			bson.M{"$and": []interface{}{
+				bson.M{"$gt": []interface{}{"$check_in", nil}},
+				bson.M{"$lte": []interface{}{"$check_out", nil}},
+				bson.M{"$lt": []interface{}{"$date", today}},
+			}},
+		}}),
+	}
+}
+
+func daySummary(date time.Time, totalStudents int, day attendanceCounts) models.ClassDaySummary {
+	summary := models.ClassDaySummary{
+		Date:          date,
+		TotalStudents: totalStudents,
+		Present:       day.Present,
+		Late:          day.Late,
+		VeryLate:      day.VeryLate,
+		Absent:        day.Absent,
+		Sick:          day.Sick,
+		Excused:       day.Excused,
+		EarlyLeave:    day.EarlyLeave,
+		NoCheckout:    day.NoCheckout,
+		NotCheckedIn:  totalStudents - day.Recorded,
+		CheckedOut:    day.CheckedOut,
+		Flagged:       day.Flagged,
+	}
+	if summary.NotCheckedIn < 0 {
+		summary.NotCheckedIn = 0
+	}
+	if totalStudents > 0 {
+		summary.Percentage = float64(day.attended()) / float64(totalStudents) * 100
+	}
+	return summary
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CorrectionService struct {
+	db                *mongo.Database
+	ctx               context.Context
+	config            *config.Config
+	attendanceService AttendanceServiceInterface
+}
+
+type CorrectionServiceInterface interface {
+	SubmitRequest(userID primitive.ObjectID, req *models.CorrectionRequestInput) (*models.CorrectionRequest, error)
+	ListOwnRequests(userID primitive.ObjectID) ([]models.CorrectionRequest, error)
+	ListRequests(actor *models.User, status string) ([]models.CorrectionRequestReport, error)
+	ReviewRequest(actor *models.User, requestID string, approve bool, note string) (*models.CorrectionRequest, error)
+	AttachEvidence(userID primitive.ObjectID, requestID string, evidence *models.Selfie) (*models.CorrectionRequest, *models.Selfie, error)
+}
+
+func NewCorrectionService(db *mongo.Database, cfg *config.Config) CorrectionServiceInterface {
+	return &CorrectionService{
+		db:                db,
+		ctx:               context.Background(),
+		config:            cfg,
+		attendanceService: NewAttendanceService(db, cfg),
+	}
+}
+
+func (s *CorrectionService) SubmitRequest(userID primitive.ObjectID, req *models.CorrectionRequestInput) (*models.CorrectionRequest, error) {
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return nil, errors.New("invalid date format, use YYYY-MM-DD")
+	}
+
+	now := time.Now().UTC()
+	if date.After(s.config.Today()) {
+		return nil, errors.New("cannot request correction for a future date")
+	}
+
+	checkIn := req.ClaimedCheckIn.UTC()
+	checkOut := utcTime(req.ClaimedCheckOut)
+	if err := validateCorrectionTimes(s.config, date, &checkIn, checkOut); err != nil {
+		return nil, err
+	}
+
+	collection := s.db.Collection("correction_requests")
+	count, err := collection.CountDocuments(s.ctx, bson.M{
+		"user_id": userID,
+		"date":    date,
+		"status":  bson.M{"$in": []string{models.CorrectionStatusPending, models.CorrectionStatusProcessing}},
+	})
+	if err != nil {
+		return nil, errors.New("database error")
+	}
+	if count > 0 {
+		return nil, errors.New("you already have a pending correction request for this date")
+	}
+
+	request := models.CorrectionRequest{
+		UserID:          userID,
+		Date:            date,
+		ClaimedCheckIn:  checkIn,
+		ClaimedCheckOut: checkOut,
+		Reason:          utils.SanitizeInput(req.Reason),
+		Status:          models.CorrectionStatusPending,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	result, err := collection.InsertOne(s.ctx, request)
+	if err != nil {
+		log.Printf("Error creating correction request: %v", err)
+		return nil, errors.New("failed to create correction request")
+	}
+	request.ID = result.InsertedID.(primitive.ObjectID)
+
+	return &request, nil
+}
+
+func (s *CorrectionService) ListOwnRequests(userID primitive.ObjectID) ([]models.CorrectionRequest, error) {
+	return s.findRequests(bson.M{"user_id": userID})
+}
+
+func (s *CorrectionService) ListRequests(actor *models.User, status string) ([]models.CorrectionRequestReport, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	students, err := manageableStudentIDs(s.ctx, s.db, actor)
+	if err != nil {
+		return nil, err
+	}
+	if students != nil {
+		var ids []primitive.ObjectID
+		for id := range students {
+			ids = append(ids, id)
+		}
+		filter["user_id"] = bson.M{"$in": ids}
+	}
+
+	requests, err := s.findRequests(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []primitive.ObjectID
+	for _, request := range requests {
+		ids = append(ids, request.UserID)
+	}
+	users, err := loadUsersByID(s.ctx, s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]models.CorrectionRequestReport, 0, len(requests))
+	for _, request := range requests {
+		user := users[request.UserID]
+		reports = append(reports, models.CorrectionRequestReport{
+			CorrectionRequest: request,
+			User:              user.ToPublic(),
+		})
+	}
+	return reports, nil
+}
+
+func (s *CorrectionService) ReviewRequest(actor *models.User, requestID string, approve bool, note string) (*models.CorrectionRequest, error) {
+	objectID, err := primitive.ObjectIDFromHex(requestID)
+	if err != nil {
+		return nil, errors.New("invalid request ID")
+	}
+
+	collection := s.db.Collection("correction_requests")
+	var request models.CorrectionRequest
+	if err := collection.FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&request); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("correction request not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	if request.Status != models.CorrectionStatusPending {
+		return nil, errors.New("correction request has already been reviewed")
+	}
+
+	var student models.User
+	if err := s.db.Collection("users").FindOne(s.ctx, bson.M{"_id": request.UserID}).Decode(&student); err != nil {
+		return nil, errors.New("student not found")
+	}
+	if !CanManageStudent(actor, &student) {
+		return nil, errors.New("you are not the homeroom teacher of this student")
+	}
+
+	// klaim request dulu (pending -> processing) supaya hanya satu reviewer yang menulis
+	// ke record absensi; reviewer lain langsung ditolak
+	now := time.Now().UTC()
+	err = collection.FindOneAndUpdate(
+		s.ctx,
+		bson.M{"_id": request.ID, "status": models.CorrectionStatusPending},
+		bson.M{"$set": bson.M{"status": models.CorrectionStatusProcessing, "reviewed_by": actor.ID, "updated_at": now}},
+	).Err()
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("correction request has already been reviewed")
+	}
+	if err != nil {
+		log.Printf("Error claiming correction request %s: %v", request.ID.Hex(), err)
+		return nil, errors.New("failed to update correction request")
+	}
+
+	status := models.CorrectionStatusRejected
+	note = utils.SanitizeInput(note)
+	update := bson.M{
+		"reviewed_by": actor.ID,
+		"reviewed_at": now,
+		"review_note": note,
+		"updated_at":  now,
+	}
+
+	if approve {
+		status = models.CorrectionStatusApproved
+
+		reason := "Correction request approved: " + request.Reason
+		if note != "" {
+			reason += " (" + note + ")"
+		}
+
+		attendance, err := s.attendanceService.ApplyCorrection(actor, &AttendanceCorrection{
+			StudentID: request.UserID,
+			Date:      request.Date,
+			Status:    s.attendanceService.DetermineStatus(request.ClaimedCheckIn),
+			CheckIn:   &request.ClaimedCheckIn,
+			CheckOut:  request.ClaimedCheckOut,
+			Reason:    reason,
+			Source:    models.CorrectionSourceRequest,
+			RequestID: &request.ID,
+		})
+		if err != nil {
+			s.releaseClaim(request.ID)
+			return nil, err
+		}
+
+		update["attendance_id"] = attendance.ID
+		request.AttendanceID = &attendance.ID
+	}
+	update["status"] = status
+
+	_, err = collection.UpdateOne(s.ctx, bson.M{"_id": request.ID, "status": models.CorrectionStatusProcessing}, bson.M{"$set": update})
+	if err != nil {
+		// koreksi absensi sudah tersimpan, request tetap processing dan bisa dicek manual
+		log.Printf("Error finishing correction request %s: %v", request.ID.Hex(), err)
+		return nil, errors.New("failed to update correction request")
+	}
+
+	request.Status = status
+	request.ReviewedBy = &actor.ID
+	request.ReviewedAt = &now
+	request.ReviewNote = note
+	request.UpdatedAt = now
+
+	log.Printf("Correction request %s %s by %s", request.ID.Hex(), status, actor.Email)
+	return &request, nil
+}
+
+// AttachEvidence menyimpan foto bukti ke pengajuan milik siswa yang masih pending dan
+// mengembalikan foto lama (jika ada) untuk dihapus pemanggil dari storage
+func (s *CorrectionService) AttachEvidence(userID primitive.ObjectID, requestID string, evidence *models.Selfie) (*models.CorrectionRequest, *models.Selfie, error) {
+	objectID, err := primitive.ObjectIDFromHex(requestID)
+	if err != nil {
+		return nil, nil, errors.New("invalid request ID")
+	}
+
+	var previous models.CorrectionRequest
+	err = s.db.Collection("correction_requests").FindOneAndUpdate(
+		s.ctx,
+		bson.M{"_id": objectID, "user_id": userID, "status": models.CorrectionStatusPending},
+		bson.M{"$set": bson.M{"evidence": evidence, "updated_at": time.Now().UTC()}},
+	).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, errors.New("pending correction request not found")
+	}
+	if err != nil {
+		log.Printf("Error attaching correction evidence: %v", err)
+		return nil, nil, errors.New("failed to save evidence")
+	}
+
+	replaced := previous.Evidence
+	request := previous
+	request.Evidence = evidence
+	return &request, replaced, nil
+}
+
+// releaseClaim mengembalikan request ke pending jika koreksi gagal diterapkan
+func (s *CorrectionService) releaseClaim(requestID primitive.ObjectID) {
+	_, err := s.db.Collection("correction_requests").UpdateOne(
+		s.ctx,
+		bson.M{"_id": requestID, "status": models.CorrectionStatusProcessing},
+		bson.M{
+			"$set":   bson.M{"status": models.CorrectionStatusPending, "updated_at": time.Now().UTC()},
+			"$unset": bson.M{"reviewed_by": ""},
+		},
+	)
+	if err != nil {
+		log.Printf("Warning: failed to release correction request %s: %v", requestID.Hex(), err)
+	}
+}
+
+func (s *CorrectionService) findRequests(filter bson.M) ([]models.CorrectionRequest, error) {
+	cursor, err := s.db.Collection("correction_requests").Find(
+		s.ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, errors.New("failed to fetch correction requests")
+	}
+	defer cursor.Close(s.ctx)
+
+	requests := []models.CorrectionRequest{}
+	if err = cursor.All(s.ctx, &requests); err != nil {
+		return nil, errors.New("failed to decode correction requests")
+	}
+
+	return requests, nil
+}
@@ -0,0 +1,531 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/security"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	AttemptMissingSignature = "missing_signature"
+	AttemptUnboundDevice    = "unbound_device"
+	AttemptInvalidSignature = "invalid_signature"
+	AttemptExpiredSignature = "expired_signature"
+	AttemptReplayedRequest  = "replayed_request"
+)
+
+var errDeviceNonceUsed = errors.New("device request has already been used")
+
+// SignedRequest berisi data request yang ditandatangani oleh device
+type SignedRequest struct {
+	DeviceID  string
+	Timestamp string
+	Nonce     string
+	Signature string
+	Method    string
+	Path      string
+	Body      []byte
+}
+
+// DeviceVerificationError menyimpan alasan penolakan untuk laporan percobaan absensi
+type DeviceVerificationError struct {
+	Reason  string
+	Message string
+}
+
+func (e *DeviceVerificationError) Error() string {
+	return e.Message
+}
+
+type DeviceService struct {
+	db     *mongo.Database
+	ctx    context.Context
+	config *config.Config
+}
+
+type DeviceServiceInterface interface {
+	RegisterDevice(userID primitive.ObjectID, req *models.RegisterDeviceRequest, signed *SignedRequest) (*models.Device, error)
+	ListDevices(userID primitive.ObjectID) ([]models.Device, error)
+	VerifySignedRequest(userID primitive.ObjectID, signed *SignedRequest) (*models.Device, error)
+	ConsumeNonce(deviceID, nonce string) error
+	ReleaseNonce(deviceID, nonce string)
+	VerifyOfflineRecord(userID primitive.ObjectID, record *models.OfflineRecord) (*models.Device, error)
+	RecordAttempt(attempt *models.DeviceAttempt)
+	ListAttempts(actor *models.User, from, to time.Time, limit int) ([]models.DeviceAttemptReport, error)
+	RequestReset(userID primitive.ObjectID, req *models.DeviceResetRequestInput) (*models.DeviceResetRequest, error)
+	ListOwnResetRequests(userID primitive.ObjectID) ([]models.DeviceResetRequest, error)
+	ListResetRequests(actor *models.User, status string) ([]models.DeviceResetRequest, error)
+	ReviewResetRequest(actor *models.User, requestID string, approve bool, note string) (*models.DeviceResetRequest, error)
+}
+
+func NewDeviceService(db *mongo.Database, cfg *config.Config) DeviceServiceInterface {
+	return &DeviceService{
+		db:     db,
+		ctx:    context.Background(),
+		config: cfg,
+	}
+}
+
+func (s *DeviceService) RegisterDevice(userID primitive.ObjectID, req *models.RegisterDeviceRequest, signed *SignedRequest) (*models.Device, error) {
+	if signed.DeviceID == "" {
+		return nil, errors.New("X-Device-ID header is required")
+	}
+
+	// Bukti kepemilikan private key: request registrasi harus ditandatangani key yang didaftarkan
+	if err := security.CheckSignatureTimestamp(signed.Timestamp, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := security.CheckNonce(signed.Nonce); err != nil {
+		return nil, err
+	}
+	message := security.CanonicalRequest(signed.Method, signed.Path, signed.Timestamp, signed.Nonce, signed.Body)
+	if err := security.VerifyDeviceSignature(req.PublicKey, message, signed.Signature); err != nil {
+		return nil, err
+	}
+	if err := s.consumeNonce(signed.DeviceID, signed.Nonce); err != nil {
+		return nil, err
+	}
+
+	collection := s.db.Collection("devices")
+
+	if err := s.checkDeviceUnbound(userID, signed.DeviceID); err != nil {
+		return nil, err
+	}
+
+	slot, err := s.freeSlot(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	device := models.Device{
+		UserID:    userID,
+		DeviceID:  signed.DeviceID,
+		PublicKey: req.PublicKey,
+		Name:      utils.SanitizeInput(req.Name),
+		Platform:  req.Platform,
+		Status:    models.DeviceStatusActive,
+		Slot:      &slot,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// index unik parsial (device_id aktif, user_id + slot aktif) menolak registrasi bersamaan
+	// yang mengikat device yang sama dua kali atau melewati MAX_DEVICES_PER_USER
+	result, err := collection.InsertOne(s.ctx, device)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			if err := s.checkDeviceUnbound(userID, signed.DeviceID); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("another device registration is in progress, please try again")
+		}
+		log.Printf("Error registering device: %v", err)
+		return nil, errors.New("failed to register device")
+	}
+	device.ID = result.InsertedID.(primitive.ObjectID)
+
+	log.Printf("Device %s bound to user %s", device.DeviceID, userID.Hex())
+	return &device, nil
+}
+
+// checkDeviceUnbound memastikan device belum terikat aktif ke akun mana pun
+func (s *DeviceService) checkDeviceUnbound(userID primitive.ObjectID, deviceID string) error {
+	var existing models.Device
+	err := s.db.Collection("devices").FindOne(s.ctx, bson.M{
+		"device_id": deviceID,
+		"status":    models.DeviceStatusActive,
+	}).Decode(&existing)
+	if err == nil {
+		if existing.UserID == userID {
+			return errors.New("device already registered")
+		}
+		return errors.New("device is registered to another account")
+	}
+	if err != mongo.ErrNoDocuments {
+		return errors.New("database error")
+	}
+	return nil
+}
+
+// freeSlot mencari slot terkecil yang belum dipakai device aktif user. Slot berada di
+// rentang 0..MAX_DEVICES_PER_USER-1 sehingga index unik user_id + slot membatasi jumlah device.
+func (s *DeviceService) freeSlot(userID primitive.ObjectID) (int, error) {
+	cursor, err := s.db.Collection("devices").Find(s.ctx, bson.M{
+		"user_id": userID,
+		"status":  models.DeviceStatusActive,
+	}, options.Find().SetProjection(bson.M{"slot": 1}))
+	if err != nil {
+		return 0, errors.New("database error")
+	}
+	defer cursor.Close(s.ctx)
+
+	var active []models.Device
+	if err := cursor.All(s.ctx, &active); err != nil {
+		return 0, errors.New("database error")
+	}
+
+	maxDevicesErr := errors.New("maximum number of devices reached, request a device reset from your homeroom teacher")
+	if len(active) >= s.config.MaxDevicesPerUser {
+		return 0, maxDevicesErr
+	}
+
+	used := make(map[int]bool)
+	for _, device := range active {
+		if device.Slot != nil {
+			used[*device.Slot] = true
+		}
+	}
+	for slot := 0; slot < s.config.MaxDevicesPerUser; slot++ {
+		if !used[slot] {
+			return slot, nil
+		}
+	}
+	return 0, maxDevicesErr
+}
+
+// consumeNonce mencatat pasangan device + nonce. Request dengan nonce yang sama dalam
+// NonceRetention ditolak sebagai replay; data dihapus otomatis oleh TTL index.
+func (s *DeviceService) consumeNonce(deviceID, nonce string) error {
+	_, err := s.db.Collection("device_nonces").InsertOne(s.ctx, bson.M{
+		"device_id":  deviceID,
+		"nonce":      nonce,
+		"created_at": time.Now().UTC(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return errDeviceNonceUsed
+	}
+	if err != nil {
+		log.Printf("Error saving device nonce: %v", err)
+		return errors.New("database error")
+	}
+	return nil
+}
+
+// ConsumeNonce mencatat nonce request yang tanda tangannya sudah diverifikasi
+func (s *DeviceService) ConsumeNonce(deviceID, nonce string) error {
+	if err := s.consumeNonce(deviceID, nonce); err != nil {
+		if errors.Is(err, errDeviceNonceUsed) {
+			return &DeviceVerificationError{Reason: AttemptReplayedRequest, Message: err.Error()}
+		}
+		return err
+	}
+	return nil
+}
+
+// ReleaseNonce melepas nonce request yang gagal di server supaya retry yang sama bisa diproses
+func (s *DeviceService) ReleaseNonce(deviceID, nonce string) {
+	if _, err := s.db.Collection("device_nonces").DeleteOne(s.ctx, bson.M{"device_id": deviceID, "nonce": nonce}); err != nil {
+		log.Printf("Warning: failed to release device nonce: %v", err)
+	}
+}
+
+func (s *DeviceService) ListDevices(userID primitive.ObjectID) ([]models.Device, error) {
+	cursor, err := s.db.Collection("devices").Find(
+		s.ctx,
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, errors.New("failed to fetch devices")
+	}
+	defer cursor.Close(s.ctx)
+
+	devices := []models.Device{}
+	if err = cursor.All(s.ctx, &devices); err != nil {
+		return nil, errors.New("failed to decode devices")
+	}
+
+	return devices, nil
+}
+
+// VerifySignedRequest memastikan request berasal dari device aktif milik user. Nonce belum
+// dicatat di sini; pemanggil memanggil ConsumeNonce setelah retry idempotent dijawab dari
+// respons tersimpan, supaya retry yang sama persis tidak ditolak sebagai replay.
+func (s *DeviceService) VerifySignedRequest(userID primitive.ObjectID, signed *SignedRequest) (*models.Device, error) {
+	if signed.DeviceID == "" || signed.Signature == "" || signed.Timestamp == "" || signed.Nonce == "" {
+		return nil, &DeviceVerificationError{Reason: AttemptMissingSignature, Message: "request must be signed by a registered device"}
+	}
+
+	var device models.Device
+	err := s.db.Collection("devices").FindOne(s.ctx, bson.M{
+		"user_id":   userID,
+		"device_id": signed.DeviceID,
+		"status":    models.DeviceStatusActive,
+	}).Decode(&device)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, &DeviceVerificationError{Reason: AttemptUnboundDevice, Message: "device is not registered to your account"}
+		}
+		return nil, errors.New("database error")
+	}
+
+	if err := security.CheckSignatureTimestamp(signed.Timestamp, time.Now()); err != nil {
+		return nil, &DeviceVerificationError{Reason: AttemptExpiredSignature, Message: err.Error()}
+	}
+
+	if err := security.CheckNonce(signed.Nonce); err != nil {
+		return nil, &DeviceVerificationError{Reason: AttemptInvalidSignature, Message: err.Error()}
+	}
+
+	message := security.CanonicalRequest(signed.Method, signed.Path, signed.Timestamp, signed.Nonce, signed.Body)
+	if err := security.VerifyDeviceSignature(device.PublicKey, message, signed.Signature); err != nil {
+		return nil, &DeviceVerificationError{Reason: AttemptInvalidSignature, Message: err.Error()}
+	}
+
+	now := time.Now().UTC()
+	_, err = s.db.Collection("devices").UpdateOne(s.ctx, bson.M{"_id": device.ID}, bson.M{
+		"$set": bson.M{"last_used_at": now},
+	})
+	if err != nil {
+		log.Printf("Warning: failed to update device last use: %v", err)
+	}
+	device.LastUsedAt = &now
+
+	return &device, nil
+}
+
+// VerifyOfflineRecord memastikan record check in offline ditandatangani device aktif milik user.
+// Timestamp tidak dicek di sini karena record memang dikirim belakangan.
+func (s *DeviceService) VerifyOfflineRecord(userID primitive.ObjectID, record *models.OfflineRecord) (*models.Device, error) {
+	var device models.Device
+	err := s.db.Collection("devices").FindOne(s.ctx, bson.M{
+		"user_id":   userID,
+		"device_id": record.DeviceID,
+		"status":    models.DeviceStatusActive,
+	}).Decode(&device)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, &DeviceVerificationError{Reason: AttemptUnboundDevice, Message: "device is not registered to your account"}
+		}
+		return nil, errors.New("database error")
+	}
+
+	if err := security.VerifyDeviceSignature(device.PublicKey, security.OfflineMessage(record.Payload), record.Signature); err != nil {
+		return nil, &DeviceVerificationError{Reason: AttemptInvalidSignature, Message: err.Error()}
+	}
+
+	return &device, nil
+}
+
+func (s *DeviceService) RecordAttempt(attempt *models.DeviceAttempt) {
+	attempt.CreatedAt = time.Now().UTC()
+	if _, err := s.db.Collection("device_attempts").InsertOne(s.ctx, attempt); err != nil {
+		log.Printf("Warning: failed to record device attempt: %v", err)
+		return
+	}
+
+	log.Printf("Attendance attempt from unbound device: user %s, device %q, reason %s", attempt.UserID.Hex(), attempt.DeviceID, attempt.Reason)
+}
+
+func (s *DeviceService) ListAttempts(actor *models.User, from, to time.Time, limit int) ([]models.DeviceAttemptReport, error) {
+	students, err := s.manageableStudents(actor)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}
+	if students != nil {
+		var ids []primitive.ObjectID
+		for id := range students {
+			ids = append(ids, id)
+		}
+		filter["user_id"] = bson.M{"$in": ids}
+	}
+
+	cursor, err := s.db.Collection("device_attempts").Find(
+		s.ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, errors.New("failed to fetch device attempts")
+	}
+	defer cursor.Close(s.ctx)
+
+	var attempts []models.DeviceAttempt
+	if err = cursor.All(s.ctx, &attempts); err != nil {
+		return nil, errors.New("failed to decode device attempts")
+	}
+
+	users, err := s.loadUsers(attempts)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []models.DeviceAttemptReport{}
+	for _, attempt := range attempts {
+		report := models.DeviceAttemptReport{DeviceAttempt: attempt}
+		if user, ok := users[attempt.UserID]; ok {
+			report.User = user.ToPublic()
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func (s *DeviceService) RequestReset(userID primitive.ObjectID, req *models.DeviceResetRequestInput) (*models.DeviceResetRequest, error) {
+	collection := s.db.Collection("device_reset_requests")
+
+	pending, err := collection.CountDocuments(s.ctx, bson.M{"user_id": userID, "status": models.ResetStatusPending})
+	if err != nil {
+		return nil, errors.New("database error")
+	}
+	if pending > 0 {
+		return nil, errors.New("a device reset request is already pending")
+	}
+
+	now := time.Now().UTC()
+	request := models.DeviceResetRequest{
+		UserID:    userID,
+		Reason:    utils.SanitizeInput(req.Reason),
+		Status:    models.ResetStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	result, err := collection.InsertOne(s.ctx, request)
+	if err != nil {
+		log.Printf("Error creating device reset request: %v", err)
+		return nil, errors.New("failed to create device reset request")
+	}
+	request.ID = result.InsertedID.(primitive.ObjectID)
+
+	return &request, nil
+}
+
+func (s *DeviceService) ListOwnResetRequests(userID primitive.ObjectID) ([]models.DeviceResetRequest, error) {
+	return s.findResetRequests(bson.M{"user_id": userID})
+}
+
+func (s *DeviceService) ListResetRequests(actor *models.User, status string) ([]models.DeviceResetRequest, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	students, err := s.manageableStudents(actor)
+	if err != nil {
+		return nil, err
+	}
+	if students != nil {
+		var ids []primitive.ObjectID
+		for id := range students {
+			ids = append(ids, id)
+		}
+		filter["user_id"] = bson.M{"$in": ids}
+	}
+
+	return s.findResetRequests(filter)
+}
+
+func (s *DeviceService) ReviewResetRequest(actor *models.User, requestID string, approve bool, note string) (*models.DeviceResetRequest, error) {
+	objectID, err := primitive.ObjectIDFromHex(requestID)
+	if err != nil {
+		return nil, errors.New("invalid request ID")
+	}
+
+	collection := s.db.Collection("device_reset_requests")
+	var request models.DeviceResetRequest
+	if err := collection.FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&request); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("device reset request not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	if request.Status != models.ResetStatusPending {
+		return nil, errors.New("device reset request has already been reviewed")
+	}
+
+	var student models.User
+	if err := s.db.Collection("users").FindOne(s.ctx, bson.M{"_id": request.UserID}).Decode(&student); err != nil {
+		return nil, errors.New("student not found")
+	}
+	if !CanManageStudent(actor, &student) {
+		return nil, errors.New("you are not the homeroom teacher of this student")
+	}
+
+	now := time.Now().UTC()
+	status := models.ResetStatusRejected
+	if approve {
+		status = models.ResetStatusApproved
+
+		_, err := s.db.Collection("devices").UpdateMany(s.ctx, bson.M{
+			"user_id": request.UserID,
+			"status":  models.DeviceStatusActive,
+		}, bson.M{"$set": bson.M{
+			"status":     models.DeviceStatusRevoked,
+			"revoked_at": now,
+			"updated_at": now,
+		}})
+		if err != nil {
+			log.Printf("Error revoking devices: %v", err)
+			return nil, errors.New("failed to revoke devices")
+		}
+	}
+
+	update := bson.M{
+		"status":      status,
+		"reviewed_by": actor.ID,
+		"reviewed_at": now,
+		"review_note": utils.SanitizeInput(note),
+		"updated_at":  now,
+	}
+	if _, err := collection.UpdateOne(s.ctx, bson.M{"_id": request.ID}, bson.M{"$set": update}); err != nil {
+		return nil, errors.New("failed to update device reset request")
+	}
+
+	request.Status = status
+	request.ReviewedBy = &actor.ID
+	request.ReviewedAt = &now
+	request.ReviewNote = utils.SanitizeInput(note)
+	request.UpdatedAt = now
+
+	log.Printf("Device reset request %s %s by %s", request.ID.Hex(), status, actor.Email)
+	return &request, nil
+}
+
+func (s *DeviceService) findResetRequests(filter bson.M) ([]models.DeviceResetRequest, error) {
+	cursor, err := s.db.Collection("device_reset_requests").Find(
+		s.ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, errors.New("failed to fetch device reset requests")
+	}
+	defer cursor.Close(s.ctx)
+
+	requests := []models.DeviceResetRequest{}
+	if err = cursor.All(s.ctx, &requests); err != nil {
+		return nil, errors.New("failed to decode device reset requests")
+	}
+
+	return requests, nil
+}
+
+// manageableStudents mengembalikan ID siswa yang bisa dikelola actor, nil untuk admin
+func (s *DeviceService) manageableStudents(actor *models.User) (map[primitive.ObjectID]bool, error) {
+	return manageableStudentIDs(s.ctx, s.db, actor)
+}
+
+func (s *DeviceService) loadUsers(attempts []models.DeviceAttempt) (map[primitive.ObjectID]models.User, error) {
+	var ids []primitive.ObjectID
+	for _, attempt := range attempts {
+		ids = append(ids, attempt.UserID)
+	}
+
+	return loadUsersByID(s.ctx, s.db, ids)
+}
@@ -0,0 +1,439 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// tanpa huruf/angka yang mirip (0/O, 1/I/L) karena kode dibacakan atau ditulis di kertas
+const (
+	inviteCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	inviteCodeLength   = 10
+)
+
+var errInvalidInvite = errors.New("invite code is invalid or has expired")
+
+type GuardianService struct {
+	db     *mongo.Database
+	ctx    context.Context
+	config *config.Config
+}
+
+type GuardianServiceInterface interface {
+	CreateInvite(actor *models.User, studentID string, req *models.CreateGuardianInviteRequest) (*models.GuardianInviteCode, error)
+	ListGuardians(actor *models.User, studentID string) ([]models.StudentGuardian, error)
+	Unlink(actor *models.User, studentID, linkID string) error
+	RegisterGuardian(req *models.RegisterGuardianRequest) (*models.User, error)
+	LinkStudent(guardian *models.User, code string) (*models.GuardianChild, error)
+	ListChildren(guardianID primitive.ObjectID) ([]models.GuardianChild, error)
+	FindChild(guardianID primitive.ObjectID, studentID string) (*models.User, error)
+}
+
+func NewGuardianService(db *mongo.Database, cfg *config.Config) GuardianServiceInterface {
+	return &GuardianService{
+		db:     db,
+		ctx:    context.Background(),
+		config: cfg,
+	}
+}
+
+// CreateInvite menerbitkan kode undangan untuk siswa, hanya wali kelas siswa tersebut atau admin
+func (s *GuardianService) CreateInvite(actor *models.User, studentID string, req *models.CreateGuardianInviteRequest) (*models.GuardianInviteCode, error) {
+	student, err := s.manageableStudent(actor, studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		log.Printf("Error generating guardian invite code: %v", err)
+		return nil, errors.New("failed to generate invite code")
+	}
+
+	now := time.Now().UTC()
+	invite := models.GuardianInvite{
+		StudentID: student.ID,
+		CodeHash:  hashAPIKey(normalizeInviteCode(code)),
+		Relation:  utils.SanitizeInput(req.Relation),
+		CreatedBy: actor.ID,
+		ExpiresAt: now.Add(time.Duration(s.config.GuardianInviteTTLHours) * time.Hour),
+		CreatedAt: now,
+	}
+
+	result, err := s.db.Collection("guardian_invites").InsertOne(s.ctx, invite)
+	if err != nil {
+		log.Printf("Error creating guardian invite: %v", err)
+		return nil, errors.New("failed to create invite code")
+	}
+	invite.ID = result.InsertedID.(primitive.ObjectID)
+
+	log.Printf("Guardian invite %s for student %s created by %s", invite.ID.Hex(), student.ID.Hex(), actor.Email)
+	return &models.GuardianInviteCode{Invite: invite, Code: code}, nil
+}
+
+func (s *GuardianService) ListGuardians(actor *models.User, studentID string) ([]models.StudentGuardian, error) {
+	student, err := s.manageableStudent(actor, studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := s.findLinks(bson.M{"student_id": student.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.GuardianID)
+	}
+	users, err := loadUsersByID(s.ctx, s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	guardians := make([]models.StudentGuardian, 0, len(links))
+	for _, link := range links {
+		user, ok := users[link.GuardianID]
+		if !ok {
+			continue
+		}
+		guardians = append(guardians, models.StudentGuardian{
+			LinkID:   link.ID,
+			User:     user.ToPublic(),
+			Relation: link.Relation,
+			LinkedAt: link.CreatedAt,
+		})
+	}
+	return guardians, nil
+}
+
+// Unlink memutus tautan orang tua dari siswa, akun orang tua tetap ada
+func (s *GuardianService) Unlink(actor *models.User, studentID, linkID string) error {
+	student, err := s.manageableStudent(actor, studentID)
+	if err != nil {
+		return err
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(linkID)
+	if err != nil {
+		return errors.New("invalid link ID")
+	}
+
+	result, err := s.db.Collection("guardian_links").DeleteOne(s.ctx, bson.M{"_id": objectID, "student_id": student.ID})
+	if err != nil {
+		return errors.New("failed to unlink guardian")
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("guardian link not found")
+	}
+
+	log.Printf("Guardian link %s for student %s removed by %s", objectID.Hex(), student.ID.Hex(), actor.Email)
+	return nil
+}
+
+// RegisterGuardian membuat akun orang tua sekaligus menautkannya ke siswa pemilik kode undangan
+func (s *GuardianService) RegisterGuardian(req *models.RegisterGuardianRequest) (*models.User, error) {
+	email := utils.SanitizeInput(req.Email)
+	count, err := s.db.Collection("users").CountDocuments(s.ctx, bson.M{"email": email})
+	if err != nil {
+		return nil, errors.New("database error")
+	}
+	if count > 0 {
+		return nil, errors.New("email already registered")
+	}
+
+	invite, err := s.findInvite(req.InviteCode)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return nil, errors.New("failed to process password")
+	}
+
+	now := time.Now().UTC()
+	user := models.User{
+		ID:        primitive.NewObjectID(),
+		Name:      utils.SanitizeInput(req.Name),
+		Email:     email,
+		Password:  hashedPassword,
+		Phone:     utils.SanitizeInput(req.Phone),
+		Role:      models.RoleGuardian,
+		IsActive:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.claimInvite(invite, user.ID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.db.Collection("users").InsertOne(s.ctx, user); err != nil {
+		s.releaseInvite(invite)
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("email already registered")
+		}
+		log.Printf("Error creating guardian: %v", err)
+		return nil, errors.New("failed to create user")
+	}
+
+	if _, err := s.createLink(&user, invite); err != nil {
+		// akun tanpa anak tidak berguna, hapus supaya pendaftaran bisa diulang dengan kode yang sama
+		if _, delErr := s.db.Collection("users").DeleteOne(s.ctx, bson.M{"_id": user.ID}); delErr != nil {
+			log.Printf("Error removing guardian %s after failed link: %v", user.ID.Hex(), delErr)
+		}
+		s.releaseInvite(invite)
+		return nil, err
+	}
+
+	log.Printf("Guardian registered: %s linked to student %s", user.Email, invite.StudentID.Hex())
+	return &user, nil
+}
+
+// LinkStudent menautkan anak berikutnya ke akun orang tua yang sudah ada
+func (s *GuardianService) LinkStudent(guardian *models.User, code string) (*models.GuardianChild, error) {
+	invite, err := s.findInvite(code)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.db.Collection("guardian_links").CountDocuments(s.ctx, bson.M{"guardian_id": guardian.ID, "student_id": invite.StudentID})
+	if err != nil {
+		return nil, errors.New("database error")
+	}
+	if count > 0 {
+		return nil, errors.New("student is already linked to your account")
+	}
+
+	if err := s.claimInvite(invite, guardian.ID); err != nil {
+		return nil, err
+	}
+
+	link, err := s.createLink(guardian, invite)
+	if err != nil {
+		s.releaseInvite(invite)
+		return nil, err
+	}
+
+	student, err := s.findStudent(invite.StudentID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Guardian %s linked to student %s", guardian.Email, student.ID.Hex())
+	return &models.GuardianChild{User: student.ToPublic(), Relation: link.Relation, LinkedAt: link.CreatedAt}, nil
+}
+
+func (s *GuardianService) ListChildren(guardianID primitive.ObjectID) ([]models.GuardianChild, error) {
+	links, err := s.findLinks(bson.M{"guardian_id": guardianID})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.StudentID)
+	}
+	users, err := loadUsersByID(s.ctx, s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make([]models.GuardianChild, 0, len(links))
+	for _, link := range links {
+		user, ok := users[link.StudentID]
+		if !ok || !user.IsActive {
+			continue
+		}
+		children = append(children, models.GuardianChild{
+			User:     user.ToPublic(),
+			Relation: link.Relation,
+			LinkedAt: link.CreatedAt,
+		})
+	}
+	return children, nil
+}
+
+// FindChild mengembalikan siswa jika sudah ditautkan ke orang tua tersebut
+func (s *GuardianService) FindChild(guardianID primitive.ObjectID, studentID string) (*models.User, error) {
+	objectID, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil, errors.New("invalid student ID")
+	}
+
+	err = s.db.Collection("guardian_links").FindOne(s.ctx, bson.M{"guardian_id": guardianID, "student_id": objectID}).Err()
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("student is not linked to your account")
+		}
+		return nil, errors.New("database error")
+	}
+
+	student, err := s.findStudent(objectID)
+	if err != nil {
+		return nil, err
+	}
+	if !student.IsActive {
+		return nil, errors.New("student account is inactive")
+	}
+	return student, nil
+}
+
+func (s *GuardianService) manageableStudent(actor *models.User, studentID string) (*models.User, error) {
+	objectID, err := primitive.ObjectIDFromHex(studentID)
+	if err != nil {
+		return nil, errors.New("invalid student ID")
+	}
+
+	student, err := s.findStudent(objectID)
+	if err != nil {
+		return nil, err
+	}
+	if !CanManageStudent(actor, student) {
+		return nil, errors.New("you are not the homeroom teacher of this student")
+	}
+	return student, nil
+}
+
+func (s *GuardianService) findStudent(studentID primitive.ObjectID) (*models.User, error) {
+	var student models.User
+	if err := s.db.Collection("users").FindOne(s.ctx, bson.M{"_id": studentID}).Decode(&student); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("student not found")
+		}
+		return nil, errors.New("database error")
+	}
+	if student.HasRole(models.RoleTeacher, models.RoleAdmin, models.RoleGuardian) {
+		return nil, errors.New("student not found")
+	}
+	return &student, nil
+}
+
+// findInvite mencari kode yang belum dipakai dan belum kedaluwarsa
+func (s *GuardianService) findInvite(code string) (*models.GuardianInvite, error) {
+	var invite models.GuardianInvite
+	err := s.db.Collection("guardian_invites").FindOne(s.ctx, bson.M{
+		"code_hash":  hashAPIKey(normalizeInviteCode(code)),
+		"used_by":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now().UTC()},
+	}).Decode(&invite)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errInvalidInvite
+		}
+		return nil, errors.New("database error")
+	}
+
+	student, err := s.findStudent(invite.StudentID)
+	if err != nil || !student.IsActive {
+		return nil, errInvalidInvite
+	}
+	return &invite, nil
+}
+
+// claimInvite menandai kode terpakai; filter used_by mencegah satu kode dipakai dua kali
+func (s *GuardianService) claimInvite(invite *models.GuardianInvite, guardianID primitive.ObjectID) error {
+	now := time.Now().UTC()
+	result, err := s.db.Collection("guardian_invites").UpdateOne(s.ctx,
+		bson.M{"_id": invite.ID, "used_by": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_by": guardianID, "used_at": now}},
+	)
+	if err != nil {
+		return errors.New("failed to use invite code")
+	}
+	if result.MatchedCount == 0 {
+		return errInvalidInvite
+	}
+	invite.UsedBy = &guardianID
+	invite.UsedAt = &now
+	return nil
+}
+
+func (s *GuardianService) releaseInvite(invite *models.GuardianInvite) {
+	_, err := s.db.Collection("guardian_invites").UpdateOne(s.ctx,
+		bson.M{"_id": invite.ID},
+		bson.M{"$unset": bson.M{"used_by": "", "used_at": ""}},
+	)
+	if err != nil {
+		log.Printf("Error releasing guardian invite %s: %v", invite.ID.Hex(), err)
+	}
+}
+
+func (s *GuardianService) createLink(guardian *models.User, invite *models.GuardianInvite) (*models.GuardianLink, error) {
+	link := models.GuardianLink{
+		GuardianID: guardian.ID,
+		StudentID:  invite.StudentID,
+		Relation:   invite.Relation,
+		InviteID:   invite.ID,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	result, err := s.db.Collection("guardian_links").InsertOne(s.ctx, link)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("student is already linked to your account")
+		}
+		log.Printf("Error linking guardian %s: %v", guardian.ID.Hex(), err)
+		return nil, errors.New("failed to link student")
+	}
+	link.ID = result.InsertedID.(primitive.ObjectID)
+	return &link, nil
+}
+
+func (s *GuardianService) findLinks(filter bson.M) ([]models.GuardianLink, error) {
+	cursor, err := s.db.Collection("guardian_links").Find(s.ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, errors.New("failed to fetch guardian links")
+	}
+	defer cursor.Close(s.ctx)
+
+	links := []models.GuardianLink{}
+	if err = cursor.All(s.ctx, &links); err != nil {
+		return nil, errors.New("failed to decode guardian links")
+	}
+	return links, nil
+}
+
+// generateInviteCode menghasilkan kode format XXXXX-XXXXX
+func generateInviteCode() (string, error) {
+	code, err := randomString(inviteCodeAlphabet, inviteCodeLength)
+	if err != nil {
+		return "", err
+	}
+	return code[:inviteCodeLength/2] + "-" + code[inviteCodeLength/2:], nil
+}
+
+// randomString menghasilkan n karakter acak (crypto/rand) dari alphabet
+func randomString(alphabet string, n int) (string, error) {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range b {
+		r, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[r.Int64()]
+	}
+	return string(b), nil
+}
+
+// normalizeInviteCode membuat kode tidak peka huruf besar/kecil, spasi, dan tanda hubung
+func normalizeInviteCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
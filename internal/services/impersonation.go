@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ImpersonationService mencatat setiap sesi impersonate admin sebagai jejak audit
+type ImpersonationService struct {
+	db     *mongo.Database
+	ctx    context.Context
+	config *config.Config
+}
+
+type ImpersonationServiceInterface interface {
+	StartSession(admin, user *models.User, clientIP, userAgent string) (*models.ImpersonationSession, error)
+	ListSessions(adminID, userID string, limit int) ([]models.ImpersonationSession, error)
+}
+
+func NewImpersonationService(db *mongo.Database, cfg *config.Config) ImpersonationServiceInterface {
+	return &ImpersonationService{
+		db:     db,
+		ctx:    context.Background(),
+		config: cfg,
+	}
+}
+
+// StartSession menyimpan sesi sebelum token dibuat; jika gagal disimpan token tidak boleh dikeluarkan
+func (s *ImpersonationService) StartSession(admin, user *models.User, clientIP, userAgent string) (*models.ImpersonationSession, error) {
+	now := time.Now().UTC()
+	session := models.ImpersonationSession{
+		ID:         primitive.NewObjectID(),
+		AdminID:    admin.ID,
+		AdminEmail: admin.Email,
+		UserID:     user.ID,
+		UserEmail:  user.Email,
+		UserRole:   user.GetRole(),
+		ClientIP:   clientIP,
+		UserAgent:  userAgent,
+		StartedAt:  now,
+		ExpiresAt:  now.Add(time.Duration(s.config.ImpersonationTTLMinutes) * time.Minute),
+	}
+
+	if _, err := s.sessions().InsertOne(s.ctx, session); err != nil {
+		log.Printf("Error saving impersonation session: %v", err)
+		return nil, errors.New("failed to record impersonation session")
+	}
+
+	return &session, nil
+}
+
+func (s *ImpersonationService) ListSessions(adminID, userID string, limit int) ([]models.ImpersonationSession, error) {
+	filter := bson.M{}
+	if adminID != "" {
+		objectID, err := primitive.ObjectIDFromHex(adminID)
+		if err != nil {
+			return nil, errors.New("invalid admin ID")
+		}
+		filter["admin_id"] = objectID
+	}
+	if userID != "" {
+		objectID, err := primitive.ObjectIDFromHex(userID)
+		if err != nil {
+			return nil, errors.New("invalid user ID")
+		}
+		filter["user_id"] = objectID
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	cursor, err := s.sessions().Find(s.ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, errors.New("failed to fetch impersonation sessions")
+	}
+	defer cursor.Close(s.ctx)
+
+	sessions := []models.ImpersonationSession{}
+	if err = cursor.All(s.ctx, &sessions); err != nil {
+		return nil, errors.New("failed to decode impersonation sessions")
+	}
+
+	return sessions, nil
+}
+
+func (s *ImpersonationService) sessions() *mongo.Collection {
+	return s.db.Collection("impersonation_sessions")
+}
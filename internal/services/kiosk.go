@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type KioskService struct {
+	db     *mongo.Database
+	ctx    context.Context
+	config *config.Config
+}
+
+type KioskServiceInterface interface {
+	CreateKiosk(actorID primitive.ObjectID, req *models.CreateKioskRequest) (*models.KioskCredentials, error)
+	ListKiosks() ([]models.Kiosk, error)
+	GetKiosk(id string) (*models.Kiosk, error)
+	UpdateKiosk(id string, req *models.UpdateKioskRequest) (*models.Kiosk, error)
+	RotateKey(id string) (*models.KioskCredentials, error)
+	DeleteKiosk(id string) error
+	AuthenticateKiosk(apiKey string) (*models.Kiosk, error)
+	GenerateQRCode(kiosk *models.Kiosk) *models.KioskQRCode
+	VerifyQRToken(token string) (*models.Kiosk, int64, error)
+	CheckProximity(kiosk *models.Kiosk, latitude, longitude float64) error
+	ConsumeScan(kioskID primitive.ObjectID, window int64, userID primitive.ObjectID, action string) error
+	ReleaseScan(kioskID primitive.ObjectID, window int64, userID primitive.ObjectID, action string)
+}
+
+func NewKioskService(db *mongo.Database, cfg *config.Config) KioskServiceInterface {
+	return &KioskService{
+		db:     db,
+		ctx:    context.Background(),
+		config: cfg,
+	}
+}
+
+func (s *KioskService) CreateKiosk(actorID primitive.ObjectID, req *models.CreateKioskRequest) (*models.KioskCredentials, error) {
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate kiosk secret")
+	}
+	apiKey, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate kiosk API key")
+	}
+
+	now := time.Now().UTC()
+	kiosk := models.Kiosk{
+		Name: utils.SanitizeInput(req.Name),
+		Type: req.Type,
+		Location: models.Location{
+			Latitude:  req.Latitude,
+			Longitude: req.Longitude,
+			Address:   utils.SanitizeInput(req.Address),
+		},
+		Secret:     secret,
+		APIKeyHash: hashAPIKey(apiKey),
+		IsActive:   true,
+		CreatedBy:  &actorID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	result, err := s.db.Collection("kiosks").InsertOne(s.ctx, kiosk)
+	if err != nil {
+		log.Printf("Error creating kiosk: %v", err)
+		return nil, errors.New("failed to create kiosk")
+	}
+	kiosk.ID = result.InsertedID.(primitive.ObjectID)
+
+	log.Printf("Kiosk registered: %s (ID: %s)", kiosk.Name, kiosk.ID.Hex())
+	return &models.KioskCredentials{Kiosk: kiosk, APIKey: apiKey}, nil
+}
+
+func (s *KioskService) ListKiosks() ([]models.Kiosk, error) {
+	cursor, err := s.db.Collection("kiosks").Find(s.ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, errors.New("failed to fetch kiosks")
+	}
+	defer cursor.Close(s.ctx)
+
+	kiosks := []models.Kiosk{}
+	if err = cursor.All(s.ctx, &kiosks); err != nil {
+		return nil, errors.New("failed to decode kiosks")
+	}
+
+	return kiosks, nil
+}
+
+func (s *KioskService) GetKiosk(id string) (*models.Kiosk, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid kiosk ID")
+	}
+
+	return s.findKiosk(bson.M{"_id": objectID})
+}
+
+func (s *KioskService) UpdateKiosk(id string, req *models.UpdateKioskRequest) (*models.Kiosk, error) {
+	kiosk, err := s.GetKiosk(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updateDoc := bson.M{"updated_at": time.Now().UTC()}
+	if req.Name != "" {
+		updateDoc["name"] = utils.SanitizeInput(req.Name)
+	}
+	if req.Type != "" {
+		updateDoc["type"] = req.Type
+	}
+	if req.Latitude != nil {
+		updateDoc["location.latitude"] = *req.Latitude
+	}
+	if req.Longitude != nil {
+		updateDoc["location.longitude"] = *req.Longitude
+	}
+	if req.Address != "" {
+		updateDoc["location.address"] = utils.SanitizeInput(req.Address)
+	}
+	if req.IsActive != nil {
+		updateDoc["is_active"] = *req.IsActive
+	}
+
+	if _, err := s.db.Collection("kiosks").UpdateOne(s.ctx, bson.M{"_id": kiosk.ID}, bson.M{"$set": updateDoc}); err != nil {
+		log.Printf("Error updating kiosk: %v", err)
+		return nil, errors.New("failed to update kiosk")
+	}
+
+	return s.GetKiosk(id)
+}
+
+// RotateKey mengganti API key dan secret QR, kiosk lama langsung tidak berlaku
+func (s *KioskService) RotateKey(id string) (*models.KioskCredentials, error) {
+	kiosk, err := s.GetKiosk(id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate kiosk secret")
+	}
+	apiKey, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate kiosk API key")
+	}
+
+	_, err = s.db.Collection("kiosks").UpdateOne(s.ctx, bson.M{"_id": kiosk.ID}, bson.M{"$set": bson.M{
+		"secret":       secret,
+		"api_key_hash": hashAPIKey(apiKey),
+		"updated_at":   time.Now().UTC(),
+	}})
+	if err != nil {
+		log.Printf("Error rotating kiosk key: %v", err)
+		return nil, errors.New("failed to rotate kiosk key")
+	}
+
+	kiosk, err = s.GetKiosk(id)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Kiosk key rotated: %s (ID: %s)", kiosk.Name, kiosk.ID.Hex())
+	return &models.KioskCredentials{Kiosk: *kiosk, APIKey: apiKey}, nil
+}
+
+func (s *KioskService) DeleteKiosk(id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid kiosk ID")
+	}
+
+	// Soft delete, record absensi lama tetap merujuk ke kiosk ini
+	result, err := s.db.Collection("kiosks").UpdateOne(s.ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{
+		"is_active":  false,
+		"updated_at": time.Now().UTC(),
+	}})
+	if err != nil {
+		log.Printf("Error deleting kiosk: %v", err)
+		return errors.New("failed to delete kiosk")
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("kiosk not found")
+	}
+
+	return nil
+}
+
+func (s *KioskService) AuthenticateKiosk(apiKey string) (*models.Kiosk, error) {
+	if apiKey == "" {
+		return nil, errors.New("kiosk API key is required")
+	}
+
+	kiosk, err := s.findKiosk(bson.M{"api_key_hash": hashAPIKey(apiKey), "is_active": true})
+	if err != nil {
+		return nil, errors.New("invalid kiosk API key")
+	}
+
+	now := time.Now().UTC()
+	if _, err := s.db.Collection("kiosks").UpdateOne(s.ctx, bson.M{"_id": kiosk.ID}, bson.M{"$set": bson.M{"last_seen_at": now}}); err != nil {
+		log.Printf("Warning: failed to update kiosk last seen: %v", err)
+	}
+	kiosk.LastSeenAt = &now
+
+	return kiosk, nil
+}
+
+// GenerateQRCode membuat token untuk time window saat ini: <kioskID>.<window>.<hmac>
+func (s *KioskService) GenerateQRCode(kiosk *models.Kiosk) *models.KioskQRCode {
+	period := s.rotationPeriod()
+	now := time.Now().Unix()
+	window := now / period
+
+	expiresAt := time.Unix((window+1)*period, 0).UTC()
+
+	return &models.KioskQRCode{
+		Token:     kiosk.ID.Hex() + "." + strconv.FormatInt(window, 10) + "." + signQRWindow(kiosk, window),
+		ExpiresAt: expiresAt,
+		RefreshIn: int(expiresAt.Unix() - now),
+	}
+}
+
+// VerifyQRToken memvalidasi HMAC dan memastikan token berasal dari window sekarang
+// atau satu window sebelumnya (toleransi waktu scan)
+func (s *KioskService) VerifyQRToken(token string) (*models.Kiosk, int64, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, 0, errors.New("invalid QR code")
+	}
+
+	kioskID, err := primitive.ObjectIDFromHex(parts[0])
+	if err != nil {
+		return nil, 0, errors.New("invalid QR code")
+	}
+	window, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, 0, errors.New("invalid QR code")
+	}
+
+	currentWindow := time.Now().Unix() / s.rotationPeriod()
+	if window != currentWindow && window != currentWindow-1 {
+		return nil, 0, errors.New("QR code has expired, scan the latest code")
+	}
+
+	kiosk, err := s.findKiosk(bson.M{"_id": kioskID, "is_active": true})
+	if err != nil {
+		return nil, 0, errors.New("invalid QR code")
+	}
+
+	expected := signQRWindow(kiosk, window)
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, 0, errors.New("invalid QR code")
+	}
+
+	return kiosk, window, nil
+}
+
+// ConsumeScan menandai token sudah dipakai oleh user, scan kedua ditolak
+func (s *KioskService) ConsumeScan(kioskID primitive.ObjectID, window int64, userID primitive.ObjectID, action string) error {
+	_, err := s.db.Collection("kiosk_scans").InsertOne(s.ctx, models.KioskScan{
+		KioskID:   kioskID,
+		Window:    window,
+		UserID:    userID,
+		Action:    action,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New("QR code already used, scan the latest code")
+		}
+		log.Printf("Error recording kiosk scan: %v", err)
+		return errors.New("failed to record scan")
+	}
+
+	return nil
+}
+
+// ReleaseScan menghapus tanda scan ketika absensinya gagal (di luar jendela, sudah absen),
+// supaya siswa masih bisa memakai QR yang sama setelah memperbaiki masalahnya
+func (s *KioskService) ReleaseScan(kioskID primitive.ObjectID, window int64, userID primitive.ObjectID, action string) {
+	_, err := s.db.Collection("kiosk_scans").DeleteOne(s.ctx, bson.M{
+		"kiosk_id": kioskID,
+		"window":   window,
+		"user_id":  userID,
+		"action":   action,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to release kiosk scan: %v", err)
+	}
+}
+
+// CheckProximity memastikan lokasi HP siswa saat scan berada di dekat kiosk. Foto QR yang
+// diteruskan ke siswa lain tidak berguna tanpa berada di lokasi kiosk. Kiosk tanpa
+// koordinat hanya dicek terhadap radius sekolah oleh AttendanceService.
+func (s *KioskService) CheckProximity(kiosk *models.Kiosk, latitude, longitude float64) error {
+	if kiosk.Location.Latitude == 0 && kiosk.Location.Longitude == 0 {
+		return nil
+	}
+
+	distance := utils.CalculateDistanceInMeters(latitude, longitude, kiosk.Location.Latitude, kiosk.Location.Longitude)
+	if distance > s.config.KioskRadiusMeters {
+		return errors.New("you must be near the kiosk to scan its QR code")
+	}
+	return nil
+}
+
+func (s *KioskService) findKiosk(filter bson.M) (*models.Kiosk, error) {
+	var kiosk models.Kiosk
+	err := s.db.Collection("kiosks").FindOne(s.ctx, filter).Decode(&kiosk)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("kiosk not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	return &kiosk, nil
+}
+
+func (s *KioskService) rotationPeriod() int64 {
+	if s.config.KioskQRRotationSeconds <= 0 {
+		return 30
+	}
+	return int64(s.config.KioskQRRotationSeconds)
+}
+
+func signQRWindow(kiosk *models.Kiosk, window int64) string {
+	mac := hmac.New(sha256.New, []byte(kiosk.Secret))
+	mac.Write([]byte(kiosk.ID.Hex() + ":" + strconv.FormatInt(window, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func hashAPIKey(apiKey string) string {
+	hash := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	leaveMaxDays      = 14 // panjang rentang satu pengajuan
+	leaveBackdateDays = 7  // surat sakit boleh menyusul setelah siswa masuk lagi
+	leaveAdvanceDays  = 30
+)
+
+type LeaveService struct {
+	db                *mongo.Database
+	ctx               context.Context
+	config            *config.Config
+	attendanceService AttendanceServiceInterface
+}
+
+type LeaveServiceInterface interface {
+	SubmitRequest(actor *models.User, studentID primitive.ObjectID, req *models.LeaveRequestInput) (*models.LeaveRequest, error)
+	ListStudentRequests(studentID primitive.ObjectID) ([]models.LeaveRequest, error)
+	ListRequests(actor *models.User, status string) ([]models.LeaveRequestReport, error)
+	ReviewRequest(actor *models.User, requestID string, approve bool, note string) (*models.LeaveRequest, error)
+}
+
+func NewLeaveService(db *mongo.Database, cfg *config.Config) LeaveServiceInterface {
+	return &LeaveService{
+		db:                db,
+		ctx:               context.Background(),
+		config:            cfg,
+		attendanceService: NewAttendanceService(db, cfg),
+	}
+}
+
+// SubmitRequest mencatat pengajuan dari siswa sendiri atau orang tuanya. Orang tua hanya
+// boleh mengajukan surat sakit, izin tetap diajukan siswa.
+func (s *LeaveService) SubmitRequest(actor *models.User, studentID primitive.ObjectID, req *models.LeaveRequestInput) (*models.LeaveRequest, error) {
+	if actor.HasRole(models.RoleGuardian) && req.Type != models.LeaveTypeSick {
+		return nil, errors.New("guardians can only submit sick notes")
+	}
+
+	from, err := time.Parse("2006-01-02", req.From)
+	if err != nil {
+		return nil, errors.New("invalid from date, use YYYY-MM-DD")
+	}
+	to, err := time.Parse("2006-01-02", req.To)
+	if err != nil {
+		return nil, errors.New("invalid to date, use YYYY-MM-DD")
+	}
+
+	today := s.config.Today()
+	switch {
+	case to.Before(from):
+		return nil, errors.New("to date must not be before from date")
+	case int(to.Sub(from).Hours()/24)+1 > leaveMaxDays:
+		return nil, errors.New("leave request cannot exceed 14 days")
+	case from.Before(today.AddDate(0, 0, -leaveBackdateDays)):
+		return nil, errors.New("leave request can only be submitted up to 7 days after the date")
+	case to.After(today.AddDate(0, 0, leaveAdvanceDays)):
+		return nil, errors.New("leave request can only be submitted up to 30 days in advance")
+	}
+
+	collection := s.db.Collection("leave_requests")
+	count, err := collection.CountDocuments(s.ctx, bson.M{
+		"student_id": studentID,
+		"status":     bson.M{"$in": []string{models.LeaveStatusPending, models.LeaveStatusApproved}},
+		"from":       bson.M{"$lte": to},
+		"to":         bson.M{"$gte": from},
+	})
+	if err != nil {
+		return nil, errors.New("database error")
+	}
+	if count > 0 {
+		return nil, errors.New("another leave request already covers some of these dates")
+	}
+
+	now := time.Now().UTC()
+	request := models.LeaveRequest{
+		StudentID:       studentID,
+		Type:            req.Type,
+		From:            from,
+		To:              to,
+		Reason:          utils.SanitizeInput(req.Reason),
+		EvidenceURL:     req.EvidenceURL,
+		Status:          models.LeaveStatusPending,
+		SubmittedBy:     actor.ID,
+		SubmittedByRole: actor.GetRole(),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	result, err := collection.InsertOne(s.ctx, request)
+	if err != nil {
+		log.Printf("Error creating leave request: %v", err)
+		return nil, errors.New("failed to create leave request")
+	}
+	request.ID = result.InsertedID.(primitive.ObjectID)
+
+	log.Printf("Leave request %s (%s) for student %s submitted by %s", request.ID.Hex(), request.Type, studentID.Hex(), actor.Email)
+	return &request, nil
+}
+
+func (s *LeaveService) ListStudentRequests(studentID primitive.ObjectID) ([]models.LeaveRequest, error) {
+	return s.findRequests(bson.M{"student_id": studentID})
+}
+
+func (s *LeaveService) ListRequests(actor *models.User, status string) ([]models.LeaveRequestReport, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	students, err := manageableStudentIDs(s.ctx, s.db, actor)
+	if err != nil {
+		return nil, err
+	}
+	if students != nil {
+		ids := []primitive.ObjectID{}
+		for id := range students {
+			ids = append(ids, id)
+		}
+		filter["student_id"] = bson.M{"$in": ids}
+	}
+
+	requests, err := s.findRequests(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []primitive.ObjectID
+	for _, request := range requests {
+		ids = append(ids, request.StudentID)
+	}
+	users, err := loadUsersByID(s.ctx, s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]models.LeaveRequestReport, 0, len(requests))
+	for _, request := range requests {
+		user := users[request.StudentID]
+		reports = append(reports, models.LeaveRequestReport{
+			LeaveRequest: request,
+			User:         user.ToPublic(),
+		})
+	}
+	return reports, nil
+}
+
+// ReviewRequest menyetujui atau menolak pengajuan. Persetujuan mencatat setiap hari sekolah
+// di rentang tanggal sebagai sick/excused lewat ApplyCorrection, kecuali hari siswa ternyata
+// sudah check in. Hari yang gagal dicatat dikembalikan di failed_dates; jika tidak ada satu
+// hari pun yang berhasil, pengajuan dikembalikan ke pending supaya bisa disetujui ulang.
+func (s *LeaveService) ReviewRequest(actor *models.User, requestID string, approve bool, note string) (*models.LeaveRequest, error) {
+	objectID, err := primitive.ObjectIDFromHex(requestID)
+	if err != nil {
+		return nil, errors.New("invalid request ID")
+	}
+
+	collection := s.db.Collection("leave_requests")
+	var request models.LeaveRequest
+	if err := collection.FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&request); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("leave request not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	if request.Status != models.LeaveStatusPending {
+		return nil, errors.New("leave request has already been reviewed")
+	}
+
+	var student models.User
+	if err := s.db.Collection("users").FindOne(s.ctx, bson.M{"_id": request.StudentID}).Decode(&student); err != nil {
+		return nil, errors.New("student not found")
+	}
+	if !CanManageStudent(actor, &student) {
+		return nil, errors.New("you are not the homeroom teacher of this student")
+	}
+
+	// klaim request dulu (pending -> processing) supaya hanya satu reviewer yang menulis
+	// ke record absensi; reviewer lain langsung ditolak
+	now := time.Now().UTC()
+	err = collection.FindOneAndUpdate(
+		s.ctx,
+		bson.M{"_id": request.ID, "status": models.LeaveStatusPending},
+		bson.M{"$set": bson.M{"status": models.LeaveStatusProcessing, "reviewed_by": actor.ID, "updated_at": now}},
+	).Err()
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("leave request has already been reviewed")
+	}
+	if err != nil {
+		log.Printf("Error claiming leave request %s: %v", request.ID.Hex(), err)
+		return nil, errors.New("failed to update leave request")
+	}
+
+	status := models.LeaveStatusRejected
+	note = utils.SanitizeInput(note)
+	request.ReviewNote = note
+	update := bson.M{
+		"reviewed_by": actor.ID,
+		"reviewed_at": now,
+		"review_note": note,
+		"updated_at":  now,
+	}
+
+	if approve {
+		status = models.LeaveStatusApproved
+
+		ids, failures := s.applyLeave(actor, &request)
+		if len(ids) == 0 && len(failures) > 0 {
+			s.releaseClaim(request.ID)
+			return nil, errors.New("failed to record attendance for " + failures[0].Date + ": " + failures[0].Error)
+		}
+
+		request.AttendanceIDs = ids
+		request.FailedDates = failures
+		update["attendance_ids"] = ids
+		if len(failures) > 0 {
+			update["failed_dates"] = failures
+		}
+	}
+	update["status"] = status
+
+	_, err = collection.UpdateOne(s.ctx, bson.M{"_id": request.ID, "status": models.LeaveStatusProcessing}, bson.M{"$set": update})
+	if err != nil {
+		// absensi sudah tercatat, request tetap processing dan bisa dicek manual
+		log.Printf("Error finishing leave request %s: %v", request.ID.Hex(), err)
+		return nil, errors.New("failed to update leave request")
+	}
+
+	request.Status = status
+	request.ReviewedBy = &actor.ID
+	request.ReviewedAt = &now
+	request.UpdatedAt = now
+
+	log.Printf("Leave request %s %s by %s (%d day(s) failed)", request.ID.Hex(), status, actor.Email, len(request.FailedDates))
+	return &request, nil
+}
+
+// applyLeave mencatat setiap hari sekolah di rentang pengajuan dan mengembalikan record yang
+// berhasil beserta hari yang gagal
+func (s *LeaveService) applyLeave(actor *models.User, request *models.LeaveRequest) ([]primitive.ObjectID, []models.LeaveDayFailure) {
+	label := "Sick note approved: "
+	if request.Type == models.LeaveTypeExcused {
+		label = "Leave request approved: "
+	}
+	reason := label + request.Reason
+	if request.ReviewNote != "" {
+		reason += " (" + request.ReviewNote + ")"
+	}
+
+	ids := []primitive.ObjectID{}
+	var failures []models.LeaveDayFailure
+	for date := request.From; !date.After(request.To); date = date.AddDate(0, 0, 1) {
+		if !utils.IsSchoolDay(date) {
+			continue
+		}
+
+		existing, err := s.attendanceService.GetAttendanceByDate(request.StudentID.Hex(), date)
+		if err == nil && existing != nil && !existing.Voided && existing.CheckIn != nil {
+			continue
+		}
+
+		attendance, err := s.attendanceService.ApplyCorrection(actor, &AttendanceCorrection{
+			StudentID: request.StudentID,
+			Date:      date,
+			Status:    request.Type,
+			Reason:    reason,
+			Source:    models.CorrectionSourceLeave,
+			RequestID: &request.ID,
+		})
+		if err != nil {
+			log.Printf("Error applying leave request %s for %s: %v", request.ID.Hex(), dateKey(date), err)
+			failures = append(failures, models.LeaveDayFailure{Date: dateKey(date), Error: err.Error()})
+			continue
+		}
+		ids = append(ids, attendance.ID)
+	}
+	return ids, failures
+}
+
+func (s *LeaveService) releaseClaim(requestID primitive.ObjectID) {
+	_, err := s.db.Collection("leave_requests").UpdateOne(
+		s.ctx,
+		bson.M{"_id": requestID, "status": models.LeaveStatusProcessing},
+		bson.M{
+			"$set":   bson.M{"status": models.LeaveStatusPending, "updated_at": time.Now().UTC()},
+			"$unset": bson.M{"reviewed_by": ""},
+		},
+	)
+	if err != nil {
+		log.Printf("Warning: failed to release leave request %s: %v", requestID.Hex(), err)
+	}
+}
+
+func (s *LeaveService) findRequests(filter bson.M) ([]models.LeaveRequest, error) {
+	cursor, err := s.db.Collection("leave_requests").Find(
+		s.ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, errors.New("failed to fetch leave requests")
+	}
+	defer cursor.Close(s.ctx)
+
+	requests := []models.LeaveRequest{}
+	if err = cursor.All(s.ctx, &requests); err != nil {
+		return nil, errors.New("failed to decode leave requests")
+	}
+
+	return requests, nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"ujikom-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// leaveAttendance mencatat koreksi di memori dan gagal untuk tanggal tertentu
+type leaveAttendance struct {
+	AttendanceServiceInterface
+	checkedIn map[string]bool
+	fail      map[string]error
+	applied   []string
+}
+
+func (f *leaveAttendance) GetAttendanceByDate(userID string, date time.Time) (*models.Attendance, error) {
+	if f.checkedIn[dateKey(date)] {
+		checkIn := date.Add(7 * time.Hour)
+		return &models.Attendance{CheckIn: &checkIn}, nil
+	}
+	return nil, nil
+}
+
+func (f *leaveAttendance) ApplyCorrection(actor *models.User, correction *AttendanceCorrection) (*models.Attendance, error) {
+	key := dateKey(correction.Date)
+	if err := f.fail[key]; err != nil {
+		return nil, err
+	}
+	f.applied = append(f.applied, key)
+	return &models.Attendance{ID: primitive.NewObjectID(), DateKey: key}, nil
+}
+
+func TestApplyLeaveReportsFailedDays(t *testing.T) {
+	attendance := &leaveAttendance{
+		checkedIn: map[string]bool{"2026-10-20": true},
+		fail:      map[string]error{"2026-10-21": errAttendanceChanged},
+	}
+	service := &LeaveService{attendanceService: attendance}
+	request := &models.LeaveRequest{
+		ID:        primitive.NewObjectID(),
+		StudentID: primitive.NewObjectID(),
+		Type:      models.LeaveTypeSick,
+		// Senin sampai Senin berikutnya, akhir pekan dilewati
+		From:   time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC),
+		Reason: "Demam",
+	}
+
+	ids, failures := service.applyLeave(&models.User{}, request)
+
+	wantApplied := []string{"2026-10-19", "2026-10-22", "2026-10-23", "2026-10-26"}
+	if len(ids) != len(wantApplied) || len(attendance.applied) != len(wantApplied) {
+		t.Fatalf("applied %v, want %v", attendance.applied, wantApplied)
+	}
+	for i, key := range wantApplied {
+		if attendance.applied[i] != key {
+			t.Errorf("applied %v, want %v", attendance.applied, wantApplied)
+			break
+		}
+	}
+	if len(failures) != 1 || failures[0].Date != "2026-10-21" || failures[0].Error != errAttendanceChanged.Error() {
+		t.Errorf("failures = %+v, want only 2026-10-21 with the conflict error", failures)
+	}
+}
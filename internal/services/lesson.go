@@ -0,0 +1,682 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LessonService struct {
+	db                *mongo.Database
+	ctx               context.Context
+	config            *config.Config
+	timetableService  TimetableServiceInterface
+	attendanceService AttendanceServiceInterface
+}
+
+type LessonServiceInterface interface {
+	OpenSession(actor *models.User, req *models.OpenSessionRequest) (*models.LessonSession, error)
+	ListTeacherSessions(actor *models.User, date time.Time) ([]models.LessonSession, error)
+	GetSessionDetail(actor *models.User, sessionID string) (*models.LessonSessionDetail, error)
+	CloseSession(actor *models.User, sessionID string) (*models.LessonSession, error)
+	BulkMark(actor *models.User, sessionID string, req *models.BulkMarkRequest) ([]models.LessonAttendance, error)
+
+	ListOpenSessions(student *models.User) ([]models.LessonSession, error)
+	CheckIn(student *models.User, sessionID string, req *models.AttendanceRequest) (*models.LessonAttendance, error)
+	GetLessonHistory(userID primitive.ObjectID, date time.Time) ([]models.LessonAttendance, error)
+
+	GetSubjectReport(actor *models.User, subjectID, classID string, from, to time.Time) (*models.SubjectReport, error)
+}
+
+func NewLessonService(db *mongo.Database, cfg *config.Config) LessonServiceInterface {
+	return &LessonService{
+		db:                db,
+		ctx:               context.Background(),
+		config:            cfg,
+		timetableService:  NewTimetableService(db),
+		attendanceService: NewAttendanceService(db, cfg),
+	}
+}
+
+func (s *LessonService) OpenSession(actor *models.User, req *models.OpenSessionRequest) (*models.LessonSession, error) {
+	period, err := s.timetableService.GetPeriod(req.PeriodID)
+	if err != nil {
+		return nil, err
+	}
+	if !period.IsActive {
+		return nil, errors.New("timetable period is no longer active")
+	}
+	if !actor.HasRole(models.RoleAdmin) && period.TeacherID != actor.ID {
+		return nil, errors.New("you are not the teacher of this lesson")
+	}
+
+	date := s.config.Today()
+	if req.Date != "" {
+		date, err = time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			return nil, errors.New("invalid date format, use YYYY-MM-DD")
+		}
+	}
+	if isoWeekday(date) != period.DayOfWeek {
+		return nil, errors.New("lesson is not scheduled on this date")
+	}
+
+	now := time.Now().UTC()
+	session := models.LessonSession{
+		PeriodID:  period.ID,
+		ClassID:   period.ClassID,
+		Kelas:     period.Kelas,
+		SubjectID: period.SubjectID,
+		TeacherID: period.TeacherID,
+		Date:      date,
+		StartTime: period.StartTime,
+		EndTime:   period.EndTime,
+		Status:    models.SessionStatusOpen,
+		OpenedAt:  now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	result, err := s.db.Collection("lesson_sessions").InsertOne(s.ctx, session)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("session for this lesson has already been opened")
+		}
+		log.Printf("Error opening lesson session: %v", err)
+		return nil, errors.New("failed to open lesson session")
+	}
+	session.ID = result.InsertedID.(primitive.ObjectID)
+
+	log.Printf("Lesson session %s opened for %s by %s", session.ID.Hex(), session.Kelas, actor.Email)
+	return &session, nil
+}
+
+func (s *LessonService) ListTeacherSessions(actor *models.User, date time.Time) ([]models.LessonSession, error) {
+	filter := bson.M{"date": startOfDayUTC(date)}
+	if !actor.HasRole(models.RoleAdmin) {
+		filter["teacher_id"] = actor.ID
+	}
+
+	return s.findSessions(filter)
+}
+
+func (s *LessonService) GetSessionDetail(actor *models.User, sessionID string) (*models.LessonSessionDetail, error) {
+	session, err := s.getTeacherSession(actor, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	students, err := s.classStudents(session.ClassID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.findRecords(bson.M{"session_id": session.ID})
+	if err != nil {
+		return nil, err
+	}
+	byUser := make(map[primitive.ObjectID]models.LessonAttendance)
+	for _, record := range records {
+		byUser[record.UserID] = record
+	}
+
+	roster := make([]models.LessonRosterEntry, 0, len(students))
+	for _, student := range students {
+		entry := models.LessonRosterEntry{User: student.ToPublic()}
+		if record, ok := byUser[student.ID]; ok {
+			entry.Attendance = &record
+		}
+		roster = append(roster, entry)
+	}
+
+	return &models.LessonSessionDetail{Session: *session, Roster: roster}, nil
+}
+
+// CloseSession menutup sesi, siswa yang belum absen otomatis ditandai absent
+func (s *LessonService) CloseSession(actor *models.User, sessionID string) (*models.LessonSession, error) {
+	session, err := s.getTeacherSession(actor, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status == models.SessionStatusClosed {
+		return nil, errors.New("lesson session is already closed")
+	}
+
+	students, err := s.classStudents(session.ClassID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.findRecords(bson.M{"session_id": session.ID})
+	if err != nil {
+		return nil, err
+	}
+	marked := make(map[primitive.ObjectID]bool)
+	for _, record := range records {
+		marked[record.UserID] = true
+	}
+
+	now := time.Now().UTC()
+	for _, student := range students {
+		if marked[student.ID] {
+			continue
+		}
+		record := models.LessonAttendance{
+			SessionID: session.ID,
+			PeriodID:  session.PeriodID,
+			SubjectID: session.SubjectID,
+			UserID:    student.ID,
+			Date:      session.Date,
+			Status:    models.LessonStatusAbsent,
+			Method:    models.LessonMarkTeacher,
+			MarkedBy:  &actor.ID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if _, err := s.db.Collection("lesson_attendances").InsertOne(s.ctx, record); err != nil && !mongo.IsDuplicateKeyError(err) {
+			log.Printf("Error marking absent for %s: %v", student.ID.Hex(), err)
+			return nil, errors.New("failed to mark absent students")
+		}
+	}
+
+	_, err = s.db.Collection("lesson_sessions").UpdateOne(s.ctx, bson.M{"_id": session.ID}, bson.M{"$set": bson.M{
+		"status":     models.SessionStatusClosed,
+		"closed_at":  now,
+		"updated_at": now,
+	}})
+	if err != nil {
+		log.Printf("Error closing lesson session: %v", err)
+		return nil, errors.New("failed to close lesson session")
+	}
+
+	for _, student := range students {
+		s.deriveDailyAttendance(student.ID, session.Date)
+	}
+
+	session.Status = models.SessionStatusClosed
+	session.ClosedAt = &now
+	session.UpdatedAt = now
+
+	return session, nil
+}
+
+func (s *LessonService) BulkMark(actor *models.User, sessionID string, req *models.BulkMarkRequest) ([]models.LessonAttendance, error) {
+	session, err := s.getTeacherSession(actor, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	students, err := s.classStudents(session.ClassID)
+	if err != nil {
+		return nil, err
+	}
+	inClass := make(map[primitive.ObjectID]bool)
+	for _, student := range students {
+		inClass[student.ID] = true
+	}
+
+	now := time.Now().UTC()
+	collection := s.db.Collection("lesson_attendances")
+	var studentIDs []primitive.ObjectID
+
+	for _, mark := range req.Records {
+		studentID, err := primitive.ObjectIDFromHex(mark.StudentID)
+		if err != nil || !inClass[studentID] {
+			return nil, errors.New("student " + mark.StudentID + " is not in class " + session.Kelas)
+		}
+
+		_, err = collection.UpdateOne(
+			s.ctx,
+			bson.M{"session_id": session.ID, "user_id": studentID},
+			bson.M{
+				"$set": bson.M{
+					"status":     mark.Status,
+					"method":     models.LessonMarkTeacher,
+					"marked_by":  actor.ID,
+					"note":       utils.SanitizeInput(mark.Note),
+					"updated_at": now,
+				},
+				"$setOnInsert": bson.M{
+					"period_id":  session.PeriodID,
+					"subject_id": session.SubjectID,
+					"date":       session.Date,
+					"created_at": now,
+				},
+			},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			log.Printf("Error marking lesson attendance: %v", err)
+			return nil, errors.New("failed to save lesson attendance")
+		}
+		studentIDs = append(studentIDs, studentID)
+	}
+
+	for _, studentID := range studentIDs {
+		s.deriveDailyAttendance(studentID, session.Date)
+	}
+
+	return s.findRecords(bson.M{"session_id": session.ID, "user_id": bson.M{"$in": studentIDs}})
+}
+
+func (s *LessonService) ListOpenSessions(student *models.User) ([]models.LessonSession, error) {
+	if student.ClassID == nil {
+		return []models.LessonSession{}, nil
+	}
+
+	return s.findSessions(bson.M{
+		"class_id": *student.ClassID,
+		"date":     s.config.Today(),
+		"status":   models.SessionStatusOpen,
+	})
+}
+
+func (s *LessonService) CheckIn(student *models.User, sessionID string, req *models.AttendanceRequest) (*models.LessonAttendance, error) {
+	session, err := s.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.SessionStatusOpen {
+		return nil, errors.New("lesson session is closed")
+	}
+	// sesi yang lupa ditutup tidak boleh dipakai check in di hari lain
+	if !session.Date.Equal(s.config.Today()) {
+		return nil, errors.New("lesson session is not for today")
+	}
+	if student.ClassID == nil || *student.ClassID != session.ClassID {
+		return nil, errors.New("this lesson is not for your class")
+	}
+	if !s.attendanceService.IsValidLocation(req.Latitude, req.Longitude) {
+		return nil, errors.New("location is outside school area")
+	}
+
+	now := time.Now().UTC()
+	record := models.LessonAttendance{
+		SessionID:   session.ID,
+		PeriodID:    session.PeriodID,
+		SubjectID:   session.SubjectID,
+		UserID:      student.ID,
+		Date:        session.Date,
+		Status:      s.lessonStatus(session, now),
+		Method:      models.LessonMarkSelf,
+		CheckedInAt: &now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	result, err := s.db.Collection("lesson_attendances").InsertOne(s.ctx, record)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("already checked in to this lesson")
+		}
+		log.Printf("Error creating lesson attendance: %v", err)
+		return nil, errors.New("failed to create lesson attendance")
+	}
+	record.ID = result.InsertedID.(primitive.ObjectID)
+
+	s.deriveDailyAttendance(student.ID, session.Date)
+
+	return &record, nil
+}
+
+func (s *LessonService) GetLessonHistory(userID primitive.ObjectID, date time.Time) ([]models.LessonAttendance, error) {
+	return s.findRecords(bson.M{"user_id": userID, "date": startOfDayUTC(date)})
+}
+
+func (s *LessonService) GetSubjectReport(actor *models.User, subjectID, classID string, from, to time.Time) (*models.SubjectReport, error) {
+	subject, err := s.timetableService.GetSubject(subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var class *models.Class
+	if classID != "" {
+		objectID, err := primitive.ObjectIDFromHex(classID)
+		if err != nil {
+			return nil, errors.New("invalid class ID")
+		}
+		if class, err = findClass(s.ctx, s.db, objectID); err != nil {
+			return nil, err
+		}
+	}
+
+	if !actor.HasRole(models.RoleAdmin) {
+		if class == nil {
+			return nil, errors.New("class_id is required")
+		}
+		allowed, err := s.canViewClassSubject(actor, subject.ID, class.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, errors.New("you do not teach this subject in this class")
+		}
+	}
+
+	from = startOfDayUTC(from)
+	to = startOfDayUTC(to).Add(24 * time.Hour)
+	sessionFilter := bson.M{
+		"subject_id": subject.ID,
+		"date":       bson.M{"$gte": from, "$lt": to},
+	}
+	if class != nil {
+		sessionFilter["class_id"] = class.ID
+	}
+
+	sessions, err := s.findSessions(sessionFilter)
+	if err != nil {
+		return nil, err
+	}
+	var sessionIDs []primitive.ObjectID
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.ID)
+	}
+
+	report := &models.SubjectReport{
+		Subject:  *subject,
+		From:     from,
+		To:       to.Add(-time.Nanosecond),
+		Sessions: int64(len(sessions)),
+		Students: []models.SubjectReportRow{},
+	}
+	if class != nil {
+		report.ClassID = &class.ID
+		report.Kelas = class.Name
+	}
+	if len(sessionIDs) == 0 {
+		return report, nil
+	}
+
+	cursor, err := s.db.Collection("lesson_attendances").Aggregate(s.ctx, []bson.M{
+		{"$match": bson.M{"session_id": bson.M{"$in": sessionIDs}}},
+		{"$group": bson.M{
+			"_id":   bson.M{"user_id": "$user_id", "status": "$status"},
+			"count": bson.M{"$sum": 1},
+		}},
+	})
+	if err != nil {
+		return nil, errors.New("failed to calculate subject report")
+	}
+	defer cursor.Close(s.ctx)
+
+	var results []struct {
+		ID struct {
+			UserID primitive.ObjectID `bson:"user_id"`
+			Status string             `bson:"status"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}
+	if err = cursor.All(s.ctx, &results); err != nil {
+		return nil, errors.New("failed to decode subject report")
+	}
+
+	rows := make(map[primitive.ObjectID]*models.SubjectReportRow)
+	var userIDs []primitive.ObjectID
+	for _, result := range results {
+		row, ok := rows[result.ID.UserID]
+		if !ok {
+			row = &models.SubjectReportRow{}
+			rows[result.ID.UserID] = row
+			userIDs = append(userIDs, result.ID.UserID)
+		}
+		row.Sessions += result.Count
+		switch result.ID.Status {
+		case models.LessonStatusPresent:
+			row.Present += result.Count
+		case models.LessonStatusLate:
+			row.Late += result.Count
+		case models.LessonStatusExcused:
+			row.Excused += result.Count
+		case models.LessonStatusAbsent:
+			row.Absent += result.Count
+		}
+	}
+
+	users, err := loadUsersByID(s.ctx, s.db, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range userIDs {
+		row := rows[userID]
+		user := users[userID]
+		row.User = user.ToPublic()
+		if row.Sessions > 0 {
+			row.Percentage = float64(row.Present+row.Late) / float64(row.Sessions) * 100
+		}
+		report.Students = append(report.Students, *row)
+	}
+	sort.Slice(report.Students, func(i, j int) bool {
+		return report.Students[i].User.Name < report.Students[j].User.Name
+	})
+
+	return report, nil
+}
+
+// deriveDailyAttendance menurunkan record harian dari absensi per jam pelajaran.
+// Record harian dari GPS, kiosk, atau koreksi guru tidak ditimpa.
+func (s *LessonService) deriveDailyAttendance(userID primitive.ObjectID, date time.Time) {
+	records, err := s.findRecords(bson.M{"user_id": userID, "date": date})
+	if err != nil || len(records) == 0 {
+		return
+	}
+
+	sessionIDs := make([]primitive.ObjectID, 0, len(records))
+	for _, record := range records {
+		sessionIDs = append(sessionIDs, record.SessionID)
+	}
+	sessions, err := s.findSessions(bson.M{"_id": bson.M{"$in": sessionIDs}})
+	if err != nil {
+		return
+	}
+	startTimes := make(map[primitive.ObjectID]string)
+	for _, session := range sessions {
+		startTimes[session.ID] = session.StartTime
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return startTimes[records[i].SessionID] < startTimes[records[j].SessionID]
+	})
+
+	status := models.StatusAbsent
+	var checkIn *time.Time
+	for i, record := range records {
+		if record.Status != models.LessonStatusPresent && record.Status != models.LessonStatusLate {
+			continue
+		}
+		if status == models.StatusAbsent {
+			// hadir tepat waktu di jam pertama dihitung present, selain itu late
+			status = models.StatusLate
+			if i == 0 && record.Status == models.LessonStatusPresent {
+				status = models.StatusPresent
+			}
+		}
+		if record.CheckedInAt != nil && (checkIn == nil || record.CheckedInAt.Before(*checkIn)) {
+			checkIn = record.CheckedInAt
+		}
+	}
+
+	collection := s.db.Collection("attendances")
+	var existing models.Attendance
+	err = collection.FindOne(s.ctx, bson.M{"user_id": userID, "date": dayRange(date)}).Decode(&existing)
+	now := time.Now().UTC()
+
+	if err == mongo.ErrNoDocuments {
+		attendance := models.Attendance{
+			UserID:    userID,
+			Date:      date,
+			DateKey:   dateKey(date),
+			TermID:    termIDForDate(s.ctx, s.db, date),
+			CheckIn:   checkIn,
+			Status:    status,
+			CreatedAt: now,
+			UpdatedAt: now,
+
+			VerificationMethod: models.VerificationLesson,
+		}
+		result, err := collection.InsertOne(s.ctx, attendance)
+		if err != nil {
+			if !mongo.IsDuplicateKeyError(err) {
+				log.Printf("Error deriving daily attendance for %s: %v", userID.Hex(), err)
+			}
+			return
+		}
+		attendance.ID = result.InsertedID.(primitive.ObjectID)
+
+		eventType := models.EventCheckIn
+		if status == models.StatusAbsent {
+			eventType = models.EventAbsent
+		}
+		s.attendanceService.PublishEvent(eventType, &attendance)
+		return
+	}
+	// alpa otomatis (VerificationSystem) juga diganti hasil absensi per jam
+	if err != nil || existing.Voided ||
+		(existing.VerificationMethod != models.VerificationLesson && existing.VerificationMethod != models.VerificationSystem) {
+		return
+	}
+	if existing.Status == status && timesEqual(existing.CheckIn, checkIn) {
+		return
+	}
+
+	var updated models.Attendance
+	err = collection.FindOneAndUpdate(s.ctx, bson.M{"_id": existing.ID}, bson.M{"$set": bson.M{
+		"status":              status,
+		"check_in":            checkIn,
+		"verification_method": models.VerificationLesson,
+		"updated_at":          now,
+	}}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	if err != nil {
+		log.Printf("Error deriving daily attendance for %s: %v", userID.Hex(), err)
+		return
+	}
+
+	// siswa yang tadinya alpa lalu hadir di jam berikutnya dikirim sebagai kedatangan,
+	// perubahan lain (misalnya guru mengubah tanda) sebagai koreksi
+	eventType := models.EventCorrection
+	if existing.Status == models.StatusAbsent && status != models.StatusAbsent {
+		eventType = models.EventCheckIn
+	}
+	s.attendanceService.PublishEvent(eventType, &updated)
+}
+
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// lessonStatus: hadir sebelum jam mulai + toleransi keterlambatan dihitung present
+func (s *LessonService) lessonStatus(session *models.LessonSession, at time.Time) string {
+	start, err := time.Parse("15:04", session.StartTime)
+	if err != nil {
+		return models.LessonStatusPresent
+	}
+
+	loc := s.config.Location()
+	local := at.In(loc)
+	startAt := time.Date(local.Year(), local.Month(), local.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+
+	if local.After(startAt.Add(time.Duration(s.config.GetLateThreshold()) * time.Minute)) {
+		return models.LessonStatusLate
+	}
+	return models.LessonStatusPresent
+}
+
+func (s *LessonService) canViewClassSubject(actor *models.User, subjectID, classID primitive.ObjectID) (bool, error) {
+	if actor.ClassID != nil && *actor.ClassID == classID {
+		return true, nil
+	}
+
+	count, err := s.db.Collection("timetable_periods").CountDocuments(s.ctx, bson.M{
+		"teacher_id": actor.ID,
+		"subject_id": subjectID,
+		"class_id":   classID,
+	})
+	if err != nil {
+		return false, errors.New("database error")
+	}
+	return count > 0, nil
+}
+
+func (s *LessonService) getSession(sessionID string) (*models.LessonSession, error) {
+	objectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, errors.New("invalid session ID")
+	}
+
+	var session models.LessonSession
+	if err := s.db.Collection("lesson_sessions").FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&session); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("lesson session not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	return &session, nil
+}
+
+func (s *LessonService) getTeacherSession(actor *models.User, sessionID string) (*models.LessonSession, error) {
+	session, err := s.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.HasRole(models.RoleAdmin) && session.TeacherID != actor.ID {
+		return nil, errors.New("you are not the teacher of this lesson")
+	}
+	return session, nil
+}
+
+func (s *LessonService) classStudents(classID primitive.ObjectID) ([]models.User, error) {
+	return loadClassStudents(s.ctx, s.db, classID)
+}
+
+func (s *LessonService) findSessions(filter bson.M) ([]models.LessonSession, error) {
+	cursor, err := s.db.Collection("lesson_sessions").Find(
+		s.ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "start_time", Value: 1}}),
+	)
+	if err != nil {
+		return nil, errors.New("failed to fetch lesson sessions")
+	}
+	defer cursor.Close(s.ctx)
+
+	sessions := []models.LessonSession{}
+	if err = cursor.All(s.ctx, &sessions); err != nil {
+		return nil, errors.New("failed to decode lesson sessions")
+	}
+	return sessions, nil
+}
+
+func (s *LessonService) findRecords(filter bson.M) ([]models.LessonAttendance, error) {
+	cursor, err := s.db.Collection("lesson_attendances").Find(s.ctx, filter)
+	if err != nil {
+		return nil, errors.New("failed to fetch lesson attendance")
+	}
+	defer cursor.Close(s.ctx)
+
+	records := []models.LessonAttendance{}
+	if err = cursor.All(s.ctx, &records); err != nil {
+		return nil, errors.New("failed to decode lesson attendance")
+	}
+	return records, nil
+}
+
+// isoWeekday mengembalikan 1 untuk Senin sampai 7 untuk Minggu
+func isoWeekday(t time.Time) int {
+	day := int(t.Weekday())
+	if day == 0 {
+		return 7
+	}
+	return day
+}
@@ -0,0 +1,409 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/security"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	policyRefreshInterval = time.Minute
+	// marker di collection system_markers, policy bawaan hanya diisi sekali seumur database
+	policySeedMarker = "network_policy_defaults"
+)
+
+type NetworkPolicyService struct {
+	db  *mongo.Database
+	ctx context.Context
+
+	mu       sync.RWMutex
+	seeded   bool
+	policy   *security.Policy
+	version  int64
+	loadedAt time.Time
+}
+
+type NetworkPolicyServiceInterface interface {
+	ListEntries(policyType string) ([]models.NetworkPolicyEntry, error)
+	GetEntry(id string) (*models.NetworkPolicyEntry, error)
+	CreateEntry(actorID primitive.ObjectID, req *models.CreateNetworkPolicyRequest) (*models.NetworkPolicyEntry, error)
+	UpdateEntry(actorID primitive.ObjectID, id string, req *models.UpdateNetworkPolicyRequest) (*models.NetworkPolicyEntry, error)
+	DeleteEntry(id string) error
+	CurrentPolicy() (*security.Policy, int64, error)
+	Reload() error
+	Watch(ctx context.Context)
+}
+
+func NewNetworkPolicyService(db *mongo.Database) NetworkPolicyServiceInterface {
+	return &NetworkPolicyService{
+		db:  db,
+		ctx: context.Background(),
+	}
+}
+
+func (s *NetworkPolicyService) collection() *mongo.Collection {
+	return s.db.Collection("network_policies")
+}
+
+func (s *NetworkPolicyService) ListEntries(policyType string) ([]models.NetworkPolicyEntry, error) {
+	filter := bson.M{}
+	if policyType != "" {
+		filter["type"] = policyType
+	}
+
+	cursor, err := s.collection().Find(
+		s.ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "type", Value: 1}, {Key: "value", Value: 1}}),
+	)
+	if err != nil {
+		log.Printf("Error listing network policies: %v", err)
+		return nil, errors.New("failed to fetch network policies")
+	}
+	defer cursor.Close(s.ctx)
+
+	entries := []models.NetworkPolicyEntry{}
+	if err = cursor.All(s.ctx, &entries); err != nil {
+		return nil, errors.New("failed to decode network policies")
+	}
+
+	return entries, nil
+}
+
+func (s *NetworkPolicyService) GetEntry(id string) (*models.NetworkPolicyEntry, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid policy ID")
+	}
+
+	var entry models.NetworkPolicyEntry
+	err = s.collection().FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("policy entry not found")
+		}
+		return nil, errors.New("failed to fetch policy entry")
+	}
+
+	return &entry, nil
+}
+
+func (s *NetworkPolicyService) CreateEntry(actorID primitive.ObjectID, req *models.CreateNetworkPolicyRequest) (*models.NetworkPolicyEntry, error) {
+	value, err := normalizePolicyValue(req.Type, req.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.collection().CountDocuments(s.ctx, bson.M{"type": req.Type, "value": value})
+	if err != nil {
+		return nil, errors.New("database error")
+	}
+	if count > 0 {
+		return nil, errors.New("policy entry already exists")
+	}
+
+	now := time.Now().UTC()
+	entry := models.NetworkPolicyEntry{
+		Type:        req.Type,
+		Value:       value,
+		Description: utils.SanitizeInput(req.Description),
+		IsActive:    true,
+		CreatedBy:   &actorID,
+		UpdatedBy:   &actorID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	result, err := s.collection().InsertOne(s.ctx, entry)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("policy entry already exists")
+		}
+		log.Printf("Error creating network policy: %v", err)
+		return nil, errors.New("failed to create policy entry")
+	}
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+
+	s.invalidate()
+	log.Printf("Network policy created: %s=%s by %s", entry.Type, entry.Value, actorID.Hex())
+	return &entry, nil
+}
+
+func (s *NetworkPolicyService) UpdateEntry(actorID primitive.ObjectID, id string, req *models.UpdateNetworkPolicyRequest) (*models.NetworkPolicyEntry, error) {
+	entry, err := s.GetEntry(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updateDoc := bson.M{
+		"updated_by": actorID,
+		"updated_at": time.Now().UTC(),
+	}
+
+	if req.Value != "" {
+		value, err := normalizePolicyValue(entry.Type, req.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		count, err := s.collection().CountDocuments(s.ctx, bson.M{"_id": bson.M{"$ne": entry.ID}, "type": entry.Type, "value": value})
+		if err != nil {
+			return nil, errors.New("database error")
+		}
+		if count > 0 {
+			return nil, errors.New("policy entry already exists")
+		}
+		updateDoc["value"] = value
+	}
+	if req.Description != "" {
+		updateDoc["description"] = utils.SanitizeInput(req.Description)
+	}
+	if req.IsActive != nil {
+		updateDoc["is_active"] = *req.IsActive
+	}
+
+	_, err = s.collection().UpdateOne(s.ctx, bson.M{"_id": entry.ID}, bson.M{"$set": updateDoc})
+	if err != nil {
+		// entry yang sama dibuat bersamaan setelah pengecekan di atas
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("policy entry already exists")
+		}
+		log.Printf("Error updating network policy: %v", err)
+		return nil, errors.New("failed to update policy entry")
+	}
+
+	s.invalidate()
+	return s.GetEntry(id)
+}
+
+func (s *NetworkPolicyService) DeleteEntry(id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid policy ID")
+	}
+
+	result, err := s.collection().DeleteOne(s.ctx, bson.M{"_id": objectID})
+	if err != nil {
+		log.Printf("Error deleting network policy: %v", err)
+		return errors.New("failed to delete policy entry")
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("policy entry not found")
+	}
+
+	s.invalidate()
+	return nil
+}
+
+// CurrentPolicy mengembalikan policy dari cache, dimuat ulang jika cache kosong atau kedaluwarsa.
+// Jika reload gagal, policy terakhir tetap dipakai; error hanya dikembalikan saat belum
+// ada policy sama sekali yang bisa dipakai.
+func (s *NetworkPolicyService) CurrentPolicy() (*security.Policy, int64, error) {
+	s.mu.RLock()
+	policy, version, loadedAt := s.policy, s.version, s.loadedAt
+	s.mu.RUnlock()
+
+	if policy != nil && time.Since(loadedAt) < policyRefreshInterval {
+		return policy, version, nil
+	}
+
+	if err := s.Reload(); err != nil {
+		log.Printf("Warning: failed to reload network policy: %v", err)
+		if policy != nil {
+			return policy, version, nil
+		}
+		return nil, 0, errors.New("network policy unavailable")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy, s.version, nil
+}
+
+func (s *NetworkPolicyService) Reload() error {
+	if err := s.seedDefaults(); err != nil {
+		return err
+	}
+
+	cursor, err := s.collection().Find(s.ctx, bson.M{"is_active": true})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(s.ctx)
+
+	var entries []models.NetworkPolicyEntry
+	if err = cursor.All(s.ctx, &entries); err != nil {
+		return err
+	}
+
+	policy := &security.Policy{}
+	for _, entry := range entries {
+		switch entry.Type {
+		case models.PolicyTypeIPRange:
+			policy.AllowedIPRanges = append(policy.AllowedIPRanges, entry.Value)
+		case models.PolicyTypeSSID:
+			policy.AllowedSSIDs = append(policy.AllowedSSIDs, entry.Value)
+		case models.PolicyTypeSSIDPattern:
+			policy.SSIDPatterns = append(policy.SSIDPatterns, entry.Value)
+		case models.PolicyTypeCarrier:
+			policy.AllowedCarriers = append(policy.AllowedCarriers, entry.Value)
+		case models.PolicyTypeSecureNetwork:
+			policy.SecureNetworkTypes = append(policy.SecureNetworkTypes, entry.Value)
+		}
+	}
+
+	policy.Compile()
+
+	s.mu.Lock()
+	s.policy = policy
+	s.version++
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Watch mendengarkan change stream collection network_policies supaya perubahan dari
+// replica lain langsung terlihat. Jika change stream tidak didukung, cache cukup
+// kedaluwarsa sesuai policyRefreshInterval.
+func (s *NetworkPolicyService) Watch(ctx context.Context) {
+	go func() {
+		for {
+			stream, err := s.collection().Watch(ctx, mongo.Pipeline{})
+			if err != nil {
+				log.Printf("Warning: network policy change stream unavailable, using periodic refresh: %v", err)
+				return
+			}
+
+			for stream.Next(ctx) {
+				if err := s.Reload(); err != nil {
+					log.Printf("Warning: failed to reload network policy after change: %v", err)
+				}
+			}
+			stream.Close(context.Background())
+
+			if ctx.Err() != nil {
+				return
+			}
+			time.Sleep(5 * time.Second)
+		}
+	}()
+}
+
+func (s *NetworkPolicyService) invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// Isi collection dengan policy bawaan sekali saja. Marker di system_markers mencegah seed
+// ulang setelah admin sengaja mengosongkan policy; database lama yang sudah berisi policy
+// hanya diberi marker.
+func (s *NetworkPolicyService) seedDefaults() error {
+	s.mu.RLock()
+	seeded := s.seeded
+	s.mu.RUnlock()
+	if seeded {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	result, err := s.db.Collection("system_markers").UpdateOne(
+		s.ctx,
+		bson.M{"_id": policySeedMarker},
+		bson.M{"$setOnInsert": bson.M{"created_at": now}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	if err == nil && result.UpsertedCount == 1 {
+		count, err := s.collection().CountDocuments(s.ctx, bson.M{})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := s.insertDefaults(now); err != nil {
+				// lepas marker supaya seed dicoba lagi di reload berikutnya
+				s.db.Collection("system_markers").DeleteOne(s.ctx, bson.M{"_id": policySeedMarker})
+				return err
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.seeded = true
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *NetworkPolicyService) insertDefaults(now time.Time) error {
+	defaults := security.DefaultPolicy()
+	var documents []interface{}
+	add := func(policyType string, values []string) {
+		for _, value := range values {
+			documents = append(documents, models.NetworkPolicyEntry{
+				Type:        policyType,
+				Value:       value,
+				Description: "default",
+				IsActive:    true,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			})
+		}
+	}
+	add(models.PolicyTypeIPRange, defaults.AllowedIPRanges)
+	add(models.PolicyTypeSSID, defaults.AllowedSSIDs)
+	add(models.PolicyTypeSSIDPattern, defaults.SSIDPatterns)
+	add(models.PolicyTypeCarrier, defaults.AllowedCarriers)
+	add(models.PolicyTypeSecureNetwork, defaults.SecureNetworkTypes)
+
+	if _, err := s.collection().InsertMany(s.ctx, documents); err != nil {
+		return err
+	}
+
+	log.Printf("Network policy seeded with %d default entries", len(documents))
+	return nil
+}
+
+func normalizePolicyValue(policyType, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", errors.New("value is required")
+	}
+
+	switch policyType {
+	case models.PolicyTypeIPRange:
+		if strings.Contains(value, "/") {
+			if _, _, err := net.ParseCIDR(value); err != nil {
+				return "", errors.New("invalid CIDR range")
+			}
+		} else if net.ParseIP(value) == nil {
+			return "", errors.New("invalid IP address")
+		}
+	case models.PolicyTypeSSIDPattern:
+		if _, err := security.CompileSSIDPattern(value); err != nil {
+			return "", errors.New("invalid SSID pattern")
+		}
+	case models.PolicyTypeCarrier, models.PolicyTypeSecureNetwork:
+		value = strings.ToLower(value)
+	case models.PolicyTypeSSID:
+	default:
+		return "", errors.New("invalid policy type")
+	}
+
+	return value, nil
+}
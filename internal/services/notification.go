@@ -0,0 +1,577 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/notify"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	notificationRetryDelay  = 5 * time.Minute
+	notificationStaleAfter  = 2 * time.Minute
+	notificationSendTimeout = 15 * time.Second
+)
+
+// notificationWake membangunkan worker setelah notifikasi baru masuk outbox
+var notificationWake = make(chan struct{}, 1)
+
+// defaultNotificationEvents dipakai siswa dan orang tua yang belum mengatur preferensi
+var defaultNotificationEvents = []string{models.NotifyLate, models.NotifyAbsent}
+
+// contactRequiredEvents selalu dikirim ke kontak orang tua di preferensi siswa, siswa
+// hanya bisa mematikannya untuk dirinya sendiri
+var contactRequiredEvents = []string{models.NotifyLate, models.NotifyAbsent}
+
+type NotificationService struct {
+	db      *mongo.Database
+	ctx     context.Context
+	config  *config.Config
+	senders map[string]notify.Sender
+}
+
+type NotificationServiceInterface interface {
+	GetPreferences(userID primitive.ObjectID) (*models.NotificationPreference, error)
+	UpdatePreferences(userID primitive.ObjectID, req *models.UpdateNotificationPreferenceRequest) (*models.NotificationPreference, error)
+	ListNotifications(studentID *primitive.ObjectID, status string, limit int) ([]models.Notification, error)
+	AvailableChannels() []string
+	NotifyAttendance(event *models.AttendanceEvent)
+	NotifyInvitation(user *models.User, link string, expiresAt time.Time) error
+	Start(ctx context.Context)
+}
+
+func NewNotificationService(db *mongo.Database, cfg *config.Config) NotificationServiceInterface {
+	return &NotificationService{
+		db:      db,
+		ctx:     context.Background(),
+		config:  cfg,
+		senders: notify.SendersFromConfig(cfg),
+	}
+}
+
+// GetPreferences mengembalikan preferensi tersimpan atau default jika belum pernah diatur
+func (s *NotificationService) GetPreferences(userID primitive.ObjectID) (*models.NotificationPreference, error) {
+	var pref models.NotificationPreference
+	err := s.preferences().FindOne(s.ctx, bson.M{"user_id": userID}).Decode(&pref)
+	if err == mongo.ErrNoDocuments {
+		return s.defaultPreferences(userID), nil
+	}
+	if err != nil {
+		log.Printf("Error finding notification preferences: %v", err)
+		return nil, errors.New("database error")
+	}
+	return &pref, nil
+}
+
+func (s *NotificationService) UpdatePreferences(userID primitive.ObjectID, req *models.UpdateNotificationPreferenceRequest) (*models.NotificationPreference, error) {
+	if req.QuietHours != nil && req.QuietHours.Start == req.QuietHours.End {
+		return nil, errors.New("quiet hours start and end must be different")
+	}
+	for _, channel := range req.Channels {
+		if _, ok := s.senders[channel]; !ok {
+			return nil, errors.New(channel + " notifications are not available")
+		}
+	}
+
+	contacts := make([]models.NotificationContact, 0, len(req.Contacts))
+	for _, contact := range req.Contacts {
+		if contact.Email == "" && contact.Phone == "" {
+			return nil, errors.New("contact needs an email or phone number")
+		}
+		contact.Name = utils.SanitizeInput(contact.Name)
+		contact.Relation = utils.SanitizeInput(contact.Relation)
+		contacts = append(contacts, contact)
+	}
+
+	pref := models.NotificationPreference{
+		UserID:     userID,
+		Language:   req.Language,
+		Channels:   uniqueStrings(req.Channels),
+		Events:     uniqueStrings(req.Events),
+		QuietHours: req.QuietHours,
+		Contacts:   contacts,
+		PushTokens: uniqueStrings(req.PushTokens),
+		UpdatedAt:  time.Now().UTC(),
+	}
+
+	_, err := s.preferences().ReplaceOne(s.ctx, bson.M{"user_id": userID}, pref, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Printf("Error saving notification preferences: %v", err)
+		return nil, errors.New("failed to save notification preferences")
+	}
+
+	return &pref, nil
+}
+
+// ListNotifications menampilkan outbox, studentID nil berarti semua siswa (admin)
+func (s *NotificationService) ListNotifications(studentID *primitive.ObjectID, status string, limit int) ([]models.Notification, error) {
+	filter := bson.M{}
+	if studentID != nil {
+		filter["student_id"] = *studentID
+	}
+	switch status {
+	case "":
+	case models.NotificationPending, models.NotificationSending, models.NotificationSent, models.NotificationFailed:
+		filter["status"] = status
+	default:
+		return nil, errors.New("invalid status, use pending, sending, sent or failed")
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	cursor, err := s.notifications().Find(s.ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, errors.New("failed to fetch notifications")
+	}
+	defer cursor.Close(s.ctx)
+
+	notifications := []models.Notification{}
+	if err = cursor.All(s.ctx, &notifications); err != nil {
+		return nil, errors.New("failed to decode notifications")
+	}
+
+	return notifications, nil
+}
+
+func (s *NotificationService) AvailableChannels() []string {
+	channels := make([]string, 0, len(s.senders))
+	for channel := range s.senders {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// NotifyAttendance memasukkan notifikasi untuk event absensi ke outbox: ke siswa dan kontak
+// di preferensi siswa, serta ke orang tua yang ditautkan lewat guardian_links sesuai
+// preferensi milik orang tua sendiri. Dipanggil dari publishEvent di background.
+func (s *NotificationService) NotifyAttendance(event *models.AttendanceEvent) {
+	kind, at := notificationKind(event)
+	if kind == "" || len(s.senders) == 0 {
+		return
+	}
+
+	var student models.User
+	if err := s.db.Collection("users").FindOne(s.ctx, bson.M{"_id": event.UserID}).Decode(&student); err != nil {
+		log.Printf("Warning: failed to load student for notification: %v", err)
+		return
+	}
+
+	now := time.Now().UTC()
+	queued := map[string]bool{}
+	var outbox []interface{}
+	if pref, err := s.GetPreferences(student.ID); err == nil {
+		outbox = append(outbox, s.buildNotifications(&student, event, kind, at, pref, queued, func(channel string) []notificationRecipient {
+			return notificationRecipients(&student, pref, channel, kind)
+		}, now)...)
+	}
+	for _, guardian := range s.linkedGuardians(student.ID) {
+		guardian := guardian
+		pref, err := s.GetPreferences(guardian.ID)
+		if err != nil || !containsString(pref.Events, kind) {
+			continue
+		}
+		outbox = append(outbox, s.buildNotifications(&student, event, kind, at, pref, queued, func(channel string) []notificationRecipient {
+			return guardianRecipients(&guardian, pref, channel)
+		}, now)...)
+	}
+	if len(outbox) == 0 {
+		return
+	}
+
+	if _, err := s.notifications().InsertMany(s.ctx, outbox); err != nil {
+		log.Printf("Error queueing notifications for %s: %v", student.ID.Hex(), err)
+		return
+	}
+
+	select {
+	case notificationWake <- struct{}{}:
+	default:
+	}
+}
+
+// buildNotifications membuat isi outbox untuk satu pemilik preferensi. queued mencegah
+// alamat yang sama mendapat notifikasi dua kali, mis. orang tua yang juga ada di kontak siswa.
+func (s *NotificationService) buildNotifications(student *models.User, event *models.AttendanceEvent, kind string, at *time.Time, pref *models.NotificationPreference, queued map[string]bool, recipients func(channel string) []notificationRecipient, now time.Time) []interface{} {
+	data := notify.TemplateData{
+		Name:        student.Name,
+		NIS:         student.NIS,
+		Kelas:       student.Kelas,
+		Date:        notify.FormatDate(pref.Language, event.Record.Date.UTC()),
+		MinutesLate: event.Record.MinutesLate,
+	}
+	if at != nil {
+		data.Time = at.In(s.config.Location()).Format("15:04 MST")
+	}
+	subject, body, err := notify.Render(pref.Language, kind, data)
+	if err != nil {
+		log.Printf("Error rendering notification %s: %v", kind, err)
+		return nil
+	}
+
+	sendAfter := s.quietHoursEnd(pref.QuietHours, now)
+	attendanceID := event.Record.ID
+
+	var outbox []interface{}
+	for _, channel := range pref.Channels {
+		if _, ok := s.senders[channel]; !ok {
+			continue
+		}
+		for _, recipient := range recipients(channel) {
+			if queued[channel+":"+recipient.to] {
+				continue
+			}
+			queued[channel+":"+recipient.to] = true
+			outbox = append(outbox, models.Notification{
+				StudentID:    student.ID,
+				AttendanceID: &attendanceID,
+				Event:        kind,
+				Channel:      channel,
+				Recipient:    recipient.name,
+				To:           recipient.to,
+				Language:     pref.Language,
+				Subject:      subject,
+				Body:         body,
+				Status:       models.NotificationPending,
+				SendAfter:    sendAfter,
+				CreatedAt:    now,
+				UpdatedAt:    now,
+			})
+		}
+	}
+	return outbox
+}
+
+// linkedGuardians mengembalikan akun orang tua aktif yang ditautkan ke siswa
+func (s *NotificationService) linkedGuardians(studentID primitive.ObjectID) []models.User {
+	cursor, err := s.db.Collection("guardian_links").Find(s.ctx, bson.M{"student_id": studentID})
+	if err != nil {
+		log.Printf("Warning: failed to load guardian links for notification: %v", err)
+		return nil
+	}
+	var links []models.GuardianLink
+	if err := cursor.All(s.ctx, &links); err != nil || len(links) == 0 {
+		return nil
+	}
+
+	guardianIDs := make([]primitive.ObjectID, 0, len(links))
+	for _, link := range links {
+		guardianIDs = append(guardianIDs, link.GuardianID)
+	}
+	cursor, err = s.db.Collection("users").Find(s.ctx, bson.M{
+		"_id":       bson.M{"$in": guardianIDs},
+		"role":      models.RoleGuardian,
+		"is_active": true,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to load guardians for notification: %v", err)
+		return nil
+	}
+	var guardians []models.User
+	if err := cursor.All(s.ctx, &guardians); err != nil {
+		return nil
+	}
+	return guardians
+}
+
+// NotifyInvitation mengirim email undangan berisi link pembuatan password ke akun baru.
+// Tidak mengikuti preferensi siswa karena akunnya belum pernah dipakai.
+func (s *NotificationService) NotifyInvitation(user *models.User, link string, expiresAt time.Time) error {
+	if _, ok := s.senders[models.ChannelEmail]; !ok {
+		return errors.New("email notifications are not configured")
+	}
+
+	language := s.config.NotificationLanguage
+	subject, body, err := notify.Render(language, models.NotifyInvitation, notify.TemplateData{
+		Name:  user.Name,
+		NIS:   user.NIS,
+		Kelas: user.Kelas,
+		Email: user.Email,
+		Link:  link,
+		Date:  notify.FormatDate(language, expiresAt.In(s.config.Location())),
+	})
+	if err != nil {
+		log.Printf("Error rendering invitation: %v", err)
+		return errors.New("failed to render invitation")
+	}
+
+	now := time.Now().UTC()
+	_, err = s.notifications().InsertOne(s.ctx, models.Notification{
+		StudentID: user.ID,
+		Event:     models.NotifyInvitation,
+		Channel:   models.ChannelEmail,
+		Recipient: user.Name,
+		To:        user.Email,
+		Language:  language,
+		Subject:   subject,
+		Body:      body,
+		Status:    models.NotificationPending,
+		SendAfter: now,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		log.Printf("Error queueing invitation for %s: %v", user.ID.Hex(), err)
+		return errors.New("failed to queue invitation")
+	}
+
+	select {
+	case notificationWake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start menjalankan worker outbox; aman di banyak instance karena notifikasi diambil atomik
+func (s *NotificationService) Start(ctx context.Context) {
+	if len(s.senders) == 0 {
+		log.Println("Notifications: no channel configured")
+	} else {
+		log.Printf("Notifications: channels %v", s.AvailableChannels())
+	}
+
+	go func() {
+		ticker := time.NewTicker(15 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			s.requeueStale()
+			for {
+				notification, err := s.claim()
+				if err != nil || notification == nil {
+					break
+				}
+				s.send(notification)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-notificationWake:
+			}
+		}
+	}()
+}
+
+func (s *NotificationService) claim() (*models.Notification, error) {
+	now := time.Now().UTC()
+
+	var notification models.Notification
+	err := s.notifications().FindOneAndUpdate(
+		s.ctx,
+		bson.M{"status": models.NotificationPending, "send_after": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"status": models.NotificationSending, "locked_at": now}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "send_after", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&notification)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+func (s *NotificationService) send(notification *models.Notification) {
+	err := errors.New(notification.Channel + " channel is not configured")
+	if sender, ok := s.senders[notification.Channel]; ok {
+		ctx, cancel := context.WithTimeout(s.ctx, notificationSendTimeout)
+		err = sender.Send(ctx, notify.Message{
+			To:      notification.To,
+			Subject: notification.Subject,
+			Body:    notification.Body,
+		})
+		cancel()
+	}
+
+	now := time.Now().UTC()
+	attempts := notification.Attempts + 1
+	set := bson.M{"attempts": attempts, "updated_at": now}
+	unset := bson.M{"locked_at": ""}
+	switch {
+	case err == nil:
+		set["status"] = models.NotificationSent
+		set["sent_at"] = now
+		unset["error"] = ""
+	case attempts >= s.config.NotificationMaxAttempts:
+		set["status"] = models.NotificationFailed
+		set["error"] = err.Error()
+		log.Printf("Notification %s via %s failed after %d attempts: %v", notification.ID.Hex(), notification.Channel, attempts, err)
+	default:
+		set["status"] = models.NotificationPending
+		set["error"] = err.Error()
+		set["send_after"] = now.Add(time.Duration(attempts) * notificationRetryDelay)
+	}
+
+	if _, err := s.notifications().UpdateOne(s.ctx, bson.M{"_id": notification.ID}, bson.M{"$set": set, "$unset": unset}); err != nil {
+		log.Printf("Error updating notification %s: %v", notification.ID.Hex(), err)
+	}
+}
+
+func (s *NotificationService) requeueStale() {
+	_, err := s.notifications().UpdateMany(s.ctx, bson.M{
+		"status":    models.NotificationSending,
+		"locked_at": bson.M{"$lt": time.Now().UTC().Add(-notificationStaleAfter)},
+	}, bson.M{"$set": bson.M{"status": models.NotificationPending}})
+	if err != nil {
+		log.Printf("Error requeueing stale notifications: %v", err)
+	}
+}
+
+// quietHoursEnd mengembalikan now, atau akhir jam tenang jika now berada di dalamnya
+func (s *NotificationService) quietHoursEnd(quiet *models.QuietHours, now time.Time) time.Time {
+	if quiet == nil {
+		return now
+	}
+
+	local := now.In(s.config.Location())
+	clock := local.Format("15:04")
+	inQuiet := clock >= quiet.Start && clock < quiet.End
+	if quiet.Start > quiet.End {
+		inQuiet = clock >= quiet.Start || clock < quiet.End
+	}
+	if !inQuiet {
+		return now
+	}
+
+	end, err := time.Parse("15:04", quiet.End)
+	if err != nil {
+		return now
+	}
+	until := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, local.Location())
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until.UTC()
+}
+
+func (s *NotificationService) defaultPreferences(userID primitive.ObjectID) *models.NotificationPreference {
+	channels := []string{}
+	if _, ok := s.senders[models.ChannelEmail]; ok {
+		channels = append(channels, models.ChannelEmail)
+	}
+	return &models.NotificationPreference{
+		UserID:     userID,
+		Language:   s.config.NotificationLanguage,
+		Channels:   channels,
+		Events:     defaultNotificationEvents,
+		Contacts:   []models.NotificationContact{},
+		PushTokens: []string{},
+	}
+}
+
+func (s *NotificationService) preferences() *mongo.Collection {
+	return s.db.Collection("notification_preferences")
+}
+
+func (s *NotificationService) notifications() *mongo.Collection {
+	return s.db.Collection("notifications")
+}
+
+// notificationKind memetakan event absensi ke jenis notifikasi beserta jam kejadiannya
+func notificationKind(event *models.AttendanceEvent) (string, *time.Time) {
+	switch event.Type {
+	case models.EventCheckIn:
+		if event.Record.Status == models.StatusLate || event.Record.Status == models.StatusVeryLate {
+			return models.NotifyLate, event.Record.CheckIn
+		}
+		return models.NotifyArrival, event.Record.CheckIn
+	case models.EventCheckOut:
+		return models.NotifyDeparture, event.Record.CheckOut
+	case models.EventAbsent:
+		return models.NotifyAbsent, nil
+	}
+	return "", nil
+}
+
+type notificationRecipient struct {
+	name string
+	to   string
+}
+
+// notificationRecipients mengembalikan alamat tujuan channel dari preferensi siswa. Siswa
+// menerima event yang dipilihnya, kontak orang tua selalu menerima contactRequiredEvents.
+func notificationRecipients(student *models.User, pref *models.NotificationPreference, channel, kind string) []notificationRecipient {
+	var recipients []notificationRecipient
+	add := func(name, to string) {
+		if to != "" {
+			recipients = append(recipients, notificationRecipient{name: name, to: to})
+		}
+	}
+	toStudent := containsString(pref.Events, kind)
+	toContacts := toStudent || containsString(contactRequiredEvents, kind)
+
+	switch channel {
+	case models.ChannelEmail:
+		if toStudent {
+			add(student.Name, student.Email)
+		}
+		if toContacts {
+			for _, contact := range pref.Contacts {
+				add(contact.Name, contact.Email)
+			}
+		}
+	case models.ChannelWhatsApp, models.ChannelSMS:
+		if toStudent {
+			add(student.Name, student.Phone)
+		}
+		if toContacts {
+			for _, contact := range pref.Contacts {
+				add(contact.Name, contact.Phone)
+			}
+		}
+	case models.ChannelPush:
+		if toStudent {
+			for _, token := range pref.PushTokens {
+				add(student.Name, token)
+			}
+		}
+	}
+	return recipients
+}
+
+// guardianRecipients mengembalikan alamat orang tua yang ditautkan untuk channel tersebut
+func guardianRecipients(guardian *models.User, pref *models.NotificationPreference, channel string) []notificationRecipient {
+	var recipients []notificationRecipient
+	add := func(to string) {
+		if to != "" {
+			recipients = append(recipients, notificationRecipient{name: guardian.Name, to: to})
+		}
+	}
+
+	switch channel {
+	case models.ChannelEmail:
+		add(guardian.Email)
+	case models.ChannelWhatsApp, models.ChannelSMS:
+		add(guardian.Phone)
+	case models.ChannelPush:
+		for _, token := range pref.PushTokens {
+			add(token)
+		}
+	}
+	return recipients
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+	"ujikom-backend/internal/models"
+)
+
+func recipientAddresses(recipients []notificationRecipient) []string {
+	addresses := []string{}
+	for _, recipient := range recipients {
+		addresses = append(addresses, recipient.to)
+	}
+	return addresses
+}
+
+func TestNotificationRecipients(t *testing.T) {
+	student := &models.User{Name: "Budi", Email: "budi@example.com", Phone: "081200000001"}
+	contacts := []models.NotificationContact{{Name: "Ibu Siti", Email: "siti@example.com", Phone: "081200000002"}}
+
+	tests := []struct {
+		name    string
+		events  []string
+		channel string
+		kind    string
+		want    []string
+	}{
+		{"chosen event reaches student and contact", []string{models.NotifyArrival}, models.ChannelEmail, models.NotifyArrival, []string{"budi@example.com", "siti@example.com"}},
+		{"unchosen event reaches nobody", []string{models.NotifyLate}, models.ChannelEmail, models.NotifyArrival, []string{}},
+		{"late removed by student still reaches contact", []string{}, models.ChannelEmail, models.NotifyLate, []string{"siti@example.com"}},
+		{"absent removed by student still reaches contact", []string{models.NotifyArrival}, models.ChannelWhatsApp, models.NotifyAbsent, []string{"081200000002"}},
+		{"push only goes to the student", []string{}, models.ChannelPush, models.NotifyLate, []string{}},
+		{"push for chosen event", []string{models.NotifyLate}, models.ChannelPush, models.NotifyLate, []string{"push-token"}},
+	}
+
+	for _, tt := range tests {
+		pref := &models.NotificationPreference{Events: tt.events, Contacts: contacts, PushTokens: []string{"push-token"}}
+		got := recipientAddresses(notificationRecipients(student, pref, tt.channel, tt.kind))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: recipients = %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: recipients = %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestGuardianRecipients(t *testing.T) {
+	guardian := &models.User{Name: "Pak Joko", Email: "joko@example.com", Phone: "081200000003"}
+	pref := &models.NotificationPreference{PushTokens: []string{"guardian-token"}}
+
+	tests := []struct {
+		channel string
+		want    string
+	}{
+		{models.ChannelEmail, "joko@example.com"},
+		{models.ChannelSMS, "081200000003"},
+		{models.ChannelWhatsApp, "081200000003"},
+		{models.ChannelPush, "guardian-token"},
+	}
+	for _, tt := range tests {
+		got := guardianRecipients(guardian, pref, tt.channel)
+		if len(got) != 1 || got[0].to != tt.want || got[0].name != guardian.Name {
+			t.Errorf("%s: recipients = %+v, want %s", tt.channel, got, tt.want)
+		}
+	}
+
+	if got := guardianRecipients(&models.User{Name: "Tanpa HP"}, pref, models.ChannelSMS); len(got) != 0 {
+		t.Errorf("guardian without phone: recipients = %+v, want none", got)
+	}
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/security"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const offlineSyncPath = "/api/v1/attendance/offline/sync"
+
+var errOfflineDuplicate = errors.New("attendance already recorded for this date")
+
+type OfflineService struct {
+	db                *mongo.Database
+	ctx               context.Context
+	config            *config.Config
+	deviceService     DeviceServiceInterface
+	attendanceService AttendanceServiceInterface
+	policies          NetworkPolicyServiceInterface
+	engine            *security.Engine
+}
+
+type OfflineServiceInterface interface {
+	IssueNonce(userID primitive.ObjectID, deviceID string, date time.Time) (*models.OfflineNonce, error)
+	Sync(user *models.User, req *models.OfflineSyncRequest, network *security.Request) *models.OfflineSyncResponse
+}
+
+func NewOfflineService(db *mongo.Database, cfg *config.Config, policies NetworkPolicyServiceInterface) OfflineServiceInterface {
+	return &OfflineService{
+		db:                db,
+		ctx:               context.Background(),
+		config:            cfg,
+		deviceService:     NewDeviceService(db, cfg),
+		attendanceService: NewAttendanceService(db, cfg),
+		policies:          policies,
+		engine:            security.NewEngineFromConfig(cfg),
+	}
+}
+
+// IssueNonce mengeluarkan nonce acak untuk hari sekolah berikutnya, atau hari ini selama
+// jendela check in belum dibuka. Nonce yang diambil setelah jendela dibuka tidak bisa
+// dipakai untuk memundurkan jam check in. Mengambil ulang nonce untuk hari yang sama
+// mengganti nonce sebelumnya selama belum dipakai.
+func (s *OfflineService) IssueNonce(userID primitive.ObjectID, deviceID string, date time.Time) (*models.OfflineNonce, error) {
+	now := time.Now().UTC()
+	today := s.config.Today()
+	switch {
+	case date.Equal(today.AddDate(0, 0, 1)):
+	case date.Equal(today):
+		if !now.Before(s.checkInOpensAt(date)) {
+			return nil, errors.New("offline nonce for today must be requested before the check in window opens")
+		}
+	default:
+		return nil, errors.New("offline nonce is only available for tomorrow, or today before the check in window opens")
+	}
+
+	nonce, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate offline nonce")
+	}
+
+	key := dateKey(date)
+	expiresAt := s.config.DayStart(date).Add(24*time.Hour + time.Duration(s.config.OfflineMaxAgeHours)*time.Hour)
+	_, err = s.collection().UpdateOne(s.ctx,
+		bson.M{"user_id": userID, "device_id": deviceID, "date_key": key, "used_at": nil},
+		bson.M{"$set": bson.M{
+			"nonce_hash": hashAPIKey(nonce),
+			"issued_at":  now,
+			"expires_at": expiresAt,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("offline nonce for this date has already been used")
+		}
+		log.Printf("Error saving offline nonce: %v", err)
+		return nil, errors.New("failed to issue offline nonce")
+	}
+
+	return &models.OfflineNonce{
+		Date:      key,
+		DeviceID:  deviceID,
+		Nonce:     nonce,
+		IssuedAt:  now,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Sync memproses record offline satu per satu; record yang gagal tidak membatalkan record lain.
+// network berisi data request sync dari NetworkSecurityMiddleware; yang dipakai untuk
+// penilaian hanya daftar device dan posisi terakhir siswa.
+func (s *OfflineService) Sync(user *models.User, req *models.OfflineSyncRequest, network *security.Request) *models.OfflineSyncResponse {
+	response := &models.OfflineSyncResponse{Results: []models.OfflineSyncResult{}}
+
+	for i := range req.Records {
+		result := models.OfflineSyncResult{Index: i}
+
+		attendance, err := s.syncRecord(user, &req.Records[i], network)
+		switch {
+		case err == nil:
+			result.Result = models.OfflineResultAccepted
+			result.Attendance = attendance
+			response.Accepted++
+		case errors.Is(err, errOfflineDuplicate):
+			result.Result = models.OfflineResultDuplicate
+			result.Message = err.Error()
+			response.Duplicate++
+		default:
+			result.Result = models.OfflineResultRejected
+			result.Message = err.Error()
+			response.Rejected++
+		}
+
+		response.Results = append(response.Results, result)
+	}
+
+	log.Printf("Offline sync for user %s: %d accepted, %d duplicate, %d rejected", user.ID.Hex(), response.Accepted, response.Duplicate, response.Rejected)
+	return response
+}
+
+func (s *OfflineService) syncRecord(user *models.User, record *models.OfflineRecord, network *security.Request) (*models.Attendance, error) {
+	device, err := s.deviceService.VerifyOfflineRecord(user.ID, record)
+	if err != nil {
+		var verificationErr *DeviceVerificationError
+		if errors.As(err, &verificationErr) {
+			s.deviceService.RecordAttempt(&models.DeviceAttempt{
+				UserID:    user.ID,
+				DeviceID:  record.DeviceID,
+				Reason:    verificationErr.Reason,
+				Path:      offlineSyncPath,
+				ClientIP:  network.ClientIP,
+				UserAgent: network.UserAgent,
+			})
+		}
+		return nil, err
+	}
+
+	var checkIn models.OfflineCheckIn
+	if err := json.Unmarshal([]byte(record.Payload), &checkIn); err != nil {
+		return nil, errors.New("invalid offline payload")
+	}
+	if checkIn.Action != ActionCheckIn {
+		return nil, errors.New("only offline check in is supported")
+	}
+
+	nonce, err := s.consumeNonce(user.ID, device.DeviceID, checkIn.Date, checkIn.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	risk := s.assess(device, &checkIn, network)
+	if risk.Decision == security.DecisionReject {
+		s.releaseNonce(nonce.ID)
+		message := "offline check in rejected by network security check"
+		if reasons := security.TopReasons(risk, 3); len(reasons) > 0 {
+			message += " (" + strings.Join(reasons, "; ") + ")"
+		}
+		return nil, errors.New(message)
+	}
+
+	attendance, err := s.attendanceService.CheckInOffline(user.ID, device, &checkIn, nonce.IssuedAt, risk)
+	if err != nil {
+		// nonce dikembalikan supaya record yang diperbaiki bisa dikirim ulang
+		s.releaseNonce(nonce.ID)
+		return nil, err
+	}
+	return attendance, nil
+}
+
+// consumeNonce menandai nonce terpakai secara atomik sehingga satu nonce hanya bisa
+// menghasilkan satu check in
+func (s *OfflineService) consumeNonce(userID primitive.ObjectID, deviceID, date, nonce string) (*models.OfflineNonceRecord, error) {
+	if nonce == "" {
+		return nil, errors.New("invalid offline nonce")
+	}
+
+	nonceHash := hashAPIKey(nonce)
+	var record models.OfflineNonceRecord
+	err := s.collection().FindOneAndUpdate(s.ctx,
+		bson.M{
+			"user_id":    userID,
+			"device_id":  deviceID,
+			"date_key":   date,
+			"nonce_hash": nonceHash,
+			"used_at":    nil,
+			"expires_at": bson.M{"$gt": time.Now().UTC()},
+		},
+		bson.M{"$set": bson.M{"used_at": time.Now().UTC()}},
+	).Decode(&record)
+	if err == nil {
+		return &record, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		log.Printf("Error consuming offline nonce: %v", err)
+		return nil, errors.New("failed to verify offline nonce")
+	}
+
+	used := bson.M{
+		"user_id":    userID,
+		"device_id":  deviceID,
+		"date_key":   date,
+		"nonce_hash": nonceHash,
+		"used_at":    bson.M{"$ne": nil},
+	}
+	if count, _ := s.collection().CountDocuments(s.ctx, used); count > 0 {
+		return nil, errOfflineDuplicate
+	}
+	return nil, errors.New("invalid or expired offline nonce")
+}
+
+func (s *OfflineService) releaseNonce(id primitive.ObjectID) {
+	if _, err := s.collection().UpdateOne(s.ctx, bson.M{"_id": id}, bson.M{"$unset": bson.M{"used_at": ""}}); err != nil {
+		log.Printf("Error releasing offline nonce %s: %v", id.Hex(), err)
+	}
+}
+
+const offlineNetworkNotAssessed = "not assessed for offline records: network signals are not part of the signed payload"
+
+// offlineExcludedRules adalah rule jaringan yang tidak dinilai untuk record offline. IP,
+// SSID, operator dan header VPN yang tersedia berasal dari request sync yang dikirim
+// belakangan, bukan dari saat siswa check in, dan tidak ikut ditandatangani device.
+var offlineExcludedRules = map[string]string{
+	security.RuleIPRange:  offlineNetworkNotAssessed,
+	security.RuleWiFiSSID: offlineNetworkNotAssessed,
+	security.RuleCarrier:  offlineNetworkNotAssessed,
+	security.RuleVPN:      offlineNetworkNotAssessed,
+}
+
+// assess menilai record offline hanya dari sinyal yang ada di payload bertanda tangan:
+// device, lokasi dan waktu rekam. Rule jaringan dicatat sebagai skipped di breakdown.
+func (s *OfflineService) assess(device *models.Device, checkIn *models.OfflineCheckIn, network *security.Request) *models.RiskAssessment {
+	req := security.Request{
+		DeviceID:       device.DeviceID,
+		Latitude:       checkIn.Latitude,
+		Longitude:      checkIn.Longitude,
+		HasLocation:    true,
+		Time:           time.Unix(checkIn.RecordedAt, 0).UTC(),
+		KnownDeviceIDs: network.KnownDeviceIDs,
+		LastSample:     network.LastSample,
+		Excluded:       offlineExcludedRules,
+	}
+	// posisi terakhir yang tercatat setelah jam rekam tidak bisa dipakai untuk cek kecepatan
+	if req.LastSample != nil && req.LastSample.Time.After(req.Time) {
+		req.LastSample = nil
+	}
+
+	policy, _, err := s.policies.CurrentPolicy()
+	if err != nil {
+		log.Printf("Network policy unavailable for offline sync, using defaults: %v", err)
+		policy = security.DefaultPolicy()
+	}
+	return s.engine.Evaluate(&req, policy)
+}
+
+// checkInOpensAt jam jendela check in dibuka pada tanggal sekolah tersebut
+func (s *OfflineService) checkInOpensAt(date time.Time) time.Time {
+	dayStart := s.config.DayStart(date)
+	opens, err := time.Parse("15:04", s.config.WindowFor(dayStart.Weekday()).CheckInOpen)
+	if err != nil {
+		return dayStart
+	}
+	return dayStart.Add(time.Duration(opens.Hour())*time.Hour + time.Duration(opens.Minute())*time.Minute)
+}
+
+func (s *OfflineService) collection() *mongo.Collection {
+	return s.db.Collection("offline_nonces")
+}
+
+// CheckInOffline membuat record dari check in yang direkam tanpa sinyal. Status dihitung
+// dari jam asli saat direkam, record ditandai submitted_late. recorded_at tidak boleh
+// lebih awal dari saat nonce dikeluarkan.
+func (s *AttendanceService) CheckInOffline(userID primitive.ObjectID, device *models.Device, checkIn *models.OfflineCheckIn, nonceIssuedAt time.Time, risk *models.RiskAssessment) (*models.Attendance, error) {
+	date, err := time.Parse("2006-01-02", checkIn.Date)
+	if err != nil {
+		return nil, errors.New("invalid date format, use YYYY-MM-DD")
+	}
+
+	now := time.Now().UTC()
+	recordedAt := time.Unix(checkIn.RecordedAt, 0).UTC()
+	if recordedAt.After(now.Add(security.MaxSignatureSkew)) {
+		return nil, errors.New("recorded time is in the future")
+	}
+	if now.Sub(recordedAt) > time.Duration(s.config.OfflineMaxAgeHours)*time.Hour {
+		return nil, errors.New("offline record is too old to sync")
+	}
+	if !s.config.SchoolDate(recordedAt).Equal(date) {
+		return nil, errors.New("recorded time does not match the attendance date")
+	}
+	if recordedAt.Before(nonceIssuedAt) {
+		return nil, errors.New("recorded time is before the offline nonce was issued")
+	}
+	if recordedAt.Before(device.CreatedAt) {
+		return nil, errors.New("recorded time is before the device was registered")
+	}
+	if err := s.checkWindow(ActionCheckIn, recordedAt); err != nil {
+		return nil, err
+	}
+	if !s.IsValidLocation(checkIn.Latitude, checkIn.Longitude) {
+		return nil, errors.New("location is outside school area")
+	}
+
+	collection := s.db.Collection("attendances")
+	var existing models.Attendance
+	err = collection.FindOne(s.ctx, bson.M{"user_id": userID, "date": dayRange(date)}).Decode(&existing)
+	replaceAbsent := false
+	if err == nil {
+		if existing.Voided {
+			return nil, errors.New("attendance for this date was voided, contact your homeroom teacher")
+		}
+		// alpa otomatis ditimpa karena siswa sebenarnya sudah check in sebelum sinyal kembali
+		if existing.VerificationMethod != models.VerificationSystem {
+			return nil, errOfflineDuplicate
+		}
+		replaceAbsent = true
+	}
+
+	status, minutesLate := s.evaluateArrival(recordedAt)
+	attendance := models.Attendance{
+		UserID:  userID,
+		Date:    date,
+		DateKey: dateKey(date),
+		TermID:  termIDForDate(s.ctx, s.db, date),
+		CheckIn: &recordedAt,
+		Status:  status,
+		Location: models.Location{
+			Latitude:  checkIn.Latitude,
+			Longitude: checkIn.Longitude,
+			Address:   checkIn.Address,
+		},
+		DeviceID:           device.DeviceID,
+		MinutesLate:        minutesLate,
+		Flagged:            isFlagged(risk),
+		Risk:               risk,
+		VerificationMethod: models.VerificationOffline,
+		SubmittedLate:      true,
+		SubmittedAt:        &now,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+
+	if replaceAbsent {
+		attendance.ID = existing.ID
+		attendance.CreatedAt = existing.CreatedAt
+		result, err := collection.ReplaceOne(s.ctx, bson.M{"_id": existing.ID, "verification_method": models.VerificationSystem}, attendance)
+		if err != nil {
+			log.Printf("Error replacing absent attendance with offline check in: %v", err)
+			return nil, errors.New("failed to create attendance")
+		}
+		if result.MatchedCount == 0 {
+			return nil, errOfflineDuplicate
+		}
+	} else {
+		result, err := collection.InsertOne(s.ctx, attendance)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, errOfflineDuplicate
+			}
+			log.Printf("Error creating offline attendance: %v", err)
+			return nil, errors.New("failed to create attendance")
+		}
+		attendance.ID = result.InsertedID.(primitive.ObjectID)
+	}
+
+	log.Printf("Offline check in for user %s recorded at %s, submitted at %s", userID.Hex(), recordedAt.Format(time.RFC3339), now.Format(time.RFC3339))
+	s.publishEvent(models.EventCheckIn, &attendance)
+	return &attendance, nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/security"
+)
+
+type staticPolicies struct {
+	NetworkPolicyServiceInterface
+}
+
+func (staticPolicies) CurrentPolicy() (*security.Policy, int64, error) {
+	return security.DefaultPolicy(), 1, nil
+}
+
+func TestOfflineAssessIgnoresSyncNetwork(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	service := &OfflineService{policies: staticPolicies{}, engine: security.NewEngineFromConfig(config.Load())}
+
+	recordedAt := time.Date(2026, 10, 19, 0, 20, 0, 0, time.UTC)
+	checkIn := &models.OfflineCheckIn{Latitude: -7.946, Longitude: 112.615, RecordedAt: recordedAt.Unix()}
+	device := &models.Device{DeviceID: "device-1"}
+	// request sync dikirim dari rumah lewat VPN, jauh setelah check in direkam
+	network := &security.Request{
+		ClientIP:       "8.8.8.8",
+		UserAgent:      "ProtonVPN/3.0",
+		NetworkType:    "wifi",
+		WiFiSSID:       "IndiHome-123",
+		Headers:        map[string]string{"X-Mock-Location": "1"},
+		KnownDeviceIDs: []string{"device-1"},
+		LastSample:     &security.LocationSample{Latitude: -6.2, Longitude: 106.8, Time: recordedAt.Add(3 * time.Hour)},
+	}
+
+	assessment := service.assess(device, checkIn, network)
+	if assessment.Score != 0 || assessment.Decision != security.DecisionAccept {
+		t.Errorf("assessment = score %v %s, want 0 accept", assessment.Score, assessment.Decision)
+	}
+	if !assessment.EvaluatedAt.Equal(recordedAt) {
+		t.Errorf("evaluated at %v, want recorded time %v", assessment.EvaluatedAt, recordedAt)
+	}
+	for _, signal := range assessment.Signals {
+		if _, excluded := offlineExcludedRules[signal.Name]; excluded && (!signal.Skipped || signal.Reason != offlineNetworkNotAssessed) {
+			t.Errorf("%s = %+v, want skipped as not assessed for offline records", signal.Name, signal)
+		}
+	}
+
+	// device yang tidak terikat tetap dinilai dari payload
+	assessment = service.assess(&models.Device{DeviceID: "device-2"}, checkIn, network)
+	if assessment.Score == 0 {
+		t.Error("unknown device must still add risk to an offline record")
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PasswordSetupService mengelola link sekali pakai untuk akun yang dibuat tanpa password
+// (import siswa mode undangan)
+type PasswordSetupService struct {
+	db     *mongo.Database
+	ctx    context.Context
+	config *config.Config
+}
+
+type PasswordSetupServiceInterface interface {
+	Issue(userID primitive.ObjectID) (string, time.Time, error)
+	SetPassword(req *models.SetPasswordRequest) (*models.User, error)
+}
+
+func NewPasswordSetupService(db *mongo.Database, cfg *config.Config) PasswordSetupServiceInterface {
+	return &PasswordSetupService{
+		db:     db,
+		ctx:    context.Background(),
+		config: cfg,
+	}
+}
+
+// Issue membuat token baru dan mengembalikan link ke halaman set password di frontend.
+// Token lama milik user yang belum dipakai ikut dihapus.
+func (s *PasswordSetupService) Issue(userID primitive.ObjectID) (string, time.Time, error) {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		log.Printf("Error generating password setup token: %v", err)
+		return "", time.Time{}, errors.New("failed to generate token")
+	}
+
+	if _, err := s.tokens().DeleteMany(s.ctx, bson.M{"user_id": userID, "used_at": bson.M{"$exists": false}}); err != nil {
+		log.Printf("Warning: failed to remove old password setup tokens for %s: %v", userID.Hex(), err)
+	}
+
+	now := time.Now().UTC()
+	setup := models.PasswordSetupToken{
+		UserID:    userID,
+		TokenHash: hashAPIKey(token),
+		ExpiresAt: now.Add(time.Duration(s.config.PasswordSetupTTLHours) * time.Hour),
+		CreatedAt: now,
+	}
+	if _, err := s.tokens().InsertOne(s.ctx, setup); err != nil {
+		log.Printf("Error saving password setup token: %v", err)
+		return "", time.Time{}, errors.New("failed to save token")
+	}
+
+	link := strings.TrimRight(s.config.AppURL, "/") + "/set-password?token=" + token
+	return link, setup.ExpiresAt, nil
+}
+
+// SetPassword memakai token (sekali pakai) untuk mengisi password akun
+func (s *PasswordSetupService) SetPassword(req *models.SetPasswordRequest) (*models.User, error) {
+	now := time.Now().UTC()
+
+	var setup models.PasswordSetupToken
+	err := s.tokens().FindOneAndUpdate(
+		s.ctx,
+		bson.M{
+			"token_hash": hashAPIKey(strings.TrimSpace(req.Token)),
+			"used_at":    bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": now},
+		},
+		bson.M{"$set": bson.M{"used_at": now}},
+	).Decode(&setup)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("invalid or expired link")
+	}
+	if err != nil {
+		log.Printf("Error claiming password setup token: %v", err)
+		return nil, errors.New("database error")
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return nil, errors.New("failed to process password")
+	}
+
+	var user models.User
+	err = s.db.Collection("users").FindOneAndUpdate(
+		s.ctx,
+		bson.M{"_id": setup.UserID, "is_active": true},
+		bson.M{"$set": bson.M{"password": hashedPassword, "updated_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("account is not active")
+	}
+	if err != nil {
+		log.Printf("Error setting password for %s: %v", setup.UserID.Hex(), err)
+		return nil, errors.New("failed to set password")
+	}
+
+	log.Printf("Password set via setup link: %s", user.Email)
+	return &user, nil
+}
+
+func (s *PasswordSetupService) tokens() *mongo.Collection {
+	return s.db.Collection("password_setup_tokens")
+}
@@ -0,0 +1,553 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/export"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/storage"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	reportMaxDays    = 366
+	reportMaxPDFDays = 62
+	// job processing lebih lama dari ini dianggap worker-nya mati dan diantrekan ulang
+	reportStaleAfter = 15 * time.Minute
+	reportLinkTTL    = time.Hour
+)
+
+// kode status di rekap, mengikuti format rekap absensi sekolah
+var reportCodes = []string{"H", "T", "TB", "S", "I", "A"}
+
+var reportStatusCodes = map[string]string{
+	models.StatusPresent:  "H",
+	models.StatusLate:     "T",
+	models.StatusVeryLate: "TB",
+	models.StatusSick:     "S",
+	models.StatusExcused:  "I",
+	models.StatusAbsent:   "A",
+}
+
+var reportLegend = map[string]string{
+	"H":  "Hadir",
+	"T":  "Terlambat",
+	"TB": "Terlambat melewati batas",
+	"S":  "Sakit",
+	"I":  "Izin",
+	"A":  "Alpa",
+	"-":  "Tidak ada data",
+}
+
+type ReportService struct {
+	db      *mongo.Database
+	ctx     context.Context
+	config  *config.Config
+	storage storage.Storage
+	wake    chan struct{}
+}
+
+type ReportServiceInterface interface {
+	CreateReport(actor *models.User, req *models.CreateReportRequest) (*models.ReportJob, error)
+	GetJob(actor *models.User, jobID string) (*models.ReportJob, error)
+	ListJobs(actor *models.User, limit int) ([]models.ReportJob, error)
+	OpenDownload(jobID, expires, signature string) (*models.ReportJob, io.ReadCloser, error)
+	Start(ctx context.Context)
+}
+
+// NewReportService menyimpan file laporan di storage yang sama dengan selfie sehingga
+// worker dan request unduhan boleh berjalan di instance berbeda
+func NewReportService(db *mongo.Database, cfg *config.Config, fileStorage storage.Storage) ReportServiceInterface {
+	return &ReportService{
+		db:      db,
+		ctx:     context.Background(),
+		config:  cfg,
+		storage: fileStorage,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// CreateReport memvalidasi akses lalu membuat job. Laporan kecil langsung diproses,
+// laporan besar diproses worker dan statusnya bisa dicek lewat GetJob.
+func (s *ReportService) CreateReport(actor *models.User, req *models.CreateReportRequest) (*models.ReportJob, error) {
+	from, err := time.Parse("2006-01-02", req.From)
+	if err != nil {
+		return nil, errors.New("invalid from date, use YYYY-MM-DD")
+	}
+	to, err := time.Parse("2006-01-02", req.To)
+	if err != nil {
+		return nil, errors.New("invalid to date, use YYYY-MM-DD")
+	}
+	if to.Before(from) {
+		return nil, errors.New("to date must not be before from date")
+	}
+	days := int(to.Sub(from).Hours()/24) + 1
+	if days > reportMaxDays {
+		return nil, errors.New("report range is limited to one year")
+	}
+	if req.Format == models.ReportFormatPDF && days > reportMaxPDFDays {
+		return nil, errors.New("PDF report range is limited to 62 days, use CSV or XLSX for longer ranges")
+	}
+
+	job := &models.ReportJob{
+		Scope:       req.Scope,
+		Format:      req.Format,
+		From:        from,
+		To:          to,
+		Status:      models.ReportStatusPending,
+		RequestedBy: actor.ID,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if req.Scope != models.ReportScopeSchool {
+		targetID, err := primitive.ObjectIDFromHex(req.TargetID)
+		if err != nil {
+			return nil, errors.New("invalid target ID")
+		}
+		job.TargetID = &targetID
+	}
+
+	students, err := s.countStudents(actor, job)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.collection().InsertOne(s.ctx, job)
+	if err != nil {
+		log.Printf("Error creating report job: %v", err)
+		return nil, errors.New("failed to create report")
+	}
+	job.ID = result.InsertedID.(primitive.ObjectID)
+
+	if days*int(students) <= s.config.ReportSyncMaxCells {
+		if claimed, err := s.claim(bson.M{"_id": job.ID}); err == nil && claimed != nil {
+			s.process(claimed)
+		}
+		return s.GetJob(actor, job.ID.Hex())
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+func (s *ReportService) GetJob(actor *models.User, jobID string) (*models.ReportJob, error) {
+	objectID, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, errors.New("invalid report ID")
+	}
+
+	filter := bson.M{"_id": objectID}
+	if !actor.HasRole(models.RoleAdmin) {
+		filter["requested_by"] = actor.ID
+	}
+
+	var job models.ReportJob
+	if err := s.collection().FindOne(s.ctx, filter).Decode(&job); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("report not found")
+		}
+		return nil, errors.New("database error")
+	}
+	s.attachDownloadURL(&job)
+
+	return &job, nil
+}
+
+func (s *ReportService) ListJobs(actor *models.User, limit int) ([]models.ReportJob, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	filter := bson.M{}
+	if !actor.HasRole(models.RoleAdmin) {
+		filter["requested_by"] = actor.ID
+	}
+
+	cursor, err := s.collection().Find(s.ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, errors.New("failed to fetch reports")
+	}
+	defer cursor.Close(s.ctx)
+
+	jobs := []models.ReportJob{}
+	if err = cursor.All(s.ctx, &jobs); err != nil {
+		return nil, errors.New("failed to decode reports")
+	}
+	for i := range jobs {
+		s.attachDownloadURL(&jobs[i])
+	}
+
+	return jobs, nil
+}
+
+// OpenDownload memvalidasi link unduhan bertanda tangan lalu membuka file laporan dari storage
+func (s *ReportService) OpenDownload(jobID, expires, signature string) (*models.ReportJob, io.ReadCloser, error) {
+	objectID, err := primitive.ObjectIDFromHex(jobID)
+	if err != nil {
+		return nil, nil, errors.New("invalid report ID")
+	}
+	if !utils.VerifyPathSignature(utils.SigningKey(s.config, utils.SigningPurposeReport), downloadPath(objectID), expires, signature) {
+		return nil, nil, errors.New("download link is invalid or expired")
+	}
+
+	var job models.ReportJob
+	if err := s.collection().FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&job); err != nil {
+		return nil, nil, errors.New("report not found")
+	}
+	if job.Status != models.ReportStatusDone {
+		return nil, nil, errors.New("report is not ready")
+	}
+	if job.FileKey == "" {
+		return nil, nil, errors.New("report file is no longer available")
+	}
+
+	reader, err := s.storage.Open(s.ctx, job.FileKey)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Error opening report %s: %v", job.FileKey, err)
+		}
+		return nil, nil, errors.New("report file is no longer available")
+	}
+
+	return &job, reader, nil
+}
+
+// Start menjalankan worker background yang memproses job pending dan menghapus file kedaluwarsa
+func (s *ReportService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			s.requeueStale()
+			for {
+				job, err := s.claim(bson.M{})
+				if err != nil || job == nil {
+					break
+				}
+				s.process(job)
+			}
+			s.cleanupExpired()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// claim mengambil satu job pending secara atomik sehingga aman dijalankan di banyak instance
+func (s *ReportService) claim(filter bson.M) (*models.ReportJob, error) {
+	filter["status"] = models.ReportStatusPending
+	now := time.Now().UTC()
+
+	var job models.ReportJob
+	err := s.collection().FindOneAndUpdate(
+		s.ctx,
+		filter,
+		bson.M{"$set": bson.M{"status": models.ReportStatusProcessing, "started_at": now}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "created_at", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *ReportService) process(job *models.ReportJob) {
+	fileName, key, size, err := s.render(job)
+	now := time.Now().UTC()
+
+	update := bson.M{"completed_at": now}
+	if err != nil {
+		log.Printf("Report job %s failed: %v", job.ID.Hex(), err)
+		update["status"] = models.ReportStatusFailed
+		update["error"] = err.Error()
+	} else {
+		expiresAt := now.Add(time.Duration(s.config.ReportRetentionHours) * time.Hour)
+		update["status"] = models.ReportStatusDone
+		update["file_name"] = fileName
+		update["file_key"] = key
+		update["size"] = size
+		update["expires_at"] = expiresAt
+	}
+
+	if _, err := s.collection().UpdateOne(s.ctx, bson.M{"_id": job.ID}, bson.M{"$set": update}); err != nil {
+		log.Printf("Error updating report job %s: %v", job.ID.Hex(), err)
+	}
+}
+
+func (s *ReportService) render(job *models.ReportJob) (string, string, int64, error) {
+	matrix, err := s.buildMatrix(job)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, job.Format, matrix); err != nil {
+		return "", "", 0, fmt.Errorf("failed to render report: %v", err)
+	}
+
+	key := "reports/" + job.ID.Hex() + "." + job.Format
+	if err := s.storage.Put(s.ctx, key, buf.Bytes(), export.ContentType(job.Format)); err != nil {
+		return "", "", 0, fmt.Errorf("failed to store report file: %v", err)
+	}
+
+	fileName := fmt.Sprintf("%s-%s-%s.%s",
+		slugify(matrix.Title), job.From.Format("20060102"), job.To.Format("20060102"), job.Format)
+	return fileName, key, int64(buf.Len()), nil
+}
+
+// buildMatrix menyusun rekap hari x siswa dari record absensi yang tidak di-void
+func (s *ReportService) buildMatrix(job *models.ReportJob) (*models.AttendanceMatrix, error) {
+	students, title, err := s.reportStudents(job)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := &models.AttendanceMatrix{
+		Title:   title,
+		From:    job.From,
+		To:      job.To,
+		Days:    []time.Time{},
+		Rows:    []models.AttendanceRow{},
+		Legend:  reportLegend,
+		Codes:   reportCodes,
+		Created: time.Now().In(s.config.Location()),
+	}
+	dayIndex := make(map[string]int)
+	for day := job.From; !day.After(job.To); day = day.AddDate(0, 0, 1) {
+		dayIndex[day.Format("2006-01-02")] = len(matrix.Days)
+		matrix.Days = append(matrix.Days, day)
+	}
+
+	rows := make(map[primitive.ObjectID]*models.AttendanceRow)
+	ids := make([]primitive.ObjectID, 0, len(students))
+	for _, student := range students {
+		marks := make([]string, len(matrix.Days))
+		for i := range marks {
+			marks[i] = "-"
+		}
+		matrix.Rows = append(matrix.Rows, models.AttendanceRow{
+			NIS:    student.NIS,
+			Name:   student.Name,
+			Kelas:  student.Kelas,
+			Marks:  marks,
+			Totals: map[string]int{},
+		})
+		ids = append(ids, student.ID)
+	}
+	for i := range matrix.Rows {
+		rows[students[i].ID] = &matrix.Rows[i]
+	}
+	if len(ids) == 0 {
+		return matrix, nil
+	}
+
+	filter := bson.M{
+		"date":   bson.M{"$gte": job.From, "$lt": job.To.Add(24 * time.Hour)},
+		"voided": bson.M{"$ne": true},
+	}
+	if job.Scope != models.ReportScopeSchool {
+		filter["user_id"] = bson.M{"$in": ids}
+	}
+
+	cursor, err := s.db.Collection("attendances").Find(s.ctx, filter,
+		options.Find().SetProjection(bson.M{"user_id": 1, "date": 1, "status": 1}))
+	if err != nil {
+		return nil, errors.New("failed to fetch attendance records")
+	}
+	defer cursor.Close(s.ctx)
+
+	for cursor.Next(s.ctx) {
+		var record models.Attendance
+		if err := cursor.Decode(&record); err != nil {
+			return nil, errors.New("failed to decode attendance records")
+		}
+		row, ok := rows[record.UserID]
+		if !ok || record.Status == "" {
+			continue
+		}
+		index, ok := dayIndex[record.Date.UTC().Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		code, ok := reportStatusCodes[record.Status]
+		if !ok {
+			code = strings.ToUpper(record.Status[:1])
+		}
+		row.Marks[index] = code
+		row.Totals[code]++
+	}
+
+	for i := range matrix.Rows {
+		row := &matrix.Rows[i]
+		recorded := 0
+		for _, count := range row.Totals {
+			recorded += count
+		}
+		if recorded > 0 {
+			row.Percentage = float64(row.Totals["H"]+row.Totals["T"]+row.Totals["TB"]) / float64(recorded) * 100
+		}
+	}
+
+	return matrix, nil
+}
+
+// countStudents mengecek akses actor ke cakupan laporan dan menghitung jumlah siswanya
+func (s *ReportService) countStudents(actor *models.User, job *models.ReportJob) (int64, error) {
+	users := s.db.Collection("users")
+
+	switch job.Scope {
+	case models.ReportScopeSchool:
+		if !actor.HasRole(models.RoleAdmin) {
+			return 0, errors.New("only admin can export school-wide reports")
+		}
+		return users.CountDocuments(s.ctx, schoolStudentFilter())
+	case models.ReportScopeClass:
+		if !actor.HasRole(models.RoleAdmin) && (actor.ClassID == nil || *actor.ClassID != *job.TargetID) {
+			return 0, errors.New("you are not the homeroom teacher of this class")
+		}
+		if _, err := findClass(s.ctx, s.db, *job.TargetID); err != nil {
+			return 0, err
+		}
+		filter := classStudentFilter(*job.TargetID)
+		filter["is_active"] = true
+		return users.CountDocuments(s.ctx, filter)
+	default:
+		var student models.User
+		if err := users.FindOne(s.ctx, bson.M{"_id": *job.TargetID}).Decode(&student); err != nil {
+			return 0, errors.New("student not found")
+		}
+		if !CanManageStudent(actor, &student) {
+			return 0, errors.New("you are not allowed to export this student's attendance")
+		}
+		return 1, nil
+	}
+}
+
+func (s *ReportService) reportStudents(job *models.ReportJob) ([]models.User, string, error) {
+	switch job.Scope {
+	case models.ReportScopeSchool:
+		cursor, err := s.db.Collection("users").Find(s.ctx, schoolStudentFilter(),
+			options.Find().SetSort(bson.D{{Key: "kelas", Value: 1}, {Key: "name", Value: 1}}))
+		if err != nil {
+			return nil, "", errors.New("failed to fetch students")
+		}
+		defer cursor.Close(s.ctx)
+
+		var students []models.User
+		if err = cursor.All(s.ctx, &students); err != nil {
+			return nil, "", errors.New("failed to decode students")
+		}
+		return students, "Rekap Absensi Sekolah", nil
+	case models.ReportScopeClass:
+		class, err := findClass(s.ctx, s.db, *job.TargetID)
+		if err != nil {
+			return nil, "", err
+		}
+		students, err := loadClassStudents(s.ctx, s.db, class.ID)
+		return students, "Rekap Absensi Kelas " + class.Name, err
+	default:
+		var student models.User
+		if err := s.db.Collection("users").FindOne(s.ctx, bson.M{"_id": *job.TargetID}).Decode(&student); err != nil {
+			return nil, "", errors.New("student not found")
+		}
+		return []models.User{student}, "Rekap Absensi " + student.Name, nil
+	}
+}
+
+func (s *ReportService) requeueStale() {
+	_, err := s.collection().UpdateMany(s.ctx, bson.M{
+		"status":     models.ReportStatusProcessing,
+		"started_at": bson.M{"$lt": time.Now().UTC().Add(-reportStaleAfter)},
+	}, bson.M{"$set": bson.M{"status": models.ReportStatusPending}})
+	if err != nil {
+		log.Printf("Error requeueing stale report jobs: %v", err)
+	}
+}
+
+func (s *ReportService) cleanupExpired() {
+	cursor, err := s.collection().Find(s.ctx, bson.M{
+		"status":     models.ReportStatusDone,
+		"expires_at": bson.M{"$lt": time.Now().UTC()},
+	})
+	if err != nil {
+		return
+	}
+	defer cursor.Close(s.ctx)
+
+	for cursor.Next(s.ctx) {
+		var job models.ReportJob
+		if err := cursor.Decode(&job); err != nil {
+			continue
+		}
+		if job.FileKey != "" {
+			if err := s.storage.Delete(s.ctx, job.FileKey); err != nil {
+				log.Printf("Error removing expired report %s: %v", job.FileKey, err)
+				continue
+			}
+		}
+		s.collection().UpdateOne(s.ctx, bson.M{"_id": job.ID}, bson.M{
+			"$set":   bson.M{"status": models.ReportStatusExpired},
+			"$unset": bson.M{"file_key": ""},
+		})
+	}
+}
+
+func (s *ReportService) attachDownloadURL(job *models.ReportJob) {
+	if job.Status != models.ReportStatusDone {
+		return
+	}
+	expiresAt := time.Now().Add(reportLinkTTL)
+	if job.ExpiresAt != nil && job.ExpiresAt.Before(expiresAt) {
+		expiresAt = *job.ExpiresAt
+	}
+	job.DownloadURL = utils.SignPath(utils.SigningKey(s.config, utils.SigningPurposeReport), downloadPath(job.ID), expiresAt)
+}
+
+func (s *ReportService) collection() *mongo.Collection {
+	return s.db.Collection("report_jobs")
+}
+
+func downloadPath(jobID primitive.ObjectID) string {
+	return "/api/v1/downloads/reports/" + jobID.Hex()
+}
+
+func schoolStudentFilter() bson.M {
+	return bson.M{
+		"role":      bson.M{"$nin": []string{models.RoleTeacher, models.RoleAdmin, models.RoleGuardian}},
+		"is_active": true,
+	}
+}
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slugify(value string) string {
+	return strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(value), "-"), "-")
+}
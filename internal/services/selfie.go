@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/media"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/storage"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SelfieService struct {
+	db      *mongo.Database
+	ctx     context.Context
+	config  *config.Config
+	storage storage.Storage
+}
+
+type SelfieServiceInterface interface {
+	Store(userID primitive.ObjectID, kind string, data []byte) (*models.Selfie, error)
+	StoreEvidence(userID primitive.ObjectID, data []byte) (*models.Selfie, error)
+	Discard(selfie *models.Selfie)
+	AttachURLs(attendance *models.Attendance)
+	AttachEvidenceURLs(request *models.CorrectionRequest)
+	Open(attendanceID, kind, variant, expires, signature string) (io.ReadCloser, string, error)
+	OpenEvidence(requestID, variant, expires, signature string) (io.ReadCloser, string, error)
+}
+
+func NewSelfieService(db *mongo.Database, cfg *config.Config, store storage.Storage) SelfieServiceInterface {
+	return &SelfieService{
+		db:      db,
+		ctx:     context.Background(),
+		config:  cfg,
+		storage: store,
+	}
+}
+
+// Store memvalidasi foto, membuang EXIF, membuat thumbnail, lalu menyimpan keduanya.
+// Jika absensi gagal dibuat, pemanggil harus membuang hasilnya lewat Discard.
+func (s *SelfieService) Store(userID primitive.ObjectID, kind string, data []byte) (*models.Selfie, error) {
+	base := fmt.Sprintf("selfies/%s/%s/%s-%s", userID.Hex(), dateKey(s.config.Today()), kind, primitive.NewObjectID().Hex())
+	return s.storeImage(base, "selfie", data)
+}
+
+// StoreEvidence menyimpan foto bukti pengajuan koreksi lewat pipeline yang sama dengan
+// selfie (hanya JPEG/PNG, di-encode ulang tanpa EXIF)
+func (s *SelfieService) StoreEvidence(userID primitive.ObjectID, data []byte) (*models.Selfie, error) {
+	base := fmt.Sprintf("evidence/%s/%s", userID.Hex(), primitive.NewObjectID().Hex())
+	return s.storeImage(base, "evidence", data)
+}
+
+func (s *SelfieService) storeImage(base, label string, data []byte) (*models.Selfie, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s is empty", label)
+	}
+	if len(data) > s.config.SelfieMaxBytes {
+		return nil, fmt.Errorf("%s must not exceed %d KB", label, s.config.SelfieMaxBytes/1024)
+	}
+
+	full, thumb, err := media.ProcessSelfie(data, s.config.SelfieMaxDimension, s.config.SelfieThumbSize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	selfie := &models.Selfie{
+		Key:          base + ".jpg",
+		ThumbnailKey: base + "-thumb.jpg",
+		ContentType:  full.ContentType,
+		Size:         len(full.Data),
+		Width:        full.Width,
+		Height:       full.Height,
+		UploadedAt:   now,
+	}
+
+	if err := s.storage.Put(s.ctx, selfie.Key, full.Data, full.ContentType); err != nil {
+		log.Printf("Error storing %s %s: %v", label, selfie.Key, err)
+		return nil, fmt.Errorf("failed to store %s", label)
+	}
+	if err := s.storage.Put(s.ctx, selfie.ThumbnailKey, thumb.Data, thumb.ContentType); err != nil {
+		log.Printf("Error storing %s thumbnail %s: %v", label, selfie.ThumbnailKey, err)
+		s.Discard(selfie)
+		return nil, fmt.Errorf("failed to store %s", label)
+	}
+
+	return selfie, nil
+}
+
+// Discard menghapus selfie yang sudah tersimpan tapi tidak jadi dipakai
+func (s *SelfieService) Discard(selfie *models.Selfie) {
+	if selfie == nil {
+		return
+	}
+	for _, key := range []string{selfie.Key, selfie.ThumbnailKey} {
+		if err := s.storage.Delete(s.ctx, key); err != nil {
+			log.Printf("Error deleting selfie %s: %v", key, err)
+		}
+	}
+}
+
+// AttachURLs mengisi link bertanda tangan untuk selfie record. Pemanggil harus sudah
+// memastikan actor boleh melihat record (siswa pemilik atau guru/admin kelasnya).
+func (s *SelfieService) AttachURLs(attendance *models.Attendance) {
+	expiresAt := time.Now().Add(time.Duration(s.config.SelfieURLTTLMinutes) * time.Minute)
+
+	selfies := map[string]*models.Selfie{
+		models.SelfieCheckIn:  attendance.CheckInSelfie,
+		models.SelfieCheckOut: attendance.CheckOutSelfie,
+	}
+	for kind, selfie := range selfies {
+		if selfie == nil {
+			continue
+		}
+		selfie.URL = utils.SignPath(utils.SigningKey(s.config, utils.SigningPurposeSelfie), selfiePath(attendance.ID, kind, models.SelfieVariantFull), expiresAt)
+		selfie.ThumbnailURL = utils.SignPath(utils.SigningKey(s.config, utils.SigningPurposeSelfie), selfiePath(attendance.ID, kind, models.SelfieVariantThumbnail), expiresAt)
+	}
+}
+
+// AttachEvidenceURLs mengisi link bertanda tangan untuk foto bukti pengajuan koreksi.
+// Pemanggil harus sudah memastikan actor boleh melihat pengajuan tersebut.
+func (s *SelfieService) AttachEvidenceURLs(request *models.CorrectionRequest) {
+	if request.Evidence == nil {
+		return
+	}
+	expiresAt := time.Now().Add(time.Duration(s.config.SelfieURLTTLMinutes) * time.Minute)
+	request.Evidence.URL = utils.SignPath(utils.SigningKey(s.config, utils.SigningPurposeEvidence), evidencePath(request.ID, models.SelfieVariantFull), expiresAt)
+	request.Evidence.ThumbnailURL = utils.SignPath(utils.SigningKey(s.config, utils.SigningPurposeEvidence), evidencePath(request.ID, models.SelfieVariantThumbnail), expiresAt)
+}
+
+// OpenEvidence membuka foto bukti lewat link bertanda tangan dari AttachEvidenceURLs
+func (s *SelfieService) OpenEvidence(requestID, variant, expires, signature string) (io.ReadCloser, string, error) {
+	objectID, err := primitive.ObjectIDFromHex(requestID)
+	if err != nil {
+		return nil, "", errors.New("invalid request ID")
+	}
+	if variant != models.SelfieVariantFull && variant != models.SelfieVariantThumbnail {
+		return nil, "", errors.New("invalid evidence variant")
+	}
+
+	if !utils.VerifyPathSignature(utils.SigningKey(s.config, utils.SigningPurposeEvidence), evidencePath(objectID, variant), expires, signature) {
+		return nil, "", errors.New("invalid or expired download link")
+	}
+
+	var request models.CorrectionRequest
+	err = s.db.Collection("correction_requests").FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&request)
+	if err != nil || request.Evidence == nil {
+		return nil, "", errors.New("evidence not found")
+	}
+
+	key := request.Evidence.Key
+	if variant == models.SelfieVariantThumbnail {
+		key = request.Evidence.ThumbnailKey
+	}
+
+	reader, err := s.storage.Open(s.ctx, key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Error opening evidence %s: %v", key, err)
+		}
+		return nil, "", errors.New("evidence not found")
+	}
+	return reader, request.Evidence.ContentType, nil
+}
+
+// Open membuka file selfie lewat link bertanda tangan dari AttachURLs
+func (s *SelfieService) Open(attendanceID, kind, variant, expires, signature string) (io.ReadCloser, string, error) {
+	objectID, err := primitive.ObjectIDFromHex(attendanceID)
+	if err != nil {
+		return nil, "", errors.New("invalid attendance ID")
+	}
+	if kind != models.SelfieCheckIn && kind != models.SelfieCheckOut {
+		return nil, "", errors.New("invalid selfie type")
+	}
+	if variant != models.SelfieVariantFull && variant != models.SelfieVariantThumbnail {
+		return nil, "", errors.New("invalid selfie variant")
+	}
+
+	if !utils.VerifyPathSignature(utils.SigningKey(s.config, utils.SigningPurposeSelfie), selfiePath(objectID, kind, variant), expires, signature) {
+		return nil, "", errors.New("invalid or expired download link")
+	}
+
+	var attendance models.Attendance
+	err = s.db.Collection("attendances").FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&attendance)
+	if err != nil {
+		return nil, "", errors.New("selfie not found")
+	}
+
+	selfie := attendance.CheckInSelfie
+	if kind == models.SelfieCheckOut {
+		selfie = attendance.CheckOutSelfie
+	}
+	if selfie == nil {
+		return nil, "", errors.New("selfie not found")
+	}
+
+	key := selfie.Key
+	if variant == models.SelfieVariantThumbnail {
+		key = selfie.ThumbnailKey
+	}
+
+	reader, err := s.storage.Open(s.ctx, key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Error opening selfie %s: %v", key, err)
+		}
+		return nil, "", errors.New("selfie not found")
+	}
+	return reader, selfie.ContentType, nil
+}
+
+func selfiePath(attendanceID primitive.ObjectID, kind, variant string) string {
+	return "/api/v1/downloads/selfies/" + attendanceID.Hex() + "/" + kind + "/" + variant
+}
+
+func evidencePath(requestID primitive.ObjectID, variant string) string {
+	return "/api/v1/downloads/corrections/" + requestID.Hex() + "/evidence/" + variant
+}
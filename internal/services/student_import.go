@@ -0,0 +1,405 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	initialPasswordLength = 10
+	// tanpa huruf/angka yang mirip (0/O, 1/l/I) karena password dibagikan dalam bentuk cetak
+	initialPasswordAlphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKMNPQRSTUVWXYZ23456789"
+)
+
+// StudentImportService membuat/memperbarui akun siswa secara massal dari file roster.
+// Baris dicocokkan dengan akun yang ada lewat NIS.
+type StudentImportService struct {
+	db            *mongo.Database
+	ctx           context.Context
+	config        *config.Config
+	validator     *validator.Validate
+	classService  ClassServiceInterface
+	passwordSetup PasswordSetupServiceInterface
+	notifier      NotificationServiceInterface
+	webhooks      WebhookServiceInterface
+}
+
+type StudentImportServiceInterface interface {
+	Import(records []models.StudentImportRecord, opts *models.StudentImportOptions) (*models.StudentImportReport, error)
+}
+
+func NewStudentImportService(db *mongo.Database, cfg *config.Config) StudentImportServiceInterface {
+	return &StudentImportService{
+		db:            db,
+		ctx:           context.Background(),
+		config:        cfg,
+		validator:     validator.New(),
+		classService:  NewClassService(db),
+		passwordSetup: NewPasswordSetupService(db, cfg),
+		notifier:      NewNotificationService(db, cfg),
+		webhooks:      NewWebhookService(db, cfg),
+	}
+}
+
+// importRow baris yang sudah divalidasi beserta kelas dan akun yang cocok
+type importRow struct {
+	record   *models.StudentImportRecord
+	result   *models.StudentImportResult
+	class    *models.Class
+	major    *models.Major
+	existing *models.User
+}
+
+// Import memvalidasi semua baris terlebih dahulu. Data hanya ditulis jika seluruh baris
+// valid dan bukan dry run, sehingga file yang salah tidak pernah tersimpan sebagian.
+func (s *StudentImportService) Import(records []models.StudentImportRecord, opts *models.StudentImportOptions) (*models.StudentImportReport, error) {
+	if opts.Mode == models.ImportModeInvite && !containsString(s.notifier.AvailableChannels(), models.ChannelEmail) {
+		return nil, errors.New("invite mode needs email notifications to be configured")
+	}
+
+	rows, err := s.validate(records)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.StudentImportReport{
+		DryRun:  opts.DryRun,
+		Mode:    opts.Mode,
+		Total:   len(rows),
+		Results: make([]models.StudentImportResult, len(rows)),
+	}
+	for i, row := range rows {
+		report.Results[i] = *row.result
+		if row.result.Action == models.ImportActionError {
+			report.Failed++
+		}
+	}
+	if report.Failed > 0 || opts.DryRun {
+		report.Created, report.Updated = countActions(report.Results)
+		return report, nil
+	}
+
+	var creates []*importRow
+	for _, row := range rows {
+		if row.existing == nil {
+			creates = append(creates, row)
+		} else {
+			s.update(row)
+		}
+	}
+	if len(creates) > 0 {
+		s.create(creates, opts.Mode)
+	}
+
+	report.Applied = true
+	report.Failed = 0
+	for i, row := range rows {
+		report.Results[i] = *row.result
+		if row.result.Action == models.ImportActionError {
+			report.Failed++
+		}
+	}
+	report.Created, report.Updated = countActions(report.Results)
+
+	log.Printf("Student import: %d created, %d updated, %d failed", report.Created, report.Updated, report.Failed)
+	return report, nil
+}
+
+func (s *StudentImportService) validate(records []models.StudentImportRecord) ([]*importRow, error) {
+	classes, err := s.classService.ListClasses("", 0, false)
+	if err != nil {
+		return nil, err
+	}
+	majors, err := s.classService.ListMajors(true)
+	if err != nil {
+		return nil, err
+	}
+
+	classByName := make(map[string]*models.Class, len(classes))
+	for i := range classes {
+		classByName[normalizeClassName(classes[i].Name)] = &classes[i]
+	}
+	majorByID := make(map[primitive.ObjectID]*models.Major, len(majors))
+	for i := range majors {
+		majorByID[majors[i].ID] = &majors[i]
+	}
+
+	nisList := make([]string, 0, len(records))
+	emails := make([]string, 0, len(records))
+	for _, record := range records {
+		nisList = append(nisList, record.NIS)
+		emails = append(emails, record.Email)
+	}
+	byNIS, err := s.findUsers(bson.M{"nis": bson.M{"$in": nisList}}, func(u *models.User) string { return u.NIS })
+	if err != nil {
+		return nil, err
+	}
+	byEmail, err := s.findUsers(bson.M{"email": bson.M{"$in": emails}}, func(u *models.User) string { return u.Email })
+	if err != nil {
+		return nil, err
+	}
+
+	seenNIS := map[string]int{}
+	seenEmail := map[string]int{}
+	rows := make([]*importRow, 0, len(records))
+	for i := range records {
+		record := &records[i]
+		row := &importRow{
+			record: record,
+			result: &models.StudentImportResult{
+				Line:  record.Line,
+				NIS:   record.NIS,
+				Name:  record.Name,
+				Class: record.Class,
+			},
+		}
+		rows = append(rows, row)
+
+		var rowErrors []string
+		if err := s.validator.Struct(record); err != nil {
+			rowErrors = append(rowErrors, utils.ValidatorErrors(err)...)
+		}
+
+		if line, ok := seenNIS[record.NIS]; ok && record.NIS != "" {
+			rowErrors = append(rowErrors, "duplicate NIS, also on line "+strconv.Itoa(line))
+		} else {
+			seenNIS[record.NIS] = record.Line
+		}
+		if line, ok := seenEmail[record.Email]; ok && record.Email != "" {
+			rowErrors = append(rowErrors, "duplicate email, also on line "+strconv.Itoa(line))
+		} else {
+			seenEmail[record.Email] = record.Line
+		}
+
+		if record.Class != "" {
+			row.class = classByName[normalizeClassName(record.Class)]
+			if row.class == nil {
+				rowErrors = append(rowErrors, "class not found: "+record.Class)
+			} else {
+				row.major = majorByID[row.class.MajorID]
+				if record.Major != "" && (row.major == nil ||
+					(!strings.EqualFold(record.Major, row.major.Code) && !strings.EqualFold(record.Major, row.major.Name))) {
+					rowErrors = append(rowErrors, "major does not match class "+row.class.Name)
+				}
+			}
+		}
+
+		switch matches := byNIS[record.NIS]; {
+		case len(matches) > 1:
+			rowErrors = append(rowErrors, "NIS matches more than one account")
+		case len(matches) == 1 && !matches[0].HasRole(models.RoleStudent):
+			rowErrors = append(rowErrors, "NIS belongs to a non-student account")
+		case len(matches) == 1:
+			row.existing = &matches[0]
+		}
+		for _, owner := range byEmail[record.Email] {
+			if row.existing == nil || owner.ID != row.existing.ID {
+				rowErrors = append(rowErrors, "email already used by another account")
+				break
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			row.result.Action = models.ImportActionError
+			row.result.Errors = rowErrors
+			continue
+		}
+		row.result.Action = models.ImportActionCreate
+		if row.existing != nil {
+			row.result.Action = models.ImportActionUpdate
+			id := row.existing.ID
+			row.result.UserID = &id
+		}
+	}
+
+	return rows, nil
+}
+
+// create menyimpan siswa baru. Hash bcrypt dikerjakan paralel karena file bisa berisi ribuan baris.
+func (s *StudentImportService) create(rows []*importRow, mode string) {
+	passwords := make([]string, len(rows))
+	hashes := make([]string, len(rows))
+	failed := make([]error, len(rows))
+
+	if mode == models.ImportModePassword {
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < runtime.NumCPU(); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					password, err := randomString(initialPasswordAlphabet, initialPasswordLength)
+					if err == nil {
+						hashes[i], err = utils.HashPassword(password)
+					}
+					passwords[i], failed[i] = password, err
+				}
+			}()
+		}
+		for i := range rows {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+	}
+
+	for i, row := range rows {
+		if failed[i] != nil {
+			log.Printf("Error generating password for import line %d: %v", row.record.Line, failed[i])
+			s.fail(row, "failed to generate password")
+			continue
+		}
+
+		now := time.Now().UTC()
+		classID := row.class.ID
+		user := models.User{
+			ID:        primitive.NewObjectID(),
+			NIS:       row.record.NIS,
+			Name:      utils.SanitizeInput(row.record.Name),
+			Kelas:     row.class.Name,
+			ClassID:   &classID,
+			Email:     row.record.Email,
+			Password:  hashes[i],
+			Phone:     utils.SanitizeInput(row.record.Phone),
+			Role:      models.RoleStudent,
+			IsActive:  true,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if row.major != nil {
+			majorID := row.major.ID
+			user.MajorID = &majorID
+			user.Jurusan = row.major.Name
+		}
+
+		if _, err := s.db.Collection("users").InsertOne(s.ctx, user); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				s.fail(row, duplicateUserMessage(err))
+			} else {
+				log.Printf("Error creating student from import line %d: %v", row.record.Line, err)
+				s.fail(row, "failed to create user")
+			}
+			continue
+		}
+
+		row.result.UserID = &user.ID
+		row.result.InitialPassword = passwords[i]
+		s.webhooks.Enqueue(models.WebhookUserRegistered, primitive.NewObjectID().Hex(), user.UserPublic())
+
+		if mode == models.ImportModeInvite {
+			if err := s.invite(&user); err != nil {
+				row.result.Errors = append(row.result.Errors, "account created but invitation failed: "+err.Error())
+				continue
+			}
+			row.result.Invited = true
+		}
+	}
+}
+
+func (s *StudentImportService) invite(user *models.User) error {
+	link, expiresAt, err := s.passwordSetup.Issue(user.ID)
+	if err != nil {
+		return err
+	}
+	return s.notifier.NotifyInvitation(user, link, expiresAt)
+}
+
+// update memperbarui data siswa yang sudah ada, password tidak disentuh
+func (s *StudentImportService) update(row *importRow) {
+	set := bson.M{
+		"name":       utils.SanitizeInput(row.record.Name),
+		"kelas":      row.class.Name,
+		"class_id":   row.class.ID,
+		"email":      row.record.Email,
+		"updated_at": time.Now().UTC(),
+	}
+	if row.major != nil {
+		set["major_id"] = row.major.ID
+		set["jurusan"] = row.major.Name
+	}
+	if row.record.Phone != "" {
+		set["phone"] = utils.SanitizeInput(row.record.Phone)
+	}
+
+	_, err := s.db.Collection("users").UpdateOne(s.ctx, bson.M{"_id": row.existing.ID}, bson.M{"$set": set})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			s.fail(row, duplicateUserMessage(err))
+			return
+		}
+		log.Printf("Error updating student from import line %d: %v", row.record.Line, err)
+		s.fail(row, "failed to update user")
+	}
+}
+
+func (s *StudentImportService) fail(row *importRow, message string) {
+	row.result.Action = models.ImportActionError
+	row.result.Errors = append(row.result.Errors, message)
+}
+
+// findUsers mengelompokkan user hasil query berdasarkan key
+func (s *StudentImportService) findUsers(filter bson.M, key func(*models.User) string) (map[string][]models.User, error) {
+	cursor, err := s.db.Collection("users").Find(s.ctx, filter)
+	if err != nil {
+		log.Printf("Error loading users for import: %v", err)
+		return nil, errors.New("database error")
+	}
+	defer cursor.Close(s.ctx)
+
+	var users []models.User
+	if err = cursor.All(s.ctx, &users); err != nil {
+		return nil, errors.New("failed to decode users")
+	}
+
+	grouped := make(map[string][]models.User, len(users))
+	for i := range users {
+		k := key(&users[i])
+		grouped[k] = append(grouped[k], users[i])
+	}
+	return grouped, nil
+}
+
+func countActions(results []models.StudentImportResult) (created, updated int) {
+	for _, result := range results {
+		switch result.Action {
+		case models.ImportActionCreate:
+			created++
+		case models.ImportActionUpdate:
+			updated++
+		}
+	}
+	return created, updated
+}
+
+// normalizeClassName mengabaikan huruf besar/kecil dan tanda baca, "xii-rpl 1" cocok dengan "XII RPL 1"
+func normalizeClassName(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		space = true
+	}
+	return b.String()
+}
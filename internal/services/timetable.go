@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TimetableService struct {
+	db  *mongo.Database
+	ctx context.Context
+}
+
+type TimetableServiceInterface interface {
+	CreateSubject(req *models.CreateSubjectRequest) (*models.Subject, error)
+	ListSubjects(includeInactive bool) ([]models.Subject, error)
+	GetSubject(id string) (*models.Subject, error)
+	UpdateSubject(id string, req *models.UpdateSubjectRequest) (*models.Subject, error)
+	DeleteSubject(id string) error
+
+	CreatePeriod(req *models.CreatePeriodRequest) (*models.TimetablePeriod, error)
+	ListPeriods(classID string, teacherID *primitive.ObjectID, dayOfWeek int) ([]models.TimetablePeriod, error)
+	GetPeriod(id string) (*models.TimetablePeriod, error)
+	UpdatePeriod(id string, req *models.UpdatePeriodRequest) (*models.TimetablePeriod, error)
+	DeletePeriod(id string) error
+}
+
+func NewTimetableService(db *mongo.Database) TimetableServiceInterface {
+	return &TimetableService{
+		db:  db,
+		ctx: context.Background(),
+	}
+}
+
+func (s *TimetableService) CreateSubject(req *models.CreateSubjectRequest) (*models.Subject, error) {
+	now := time.Now().UTC()
+	subject := models.Subject{
+		Code:      strings.ToUpper(strings.TrimSpace(req.Code)),
+		Name:      utils.SanitizeInput(req.Name),
+		IsActive:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	result, err := s.db.Collection("subjects").InsertOne(s.ctx, subject)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("subject code already exists")
+		}
+		log.Printf("Error creating subject: %v", err)
+		return nil, errors.New("failed to create subject")
+	}
+	subject.ID = result.InsertedID.(primitive.ObjectID)
+
+	return &subject, nil
+}
+
+func (s *TimetableService) ListSubjects(includeInactive bool) ([]models.Subject, error) {
+	filter := bson.M{}
+	if !includeInactive {
+		filter["is_active"] = true
+	}
+
+	cursor, err := s.db.Collection("subjects").Find(s.ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, errors.New("failed to fetch subjects")
+	}
+	defer cursor.Close(s.ctx)
+
+	subjects := []models.Subject{}
+	if err = cursor.All(s.ctx, &subjects); err != nil {
+		return nil, errors.New("failed to decode subjects")
+	}
+
+	return subjects, nil
+}
+
+func (s *TimetableService) GetSubject(id string) (*models.Subject, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid subject ID")
+	}
+
+	var subject models.Subject
+	if err := s.db.Collection("subjects").FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&subject); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("subject not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	return &subject, nil
+}
+
+func (s *TimetableService) UpdateSubject(id string, req *models.UpdateSubjectRequest) (*models.Subject, error) {
+	subject, err := s.GetSubject(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updateDoc := bson.M{"updated_at": time.Now().UTC()}
+	if req.Code != "" {
+		updateDoc["code"] = strings.ToUpper(strings.TrimSpace(req.Code))
+	}
+	if req.Name != "" {
+		updateDoc["name"] = utils.SanitizeInput(req.Name)
+	}
+	if req.IsActive != nil {
+		updateDoc["is_active"] = *req.IsActive
+	}
+
+	if _, err := s.db.Collection("subjects").UpdateOne(s.ctx, bson.M{"_id": subject.ID}, bson.M{"$set": updateDoc}); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("subject code already exists")
+		}
+		log.Printf("Error updating subject: %v", err)
+		return nil, errors.New("failed to update subject")
+	}
+
+	return s.GetSubject(id)
+}
+
+func (s *TimetableService) DeleteSubject(id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid subject ID")
+	}
+
+	// Soft delete, rekap absensi lama tetap merujuk ke mapel ini
+	result, err := s.db.Collection("subjects").UpdateOne(s.ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{
+		"is_active":  false,
+		"updated_at": time.Now().UTC(),
+	}})
+	if err != nil {
+		log.Printf("Error deleting subject: %v", err)
+		return errors.New("failed to delete subject")
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("subject not found")
+	}
+
+	return nil
+}
+
+func (s *TimetableService) CreatePeriod(req *models.CreatePeriodRequest) (*models.TimetablePeriod, error) {
+	subjectID, err := primitive.ObjectIDFromHex(req.SubjectID)
+	if err != nil {
+		return nil, errors.New("invalid subject ID")
+	}
+	teacherID, err := primitive.ObjectIDFromHex(req.TeacherID)
+	if err != nil {
+		return nil, errors.New("invalid teacher ID")
+	}
+
+	if req.EndTime <= req.StartTime {
+		return nil, errors.New("end time must be after start time")
+	}
+	classID, err := primitive.ObjectIDFromHex(req.ClassID)
+	if err != nil {
+		return nil, errors.New("invalid class ID")
+	}
+	class, err := findClass(s.ctx, s.db, classID)
+	if err != nil {
+		return nil, err
+	}
+	if !class.IsActive {
+		return nil, errors.New("class is not active")
+	}
+	if err := s.validateReferences(subjectID, teacherID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	period := models.TimetablePeriod{
+		ClassID:      class.ID,
+		Kelas:        class.Name,
+		SubjectID:    subjectID,
+		TeacherID:    teacherID,
+		DayOfWeek:    req.DayOfWeek,
+		PeriodNumber: req.PeriodNumber,
+		StartTime:    req.StartTime,
+		EndTime:      req.EndTime,
+		IsActive:     true,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	result, err := s.db.Collection("timetable_periods").InsertOne(s.ctx, period)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("class already has a lesson in this period")
+		}
+		log.Printf("Error creating timetable period: %v", err)
+		return nil, errors.New("failed to create timetable period")
+	}
+	period.ID = result.InsertedID.(primitive.ObjectID)
+
+	return &period, nil
+}
+
+func (s *TimetableService) ListPeriods(classID string, teacherID *primitive.ObjectID, dayOfWeek int) ([]models.TimetablePeriod, error) {
+	filter := bson.M{"is_active": true}
+	if classID != "" {
+		objectID, err := primitive.ObjectIDFromHex(classID)
+		if err != nil {
+			return nil, errors.New("invalid class ID")
+		}
+		filter["class_id"] = objectID
+	}
+	if teacherID != nil {
+		filter["teacher_id"] = *teacherID
+	}
+	if dayOfWeek > 0 {
+		filter["day_of_week"] = dayOfWeek
+	}
+
+	cursor, err := s.db.Collection("timetable_periods").Find(
+		s.ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "kelas", Value: 1}, {Key: "day_of_week", Value: 1}, {Key: "period_number", Value: 1}}),
+	)
+	if err != nil {
+		return nil, errors.New("failed to fetch timetable")
+	}
+	defer cursor.Close(s.ctx)
+
+	periods := []models.TimetablePeriod{}
+	if err = cursor.All(s.ctx, &periods); err != nil {
+		return nil, errors.New("failed to decode timetable")
+	}
+
+	return periods, nil
+}
+
+func (s *TimetableService) GetPeriod(id string) (*models.TimetablePeriod, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid period ID")
+	}
+
+	var period models.TimetablePeriod
+	if err := s.db.Collection("timetable_periods").FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&period); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("timetable period not found")
+		}
+		return nil, errors.New("database error")
+	}
+
+	return &period, nil
+}
+
+func (s *TimetableService) UpdatePeriod(id string, req *models.UpdatePeriodRequest) (*models.TimetablePeriod, error) {
+	period, err := s.GetPeriod(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updateDoc := bson.M{"updated_at": time.Now().UTC()}
+	if req.SubjectID != "" {
+		subjectID, err := primitive.ObjectIDFromHex(req.SubjectID)
+		if err != nil {
+			return nil, errors.New("invalid subject ID")
+		}
+		period.SubjectID = subjectID
+		updateDoc["subject_id"] = subjectID
+	}
+	if req.TeacherID != "" {
+		teacherID, err := primitive.ObjectIDFromHex(req.TeacherID)
+		if err != nil {
+			return nil, errors.New("invalid teacher ID")
+		}
+		period.TeacherID = teacherID
+		updateDoc["teacher_id"] = teacherID
+	}
+	if req.DayOfWeek != 0 {
+		updateDoc["day_of_week"] = req.DayOfWeek
+	}
+	if req.PeriodNumber != 0 {
+		updateDoc["period_number"] = req.PeriodNumber
+	}
+	if req.StartTime != "" {
+		period.StartTime = req.StartTime
+		updateDoc["start_time"] = req.StartTime
+	}
+	if req.EndTime != "" {
+		period.EndTime = req.EndTime
+		updateDoc["end_time"] = req.EndTime
+	}
+	if req.IsActive != nil {
+		updateDoc["is_active"] = *req.IsActive
+	}
+
+	if period.EndTime <= period.StartTime {
+		return nil, errors.New("end time must be after start time")
+	}
+	if err := s.validateReferences(period.SubjectID, period.TeacherID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.db.Collection("timetable_periods").UpdateOne(s.ctx, bson.M{"_id": period.ID}, bson.M{"$set": updateDoc}); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New("class already has a lesson in this period")
+		}
+		log.Printf("Error updating timetable period: %v", err)
+		return nil, errors.New("failed to update timetable period")
+	}
+
+	return s.GetPeriod(id)
+}
+
+func (s *TimetableService) DeletePeriod(id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid period ID")
+	}
+
+	result, err := s.db.Collection("timetable_periods").UpdateOne(s.ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{
+		"is_active":  false,
+		"updated_at": time.Now().UTC(),
+	}})
+	if err != nil {
+		log.Printf("Error deleting timetable period: %v", err)
+		return errors.New("failed to delete timetable period")
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("timetable period not found")
+	}
+
+	return nil
+}
+
+func (s *TimetableService) validateReferences(subjectID, teacherID primitive.ObjectID) error {
+	count, err := s.db.Collection("subjects").CountDocuments(s.ctx, bson.M{"_id": subjectID, "is_active": true})
+	if err != nil {
+		return errors.New("database error")
+	}
+	if count == 0 {
+		return errors.New("subject not found")
+	}
+
+	var teacher models.User
+	if err := s.db.Collection("users").FindOne(s.ctx, bson.M{"_id": teacherID}).Decode(&teacher); err != nil {
+		return errors.New("teacher not found")
+	}
+	if !teacher.HasRole(models.RoleTeacher, models.RoleAdmin) {
+		return errors.New("assigned user is not a teacher")
+	}
+
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"log"
+	"regexp"
+	"strings"
 	"time"
 	"ujikom-backend/internal/models"
 	"ujikom-backend/internal/utils"
@@ -14,9 +16,18 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// errAdminPasswordReset dikembalikan ResetPassword untuk akun admin
+var errAdminPasswordReset = errors.New("admin passwords cannot be reset by another admin")
+
+// IsAdminPasswordReset true jika reset ditolak karena targetnya akun admin
+func IsAdminPasswordReset(err error) bool {
+	return errors.Is(err, errAdminPasswordReset)
+}
+
 type UserService struct {
-	db  *mongo.Database
-	ctx context.Context
+	db           *mongo.Database
+	ctx          context.Context
+	classService ClassServiceInterface
 }
 
 type UserServiceInterface interface {
@@ -25,8 +36,11 @@ type UserServiceInterface interface {
 	UpdateUserProfile(userID string, req *models.UpdateProfileRequest) (*models.User, error)
 	ChangeUserPassword(userID string, req *models.ChangePasswordRequest) error
 	DeleteUser(userID string) error
-	GetAllUsers(limit, offset int) ([]models.User, int64, error)
-	SearchUsers(query string, limit, offset int) ([]models.User, int64, error)
+	GetAllUsers(filter *models.UserFilter, limit, offset int) ([]models.User, int64, error)
+	SearchUsers(search string, filter *models.UserFilter, limit, offset int) ([]models.User, int64, error)
+	UpdateUser(actor *models.User, userID string, req *models.AdminUpdateUserRequest) (*models.User, error)
+	ResetPassword(userID string) (*models.User, string, error)
+	ActivateUser(userID string) error
 	GetUserProfile(userID string) (*models.User, error)
 	UpdateUserAvatar(userID, avatarURL string) error
 	GetUserActivity(userID string) (map[string]interface{}, error)
@@ -34,8 +48,9 @@ type UserServiceInterface interface {
 
 func NewUserService(db *mongo.Database) UserServiceInterface {
 	return &UserService{
-		db:  db,
-		ctx: context.Background(),
+		db:           db,
+		ctx:          context.Background(),
+		classService: NewClassService(db),
 	}
 }
 
@@ -244,17 +259,52 @@ func (s *UserService) DeleteUser(userID string) error {
 	return nil
 }
 
-func (s *UserService) GetAllUsers(limit, offset int) ([]models.User, int64, error) {
+// GetAllUsers daftar user untuk admin dengan filter role/kelas/jurusan/status dan urutan
+func (s *UserService) GetAllUsers(filter *models.UserFilter, limit, offset int) ([]models.User, int64, error) {
+	query, err := userFilterQuery(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return s.findUsers(query, filter.Sort, limit, offset)
+}
+
+// SearchUsers seperti GetAllUsers, ditambah pencarian nama, email, atau NIS
+func (s *UserService) SearchUsers(search string, filter *models.UserFilter, limit, offset int) ([]models.User, int64, error) {
+	query, err := userFilterQuery(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pattern := regexp.QuoteMeta(strings.TrimSpace(search))
+	query["$or"] = []bson.M{
+		{"name": bson.M{"$regex": pattern, "$options": "i"}},
+		{"email": bson.M{"$regex": pattern, "$options": "i"}},
+		{"nis": bson.M{"$regex": "^" + pattern}},
+	}
+
+	return s.findUsers(query, filter.Sort, limit, offset)
+}
+
+func (s *UserService) findUsers(filter bson.M, sort string, limit, offset int) ([]models.User, int64, error) {
 	collection := s.db.Collection("users")
 
 	if limit <= 0 {
 		limit = 10
 	}
 	if limit > 100 {
-		limit = 100 
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
 	}
 
-	total, err := collection.CountDocuments(s.ctx, bson.M{"is_active": true})
+	sortDoc, err := userSort(sort)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := collection.CountDocuments(s.ctx, filter)
 	if err != nil {
 		return nil, 0, errors.New("failed to count users")
 	}
@@ -262,16 +312,16 @@ func (s *UserService) GetAllUsers(limit, offset int) ([]models.User, int64, erro
 	findOptions := options.Find()
 	findOptions.SetLimit(int64(limit))
 	findOptions.SetSkip(int64(offset))
-	findOptions.SetSort(bson.M{"created_at": -1}) 
+	findOptions.SetSort(sortDoc)
 
-	cursor, err := collection.Find(s.ctx, bson.M{"is_active": true}, findOptions)
+	cursor, err := collection.Find(s.ctx, filter, findOptions)
 	if err != nil {
 		log.Printf("Error finding users: %v", err)
 		return nil, 0, errors.New("failed to retrieve users")
 	}
 	defer cursor.Close(s.ctx)
 
-	var users []models.User
+	users := []models.User{}
 	if err = cursor.All(s.ctx, &users); err != nil {
 		log.Printf("Error decoding users: %v", err)
 		return nil, 0, errors.New("failed to decode users")
@@ -280,48 +330,234 @@ func (s *UserService) GetAllUsers(limit, offset int) ([]models.User, int64, erro
 	return users, total, nil
 }
 
-func (s *UserService) SearchUsers(query string, limit, offset int) ([]models.User, int64, error) {
-	collection := s.db.Collection("users")
+// UpdateUser perubahan data user oleh admin
+func (s *UserService) UpdateUser(actor *models.User, userID string, req *models.AdminUpdateUserRequest) (*models.User, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
 
-	if limit <= 0 {
-		limit = 10
+	set := bson.M{"updated_at": time.Now().UTC()}
+	unset := bson.M{}
+
+	if req.Name != "" {
+		set["name"] = utils.SanitizeInput(req.Name)
 	}
-	if limit > 100 {
-		limit = 100
+	if req.Phone != "" {
+		set["phone"] = utils.SanitizeInput(req.Phone)
+	}
+	if email := utils.SanitizeInput(req.Email); email != "" && email != user.Email {
+		if err := s.ensureUnique("email", email, user.ID); err != nil {
+			return nil, err
+		}
+		set["email"] = email
+	}
+	if nis := strings.TrimSpace(req.NIS); nis != "" && nis != user.NIS {
+		if err := s.ensureUnique("nis", nis, user.ID); err != nil {
+			return nil, err
+		}
+		set["nis"] = nis
+	}
+
+	role := user.GetRole()
+	if req.Role != "" && req.Role != role {
+		switch {
+		case user.ID == actor.ID:
+			return nil, errors.New("you cannot change your own role")
+		case role == models.RoleGuardian:
+			return nil, errors.New("guardian accounts cannot change role")
+		case role == models.RoleTeacher && user.ClassID != nil:
+			return nil, errors.New("remove the teacher as homeroom teacher first")
+		case role == models.RoleStudent:
+			// class_id guru dibaca sebagai kelas perwalian, kelas siswa lama harus dilepas
+			unset["class_id"] = ""
+			unset["kelas"] = ""
+			unset["major_id"] = ""
+			unset["jurusan"] = ""
+		}
+		set["role"] = req.Role
+		role = req.Role
+	}
+
+	if req.ClassID != "" || req.MajorID != "" {
+		if role != models.RoleStudent {
+			return nil, errors.New("only students can be assigned to a class")
+		}
+
+		classID := req.ClassID
+		if classID == "" {
+			if user.ClassID == nil {
+				return nil, errors.New("class_id is required")
+			}
+			classID = user.ClassID.Hex()
+		}
+
+		class, major, err := s.classService.ResolveEnrollment(classID, req.MajorID)
+		if err != nil {
+			return nil, err
+		}
+		set["class_id"] = class.ID
+		set["kelas"] = class.Name
+		set["major_id"] = major.ID
+		set["jurusan"] = major.Name
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
 	}
 
-	searchFilter := bson.M{
-		"is_active": true,
-		"$or": []bson.M{
-			{"name": bson.M{"$regex": query, "$options": "i"}},
-			{"email": bson.M{"$regex": query, "$options": "i"}},
+	if _, err := s.db.Collection("users").UpdateOne(s.ctx, bson.M{"_id": user.ID}, update); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errors.New(duplicateUserMessage(err))
+		}
+		log.Printf("Error updating user %s: %v", user.ID.Hex(), err)
+		return nil, errors.New("failed to update user")
+	}
+
+	log.Printf("User %s updated by admin %s", user.ID.Hex(), actor.Email)
+	return s.GetUserByID(userID)
+}
+
+// ResetPassword mengganti password dengan password sementara yang hanya ditampilkan sekali ke
+// admin. Sama seperti impersonate, akun admin tidak bisa direset oleh admin lain.
+func (s *UserService) ResetPassword(userID string) (*models.User, string, error) {
+	user, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if user.HasRole(models.RoleAdmin) {
+		return nil, "", errAdminPasswordReset
+	}
+	if !user.IsActive {
+		return nil, "", errors.New("user account is inactive")
+	}
+
+	password, err := randomString(initialPasswordAlphabet, initialPasswordLength)
+	if err != nil {
+		log.Printf("Error generating temporary password: %v", err)
+		return nil, "", errors.New("failed to generate password")
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return nil, "", errors.New("failed to process password")
+	}
+
+	_, err = s.db.Collection("users").UpdateOne(s.ctx, bson.M{"_id": user.ID}, bson.M{
+		"$set": bson.M{
+			"password":   hashedPassword,
+			"updated_at": time.Now().UTC(),
 		},
+	})
+	if err != nil {
+		log.Printf("Error resetting password: %v", err)
+		return nil, "", errors.New("failed to reset password")
+	}
+
+	// link set password dari import yang belum dipakai tidak berlaku lagi
+	if _, err := s.db.Collection("password_setup_tokens").DeleteMany(s.ctx, bson.M{
+		"user_id": user.ID,
+		"used_at": bson.M{"$exists": false},
+	}); err != nil {
+		log.Printf("Warning: failed to remove password setup tokens for %s: %v", user.ID.Hex(), err)
+	}
+
+	log.Printf("Password reset for user: %s (ID: %s)", user.Email, userID)
+	return user, password, nil
+}
+
+func (s *UserService) ActivateUser(userID string) error {
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.New("invalid user ID format")
 	}
 
-	total, err := collection.CountDocuments(s.ctx, searchFilter)
+	result, err := s.db.Collection("users").UpdateOne(s.ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"is_active":  true,
+			"updated_at": time.Now().UTC(),
+		},
+	})
 	if err != nil {
-		return nil, 0, errors.New("failed to count search results")
+		log.Printf("Error activating user: %v", err)
+		return errors.New("failed to activate user")
 	}
 
-	findOptions := options.Find()
-	findOptions.SetLimit(int64(limit))
-	findOptions.SetSkip(int64(offset))
-	findOptions.SetSort(bson.M{"created_at": -1})
+	if result.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+
+	log.Printf("User activated: %s", userID)
+	return nil
+}
 
-	cursor, err := collection.Find(s.ctx, searchFilter, findOptions)
+// ensureUnique memastikan nilai field belum dipakai user lain
+func (s *UserService) ensureUnique(field, value string, userID primitive.ObjectID) error {
+	count, err := s.db.Collection("users").CountDocuments(s.ctx, bson.M{field: value, "_id": bson.M{"$ne": userID}})
 	if err != nil {
-		log.Printf("Error searching users: %v", err)
-		return nil, 0, errors.New("failed to search users")
+		return errors.New("database error")
 	}
-	defer cursor.Close(s.ctx)
+	if count > 0 {
+		return errors.New(field + " already registered")
+	}
+	return nil
+}
 
-	var users []models.User
-	if err = cursor.All(s.ctx, &users); err != nil {
-		log.Printf("Error decoding search results: %v", err)
-		return nil, 0, errors.New("failed to decode search results")
+func userFilterQuery(filter *models.UserFilter) (bson.M, error) {
+	query := bson.M{}
+
+	switch filter.Status {
+	case "", models.UserStatusActive:
+		query["is_active"] = true
+	case models.UserStatusInactive:
+		query["is_active"] = false
+	case models.UserStatusAll:
+	default:
+		return nil, errors.New("invalid status, use active, inactive or all")
 	}
 
-	return users, total, nil
+	switch filter.Role {
+	case "":
+	case models.RoleStudent:
+		// akun lama tanpa role dianggap siswa
+		query["role"] = bson.M{"$nin": []string{models.RoleTeacher, models.RoleAdmin, models.RoleGuardian}}
+	case models.RoleTeacher, models.RoleAdmin, models.RoleGuardian:
+		query["role"] = filter.Role
+	default:
+		return nil, errors.New("invalid role, use student, teacher, admin or guardian")
+	}
+
+	if filter.ClassID != nil {
+		query["class_id"] = *filter.ClassID
+	}
+	if filter.MajorID != nil {
+		query["major_id"] = *filter.MajorID
+	}
+
+	return query, nil
+}
+
+// userSort menerjemahkan ?sort=name atau ?sort=-created_at, default terbaru dulu
+func userSort(sort string) (bson.D, error) {
+	if sort == "" {
+		return bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}, nil
+	}
+
+	order := 1
+	field := sort
+	if strings.HasPrefix(sort, "-") {
+		order = -1
+		field = sort[1:]
+	}
+
+	switch field {
+	case "created_at", "name", "nis", "kelas", "email":
+		return bson.D{{Key: field, Value: order}, {Key: "_id", Value: order}}, nil
+	default:
+		return nil, errors.New("invalid sort, use created_at, name, nis, kelas or email")
+	}
 }
 
 func (s *UserService) GetUserActivity(userID string) (map[string]interface{}, error) {
@@ -349,4 +585,12 @@ func (s *UserService) GetUserActivity(userID string) (map[string]interface{}, er
 
 func (s *UserService) isProfileComplete(user *models.User) bool {
 	return user.Name != "" && user.Email != "" && user.Phone != ""
-}
\ No newline at end of file
+}
+
+// duplicateUserMessage membedakan bentrok NIS dan email dari nama index di pesan error
+func duplicateUserMessage(err error) string {
+	if strings.Contains(err.Error(), "nis_unique") {
+		return "NIS already registered"
+	}
+	return "email already registered"
+}
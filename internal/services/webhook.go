@@ -0,0 +1,660 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	webhookBaseBackoff = time.Minute
+	webhookMaxBackoff  = 6 * time.Hour
+	// delivery sending lebih lama dari ini dianggap worker-nya mati dan diantrekan ulang
+	webhookStaleAfter   = 2 * time.Minute
+	webhookDrainLimit   = 4096
+	webhookHistoryLimit = 20
+)
+
+// webhookWake membangunkan worker setelah Enqueue; dibagi antar instance service
+// karena event dibuat dari banyak service (absensi, auth, user)
+var webhookWake = make(chan struct{}, 1)
+
+type WebhookService struct {
+	db     *mongo.Database
+	ctx    context.Context
+	config *config.Config
+	client *http.Client
+}
+
+type WebhookServiceInterface interface {
+	CreateWebhook(actorID primitive.ObjectID, req *models.CreateWebhookRequest) (*models.WebhookCredentials, error)
+	ListWebhooks() ([]models.Webhook, error)
+	GetWebhook(id string) (*models.Webhook, error)
+	UpdateWebhook(id string, req *models.UpdateWebhookRequest) (*models.Webhook, error)
+	RotateSecret(id string) (*models.WebhookCredentials, error)
+	DeleteWebhook(id string) error
+	Ping(id string) (*models.WebhookDelivery, error)
+	ListDeliveries(webhookID, status string, limit int) ([]models.WebhookDelivery, error)
+	RetryDelivery(id string) (*models.WebhookDelivery, error)
+	Enqueue(eventType, eventID string, data interface{})
+	Start(ctx context.Context)
+}
+
+func NewWebhookService(db *mongo.Database, cfg *config.Config) WebhookServiceInterface {
+	return &WebhookService{
+		db:     db,
+		ctx:    context.Background(),
+		config: cfg,
+		client: newWebhookClient(cfg),
+	}
+}
+
+// newWebhookClient membuat HTTP client yang hanya bisa terhubung ke IP publik. IP dicek
+// di Control dialer (setelah DNS di-resolve) sehingga hostname yang mengarah atau
+// berganti ke IP internal tetap ditolak.
+func newWebhookClient(cfg *config.Config) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !cfg.WebhookAllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isBlockedWebhookIP(ip) {
+				return fmt.Errorf("webhook destination %s is not allowed", host)
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: time.Duration(cfg.WebhookTimeoutSeconds) * time.Second,
+		Transport: &http.Transport{
+			// tanpa proxy dari environment, koneksi selalu lewat dialer di atas
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// redirect tidak diikuti, body yang sudah ditandatangani hanya dikirim ke URL terdaftar
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// cgnatRange (100.64.0.0/10) tidak termasuk IsPrivate tetapi tetap bukan alamat publik
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isBlockedWebhookIP menolak loopback, jaringan privat, link-local (termasuk metadata
+// cloud 169.254.169.254), multicast, dan alamat unspecified
+func isBlockedWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		cgnatRange.Contains(ip)
+}
+
+func (s *WebhookService) CreateWebhook(actorID primitive.ObjectID, req *models.CreateWebhookRequest) (*models.WebhookCredentials, error) {
+	if err := s.validateURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate webhook secret")
+	}
+
+	now := time.Now().UTC()
+	webhook := models.Webhook{
+		Name:      utils.SanitizeInput(req.Name),
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    uniqueStrings(req.Events),
+		IsActive:  true,
+		CreatedBy: &actorID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	result, err := s.webhooks().InsertOne(s.ctx, webhook)
+	if err != nil {
+		log.Printf("Error creating webhook: %v", err)
+		return nil, errors.New("failed to create webhook")
+	}
+	webhook.ID = result.InsertedID.(primitive.ObjectID)
+
+	log.Printf("Webhook registered: %s (ID: %s)", webhook.Name, webhook.ID.Hex())
+	return &models.WebhookCredentials{Webhook: webhook, Secret: secret}, nil
+}
+
+func (s *WebhookService) ListWebhooks() ([]models.Webhook, error) {
+	cursor, err := s.webhooks().Find(s.ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, errors.New("failed to fetch webhooks")
+	}
+	defer cursor.Close(s.ctx)
+
+	webhooks := []models.Webhook{}
+	if err = cursor.All(s.ctx, &webhooks); err != nil {
+		return nil, errors.New("failed to decode webhooks")
+	}
+
+	return webhooks, nil
+}
+
+func (s *WebhookService) GetWebhook(id string) (*models.Webhook, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid webhook ID")
+	}
+
+	var webhook models.Webhook
+	if err := s.webhooks().FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&webhook); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("webhook not found")
+		}
+		log.Printf("Error finding webhook: %v", err)
+		return nil, errors.New("database error")
+	}
+
+	return &webhook, nil
+}
+
+func (s *WebhookService) UpdateWebhook(id string, req *models.UpdateWebhookRequest) (*models.Webhook, error) {
+	webhook, err := s.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updateDoc := bson.M{"updated_at": time.Now().UTC()}
+	if req.Name != "" {
+		updateDoc["name"] = utils.SanitizeInput(req.Name)
+	}
+	if req.URL != "" {
+		if err := s.validateURL(req.URL); err != nil {
+			return nil, err
+		}
+		updateDoc["url"] = req.URL
+	}
+	if len(req.Events) > 0 {
+		updateDoc["events"] = uniqueStrings(req.Events)
+	}
+	if req.IsActive != nil {
+		updateDoc["is_active"] = *req.IsActive
+	}
+
+	if _, err := s.webhooks().UpdateOne(s.ctx, bson.M{"_id": webhook.ID}, bson.M{"$set": updateDoc}); err != nil {
+		log.Printf("Error updating webhook: %v", err)
+		return nil, errors.New("failed to update webhook")
+	}
+
+	return s.GetWebhook(id)
+}
+
+// RotateSecret mengganti secret HMAC, delivery berikutnya langsung memakai secret baru
+func (s *WebhookService) RotateSecret(id string) (*models.WebhookCredentials, error) {
+	webhook, err := s.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate webhook secret")
+	}
+
+	_, err = s.webhooks().UpdateOne(s.ctx, bson.M{"_id": webhook.ID}, bson.M{"$set": bson.M{
+		"secret":     secret,
+		"updated_at": time.Now().UTC(),
+	}})
+	if err != nil {
+		log.Printf("Error rotating webhook secret: %v", err)
+		return nil, errors.New("failed to rotate webhook secret")
+	}
+
+	webhook, err = s.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Webhook secret rotated: %s (ID: %s)", webhook.Name, webhook.ID.Hex())
+	return &models.WebhookCredentials{Webhook: *webhook, Secret: secret}, nil
+}
+
+// DeleteWebhook menghapus langganan; delivery yang masih antre dipindah ke dead-letter,
+// log delivery lama tetap bisa dilihat sampai kedaluwarsa
+func (s *WebhookService) DeleteWebhook(id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid webhook ID")
+	}
+
+	result, err := s.webhooks().DeleteOne(s.ctx, bson.M{"_id": objectID})
+	if err != nil {
+		log.Printf("Error deleting webhook: %v", err)
+		return errors.New("failed to delete webhook")
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("webhook not found")
+	}
+
+	_, err = s.deliveries().UpdateMany(s.ctx, bson.M{
+		"webhook_id": objectID,
+		"status":     bson.M{"$in": []string{models.DeliveryStatusPending, models.DeliveryStatusSending}},
+	}, bson.M{
+		"$set":   bson.M{"status": models.DeliveryStatusDead, "last_error": "webhook deleted", "updated_at": time.Now().UTC()},
+		"$unset": bson.M{"next_attempt_at": "", "locked_at": ""},
+	})
+	if err != nil {
+		log.Printf("Error cancelling deliveries of webhook %s: %v", id, err)
+	}
+
+	log.Printf("Webhook deleted: %s", id)
+	return nil
+}
+
+// Ping mengirim event webhook.ping ke satu webhook untuk menguji URL dan verifikasi signature
+func (s *WebhookService) Ping(id string) (*models.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	eventID := primitive.NewObjectID().Hex()
+	payload, err := s.payload(models.WebhookPing, eventID, map[string]string{
+		"webhook_id": webhook.ID.Hex(),
+		"message":    "ping",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := newDelivery(webhook.ID, models.WebhookPing, eventID, payload)
+	result, err := s.deliveries().InsertOne(s.ctx, delivery)
+	if err != nil {
+		log.Printf("Error queueing webhook ping: %v", err)
+		return nil, errors.New("failed to queue ping")
+	}
+	delivery.ID = result.InsertedID.(primitive.ObjectID)
+
+	wakeWebhookWorker()
+	return &delivery, nil
+}
+
+func (s *WebhookService) ListDeliveries(webhookID, status string, limit int) ([]models.WebhookDelivery, error) {
+	filter := bson.M{}
+	if webhookID != "" {
+		objectID, err := primitive.ObjectIDFromHex(webhookID)
+		if err != nil {
+			return nil, errors.New("invalid webhook ID")
+		}
+		filter["webhook_id"] = objectID
+	}
+	switch status {
+	case "":
+	case models.DeliveryStatusPending, models.DeliveryStatusSending, models.DeliveryStatusDelivered, models.DeliveryStatusDead:
+		filter["status"] = status
+	default:
+		return nil, errors.New("invalid status, use pending, sending, delivered or dead")
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	cursor, err := s.deliveries().Find(s.ctx, filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, errors.New("failed to fetch webhook deliveries")
+	}
+	defer cursor.Close(s.ctx)
+
+	deliveries := []models.WebhookDelivery{}
+	if err = cursor.All(s.ctx, &deliveries); err != nil {
+		return nil, errors.New("failed to decode webhook deliveries")
+	}
+
+	return deliveries, nil
+}
+
+// RetryDelivery mengantrekan ulang delivery dari dead-letter dengan jatah percobaan baru
+func (s *WebhookService) RetryDelivery(id string) (*models.WebhookDelivery, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid delivery ID")
+	}
+
+	var delivery models.WebhookDelivery
+	if err := s.deliveries().FindOne(s.ctx, bson.M{"_id": objectID}).Decode(&delivery); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("delivery not found")
+		}
+		return nil, errors.New("database error")
+	}
+	if delivery.Status != models.DeliveryStatusDead {
+		return nil, errors.New("only dead deliveries can be retried")
+	}
+	if count, _ := s.webhooks().CountDocuments(s.ctx, bson.M{"_id": delivery.WebhookID}); count == 0 {
+		return nil, errors.New("webhook no longer exists")
+	}
+
+	now := time.Now().UTC()
+	err = s.deliveries().FindOneAndUpdate(s.ctx,
+		bson.M{"_id": objectID, "status": models.DeliveryStatusDead},
+		bson.M{
+			"$set":   bson.M{"status": models.DeliveryStatusPending, "attempts": 0, "next_attempt_at": now, "updated_at": now},
+			"$unset": bson.M{"last_error": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&delivery)
+	if err != nil {
+		log.Printf("Error requeueing webhook delivery %s: %v", id, err)
+		return nil, errors.New("failed to retry delivery")
+	}
+
+	wakeWebhookWorker()
+	return &delivery, nil
+}
+
+// Enqueue membuat delivery untuk setiap webhook aktif yang melanggan eventType. Dipanggil
+// dari alur utama (check in, registrasi), jadi error hanya dicatat di log.
+func (s *WebhookService) Enqueue(eventType, eventID string, data interface{}) {
+	cursor, err := s.webhooks().Find(s.ctx, bson.M{"is_active": true, "events": eventType},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		log.Printf("Error finding webhooks for %s: %v", eventType, err)
+		return
+	}
+	var webhooks []models.Webhook
+	if err := cursor.All(s.ctx, &webhooks); err != nil || len(webhooks) == 0 {
+		return
+	}
+
+	payload, err := s.payload(eventType, eventID, data)
+	if err != nil {
+		log.Printf("Error encoding webhook payload for %s: %v", eventType, err)
+		return
+	}
+
+	deliveries := make([]interface{}, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, newDelivery(webhook.ID, eventType, eventID, payload))
+	}
+	if _, err := s.deliveries().InsertMany(s.ctx, deliveries); err != nil {
+		log.Printf("Error queueing webhook deliveries for %s: %v", eventType, err)
+		return
+	}
+
+	wakeWebhookWorker()
+}
+
+// Start menjalankan worker pengiriman. Aman dijalankan di banyak instance karena
+// delivery diambil secara atomik.
+func (s *WebhookService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			s.requeueStale()
+			for {
+				delivery, err := s.claim()
+				if err != nil || delivery == nil {
+					break
+				}
+				s.deliver(delivery)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-webhookWake:
+			}
+		}
+	}()
+}
+
+func (s *WebhookService) claim() (*models.WebhookDelivery, error) {
+	now := time.Now().UTC()
+
+	var delivery models.WebhookDelivery
+	err := s.deliveries().FindOneAndUpdate(
+		s.ctx,
+		bson.M{"status": models.DeliveryStatusPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"status": models.DeliveryStatusSending, "locked_at": now}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (s *WebhookService) deliver(delivery *models.WebhookDelivery) {
+	var webhook models.Webhook
+	err := s.webhooks().FindOne(s.ctx, bson.M{"_id": delivery.WebhookID}).Decode(&webhook)
+	if err != nil || !webhook.IsActive {
+		s.kill(delivery, "webhook deleted or inactive")
+		return
+	}
+
+	attempt := s.send(&webhook, delivery)
+	set, unset := deliveryOutcome(delivery.Attempts+1, s.config.WebhookMaxAttempts, attempt)
+	if set["status"] == models.DeliveryStatusDead {
+		log.Printf("Webhook delivery %s to %s moved to dead-letter after %d attempts: %s", delivery.ID.Hex(), webhook.URL, delivery.Attempts+1, attempt.Error)
+	}
+
+	_, err = s.deliveries().UpdateOne(s.ctx, bson.M{"_id": delivery.ID}, bson.M{
+		"$set":   set,
+		"$unset": unset,
+		"$push":  bson.M{"history": bson.M{"$each": []models.WebhookAttempt{attempt}, "$slice": -webhookHistoryLimit}},
+	})
+	if err != nil {
+		log.Printf("Error updating webhook delivery %s: %v", delivery.ID.Hex(), err)
+	}
+}
+
+// deliveryOutcome menentukan perubahan delivery setelah percobaan ke-attempts:
+// delivered jika berhasil, dead-letter jika jatah habis, selain itu diantrekan ulang
+// dengan backoff
+func deliveryOutcome(attempts, maxAttempts int, attempt models.WebhookAttempt) (bson.M, bson.M) {
+	now := attempt.At
+	set := bson.M{"attempts": attempts, "updated_at": now}
+	unset := bson.M{"locked_at": ""}
+
+	switch {
+	case attempt.Error == "":
+		set["status"] = models.DeliveryStatusDelivered
+		set["delivered_at"] = now
+		unset["next_attempt_at"] = ""
+		unset["last_error"] = ""
+	case attempts >= maxAttempts:
+		set["status"] = models.DeliveryStatusDead
+		set["last_error"] = attempt.Error
+		unset["next_attempt_at"] = ""
+	default:
+		set["status"] = models.DeliveryStatusPending
+		set["last_error"] = attempt.Error
+		set["next_attempt_at"] = now.Add(webhookBackoff(attempts))
+	}
+	return set, unset
+}
+
+// send melakukan satu percobaan POST. Hanya status 2xx yang dianggap berhasil. Isi
+// respons tidak disimpan supaya log delivery tidak bisa dipakai membaca layanan lain.
+func (s *WebhookService) send(webhook *models.Webhook, delivery *models.WebhookDelivery) models.WebhookAttempt {
+	start := time.Now()
+	attempt := models.WebhookAttempt{At: start.UTC()}
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = "invalid webhook URL"
+		return attempt
+	}
+	timestamp := start.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ujikom-webhook/1.0")
+	req.Header.Set("X-Webhook-ID", delivery.EventID)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.Hex())
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", utils.SignWebhook(webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	// dibaca sebagian agar koneksi bisa dipakai ulang
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookDrainLimit))
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// kill memindahkan delivery ke dead-letter tanpa mencoba mengirim
+func (s *WebhookService) kill(delivery *models.WebhookDelivery, reason string) {
+	_, err := s.deliveries().UpdateOne(s.ctx, bson.M{"_id": delivery.ID}, bson.M{
+		"$set":   bson.M{"status": models.DeliveryStatusDead, "last_error": reason, "updated_at": time.Now().UTC()},
+		"$unset": bson.M{"next_attempt_at": "", "locked_at": ""},
+	})
+	if err != nil {
+		log.Printf("Error updating webhook delivery %s: %v", delivery.ID.Hex(), err)
+	}
+}
+
+func (s *WebhookService) requeueStale() {
+	_, err := s.deliveries().UpdateMany(s.ctx, bson.M{
+		"status":    models.DeliveryStatusSending,
+		"locked_at": bson.M{"$lt": time.Now().UTC().Add(-webhookStaleAfter)},
+	}, bson.M{"$set": bson.M{"status": models.DeliveryStatusPending}})
+	if err != nil {
+		log.Printf("Error requeueing stale webhook deliveries: %v", err)
+	}
+}
+
+func (s *WebhookService) validateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return errors.New("webhook URL must be an absolute http or https URL")
+	}
+	if s.config.IsProduction() && parsed.Scheme != "https" {
+		return errors.New("webhook URL must use https in production")
+	}
+	if s.config.WebhookAllowPrivateNetworks {
+		return nil
+	}
+
+	// pengecekan awal agar admin langsung tahu; penjagaan sebenarnya ada di dialer
+	host := parsed.Hostname()
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("webhook URL must not point to a local or private address")
+	}
+	if ip := net.ParseIP(host); ip != nil && isBlockedWebhookIP(ip) {
+		return errors.New("webhook URL must not point to a local or private address")
+	}
+	return nil
+}
+
+func (s *WebhookService) payload(eventType, eventID string, data interface{}) (string, error) {
+	body, err := json.Marshal(models.WebhookPayload{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+func (s *WebhookService) webhooks() *mongo.Collection {
+	return s.db.Collection("webhooks")
+}
+
+func (s *WebhookService) deliveries() *mongo.Collection {
+	return s.db.Collection("webhook_deliveries")
+}
+
+func newDelivery(webhookID primitive.ObjectID, eventType, eventID, payload string) models.WebhookDelivery {
+	now := time.Now().UTC()
+	return models.WebhookDelivery{
+		WebhookID:     webhookID,
+		EventID:       eventID,
+		Event:         eventType,
+		Payload:       payload,
+		Status:        models.DeliveryStatusPending,
+		NextAttemptAt: &now,
+		History:       []models.WebhookAttempt{},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// webhookBackoff: 1, 2, 4, 8, ... menit setelah percobaan ke-n, maksimal 6 jam
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	return backoff
+}
+
+func wakeWebhookWorker() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
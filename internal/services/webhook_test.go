@@ -0,0 +1,284 @@
+package services
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"ujikom-backend/internal/config"
+	"ujikom-backend/internal/models"
+	"ujikom-backend/internal/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testWebhookService(allowPrivate bool) *WebhookService {
+	cfg := &config.Config{
+		WebhookMaxAttempts:          3,
+		WebhookTimeoutSeconds:       2,
+		WebhookAllowPrivateNetworks: allowPrivate,
+	}
+	return &WebhookService{config: cfg, client: newWebhookClient(cfg)}
+}
+
+func testDelivery() *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		ID:      primitive.NewObjectID(),
+		EventID: "evt-1",
+		Event:   models.WebhookPing,
+		Payload: `{"id":"evt-1","type":"webhook.ping"}`,
+	}
+}
+
+func TestWebhookSendSignsPayload(t *testing.T) {
+	webhook := &models.Webhook{Secret: "webhook-secret"}
+	delivery := testDelivery()
+
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	webhook.URL = server.URL
+
+	attempt := testWebhookService(true).send(webhook, delivery)
+	if attempt.Error != "" || attempt.StatusCode != http.StatusNoContent {
+		t.Fatalf("send() = %+v, want success with 204", attempt)
+	}
+
+	if string(body) != delivery.Payload {
+		t.Errorf("body = %q, want %q", body, delivery.Payload)
+	}
+	timestamp, err := strconv.ParseInt(received.Header.Get("X-Webhook-Timestamp"), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid X-Webhook-Timestamp %q", received.Header.Get("X-Webhook-Timestamp"))
+	}
+	if got, want := received.Header.Get("X-Webhook-Signature"), utils.SignWebhook(webhook.Secret, timestamp, body); got != want {
+		t.Errorf("X-Webhook-Signature = %q, want %q", got, want)
+	}
+	if got := received.Header.Get("X-Webhook-ID"); got != delivery.EventID {
+		t.Errorf("X-Webhook-ID = %q, want %q", got, delivery.EventID)
+	}
+	if got := received.Header.Get("X-Webhook-Delivery"); got != delivery.ID.Hex() {
+		t.Errorf("X-Webhook-Delivery = %q, want %q", got, delivery.ID.Hex())
+	}
+	if got := received.Header.Get("X-Webhook-Event"); got != models.WebhookPing {
+		t.Errorf("X-Webhook-Event = %q, want %q", got, models.WebhookPing)
+	}
+}
+
+func TestWebhookSendFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "internal secret stack trace", http.StatusInternalServerError)
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "redirect is not followed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+			},
+			status: http.StatusFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			attempt := testWebhookService(true).send(&models.Webhook{URL: server.URL, Secret: "s"}, testDelivery())
+			if attempt.StatusCode != tt.status {
+				t.Errorf("StatusCode = %d, want %d", attempt.StatusCode, tt.status)
+			}
+			if attempt.Error == "" {
+				t.Error("non-2xx response must be recorded as an error")
+			}
+			if strings.Contains(attempt.Error, "secret") {
+				t.Errorf("attempt leaks response body: %q", attempt.Error)
+			}
+		})
+	}
+}
+
+func TestWebhookClientBlocksPrivateDestinations(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	// hostname yang resolve ke loopback juga harus ditolak saat dial
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	urls := []string{server.URL, "http://localhost:" + port}
+
+	for _, target := range urls {
+		attempt := testWebhookService(false).send(&models.Webhook{URL: target, Secret: "s"}, testDelivery())
+		if attempt.Error == "" || !strings.Contains(attempt.Error, "not allowed") {
+			t.Errorf("send(%s) error = %q, want destination not allowed", target, attempt.Error)
+		}
+	}
+	if hits != 0 {
+		t.Errorf("blocked requests reached the server %d times", hits)
+	}
+}
+
+func TestIsBlockedWebhookIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"8.8.8.8", false},
+		{"203.0.113.10", false},
+		{"2606:4700:4700::1111", false},
+	}
+
+	for _, tt := range tests {
+		if got := isBlockedWebhookIP(net.ParseIP(tt.ip)); got != tt.blocked {
+			t.Errorf("isBlockedWebhookIP(%s) = %v, want %v", tt.ip, got, tt.blocked)
+		}
+	}
+}
+
+func TestWebhookValidateURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://hooks.example.com/attendance", true},
+		{"http://203.0.113.10/hook", true},
+		{"ftp://example.com/hook", false},
+		{"/relative/path", false},
+		{"http://localhost:8080/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://192.168.0.10/hook", false},
+	}
+
+	service := testWebhookService(false)
+	for _, tt := range tests {
+		err := service.validateURL(tt.url)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateURL(%q) error = %v, want valid %v", tt.url, err, tt.valid)
+		}
+	}
+}
+
+func TestWebhookRetriesUntilDelivered(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	service := testWebhookService(true)
+	webhook := &models.Webhook{URL: server.URL, Secret: "s"}
+	delivery := testDelivery()
+
+	wantStatus := []string{models.DeliveryStatusPending, models.DeliveryStatusPending, models.DeliveryStatusDelivered}
+	for i, want := range wantStatus {
+		attempt := service.send(webhook, delivery)
+		set, unset := deliveryOutcome(i+1, service.config.WebhookMaxAttempts, attempt)
+
+		if set["status"] != want {
+			t.Fatalf("attempt %d status = %v, want %s", i+1, set["status"], want)
+		}
+		if set["attempts"] != i+1 {
+			t.Errorf("attempt %d attempts = %v, want %d", i+1, set["attempts"], i+1)
+		}
+		if want == models.DeliveryStatusPending {
+			next, ok := set["next_attempt_at"].(time.Time)
+			if !ok || !next.Equal(attempt.At.Add(webhookBackoff(i+1))) {
+				t.Errorf("attempt %d next_attempt_at = %v, want %v", i+1, set["next_attempt_at"], attempt.At.Add(webhookBackoff(i+1)))
+			}
+			if set["last_error"] == "" {
+				t.Errorf("attempt %d must record last_error", i+1)
+			}
+		} else {
+			if _, ok := unset["next_attempt_at"]; !ok {
+				t.Error("delivered delivery must clear next_attempt_at")
+			}
+			if _, ok := unset["last_error"]; !ok {
+				t.Error("delivered delivery must clear last_error")
+			}
+		}
+		delivery.Attempts = i + 1
+	}
+}
+
+func TestWebhookDeadLetterAfterMaxAttempts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	service := testWebhookService(true)
+	attempt := service.send(&models.Webhook{URL: server.URL, Secret: "s"}, testDelivery())
+	set, unset := deliveryOutcome(service.config.WebhookMaxAttempts, service.config.WebhookMaxAttempts, attempt)
+
+	if set["status"] != models.DeliveryStatusDead {
+		t.Errorf("status = %v, want %s", set["status"], models.DeliveryStatusDead)
+	}
+	if set["last_error"] != "unexpected status 502" {
+		t.Errorf("last_error = %v, want unexpected status 502", set["last_error"])
+	}
+	if _, ok := unset["next_attempt_at"]; !ok {
+		t.Error("dead delivery must clear next_attempt_at")
+	}
+	if attempt.StatusCode != http.StatusBadGateway || attempt.DurationMS < 0 || attempt.At.IsZero() {
+		t.Errorf("delivery log entry = %+v, want status, duration and time recorded", attempt)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{4, 8 * time.Minute},
+		{9, 256 * time.Minute},
+		{10, webhookMaxBackoff},
+		{50, webhookMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
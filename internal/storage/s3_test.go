@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSigningKeyAWSExample(t *testing.T) {
+	// contoh penurunan kunci dari dokumentasi AWS Signature Version 4
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	if got, want := hex.EncodeToString(key), "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"; got != want {
+		t.Errorf("signingKey = %s, want %s", got, want)
+	}
+}
+
+func TestS3SignKnownRequest(t *testing.T) {
+	store, err := NewS3Storage(S3Options{
+		Endpoint:  "http://localhost:9000",
+		Bucket:    "ujikom",
+		AccessKey: "minioadmin",
+		SecretKey: "minioadmin",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte("hello")
+	req, err := store.newRequest(context.Background(), http.MethodPut, "selfies/a b.jpg", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.sign(req, body, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+
+	if got, want := req.URL.String(), "http://localhost:9000/ujikom/selfies/a%20b.jpg"; got != want {
+		t.Errorf("URL = %s, want %s", got, want)
+	}
+	// dihitung terpisah mengikuti spesifikasi SigV4
+	want := "AWS4-HMAC-SHA256 Credential=minioadmin/20261019/us-east-1/s3/aws4_request, " +
+		"SignedHeaders=host;x-amz-content-sha256;x-amz-date, " +
+		"Signature=a81d75c1c25a7bcd5ec226f41f9aab89f4c8401e1959ef4c174a581da3d70b99"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization = %s, want %s", got, want)
+	}
+}
+
+func TestS3RequestURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoint  string
+		pathStyle bool
+		key       string
+		want      string
+	}{
+		{"path style", "http://minio:9000", true, "reports/1.csv", "http://minio:9000/ujikom/reports/1.csv"},
+		{"virtual host", "https://s3.ap-southeast-1.amazonaws.com", false, "reports/1.csv", "https://ujikom.s3.ap-southeast-1.amazonaws.com/reports/1.csv"},
+		{"escaped segments", "http://minio:9000", true, "selfies/u/a+b.jpg", "http://minio:9000/ujikom/selfies/u/a%2Bb.jpg"},
+		{"reserved characters", "http://minio:9000", true, "reports/x=1@b!(c).csv", "http://minio:9000/ujikom/reports/x%3D1%40b%21%28c%29.csv"},
+	}
+
+	for _, tt := range tests {
+		store, err := NewS3Storage(S3Options{Endpoint: tt.endpoint, Bucket: "ujikom", AccessKey: "a", SecretKey: "s", PathStyle: tt.pathStyle})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := store.newRequest(context.Background(), http.MethodGet, tt.key, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := req.URL.String(); got != tt.want {
+			t.Errorf("%s: URL = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	store, _ := NewS3Storage(S3Options{Endpoint: "http://minio:9000", Bucket: "ujikom", AccessKey: "a", SecretKey: "s"})
+	if _, err := store.newRequest(context.Background(), http.MethodGet, "../etc/passwd", nil); err == nil {
+		t.Error("key with .. must be rejected")
+	}
+}
+
+// fakeS3 meniru bucket S3/MinIO path style dan memverifikasi tanda tangan SigV4 dari
+// sisi server, yaitu dari host dan path yang benar-benar diterima
+type fakeS3 struct {
+	mu        sync.Mutex
+	secretKey string
+	objects   map[string][]byte
+	types     map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if !f.validSignature(r, body) {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/ujikom/")
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[key])
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeS3) validSignature(r *http.Request, body []byte) bool {
+	if r.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+		return false
+	}
+	auth := r.Header.Get("Authorization")
+	credential := between(auth, "Credential=", ",")
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 {
+		return false
+	}
+	day, region := parts[1], parts[2]
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		"",
+		"host:" + r.Host + "\n" +
+			"x-amz-content-sha256:" + r.Header.Get("X-Amz-Content-Sha256") + "\n" +
+			"x-amz-date:" + r.Header.Get("X-Amz-Date") + "\n",
+		"host;x-amz-content-sha256;x-amz-date",
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	stringToSign := "AWS4-HMAC-SHA256\n" + r.Header.Get("X-Amz-Date") + "\n" +
+		day + "/" + region + "/s3/aws4_request\n" + sha256Hex([]byte(canonicalRequest))
+
+	want := hex.EncodeToString(hmacSHA256(signingKey(f.secretKey, day, region, "s3"), stringToSign))
+	got := auth[strings.Index(auth, "Signature=")+len("Signature="):]
+	return hmac.Equal([]byte(got), []byte(want))
+}
+
+func between(s, start, end string) string {
+	i := strings.Index(s, start)
+	if i < 0 {
+		return ""
+	}
+	s = s[i+len(start):]
+	if j := strings.Index(s, end); j >= 0 {
+		return s[:j]
+	}
+	return s
+}
+
+func newFakeS3(t *testing.T, clientSecret string) *S3Storage {
+	server := httptest.NewServer(&fakeS3{secretKey: "minio-secret", objects: map[string][]byte{}, types: map[string]string{}})
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Storage(S3Options{
+		Endpoint:  server.URL,
+		Bucket:    "ujikom",
+		AccessKey: "minio",
+		SecretKey: clientSecret,
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3PutOpenDelete(t *testing.T) {
+	store := newFakeS3(t, "minio-secret")
+	ctx := context.Background()
+	key := "selfies/64b000000000000000000001/check in=1.jpg"
+
+	if err := store.Put(ctx, key, []byte("jpeg-bytes"), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	reader, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "jpeg-bytes" {
+		t.Errorf("Open = %q, want jpeg-bytes", data)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3RejectedSignature(t *testing.T) {
+	store := newFakeS3(t, "wrong-secret")
+
+	err := store.Put(context.Background(), "reports/1.csv", []byte("a,b"), "text/csv")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put with wrong secret error = %v, want status 403", err)
+	}
+}
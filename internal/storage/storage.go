@@ -0,0 +1,46 @@
+// Package storage menyimpan file unggahan (selfie absensi) di disk lokal atau
+// object storage S3-compatible
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"ujikom-backend/internal/config"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage menyimpan objek berdasarkan key berbentuk path, mis. selfies/<user>/<id>.jpg
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFromConfig memilih backend sesuai STORAGE_DRIVER (local atau s3)
+func NewFromConfig(cfg *config.Config) (Storage, error) {
+	switch cfg.StorageDriver {
+	case "s3":
+		store, err := NewS3Storage(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize s3 storage: %v", err)
+		}
+		log.Printf("File storage: s3 (%s/%s)", cfg.S3Endpoint, cfg.S3Bucket)
+		return store, nil
+	case "", "local":
+		log.Printf("File storage: local (%s)", cfg.StorageDir)
+		return NewLocalStorage(cfg.StorageDir), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.StorageDriver)
+	}
+}
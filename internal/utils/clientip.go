@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ParseTrustedProxies mengubah daftar CIDR/IP menjadi network, entry yang tidak valid dilewati
+func ParseTrustedProxies(entries []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				continue
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+func isTrustedProxy(ip net.IP, trusted []*net.IPNet) bool {
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Header yang bisa dipilih lewat TRUSTED_PROXY_HEADER
+const (
+	ProxyHeaderXForwardedFor  = "X-Forwarded-For"
+	ProxyHeaderForwarded      = "Forwarded"
+	ProxyHeaderXRealIP        = "X-Real-IP"
+	ProxyHeaderCFConnectingIP = "CF-Connecting-IP"
+)
+
+// ParseProxyHeader mengembalikan nama kanonik header proxy, false jika tidak didukung
+func ParseProxyHeader(name string) (string, bool) {
+	for _, header := range []string{ProxyHeaderXForwardedFor, ProxyHeaderForwarded, ProxyHeaderXRealIP, ProxyHeaderCFConnectingIP} {
+		if strings.EqualFold(strings.TrimSpace(name), header) {
+			return header, true
+		}
+	}
+	return "", false
+}
+
+// ResolveClientIP menentukan IP client yang sebenarnya. Hanya satu header yang dibaca,
+// yaitu header yang memang di-set oleh trusted proxy; header forwarding lain bisa dikirim
+// client apa adanya lewat proxy sehingga diabaikan. Header hanya dipercaya jika koneksi
+// datang dari trusted proxy. Untuk header multi-hop rantai ditelusuri dari kanan ke kiri
+// sampai ketemu hop pertama yang bukan trusted proxy.
+func ResolveClientIP(remoteAddr, header, value string, trusted []*net.IPNet) string {
+	remoteIP := parseHostIP(remoteAddr)
+	if remoteIP == nil {
+		return remoteAddr
+	}
+	if !isTrustedProxy(remoteIP, trusted) || strings.TrimSpace(value) == "" {
+		return remoteIP.String()
+	}
+
+	var chain []string
+	switch header {
+	case ProxyHeaderForwarded:
+		chain = parseForwardedFor(value)
+	case ProxyHeaderXForwardedFor:
+		for _, hop := range strings.Split(value, ",") {
+			chain = append(chain, strings.TrimSpace(hop))
+		}
+	case ProxyHeaderXRealIP, ProxyHeaderCFConnectingIP:
+		// header satu nilai yang ditimpa proxy, bukan ditambahkan
+		if ip := parseHostIP(value); ip != nil {
+			return ip.String()
+		}
+		return remoteIP.String()
+	default:
+		return remoteIP.String()
+	}
+
+	client := remoteIP
+	for i := len(chain) - 1; i >= 0; i-- {
+		hop := parseHostIP(chain[i])
+		if hop == nil {
+			// hop tidak valid (mis. "unknown"), tidak bisa menelusuri lebih jauh
+			break
+		}
+		client = hop
+		if !isTrustedProxy(hop, trusted) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+// parseForwardedFor mengambil semua parameter for= dari header Forwarded (RFC 7239)
+func parseForwardedFor(header string) []string {
+	var hops []string
+	for _, element := range strings.Split(header, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if !found || !strings.EqualFold(strings.TrimSpace(key), "for") {
+				continue
+			}
+			hops = append(hops, strings.Trim(strings.TrimSpace(value), `"`))
+		}
+	}
+	return hops
+}
+
+// parseHostIP menerima IP dengan atau tanpa port, termasuk format IPv6 "[::1]:8080"
+func parseHostIP(value string) net.IP {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	if ip := net.ParseIP(value); ip != nil {
+		return ip
+	}
+
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		return net.ParseIP(strings.Trim(host, "[]"))
+	}
+
+	return net.ParseIP(strings.Trim(value, "[]"))
+}
+
+// ClientIP mengembalikan IP client yang sudah di-resolve oleh ClientIPMiddleware
+func ClientIP(c *fiber.Ctx) string {
+	if ip, ok := c.Locals("client_ip").(string); ok && ip != "" {
+		return ip
+	}
+	return c.IP()
+}
@@ -0,0 +1,130 @@
+package utils
+
+import "testing"
+
+func TestResolveClientIP(t *testing.T) {
+	trusted := ParseTrustedProxies([]string{"10.0.0.0/8", "2001:db8::/32", "127.0.0.1"})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		value      string
+		want       string
+	}{
+		{
+			name:       "direct client ignores forwarded header",
+			remoteAddr: "203.0.113.7:51234",
+			header:     ProxyHeaderXForwardedFor,
+			value:      "10.0.0.1",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "trusted proxy single hop",
+			remoteAddr: "10.0.0.2:443",
+			header:     ProxyHeaderXForwardedFor,
+			value:      "198.51.100.20",
+			want:       "198.51.100.20",
+		},
+		{
+			name:       "spoofed XFF entry left of the real client",
+			remoteAddr: "10.0.0.2:443",
+			header:     ProxyHeaderXForwardedFor,
+			value:      "10.0.0.1, 198.51.100.20",
+			want:       "198.51.100.20",
+		},
+		{
+			name:       "multi-hop through trusted proxies",
+			remoteAddr: "10.0.0.2:443",
+			header:     ProxyHeaderXForwardedFor,
+			value:      "198.51.100.20, 10.1.1.1, 10.2.2.2",
+			want:       "198.51.100.20",
+		},
+		{
+			name:       "invalid hop stops the walk",
+			remoteAddr: "10.0.0.2:443",
+			header:     ProxyHeaderXForwardedFor,
+			value:      "198.51.100.20, unknown, 10.2.2.2",
+			want:       "10.2.2.2",
+		},
+		{
+			name:       "empty header falls back to remote address",
+			remoteAddr: "10.0.0.2:443",
+			header:     ProxyHeaderXForwardedFor,
+			value:      "",
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "forwarded header chain",
+			remoteAddr: "10.0.0.2:443",
+			header:     ProxyHeaderForwarded,
+			value:      `for=10.0.0.1, for=198.51.100.20;proto=https`,
+			want:       "198.51.100.20",
+		},
+		{
+			name:       "forwarded header with quoted IPv6 and port",
+			remoteAddr: "10.0.0.2:443",
+			header:     ProxyHeaderForwarded,
+			value:      `for="[2001:db8:cafe::17]:4711", for="[2a00:1450::1]"`,
+			want:       "2a00:1450::1",
+		},
+		{
+			name:       "IPv6 remote and IPv6 client in XFF",
+			remoteAddr: "[2001:db8::1]:443",
+			header:     ProxyHeaderXForwardedFor,
+			value:      "2a00:1450:4001::200e",
+			want:       "2a00:1450:4001::200e",
+		},
+		{
+			name:       "untrusted IPv6 remote ignores header",
+			remoteAddr: "[2a00:1450::9]:443",
+			header:     ProxyHeaderXForwardedFor,
+			value:      "10.0.0.1",
+			want:       "2a00:1450::9",
+		},
+		{
+			name:       "single value header from trusted proxy",
+			remoteAddr: "127.0.0.1:80",
+			header:     ProxyHeaderXRealIP,
+			value:      "198.51.100.20",
+			want:       "198.51.100.20",
+		},
+		{
+			name:       "invalid single value header falls back",
+			remoteAddr: "127.0.0.1:80",
+			header:     ProxyHeaderCFConnectingIP,
+			value:      "not-an-ip",
+			want:       "127.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ResolveClientIP(tt.remoteAddr, tt.header, tt.value, trusted)
+			if got != tt.want {
+				t.Errorf("ResolveClientIP(%q, %q, %q) = %q, want %q", tt.remoteAddr, tt.header, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProxyHeader(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"x-forwarded-for", ProxyHeaderXForwardedFor, true},
+		{" Forwarded ", ProxyHeaderForwarded, true},
+		{"CF-CONNECTING-IP", ProxyHeaderCFConnectingIP, true},
+		{"X-Client-IP", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseProxyHeader(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseProxyHeader(%q) = %q, %v, want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
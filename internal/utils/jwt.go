@@ -10,6 +10,8 @@ import (
 
 type Claims struct {
 	UserID string `json:"user_id"`
+	// ImpersonatedBy diisi ID admin jika token dibuat lewat impersonate
+	ImpersonatedBy string `json:"impersonated_by,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -36,6 +38,34 @@ func GenerateJWT(userID string) (string, error) {
 	return tokenString, nil
 }
 
+// GenerateImpersonationJWT token singkat untuk admin yang login sebagai user lain.
+// sessionID (jti) menunjuk ke catatan di impersonation_sessions.
+func GenerateImpersonationJWT(userID, adminID, sessionID string, expiresAt time.Time) (string, error) {
+	cfg := config.Load()
+
+	now := time.Now()
+	claims := Claims{
+		UserID:         userID,
+		ImpersonatedBy: adminID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "ujikom-backend",
+			Subject:   userID,
+			ID:        sessionID,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(cfg.JWTSecret))
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
 func ValidateJWT(tokenString string) (jwt.MapClaims, error) {
 	cfg := config.Load()
 	
@@ -38,4 +38,13 @@ func ValidationErrorResponse(c *fiber.Ctx, errors []string) error {
 		Data:      map[string]interface{}{"errors": errors},
 		Timestamp: time.Now().UTC().Format("2006-01-02 15:04:05"),
 	})
-}
\ No newline at end of file
+}
+// ErrorResponseWithData untuk error yang perlu detail tambahan, misalnya jendela waktu absensi
+func ErrorResponseWithData(c *fiber.Ctx, statusCode int, message string, data interface{}) error {
+	return c.Status(statusCode).JSON(APIResponse{
+		Success:   false,
+		Message:   message,
+		Data:      data,
+		Timestamp: time.Now().UTC().Format("2006-01-02 15:04:05"),
+	})
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"ujikom-backend/internal/config"
+)
+
+// Tujuan kunci turunan SigningKey. Setiap jenis link punya kunci sendiri sehingga
+// signature link selfie tidak bisa dipakai sebagai link laporan atau ticket stream.
+const (
+	SigningPurposeReport   = "report-download"
+	SigningPurposeSelfie   = "selfie-download"
+	SigningPurposeEvidence = "evidence-download"
+	SigningPurposeTicket   = "stream-ticket"
+)
+
+// SigningKey menurunkan kunci HMAC untuk satu tujuan dari SIGNING_SECRET
+func SigningKey(cfg *config.Config, purpose string) string {
+	mac := hmac.New(sha256.New, []byte(cfg.SigningSecret))
+	mac.Write([]byte(purpose))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignPath menambahkan expires dan signature (HMAC-SHA256) ke path sehingga link
+// bisa dibuka langsung di browser tanpa header Authorization
+func SignPath(secret, path string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", pathSignature(secret, path, expires))
+	return path + "?" + query.Encode()
+}
+
+// VerifyPathSignature mengecek signature dari SignPath dan masa berlakunya
+func VerifyPathSignature(secret, path, expires, signature string) bool {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+
+	expected := pathSignature(secret, path, expires)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func pathSignature(secret, path, expires string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(path + ":" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Ticket adalah isi ticket stream yang sudah diverifikasi
+type Ticket struct {
+	Subject   string
+	ID        string
+	ExpiresAt time.Time
+}
+
+// SignTicket membuat token pendek "subject.id.expires.signature" untuk koneksi yang tidak
+// bisa mengirim header Authorization, mis. EventSource dan WebSocket di browser. id acak
+// dipakai server untuk menandai ticket yang sudah dipakai.
+func SignTicket(secret, subject, id string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	return subject + "." + id + "." + expires + "." + pathSignature(secret, "ticket:"+subject+":"+id, expires)
+}
+
+// VerifyTicket mengembalikan isi ticket jika signature valid dan belum kedaluwarsa.
+// Pemakaian ulang dicek terpisah oleh pemanggil.
+func VerifyTicket(secret, ticket string) (*Ticket, bool) {
+	parts := strings.Split(ticket, ".")
+	if len(parts) != 4 || parts[1] == "" {
+		return nil, false
+	}
+	if !VerifyPathSignature(secret, "ticket:"+parts[0]+":"+parts[1], parts[2], parts[3]) {
+		return nil, false
+	}
+	expires, _ := strconv.ParseInt(parts[2], 10, 64)
+	return &Ticket{Subject: parts[0], ID: parts[1], ExpiresAt: time.Unix(expires, 0).UTC()}, true
+}
+
+// SignWebhook menghasilkan nilai header X-Webhook-Signature: "sha256=" + hex HMAC-SHA256
+// atas "<timestamp>.<body>" dengan secret webhook
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
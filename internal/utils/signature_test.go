@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"ujikom-backend/internal/config"
+)
+
+func TestSigningKeySeparatesPurposes(t *testing.T) {
+	cfg := &config.Config{JWTSecret: "jwt-secret", SigningSecret: "signing-secret"}
+	path := "/api/v1/downloads/reports/64b000000000000000000001"
+
+	signed := SignPath(SigningKey(cfg, SigningPurposeReport), path, time.Now().Add(time.Minute))
+	query, err := url.ParseQuery(signed[strings.Index(signed, "?")+1:])
+	if err != nil {
+		t.Fatalf("failed to parse signed path %q: %v", signed, err)
+	}
+	expires, signature := query.Get("expires"), query.Get("signature")
+
+	tests := []struct {
+		name   string
+		secret string
+		want   bool
+	}{
+		{"same purpose", SigningKey(cfg, SigningPurposeReport), true},
+		{"selfie key", SigningKey(cfg, SigningPurposeSelfie), false},
+		{"ticket key", SigningKey(cfg, SigningPurposeTicket), false},
+		{"jwt secret", cfg.JWTSecret, false},
+		{"raw signing secret", cfg.SigningSecret, false},
+	}
+
+	for _, tt := range tests {
+		if got := VerifyPathSignature(tt.secret, path, expires, signature); got != tt.want {
+			t.Errorf("%s: VerifyPathSignature = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyPathSignatureExpired(t *testing.T) {
+	signed := SignPath("secret", "/path", time.Now().Add(-time.Second))
+	query, _ := url.ParseQuery(signed[strings.Index(signed, "?")+1:])
+
+	if VerifyPathSignature("secret", "/path", query.Get("expires"), query.Get("signature")) {
+		t.Error("expired signature must be rejected")
+	}
+}
+
+func TestVerifyTicket(t *testing.T) {
+	key := SigningKey(&config.Config{SigningSecret: "signing-secret"}, SigningPurposeTicket)
+	expiresAt := time.Now().Add(time.Minute)
+	ticket := SignTicket(key, "user-1", "ticket-1", expiresAt)
+
+	verified, ok := VerifyTicket(key, ticket)
+	if !ok || verified.Subject != "user-1" || verified.ID != "ticket-1" || verified.ExpiresAt.Unix() != expiresAt.Unix() {
+		t.Fatalf("VerifyTicket = %+v, %v, want user-1 ticket-1", verified, ok)
+	}
+
+	tests := []struct {
+		name   string
+		ticket string
+	}{
+		{"swapped subject", "user-2" + ticket[len("user-1"):]},
+		{"swapped id", "user-1.ticket-2" + ticket[len("user-1.ticket-1"):]},
+		{"missing id", strings.Replace(ticket, ".ticket-1", "", 1)},
+		{"expired", SignTicket(key, "user-1", "ticket-1", time.Now().Add(-time.Second))},
+		{"other key", SignTicket(SigningKey(&config.Config{SigningSecret: "signing-secret"}, SigningPurposeSelfie), "user-1", "ticket-1", expiresAt)},
+	}
+	for _, tt := range tests {
+		if _, ok := VerifyTicket(key, tt.ticket); ok {
+			t.Errorf("%s: ticket must be rejected", tt.name)
+		}
+	}
+}
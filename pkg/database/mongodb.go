@@ -2,8 +2,10 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -62,8 +64,22 @@ func Connect(uri, dbName string) (*mongo.Database, error) {
 }
 
 func createAtlasIndexes(ctx context.Context, db *mongo.Database) error {
-	userCollection := db.Collection("users")
-	
+	// setiap index dibuat sendiri-sendiri supaya satu index yang gagal (mis. karena data
+	// lama bentrok) tidak membuat index lain ikut tidak terbuat
+	var failed []string
+	create := func(collection string, indexes ...mongo.IndexModel) {
+		for _, index := range indexes {
+			name := collection
+			if index.Options != nil && index.Options.Name != nil {
+				name += "." + *index.Options.Name
+			}
+			if _, err := db.Collection(collection).Indexes().CreateOne(ctx, index); err != nil {
+				log.Printf("Warning: failed to create index %s: %v", name, err)
+				failed = append(failed, name)
+			}
+		}
+	}
+
 	// Create unique index for email field
 	emailIndex := mongo.IndexModel{
 		Keys:    bson.D{{Key: "email", Value: 1}},
@@ -106,15 +122,507 @@ func createAtlasIndexes(ctx context.Context, db *mongo.Database) error {
 	}
 
 	// Create all indexes
-	_, err := userCollection.Indexes().CreateMany(ctx, indexes)
-	if err != nil {
-		return fmt.Errorf("failed to create Atlas indexes: %v", err)
+	create("users", indexes...)
+
+	// Satu nilai allowlist hanya boleh muncul sekali per tipe
+	policyIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "type", Value: 1}, {Key: "value", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("type_value_unique"),
+	}
+
+	create("network_policies", policyIndex)
+
+	deviceIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "device_id", Value: 1}, {Key: "status", Value: 1}},
+			Options: options.Index().SetName("device_status"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}},
+			Options: options.Index().SetName("user_status"),
+		},
+		// Satu device hanya boleh terikat aktif ke satu akun
+		{
+			Keys: bson.D{{Key: "device_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("active_device_unique").
+				SetPartialFilterExpression(bson.M{"status": "active"}),
+		},
+		// Slot 0..MAX_DEVICES_PER_USER-1 membatasi jumlah device aktif per user
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "slot", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("active_user_slot_unique").
+				SetPartialFilterExpression(bson.M{"status": "active", "slot": bson.M{"$exists": true}}),
+		},
+	}
+
+	create("devices", deviceIndexes...)
+
+	// Nonce request bertanda tangan device, dihapus setelah melewati rentang timestamp
+	nonceIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "device_id", Value: 1}, {Key: "nonce", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("device_nonce_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("created_at_ttl").SetExpireAfterSeconds(15 * 60),
+		},
+	}
+
+	create("device_nonces", nonceIndexes...)
+
+	// nonce check in offline: satu per siswa, device, dan hari; dihapus setelah kedaluwarsa
+	offlineNonceIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "device_id", Value: 1}, {Key: "date_key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("user_device_date_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl"),
+		},
+	}
+
+	create("offline_nonces", offlineNonceIndexes...)
+
+	attemptIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		Options: options.Index().SetName("user_created_at"),
+	}
+
+	create("device_attempts", attemptIndex)
+
+	kioskIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "api_key_hash", Value: 1}},
+		Options: options.Index().SetName("api_key_hash"),
+	}
+
+	create("kiosks", kioskIndex)
+
+	// Token QR hanya bisa dipakai sekali per siswa, data scan dihapus otomatis setelah 1 jam
+	kioskScanIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "kiosk_id", Value: 1},
+				{Key: "window", Value: 1},
+				{Key: "user_id", Value: 1},
+				{Key: "action", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetName("kiosk_window_user_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(3600).SetName("created_at_ttl"),
+		},
+	}
+
+	create("kiosk_scans", kioskScanIndexes...)
+
+	correctionIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "date", Value: 1}, {Key: "status", Value: 1}},
+			Options: options.Index().SetName("user_date_status"),
+		},
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("status_created_at"),
+		},
+	}
+
+	create("correction_requests", correctionIndexes...)
+
+	subjectIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("code_unique"),
+	}
+
+	create("subjects", subjectIndex)
+
+	// Satu kelas hanya punya satu pelajaran aktif per jam ke-N di hari yang sama
+	periodIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "class_id", Value: 1},
+				{Key: "day_of_week", Value: 1},
+				{Key: "period_number", Value: 1},
+			},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"is_active": true}).
+				SetName("class_day_period_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "teacher_id", Value: 1}, {Key: "day_of_week", Value: 1}},
+			Options: options.Index().SetName("teacher_day"),
+		},
+	}
+
+	create("timetable_periods", periodIndexes...)
+
+	sessionIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "period_id", Value: 1}, {Key: "date", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("period_date_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "teacher_id", Value: 1}, {Key: "date", Value: 1}},
+			Options: options.Index().SetName("teacher_date"),
+		},
+		{
+			Keys:    bson.D{{Key: "subject_id", Value: 1}, {Key: "class_id", Value: 1}, {Key: "date", Value: 1}},
+			Options: options.Index().SetName("subject_class_date"),
+		},
+		{
+			Keys:    bson.D{{Key: "class_id", Value: 1}, {Key: "date", Value: 1}, {Key: "status", Value: 1}},
+			Options: options.Index().SetName("class_date_status"),
+		},
+	}
+
+	create("lesson_sessions", sessionIndexes...)
+
+	lessonAttendanceIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "session_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("session_user_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "date", Value: 1}},
+			Options: options.Index().SetName("user_date"),
+		},
+	}
+
+	create("lesson_attendances", lessonAttendanceIndexes...)
+
+	academicYearIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("name_unique"),
+	}
+
+	create("academic_years", academicYearIndex)
+
+	semesterIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "academic_year_id", Value: 1}, {Key: "number", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("year_number_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "start_date", Value: 1}, {Key: "end_date", Value: 1}},
+			Options: options.Index().SetName("date_range"),
+		},
+	}
+
+	create("semesters", semesterIndexes...)
+
+	termIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "term_id", Value: 1}, {Key: "date", Value: -1}},
+		Options: options.Index().SetName("user_term_date"),
+	}
+
+	create("attendances", termIndex)
+
+	// dashboard kelas mencari record berdasarkan daftar siswa ($in) + rentang tanggal,
+	// rekap sekolah per hari memakai date + status
+	attendanceIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "date", Value: -1}},
+			Options: options.Index().SetName("user_date"),
+		},
+		{
+			Keys:    bson.D{{Key: "date", Value: 1}, {Key: "status", Value: 1}},
+			Options: options.Index().SetName("date_status"),
+		},
+	}
+
+	create("attendances", attendanceIndexes...)
+
+	// satu record per siswa per hari sekolah. Record lama tanpa date_key (belum
+	// dimigrasi atau bentrok) tidak ikut dicek.
+	attendanceDayIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "date_key", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"date_key": bson.M{"$type": "string"}}).
+			SetName("user_date_key_unique"),
+	}
+
+	// biasanya gagal karena data lama belum dimigrasi, lihat RequireIndexes
+	create("attendances", attendanceDayIndex)
+
+	idempotencyIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl"),
+	}
+
+	create("idempotency_keys", idempotencyIndex)
+
+	// ticket stream yang sudah dipakai cukup disimpan sampai ticketnya kedaluwarsa
+	streamTicketIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl"),
+	}
+
+	create("stream_tickets", streamTicketIndex)
+
+	promotionIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "academic_year_id", Value: 1}},
+		Options: options.Index().SetName("user_academic_year"),
+	}
+
+	create("class_promotions", promotionIndex)
+
+	majorIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("code_unique"),
+	}
+
+	create("majors", majorIndex)
+
+	// nama kelas unik tanpa membedakan huruf besar/kecil, satu guru hanya wali satu kelas
+	classIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetCollation(&options.Collation{Locale: "en", Strength: 2}).
+				SetName("name_unique"),
+		},
+		{
+			Keys: bson.D{{Key: "homeroom_teacher_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"homeroom_teacher_id": bson.M{"$exists": true}}).
+				SetName("homeroom_teacher_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "major_id", Value: 1}, {Key: "grade", Value: 1}},
+			Options: options.Index().SetName("major_grade"),
+		},
+	}
+
+	create("classes", classIndexes...)
+
+	userClassIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "class_id", Value: 1}, {Key: "role", Value: 1}},
+		Options: options.Index().SetName("class_role"),
+	}
+
+	create("users", userClassIndex)
+
+	reportJobIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("status_created"),
+		},
+		{
+			Keys:    bson.D{{Key: "requested_by", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("requested_by_created"),
+		},
+	}
+
+	create("report_jobs", reportJobIndexes...)
+
+	// event real-time hanya disimpan sehari untuk replay Last-Event-ID
+	attendanceEventIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("created_at_ttl").SetExpireAfterSeconds(24 * 60 * 60),
+		},
+		{
+			Keys:    bson.D{{Key: "class_id", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("class_id"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: 1}},
+			Options: options.Index().SetName("user_id"),
+		},
+	}
+
+	create("attendance_events", attendanceEventIndexes...)
+
+	webhookIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "events", Value: 1}, {Key: "is_active", Value: 1}},
+		Options: options.Index().SetName("events_active"),
+	}
+
+	create("webhooks", webhookIndex)
+
+	// log delivery disimpan 30 hari
+	webhookDeliveryIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+			Options: options.Index().SetName("status_next_attempt"),
+		},
+		{
+			Keys:    bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("webhook_created"),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("created_at_ttl").SetExpireAfterSeconds(30 * 24 * 60 * 60),
+		},
+	}
+
+	create("webhook_deliveries", webhookDeliveryIndexes...)
+
+	notificationPreferenceIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("user_unique"),
+	}
+
+	create("notification_preferences", notificationPreferenceIndex)
+
+	// outbox notifikasi disimpan 30 hari
+	notificationIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "send_after", Value: 1}},
+			Options: options.Index().SetName("status_send_after"),
+		},
+		{
+			Keys:    bson.D{{Key: "student_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("student_created"),
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("created_at_ttl").SetExpireAfterSeconds(30 * 24 * 60 * 60),
+		},
+	}
+
+	create("notifications", notificationIndexes...)
+
+	guardianInviteIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "code_hash", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("code_hash_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "student_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("student_created"),
+		},
+	}
+
+	create("guardian_invites", guardianInviteIndexes...)
+
+	// satu orang tua hanya sekali ditautkan ke siswa yang sama
+	guardianLinkIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "guardian_id", Value: 1}, {Key: "student_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("guardian_student_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "student_id", Value: 1}},
+			Options: options.Index().SetName("student_id"),
+		},
+	}
+
+	create("guardian_links", guardianLinkIndexes...)
+
+	leaveIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "student_id", Value: 1}, {Key: "from", Value: 1}},
+			Options: options.Index().SetName("student_from"),
+		},
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("status_created"),
+		},
+	}
+
+	create("leave_requests", leaveIndexes...)
+
+	// NIS unik per siswa dan dipakai import untuk mencocokkan baris file dengan akun.
+	// Index lama non-unik diganti; akun tanpa NIS (guru, admin) tidak ikut dicek.
+	if _, err := db.Collection("users").Indexes().DropOne(ctx, "nis"); err != nil && !isIndexNotFound(err) {
+		log.Printf("Warning: failed to drop old users.nis index: %v", err)
+	}
+	create("users", mongo.IndexModel{
+		Keys: bson.D{{Key: "nis", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"nis": bson.M{"$type": "string"}}).
+			SetName("nis_unique"),
+	})
+
+	passwordSetupIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("token_hash_unique"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().SetName("user_id"),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0).SetName("expires_at_ttl"),
+		},
+	}
+
+	create("password_setup_tokens", passwordSetupIndexes...)
+
+	// audit impersonate tidak punya TTL, dicari per admin atau per user
+	impersonationIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "admin_id", Value: 1}, {Key: "started_at", Value: -1}},
+			Options: options.Index().SetName("admin_started"),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "started_at", Value: -1}},
+			Options: options.Index().SetName("user_started"),
+		},
+	}
+
+	create("impersonation_sessions", impersonationIndexes...)
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d index(es) not created: %s", len(failed), strings.Join(failed, ", "))
 	}
 
 	log.Println("MongoDB Atlas indexes created successfully")
 	return nil
 }
 
+// requiredIndexes dipakai aplikasi untuk menjamin data tetap konsisten, API tidak boleh
+// jalan tanpanya
+var requiredIndexes = []struct {
+	collection string
+	name       string
+	hint       string
+}{
+	{"attendances", "user_date_key_unique", "run the rebucket_attendance_dates migration"},
+}
+
+// RequireIndexes memastikan semua index wajib sudah ada. Dipanggil saat API start,
+// bukan oleh cmd/migrate karena migrasi justru dipakai untuk memperbaiki datanya.
+func RequireIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, required := range requiredIndexes {
+		specs, err := db.Collection(required.collection).Indexes().ListSpecifications(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list %s indexes: %v", required.collection, err)
+		}
+
+		found := false
+		for _, spec := range specs {
+			if spec.Name == required.name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("required index %s.%s is missing, %s", required.collection, required.name, required.hint)
+		}
+	}
+	return nil
+}
+
+// isIndexNotFound true jika index yang akan di-drop memang belum ada
+func isIndexNotFound(err error) bool {
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && (commandErr.Code == 27 || commandErr.Name == "IndexNotFound" || commandErr.Name == "NamespaceNotFound")
+}
+
 func logAtlasInfo(ctx context.Context, client *mongo.Client, dbName string) error {
 	// Get server status
 	var serverStatus bson.M